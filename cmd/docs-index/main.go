@@ -0,0 +1,48 @@
+// docs-index builds search indexes of documentation into a cache directory,
+// so mcp-vmanomaly started with MCP_DOCS_INDEX_CACHE_DIR pointing to it doesn't index docs on startup.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/embedding"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/resources"
+)
+
+func main() {
+	cacheDir := flag.String("cache-dir", "", "Directory to write indexes to, the same as MCP_DOCS_INDEX_CACHE_DIR of the server")
+	versionsDir := flag.String("versions-dir", "", "Directory with extra doc sets, the same as MCP_DOCS_VERSIONS_DIR of the server")
+	extra := flag.String("extra", "", "Comma-separated extra docs, the same as MCP_DOCS_EXTRA of the server")
+	hybrid := flag.Bool("hybrid", false, "Also compute chunk embeddings for hybrid search, the same as MCP_DOCS_SEARCH_MODE=hybrid of the server")
+	embedderCommand := flag.String("embedder-command", "", "Local embedder command, the same as MCP_DOCS_EMBEDDER_COMMAND of the server")
+	embedderModel := flag.String("embedder-model", "", "Embedding model name, the same as MCP_DOCS_EMBEDDER_MODEL of the server")
+	flag.Parse()
+	if *cacheDir == "" {
+		fmt.Fprintln(os.Stderr, "-cache-dir is required")
+		os.Exit(2)
+	}
+
+	opts := resources.DocsOptions{VersionsDir: *versionsDir, IndexCacheDir: *cacheDir}
+	for _, p := range strings.Split(*extra, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			opts.ExtraDocs = append(opts.ExtraDocs, p)
+		}
+	}
+	if *hybrid {
+		opts.Embedder = embedding.New(*embedderCommand, *embedderModel)
+	}
+	start := time.Now()
+	if err := resources.InitDocs(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing docs: %v\n", err)
+		os.Exit(1)
+	}
+	if err := resources.BuildIndexes(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error building indexes: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Built docs indexes in %s in %s\n", *cacheDir, time.Since(start).Round(time.Millisecond))
+}
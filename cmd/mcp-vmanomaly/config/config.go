@@ -19,6 +19,25 @@ type Config struct {
 	logFile           string
 	bearerToken       string
 	customHeaders     map[string]string
+	docsVersion       string
+	docsVersionsDir   string
+	docsExtra         []string
+	docsWatchInterval time.Duration
+	docsIndexCacheDir string
+	docsSearchMode    string
+	docsEmbedderCmd   string
+	docsEmbedderModel string
+
+	subscriptionPollInterval  time.Duration
+	subscriptionMaxPerSession int
+	subscriptionMaxPollRate   float64
+
+	taskRegistryFile     string
+	taskRegistryMaxTasks int
+
+	cacheTTL time.Duration
+
+	outputDir string
 }
 
 func parseCustomHeaders(headersEnv string) map[string]string {
@@ -84,6 +103,88 @@ func InitConfig() (*Config, error) {
 
 	customHeadersMap := parseCustomHeaders(os.Getenv("VMANOMALY_HEADERS"))
 
+	// Parse extra docs
+	var docsExtra []string
+	for _, p := range strings.Split(os.Getenv("MCP_DOCS_EXTRA"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			docsExtra = append(docsExtra, p)
+		}
+	}
+
+	// Parse docs watch interval
+	var docsWatchInterval time.Duration
+	if docsWatchIntervalStr := os.Getenv("MCP_DOCS_WATCH_INTERVAL"); docsWatchIntervalStr != "" {
+		interval, err := time.ParseDuration(docsWatchIntervalStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse MCP_DOCS_WATCH_INTERVAL: %w", err)
+		}
+		if interval < 0 {
+			return nil, fmt.Errorf("MCP_DOCS_WATCH_INTERVAL must be non-negative")
+		}
+		docsWatchInterval = interval
+	}
+
+	// Parse subscription limits
+	subscriptionPollInterval := 5 * time.Second
+	if subscriptionPollIntervalStr := os.Getenv("MCP_SUBSCRIPTION_POLL_INTERVAL"); subscriptionPollIntervalStr != "" {
+		interval, err := time.ParseDuration(subscriptionPollIntervalStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse MCP_SUBSCRIPTION_POLL_INTERVAL: %w", err)
+		}
+		if interval != 0 && interval < time.Second {
+			return nil, fmt.Errorf("MCP_SUBSCRIPTION_POLL_INTERVAL must be 0 or at least 1s")
+		}
+		subscriptionPollInterval = interval
+	}
+	subscriptionMaxPerSession := 10
+	if subscriptionMaxPerSessionStr := os.Getenv("MCP_SUBSCRIPTION_MAX_PER_SESSION"); subscriptionMaxPerSessionStr != "" {
+		limit, err := strconv.Atoi(subscriptionMaxPerSessionStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse MCP_SUBSCRIPTION_MAX_PER_SESSION: %w", err)
+		}
+		if limit <= 0 {
+			return nil, fmt.Errorf("MCP_SUBSCRIPTION_MAX_PER_SESSION must be positive")
+		}
+		subscriptionMaxPerSession = limit
+	}
+	subscriptionMaxPollRate := 5.0
+	if subscriptionMaxPollRateStr := os.Getenv("MCP_SUBSCRIPTION_MAX_POLL_RATE"); subscriptionMaxPollRateStr != "" {
+		rate, err := strconv.ParseFloat(subscriptionMaxPollRateStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse MCP_SUBSCRIPTION_MAX_POLL_RATE: %w", err)
+		}
+		if rate <= 0 {
+			return nil, fmt.Errorf("MCP_SUBSCRIPTION_MAX_POLL_RATE must be positive")
+		}
+		subscriptionMaxPollRate = rate
+	}
+
+	// Parse task registry size
+	taskRegistryMaxTasks := 500
+	if taskRegistryMaxTasksStr := os.Getenv("MCP_TASK_REGISTRY_MAX_TASKS"); taskRegistryMaxTasksStr != "" {
+		limit, err := strconv.Atoi(taskRegistryMaxTasksStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse MCP_TASK_REGISTRY_MAX_TASKS: %w", err)
+		}
+		if limit <= 0 {
+			return nil, fmt.Errorf("MCP_TASK_REGISTRY_MAX_TASKS must be positive")
+		}
+		taskRegistryMaxTasks = limit
+	}
+
+	// Parse response cache TTL
+	cacheTTL := 5 * time.Minute
+	if cacheTTLStr := os.Getenv("MCP_CACHE_TTL"); cacheTTLStr != "" {
+		ttl, err := time.ParseDuration(cacheTTLStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse MCP_CACHE_TTL: %w", err)
+		}
+		if ttl < 0 {
+			return nil, fmt.Errorf("MCP_CACHE_TTL must be non-negative")
+		}
+		cacheTTL = ttl
+	}
+
 	result := &Config{
 		vmanomalyEndpoint: os.Getenv("VMANOMALY_ENDPOINT"),
 		serverMode:        strings.ToLower(os.Getenv("MCP_SERVER_MODE")),
@@ -95,6 +196,25 @@ func InitConfig() (*Config, error) {
 		logFile:           os.Getenv("MCP_LOG_FILE"),
 		bearerToken:       os.Getenv("VMANOMALY_BEARER_TOKEN"),
 		customHeaders:     customHeadersMap,
+		docsVersion:       os.Getenv("MCP_DOCS_VERSION"),
+		docsVersionsDir:   os.Getenv("MCP_DOCS_VERSIONS_DIR"),
+		docsExtra:         docsExtra,
+		docsWatchInterval: docsWatchInterval,
+		docsIndexCacheDir: os.Getenv("MCP_DOCS_INDEX_CACHE_DIR"),
+		docsSearchMode:    strings.ToLower(os.Getenv("MCP_DOCS_SEARCH_MODE")),
+		docsEmbedderCmd:   os.Getenv("MCP_DOCS_EMBEDDER_COMMAND"),
+		docsEmbedderModel: os.Getenv("MCP_DOCS_EMBEDDER_MODEL"),
+
+		subscriptionPollInterval:  subscriptionPollInterval,
+		subscriptionMaxPerSession: subscriptionMaxPerSession,
+		subscriptionMaxPollRate:   subscriptionMaxPollRate,
+
+		taskRegistryFile:     os.Getenv("MCP_TASK_REGISTRY_FILE"),
+		taskRegistryMaxTasks: taskRegistryMaxTasks,
+
+		cacheTTL: cacheTTL,
+
+		outputDir: os.Getenv("MCP_OUTPUT_DIR"),
 	}
 
 	// Validate required config
@@ -112,6 +232,18 @@ func InitConfig() (*Config, error) {
 		return nil, fmt.Errorf("MCP_LOG_LEVEL must be 'debug', 'info', 'warn', or 'error'")
 	}
 
+	// Validate docs search mode
+	if result.docsSearchMode != "" && result.docsSearchMode != "fulltext" && result.docsSearchMode != "hybrid" {
+		return nil, fmt.Errorf("MCP_DOCS_SEARCH_MODE must be 'fulltext' or 'hybrid'")
+	}
+
+	// Validate output directory
+	if result.outputDir != "" {
+		if info, err := os.Stat(result.outputDir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("MCP_OUTPUT_DIR must be an existing directory")
+		}
+	}
+
 	// Default values
 	if result.serverMode == "" {
 		result.serverMode = "stdio"
@@ -122,6 +254,9 @@ func InitConfig() (*Config, error) {
 	if result.logLevel == "" {
 		result.logLevel = "info"
 	}
+	if result.docsSearchMode == "" {
+		result.docsSearchMode = "fulltext"
+	}
 
 	return result, nil
 }
@@ -181,3 +316,69 @@ func (c *Config) BearerToken() string {
 func (c *Config) CustomHeaders() map[string]string {
 	return c.customHeaders
 }
+
+func (c *Config) DocsVersion() string {
+	return c.docsVersion
+}
+
+func (c *Config) DocsVersionsDir() string {
+	return c.docsVersionsDir
+}
+
+func (c *Config) DocsExtra() []string {
+	return c.docsExtra
+}
+
+func (c *Config) DocsWatchInterval() time.Duration {
+	return c.docsWatchInterval
+}
+
+func (c *Config) DocsIndexCacheDir() string {
+	return c.docsIndexCacheDir
+}
+
+func (c *Config) DocsHybridSearch() bool {
+	return c.docsSearchMode == "hybrid"
+}
+
+func (c *Config) DocsEmbedderCommand() string {
+	return c.docsEmbedderCmd
+}
+
+func (c *Config) DocsEmbedderModel() string {
+	return c.docsEmbedderModel
+}
+
+// SubscriptionsEnabled reports whether task resources can be subscribed to
+func (c *Config) SubscriptionsEnabled() bool {
+	return !c.disableResources && c.subscriptionPollInterval > 0
+}
+
+func (c *Config) SubscriptionPollInterval() time.Duration {
+	return c.subscriptionPollInterval
+}
+
+func (c *Config) SubscriptionMaxPerSession() int {
+	return c.subscriptionMaxPerSession
+}
+
+func (c *Config) SubscriptionMaxPollRate() float64 {
+	return c.subscriptionMaxPollRate
+}
+
+func (c *Config) TaskRegistryFile() string {
+	return c.taskRegistryFile
+}
+
+func (c *Config) TaskRegistryMaxTasks() int {
+	return c.taskRegistryMaxTasks
+}
+
+func (c *Config) CacheTTL() time.Duration {
+	return c.cacheTTL
+}
+
+// OutputDir is the directory tools may write generated files to, file output is disabled if empty
+func (c *Config) OutputDir() string {
+	return c.outputDir
+}
@@ -413,3 +413,174 @@ func TestInitConfig(t *testing.T) {
 		}
 	})
 }
+
+func TestInitConfig_Docs(t *testing.T) {
+	t.Setenv("VMANOMALY_ENDPOINT", "http://localhost:8490")
+	t.Setenv("MCP_DOCS_VERSION", "v1.24.0")
+	t.Setenv("MCP_DOCS_EXTRA", "/opt/runbooks, /opt/guides.tar.gz,")
+	t.Setenv("MCP_DOCS_WATCH_INTERVAL", "30s")
+	t.Setenv("MCP_DOCS_INDEX_CACHE_DIR", "/var/cache/docs-index")
+
+	cfg, err := InitConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.DocsVersion() != "v1.24.0" {
+		t.Errorf("Expected docs version 'v1.24.0', got: %s", cfg.DocsVersion())
+	}
+	if extra := cfg.DocsExtra(); len(extra) != 2 || extra[1] != "/opt/guides.tar.gz" {
+		t.Errorf("Unexpected extra docs: %q", extra)
+	}
+	if cfg.DocsWatchInterval() != 30*time.Second {
+		t.Errorf("Expected docs watch interval 30s, got: %s", cfg.DocsWatchInterval())
+	}
+	if cfg.DocsIndexCacheDir() != "/var/cache/docs-index" {
+		t.Errorf("Expected docs index cache dir '/var/cache/docs-index', got: %s", cfg.DocsIndexCacheDir())
+	}
+
+	if cfg.DocsHybridSearch() {
+		t.Error("Expected full-text docs search by default")
+	}
+
+	t.Setenv("MCP_DOCS_SEARCH_MODE", "Hybrid")
+	t.Setenv("MCP_DOCS_EMBEDDER_COMMAND", "python3 embed.py")
+	cfg, err = InitConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !cfg.DocsHybridSearch() || cfg.DocsEmbedderCommand() != "python3 embed.py" {
+		t.Errorf("Expected hybrid docs search with embedder command, got: %v %q", cfg.DocsHybridSearch(), cfg.DocsEmbedderCommand())
+	}
+
+	t.Setenv("MCP_DOCS_SEARCH_MODE", "semantic")
+	if _, err := InitConfig(); err == nil {
+		t.Error("Expected error for invalid docs search mode, got nil")
+	}
+
+	t.Setenv("MCP_DOCS_SEARCH_MODE", "")
+	t.Setenv("MCP_DOCS_WATCH_INTERVAL", "-1s")
+	if _, err := InitConfig(); err == nil {
+		t.Error("Expected error for negative docs watch interval, got nil")
+	}
+}
+
+func TestInitConfig_Subscriptions(t *testing.T) {
+	t.Setenv("VMANOMALY_ENDPOINT", "http://localhost:8490")
+
+	cfg, err := InitConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !cfg.SubscriptionsEnabled() {
+		t.Error("Expected subscriptions to be enabled by default")
+	}
+	if cfg.SubscriptionPollInterval() != 5*time.Second || cfg.SubscriptionMaxPerSession() != 10 || cfg.SubscriptionMaxPollRate() != 5 {
+		t.Errorf("Unexpected subscription defaults: %s %d %v", cfg.SubscriptionPollInterval(), cfg.SubscriptionMaxPerSession(), cfg.SubscriptionMaxPollRate())
+	}
+
+	t.Setenv("MCP_SUBSCRIPTION_POLL_INTERVAL", "10s")
+	t.Setenv("MCP_SUBSCRIPTION_MAX_PER_SESSION", "3")
+	t.Setenv("MCP_SUBSCRIPTION_MAX_POLL_RATE", "0.5")
+	cfg, err = InitConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.SubscriptionPollInterval() != 10*time.Second || cfg.SubscriptionMaxPerSession() != 3 || cfg.SubscriptionMaxPollRate() != 0.5 {
+		t.Errorf("Unexpected subscription limits: %s %d %v", cfg.SubscriptionPollInterval(), cfg.SubscriptionMaxPerSession(), cfg.SubscriptionMaxPollRate())
+	}
+
+	t.Setenv("MCP_SERVER_MODE", "sse")
+	if cfg, err = InitConfig(); err != nil || !cfg.SubscriptionsEnabled() {
+		t.Errorf("Expected subscriptions to be enabled in SSE mode, got: %v", err)
+	}
+	t.Setenv("MCP_SERVER_MODE", "")
+	t.Setenv("MCP_SUBSCRIPTION_POLL_INTERVAL", "0")
+	if cfg, err = InitConfig(); err != nil || cfg.SubscriptionsEnabled() {
+		t.Errorf("Expected subscriptions to be disabled with zero poll interval, got: %v", err)
+	}
+
+	for env, value := range map[string]string{
+		"MCP_SUBSCRIPTION_POLL_INTERVAL":   "100ms",
+		"MCP_SUBSCRIPTION_MAX_PER_SESSION": "0",
+		"MCP_SUBSCRIPTION_MAX_POLL_RATE":   "fast",
+	} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, value)
+			if _, err := InitConfig(); err == nil {
+				t.Errorf("Expected error for %s=%s, got nil", env, value)
+			}
+		})
+	}
+}
+
+func TestInitConfig_TaskRegistry(t *testing.T) {
+	t.Setenv("VMANOMALY_ENDPOINT", "http://localhost:8490")
+
+	cfg, err := InitConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.TaskRegistryFile() != "" || cfg.TaskRegistryMaxTasks() != 500 {
+		t.Errorf("Unexpected task registry defaults: %q %d", cfg.TaskRegistryFile(), cfg.TaskRegistryMaxTasks())
+	}
+
+	t.Setenv("MCP_TASK_REGISTRY_FILE", "/var/lib/mcp-vmanomaly/tasks.db")
+	t.Setenv("MCP_TASK_REGISTRY_MAX_TASKS", "100")
+	cfg, err = InitConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.TaskRegistryFile() != "/var/lib/mcp-vmanomaly/tasks.db" || cfg.TaskRegistryMaxTasks() != 100 {
+		t.Errorf("Unexpected task registry config: %q %d", cfg.TaskRegistryFile(), cfg.TaskRegistryMaxTasks())
+	}
+
+	t.Setenv("MCP_TASK_REGISTRY_MAX_TASKS", "-1")
+	if _, err := InitConfig(); err == nil {
+		t.Error("Expected error for negative task registry size, got nil")
+	}
+}
+
+func TestInitConfig_CacheTTL(t *testing.T) {
+	t.Setenv("VMANOMALY_ENDPOINT", "http://localhost:8490")
+
+	cfg, err := InitConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.CacheTTL() != 5*time.Minute {
+		t.Errorf("Expected cache TTL 5m, got: %s", cfg.CacheTTL())
+	}
+
+	t.Setenv("MCP_CACHE_TTL", "0")
+	if cfg, err = InitConfig(); err != nil || cfg.CacheTTL() != 0 {
+		t.Errorf("Expected disabled cache, got: %v %v", cfg, err)
+	}
+
+	t.Setenv("MCP_CACHE_TTL", "-1m")
+	if _, err := InitConfig(); err == nil {
+		t.Error("Expected error for negative cache TTL, got nil")
+	}
+}
+
+func TestInitConfig_OutputDir(t *testing.T) {
+	t.Setenv("VMANOMALY_ENDPOINT", "http://localhost:8490")
+
+	cfg, err := InitConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.OutputDir() != "" {
+		t.Errorf("Expected file output to be disabled by default, got: %s", cfg.OutputDir())
+	}
+
+	dir := t.TempDir()
+	t.Setenv("MCP_OUTPUT_DIR", dir)
+	if cfg, err = InitConfig(); err != nil || cfg.OutputDir() != dir {
+		t.Errorf("Expected output dir %s, got: %v %v", dir, cfg, err)
+	}
+
+	t.Setenv("MCP_OUTPUT_DIR", dir+"/missing")
+	if _, err := InitConfig(); err == nil {
+		t.Error("Expected error for missing output dir, got nil")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	"github.com/VictoriaMetrics/metrics"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -58,7 +59,7 @@ func New(ms *metrics.Set) *server.Hooks {
 	hooks.AddAfterReadResource(func(_ context.Context, _ any, message *mcp.ReadResourceRequest, _ *mcp.ReadResourceResult) {
 		ms.GetOrCreateCounter(fmt.Sprintf(
 			`mcp_vmanomaly_read_resource_total{uri="%s"}`,
-			message.Params.URI,
+			metricResourceURI(message.Params.URI),
 		)).Inc()
 	})
 
@@ -74,3 +75,15 @@ func New(ms *metrics.Set) *server.Hooks {
 
 	return hooks
 }
+
+// metricResourceURI returns the URI of a resource for metric labels. URIs of task resources are replaced
+// with the template and query args are dropped, so live resources don't produce unbounded label values.
+func metricResourceURI(uri string) string {
+	if strings.HasPrefix(uri, "vmanomaly://tasks/") {
+		return "vmanomaly://tasks/{task_id}"
+	}
+	if strings.HasPrefix(uri, "vmanomaly://") {
+		uri, _, _ = strings.Cut(uri, "?")
+	}
+	return uri
+}
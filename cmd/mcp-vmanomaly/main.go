@@ -15,8 +15,12 @@ import (
 
 	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/cmd/mcp-vmanomaly/config"
 	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/cmd/mcp-vmanomaly/hooks"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/embedding"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/migration"
 	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/promts"
 	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/resources"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/subscriptions"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/taskregistry"
 	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/tools"
 	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
 
@@ -79,6 +83,7 @@ func main() {
 
 	ms := metrics.NewSet()
 	client := vmanomaly.NewClient(c.VmanomalyEndpoint(), c.BearerToken(), c.CustomHeaders())
+	client.EnableCache(c.CacheTTL(), ms)
 
 	// Create tool filter that checks disabled tools from config
 	toolFilter := server.WithToolFilter(func(_ context.Context, toolsList []mcp.Tool) []mcp.Tool {
@@ -91,6 +96,15 @@ func main() {
 		return filtered
 	})
 
+	// Subscriptions of removed sessions are dropped
+	var subs *subscriptions.Manager
+	serverHooks := hooks.New(ms)
+	serverHooks.AddOnUnregisterSession(func(_ context.Context, session server.ClientSession) {
+		if subs != nil {
+			subs.RemoveSession(session.SessionID())
+		}
+	})
+
 	var mcpServer *server.MCPServer
 	if logLevel <= slog.LevelDebug {
 		mcpServer = server.NewMCPServer(
@@ -99,9 +113,9 @@ func main() {
 			server.WithRecovery(),
 			server.WithLogging(),
 			server.WithToolCapabilities(true),
-			server.WithResourceCapabilities(!c.IsResourcesDisabled(), false),
+			server.WithResourceCapabilities(c.SubscriptionsEnabled(), false),
 			server.WithPromptCapabilities(false),
-			server.WithHooks(hooks.New(ms)),
+			server.WithHooks(serverHooks),
 			toolFilter,
 		)
 	} else {
@@ -110,24 +124,61 @@ func main() {
 			fmt.Sprintf("v%s (date: %s)", version, date),
 			server.WithRecovery(),
 			server.WithToolCapabilities(true),
-			server.WithResourceCapabilities(!c.IsResourcesDisabled(), false),
+			server.WithResourceCapabilities(c.SubscriptionsEnabled(), false),
 			server.WithPromptCapabilities(false),
-			server.WithHooks(hooks.New(ms)),
+			server.WithHooks(serverHooks),
 			toolFilter,
 		)
 	}
 
-	tools.RegisterTools(mcpServer, client)
+	taskStore, err := openTaskStore(c.TaskRegistryFile())
+	if err != nil {
+		slog.Error("Failed to open task registry", "error", err)
+		os.Exit(1)
+	}
+	registry := taskregistry.New(taskStore, c.TaskRegistryMaxTasks())
+	defer registry.Close()
+
+	tools.RegisterTools(mcpServer, client, registry, tools.OutputDir(c.OutputDir()))
 
+	docsOptions := resources.DocsOptions{
+		Version:       docsVersion(c, client),
+		VersionsDir:   c.DocsVersionsDir(),
+		ExtraDocs:     c.DocsExtra(),
+		IndexCacheDir: c.DocsIndexCacheDir(),
+	}
+	if c.DocsHybridSearch() {
+		docsOptions.Embedder = embedding.New(c.DocsEmbedderCommand(), c.DocsEmbedderModel())
+	}
+	if err := resources.InitDocs(docsOptions); err != nil {
+		slog.Error("Failed to initialize documentation", "error", err)
+		os.Exit(1)
+	}
+	if c.DocsWatchInterval() > 0 && len(c.DocsExtra()) > 0 {
+		watchCtx, stopWatch := context.WithCancel(context.Background())
+		defer stopWatch()
+		go resources.WatchExtraDocs(watchCtx, c.DocsWatchInterval())
+	}
 	if !c.IsResourcesDisabled() {
-		resources.RegisterDocsResources(mcpServer)
+		if err := resources.RegisterDocsResources(mcpServer); err != nil {
+			slog.Error("Failed to register documentation resources", "error", err)
+			os.Exit(1)
+		}
+		resources.RegisterLiveResources(mcpServer, client)
+	}
+	if c.SubscriptionsEnabled() {
+		subs = subscriptions.NewManager(client.GetTaskStatus, subscriptions.ServerNotifier(mcpServer), subscriptions.Options{
+			PollInterval:  c.SubscriptionPollInterval(),
+			MaxPerSession: c.SubscriptionMaxPerSession(),
+			MaxPollRate:   c.SubscriptionMaxPollRate(),
+		})
 	}
 
 	prompts.RegisterPromptConfigRecommendation(mcpServer)
 
 	// Stdio mode - simple execution
 	if c.IsStdio() {
-		if err := server.ServeStdio(mcpServer); err != nil {
+		if err := serveStdio(mcpServer, subs); err != nil {
 			slog.Error("failed to start server in stdio mode", "error", err)
 			os.Exit(1)
 		}
@@ -166,18 +217,29 @@ func main() {
 		_, _ = w.Write([]byte("Ready\n"))
 	})
 
-	// Server mode-specific handlers
+	// Server mode-specific handlers, subscription requests are answered by subs before reaching mcp-go
+	if subs != nil {
+		go subs.Run(rootCtx)
+	}
 	switch c.ServerMode() {
 	case "sse":
 		slog.Info("Starting server in SSE mode", "addr", c.ListenAddr())
 		srv := server.NewSSEServer(mcpServer)
 		mux.Handle(srv.CompleteSsePath(), srv.SSEHandler())
-		mux.Handle(srv.CompleteMessagePath(), srv.MessageHandler())
+		if subs != nil {
+			mux.Handle(srv.CompleteMessagePath(), subs.SSEMiddleware(srv.MessageHandler(), srv.SendEventToSession))
+		} else {
+			mux.Handle(srv.CompleteMessagePath(), srv.MessageHandler())
+		}
 	case "http":
 		slog.Info("Starting server in HTTP mode", "addr", c.ListenAddr())
 		heartBeatOption := server.WithHeartbeatInterval(c.HeartbeatInterval())
 		srv := server.NewStreamableHTTPServer(mcpServer, heartBeatOption)
-		mux.Handle("/mcp", srv)
+		if subs != nil {
+			mux.Handle("/mcp", subs.Middleware(srv))
+		} else {
+			mux.Handle("/mcp", srv)
+		}
 	default:
 		slog.Error("Unknown server mode", "mode", c.ServerMode())
 		os.Exit(1)
@@ -227,3 +289,40 @@ func main() {
 
 	slog.Info("Server stopped")
 }
+
+// openTaskStore opens the file-backed task store at path, tasks are kept in memory if path is empty
+func openTaskStore(path string) (taskregistry.Store, error) {
+	if path == "" {
+		return taskregistry.NewMemoryStore(), nil
+	}
+	return taskregistry.OpenBoltStore(path)
+}
+
+// serveStdio serves MCP over stdin and stdout until they are closed or a termination signal is received.
+// Subscription requests are answered by subs if it is set.
+func serveStdio(mcpServer *server.MCPServer, subs *subscriptions.Manager) error {
+	if subs == nil {
+		return server.ServeStdio(mcpServer)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go subs.Run(ctx)
+	in, out := subs.Stdio(ctx, os.Stdin, os.Stdout)
+	return server.NewStdioServer(mcpServer).Listen(ctx, in, out)
+}
+
+// docsVersion returns the vmanomaly version to pick documentation for: the configured one
+// or the version of the connected vmanomaly, empty if it is unavailable
+func docsVersion(c *config.Config, client vmanomaly.API) string {
+	if v := c.DocsVersion(); v != "" {
+		return v
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := client.GetBuildInfo(ctx)
+	if err != nil {
+		slog.Warn("Cannot get vmanomaly version, using the latest docs", "error", err)
+		return ""
+	}
+	return migration.RuntimeVersion(info)
+}
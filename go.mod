@@ -4,9 +4,12 @@ go 1.25.5
 
 require (
 	github.com/VictoriaMetrics/metrics v1.40.2
+	github.com/VictoriaMetrics/metricsql v0.84.6
 	github.com/blevesearch/bleve/v2 v2.5.5
 	github.com/mark3labs/mcp-go v0.43.0
 	github.com/tmc/langchaingo v0.1.14
+	go.etcd.io/bbolt v1.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -51,9 +54,7 @@ require (
 	gitlab.com/golang-commonmark/markdown v0.0.0-20211110145824-bf3e522c626a // indirect
 	gitlab.com/golang-commonmark/mdurl v0.0.0-20191124015652-932350d1cb84 // indirect
 	gitlab.com/golang-commonmark/puny v0.0.0-20191124015043-9f83538fa04f // indirect
-	go.etcd.io/bbolt v1.4.0 // indirect
 	golang.org/x/sys v0.35.0 // indirect
 	golang.org/x/text v0.28.0 // indirect
 	google.golang.org/protobuf v1.36.6 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
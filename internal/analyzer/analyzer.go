@@ -0,0 +1,382 @@
+// Package analyzer performs static analysis of MetricsQL/PromQL queries before they are used
+// for anomaly detection.
+//
+// Many bad detection runs are caused by queries returning raw counters instead of rates,
+// by queries producing too many series or by Grafana placeholders which vmanomaly does not substitute.
+// Analyze parses the query with the VictoriaMetrics MetricsQL parser and reports such problems as issues,
+// while EstimateCardinality runs a cheap count() query to estimate the number of series.
+package analyzer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+// Severity levels of analysis issues
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue codes
+const (
+	IssueParseError         = "parse_error"
+	IssuePlaceholder        = "placeholder"
+	IssueRawCounter         = "raw_counter"
+	IssueMissingAggregation = "missing_aggregation"
+	IssueHighCardinalityBy  = "high_cardinality_by"
+	IssueWithoutModifier    = "without_modifier"
+	IssueWindowBelowStep    = "window_below_step"
+	IssueInvalidStep        = "invalid_step"
+	IssueTooManySeries      = "too_many_series"
+)
+
+// Issue describes a problem found in a query
+type Issue struct {
+	Severity string `json:"severity"` // error|warning
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+// Report is the result of static analysis of a MetricsQL query
+type Report struct {
+	Query        string   `json:"query"`
+	Step         string   `json:"step,omitempty"`
+	Metrics      []string `json:"metrics,omitempty"`      // Metric names used in the query
+	Functions    []string `json:"functions,omitempty"`    // Functions used in the query
+	Aggregations []string `json:"aggregations,omitempty"` // Aggregate functions used in the query
+	GroupBy      []string `json:"group_by,omitempty"`     // Labels of the outermost `by (...)` clause
+	Windows      []string `json:"windows,omitempty"`      // Lookbehind windows, e.g. 5m
+	Placeholders []string `json:"placeholders,omitempty"` // Grafana-style variables, e.g. $__interval
+	Issues       []Issue  `json:"issues,omitempty"`
+}
+
+// Valid reports whether the analysis found no errors
+func (r *Report) Valid() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			return false
+		}
+	}
+	return true
+}
+
+// Errors returns messages of all issues with error severity
+func (r *Report) Errors() []string {
+	return r.messages(SeverityError)
+}
+
+// Warnings returns messages of all issues with warning severity
+func (r *Report) Warnings() []string {
+	return r.messages(SeverityWarning)
+}
+
+func (r *Report) messages(severity string) []string {
+	var msgs []string
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			msgs = append(msgs, issue.Message)
+		}
+	}
+	return msgs
+}
+
+func (r *Report) addIssue(severity, code, format string, args ...any) {
+	r.Issues = append(r.Issues, Issue{Severity: severity, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// counterSuffixes are metric name suffixes of monotonically increasing counters
+var counterSuffixes = []string{"_total", "_count", "_sum", "_bucket"}
+
+// counterSafeFuncs are functions which turn counters into values suitable for anomaly detection
+// or which do not depend on counter values at all
+var counterSafeFuncs = map[string]bool{
+	"rate": true, "irate": true, "increase": true, "increase_pure": true, "increase_prometheus": true,
+	"delta": true, "idelta": true, "delta_prometheus": true, "rollup_rate": true, "rollup_increase": true,
+	"rollup_delta": true, "increases_over_time": true, "decreases_over_time": true, "resets": true,
+	"changes": true, "changes_prometheus": true, "count_over_time": true, "present_over_time": true,
+	"absent_over_time": true, "absent": true, "timestamp": true, "tlast_change_over_time": true,
+	"lifetime": true, "count": true, "group": true, "count_values": true,
+}
+
+// highCardinalityLabels are labels which usually have many values and explode series count when grouped by.
+// Histogram `le` buckets are checked separately, since grouping by them is expected under histogram functions.
+var highCardinalityLabels = []string{
+	"instance", "pod", "pod_name", "container", "container_id", "id", "uid", "ip", "path", "url", "uri",
+	"request_id", "trace_id", "span_id", "session_id", "user", "user_id",
+}
+
+// histogramFuncs are functions which consume `le` buckets, so grouping by `le` under them is expected
+var histogramFuncs = map[string]bool{
+	"histogram_quantile": true, "histogram_quantiles": true, "histogram_share": true, "histogram_avg": true,
+	"histogram_stddev": true, "histogram_stdvar": true, "buckets_limit": true, "prometheus_buckets": true,
+}
+
+// placeholderWindow replaces placeholders before parsing: it is a valid duration, number and window in MetricsQL
+const placeholderWindow = "1i"
+
+// Analyze parses a MetricsQL query and checks it for common problems of anomaly detection queries.
+// step is the query resolution (e.g. "1m"); if empty, window checks are skipped.
+// Analyze never fails: all problems are reported as issues of the returned report.
+// Queries the parser cannot handle are reported with a warning only, since the datasource may support
+// newer MetricsQL features than the vendored parser.
+func Analyze(query, step string) *Report {
+	r := &Report{Query: query, Step: step}
+
+	root, placeholders, err := Parse(query)
+	r.Placeholders = placeholders
+	if len(r.Placeholders) > 0 {
+		r.addIssue(SeverityError, IssuePlaceholder,
+			"query contains dashboard placeholders %s which vmanomaly does not substitute; replace them with explicit durations (e.g. [%s]) or label values",
+			strings.Join(r.Placeholders, ", "), orDefault(step, "5m"))
+	}
+	if err != nil {
+		r.addIssue(SeverityWarning, IssueParseError, "cannot parse query, so it was not checked: %s", err)
+		return r
+	}
+
+	var stepDur time.Duration
+	if step != "" {
+		if stepDur, err = vmanomaly.ParseDuration(step); err != nil {
+			r.addIssue(SeverityError, IssueInvalidStep, "invalid step %q: %s", step, err)
+		}
+	}
+
+	hasAggregation := false
+	walk(root, nil, func(e metricsql.Expr, parents []metricsql.Expr) {
+		switch e := e.(type) {
+		case *metricsql.MetricExpr:
+			name := metricName(e)
+			if name != "" && !slices.Contains(r.Metrics, name) {
+				r.Metrics = append(r.Metrics, name)
+			}
+			if isCounterName(name) && !hasCounterSafeParent(parents) {
+				r.addIssue(SeverityWarning, IssueRawCounter,
+					"%q looks like a counter but is used without rate()/increase(); raw counters grow monotonically and produce false anomalies, use e.g. rate(%s[%s])",
+					name, name, orDefault(step, "5m"))
+			}
+		case *metricsql.FuncExpr:
+			if !slices.Contains(r.Functions, e.Name) {
+				r.Functions = append(r.Functions, e.Name)
+			}
+		case *metricsql.AggrFuncExpr:
+			hasAggregation = true
+			if !slices.Contains(r.Aggregations, e.Name) {
+				r.Aggregations = append(r.Aggregations, e.Name)
+			}
+			r.checkAggrModifier(e, parents)
+		case *metricsql.RollupExpr:
+			if e.Window == nil {
+				return
+			}
+			window := string(e.Window.AppendString(nil))
+			if window == placeholderWindow && len(r.Placeholders) > 0 {
+				return
+			}
+			if !slices.Contains(r.Windows, window) {
+				r.Windows = append(r.Windows, window)
+			}
+			if stepDur == 0 {
+				return
+			}
+			if windowDur := time.Duration(e.Window.Duration(stepDur.Milliseconds())) * time.Millisecond; windowDur < stepDur {
+				r.addIssue(SeverityWarning, IssueWindowBelowStep,
+					"lookbehind window [%s] is shorter than step %s, so data between points is ignored; use a window >= step (e.g. [%s]) or omit it to let MetricsQL use the step",
+					window, step, step)
+			}
+		}
+	})
+
+	if top, ok := unwrapTop(root).(*metricsql.AggrFuncExpr); ok && top.Modifier.Op == "by" {
+		r.GroupBy = top.Modifier.Args
+	}
+	if !hasAggregation && len(r.Metrics) > 0 {
+		r.addIssue(SeverityWarning, IssueMissingAggregation,
+			"query has no aggregation, so every matching series becomes a separate model instance; consider aggregating, e.g. sum(...) by (job)")
+	}
+
+	return r
+}
+
+// Parse parses a MetricsQL query, expanding WITH templates.
+// Grafana-style placeholders outside of string literals, e.g. [$__interval] or > $threshold, are replaced
+// with a valid duration before parsing and returned in order of appearance without duplicates.
+func Parse(query string) (metricsql.Expr, []string, error) {
+	substituted, placeholders := substitutePlaceholders(query)
+	e, err := metricsql.Parse(substituted)
+	return e, placeholders, err
+}
+
+// substitutePlaceholders replaces $name and ${name} placeholders outside of string literals and comments
+func substitutePlaceholders(query string) (string, []string) {
+	var sb strings.Builder
+	var placeholders []string
+	for i := 0; i < len(query); {
+		switch c := query[i]; {
+		case c == '"' || c == '\'' || c == '`':
+			end := i + 1
+			for end < len(query) && query[end] != c {
+				if query[end] == '\\' && c != '`' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(query))
+			sb.WriteString(query[i:end])
+			i = end
+		case c == '#':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			sb.WriteString(query[i : i+end])
+			i += end
+		case c == '$':
+			end := i + 1
+			if end < len(query) && query[end] == '{' {
+				if n := strings.IndexByte(query[end:], '}'); n >= 0 {
+					end += n + 1
+				}
+			} else {
+				for end < len(query) && isIdentChar(query[end]) {
+					end++
+				}
+			}
+			if end == i+1 {
+				sb.WriteByte(c)
+				i++
+				continue
+			}
+			if name := query[i:end]; !slices.Contains(placeholders, name) {
+				placeholders = append(placeholders, name)
+			}
+			sb.WriteString(placeholderWindow)
+			i = end
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String(), placeholders
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// walk calls f for e and all its subexpressions with their ancestors, the closest one last
+func walk(e metricsql.Expr, parents []metricsql.Expr, f func(e metricsql.Expr, parents []metricsql.Expr)) {
+	f(e, parents)
+	parents = append(parents, e)
+	switch e := e.(type) {
+	case *metricsql.RollupExpr:
+		walk(e.Expr, parents, f)
+	case *metricsql.FuncExpr:
+		for _, arg := range e.Args {
+			walk(arg, parents, f)
+		}
+	case *metricsql.AggrFuncExpr:
+		for _, arg := range e.Args {
+			walk(arg, parents, f)
+		}
+	case *metricsql.BinaryOpExpr:
+		walk(e.Left, parents, f)
+		walk(e.Right, parents, f)
+	}
+}
+
+// metricName returns the metric name of a selector, or an empty string if it has none or matches it by regexp
+func metricName(me *metricsql.MetricExpr) string {
+	for _, filters := range me.LabelFilterss {
+		for _, f := range filters {
+			if f.Label == "__name__" && !f.IsRegexp && !f.IsNegative {
+				return f.Value
+			}
+		}
+	}
+	return ""
+}
+
+func (r *Report) checkAggrModifier(e *metricsql.AggrFuncExpr, parents []metricsql.Expr) {
+	labels := e.Modifier.Args
+	switch strings.ToLower(e.Modifier.Op) {
+	case "by":
+		var risky []string
+		for _, label := range labels {
+			if slices.Contains(highCardinalityLabels, label) || label == "le" && !hasHistogramParent(parents) {
+				risky = append(risky, label)
+			}
+		}
+		if len(risky) > 0 {
+			r.addIssue(SeverityWarning, IssueHighCardinalityBy,
+				"%s(...) by (%s) groups by high-cardinality label(s) %s, which may explode the number of series and models",
+				e.Name, strings.Join(labels, ", "), strings.Join(risky, ", "))
+		}
+	case "without":
+		r.addIssue(SeverityWarning, IssueWithoutModifier,
+			"%s(...) without (%s) keeps all other labels, including high-cardinality ones; prefer an explicit by (...) clause",
+			e.Name, strings.Join(labels, ", "))
+	}
+}
+
+func isCounterName(name string) bool {
+	for _, suffix := range counterSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCounterSafeParent(parents []metricsql.Expr) bool {
+	for _, p := range parents {
+		switch p := p.(type) {
+		case *metricsql.FuncExpr:
+			if counterSafeFuncs[p.Name] {
+				return true
+			}
+		case *metricsql.AggrFuncExpr:
+			if counterSafeFuncs[p.Name] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasHistogramParent(parents []metricsql.Expr) bool {
+	return slices.ContainsFunc(parents, func(p metricsql.Expr) bool {
+		fe, ok := p.(*metricsql.FuncExpr)
+		return ok && histogramFuncs[fe.Name]
+	})
+}
+
+// unwrapTop skips binary operations with scalars to get to the expression determining the result labels
+func unwrapTop(e metricsql.Expr) metricsql.Expr {
+	for {
+		be, ok := e.(*metricsql.BinaryOpExpr)
+		if !ok {
+			return e
+		}
+		if _, isNum := be.Right.(*metricsql.NumberExpr); isNum {
+			e = be.Left
+		} else if _, isNum := be.Left.(*metricsql.NumberExpr); isNum {
+			e = be.Right
+		} else {
+			return e
+		}
+	}
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
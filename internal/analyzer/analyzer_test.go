@@ -0,0 +1,215 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		step        string
+		wantCodes   []string
+		wantValid   bool
+		wantMetrics []string
+		wantGroupBy []string
+	}{
+		{
+			name:        "good query",
+			query:       `sum(rate(http_requests_total{job="api"}[5m])) by (job)`,
+			step:        "1m",
+			wantValid:   true,
+			wantMetrics: []string{"http_requests_total"},
+			wantGroupBy: []string{"job"},
+		},
+		{
+			name:        "raw counter",
+			query:       `sum(http_requests_total) by (job)`,
+			step:        "1m",
+			wantCodes:   []string{IssueRawCounter},
+			wantValid:   true,
+			wantMetrics: []string{"http_requests_total"},
+		},
+		{
+			name:      "count of counter is fine",
+			query:     `count(up_total)`,
+			wantValid: true,
+		},
+		{
+			name:      "missing aggregation",
+			query:     `rate(node_cpu_seconds_total[5m])`,
+			step:      "1m",
+			wantCodes: []string{IssueMissingAggregation},
+			wantValid: true,
+		},
+		{
+			name:      "window below step",
+			query:     `sum(rate(http_requests_total[1m]))`,
+			step:      "5m",
+			wantCodes: []string{IssueWindowBelowStep},
+			wantValid: true,
+		},
+		{
+			name:      "subquery window",
+			query:     `max(max_over_time(rate(x_total[5m])[30s:10s]))`,
+			step:      "1m",
+			wantCodes: []string{IssueWindowBelowStep},
+			wantValid: true,
+		},
+		{
+			name:      "interval placeholder",
+			query:     `sum(rate(http_requests_total[$__interval]))`,
+			step:      "1m",
+			wantCodes: []string{IssuePlaceholder},
+		},
+		{
+			name:      "label variable",
+			query:     `sum(rate(http_requests_total{job="$job"}[5m]))`,
+			wantValid: true,
+		},
+		{
+			name:      "high cardinality by",
+			query:     `sum(rate(http_requests_total[5m])) by (job, instance)`,
+			step:      "1m",
+			wantCodes: []string{IssueHighCardinalityBy},
+			wantValid: true,
+		},
+		{
+			name:      "without modifier",
+			query:     `sum without (instance) (rate(http_requests_total[5m]))`,
+			wantCodes: []string{IssueWithoutModifier},
+			wantValid: true,
+		},
+		{
+			name:        "binary operation",
+			query:       `sum(rate(errors_total[5m])) by (job) / sum(rate(requests_total[5m])) by (job) * 100`,
+			step:        "1m",
+			wantValid:   true,
+			wantMetrics: []string{"errors_total", "requests_total"},
+		},
+		{
+			name:        "WITH template",
+			query:       `WITH (f(x) = rate(x[5m])) sum(f(foo_total)) by (job)`,
+			step:        "1m",
+			wantValid:   true,
+			wantMetrics: []string{"foo_total"},
+			wantGroupBy: []string{"job"},
+		},
+		{
+			name:      "histogram buckets",
+			query:     `histogram_quantile(0.99, sum(rate(http_request_duration_seconds_bucket[5m])) by (le))`,
+			step:      "1m",
+			wantValid: true,
+		},
+		{
+			name:      "buckets without histogram function",
+			query:     `sum(rate(http_request_duration_seconds_bucket[5m])) by (le)`,
+			step:      "1m",
+			wantCodes: []string{IssueHighCardinalityBy},
+			wantValid: true,
+		},
+		{
+			name:      "threshold placeholder",
+			query:     `sum(rate(http_requests_total[5m])) > $threshold`,
+			wantCodes: []string{IssuePlaceholder},
+		},
+		{
+			name:      "parse error is not blocking",
+			query:     `sum(rate(x[5m])`,
+			wantCodes: []string{IssueParseError},
+			wantValid: true,
+		},
+		{
+			name:      "invalid step",
+			query:     `sum(x)`,
+			step:      "abc",
+			wantCodes: []string{IssueInvalidStep},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Analyze(tt.query, tt.step)
+			if r.Valid() != tt.wantValid {
+				t.Errorf("Valid() = %v, want %v (issues: %+v)", r.Valid(), tt.wantValid, r.Issues)
+			}
+			var codes []string
+			for _, issue := range r.Issues {
+				codes = append(codes, issue.Code)
+			}
+			if !slices.Equal(codes, tt.wantCodes) {
+				t.Errorf("issue codes = %v, want %v", codes, tt.wantCodes)
+			}
+			if tt.wantMetrics != nil && !slices.Equal(r.Metrics, tt.wantMetrics) {
+				t.Errorf("Metrics = %v, want %v", r.Metrics, tt.wantMetrics)
+			}
+			if tt.wantGroupBy != nil && !slices.Equal(r.GroupBy, tt.wantGroupBy) {
+				t.Errorf("GroupBy = %v, want %v", r.GroupBy, tt.wantGroupBy)
+			}
+		})
+	}
+}
+
+type fakeQuerier struct {
+	req    *vmanomaly.QueryRequest
+	result map[string]any
+	err    error
+}
+
+func (f *fakeQuerier) Query(_ context.Context, req *vmanomaly.QueryRequest) (map[string]any, error) {
+	f.req = req
+	return f.result, f.err
+}
+
+func TestEstimateCardinality(t *testing.T) {
+	q := &fakeQuerier{result: map[string]any{
+		"status": "success",
+		"data": map[string]any{
+			"resultType": "matrix",
+			"result": []any{
+				map[string]any{"metric": map[string]any{}, "values": []any{
+					[]any{1700000000.0, "120"}, []any{1700000060.0, "135"},
+				}},
+			},
+		},
+	}}
+
+	est, err := EstimateCardinality(context.Background(), q, `rate(x_total[5m])`, "1m", "", "0:0")
+	if err != nil {
+		t.Fatalf("EstimateCardinality() error = %v", err)
+	}
+	if est.Series != 135 {
+		t.Errorf("Series = %d, want 135", est.Series)
+	}
+	if q.req.Query != "count(rate(x_total[5m]))" {
+		t.Errorf("unexpected query %q", q.req.Query)
+	}
+	if q.req.TenantID == nil || *q.req.TenantID != "0:0" || q.req.DatasourceURL != nil {
+		t.Errorf("unexpected datasource params: %+v", q.req)
+	}
+	if *q.req.End-*q.req.Start != 5*60 {
+		t.Errorf("unexpected range %v..%v", *q.req.Start, *q.req.End)
+	}
+
+	q.err = errors.New("connection refused")
+	if _, err := EstimateCardinality(context.Background(), q, `x`, "1m", "", ""); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("expected query error, got %v", err)
+	}
+}
+
+func TestParse_Placeholders(t *testing.T) {
+	_, placeholders, err := Parse(`rate(x{job="$job"}[$__interval]) > ${threshold} # $comment`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// quoted placeholders are regular label values
+	if !slices.Equal(placeholders, []string{"$__interval", "${threshold}"}) {
+		t.Errorf("placeholders = %v", placeholders)
+	}
+}
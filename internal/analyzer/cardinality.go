@@ -0,0 +1,75 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+// DefaultMaxSeries is the number of series above which a query is considered too expensive for ad-hoc detection
+const DefaultMaxSeries = 1000
+
+// cardinalityPoints is the number of points requested by the count() query
+const cardinalityPoints = 5
+
+// Querier runs datasource queries, it is implemented by *vmanomaly.Client
+type Querier interface {
+	Query(ctx context.Context, req *vmanomaly.QueryRequest) (map[string]any, error)
+}
+
+// CardinalityEstimate is the result of EstimateCardinality
+type CardinalityEstimate struct {
+	Query  string `json:"query"`  // The count() query which was executed
+	Series int    `json:"series"` // Max number of series over the probed range
+}
+
+// EstimateCardinality estimates the number of series returned by a MetricsQL query
+// by running count(<query>) over a few most recent points.
+// datasourceURL and tenantID are optional and passed as is to the query endpoint.
+func EstimateCardinality(ctx context.Context, q Querier, query, step, datasourceURL, tenantID string) (*CardinalityEstimate, error) {
+	if step == "" {
+		step = "1m"
+	}
+	stepDur, err := vmanomaly.ParseDuration(step)
+	if err != nil {
+		return nil, fmt.Errorf("invalid step %q: %w", step, err)
+	}
+
+	end := float64(time.Now().Unix())
+	start := end - float64(cardinalityPoints)*stepDur.Seconds()
+	req := &vmanomaly.QueryRequest{
+		Query:          fmt.Sprintf("count(%s)", query),
+		Start:          &start,
+		End:            &end,
+		Step:           step,
+		DatasourceType: vmanomaly.DatasourceTypeVM,
+	}
+	if datasourceURL != "" {
+		req.DatasourceURL = &datasourceURL
+	}
+	if tenantID != "" {
+		req.TenantID = &tenantID
+	}
+
+	result, err := q.Query(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cardinality query failed: %w", err)
+	}
+	series, err := vmanomaly.ParseQueryResult(result)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse cardinality query result: %w", err)
+	}
+
+	estimate := &CardinalityEstimate{Query: req.Query}
+	for _, s := range series {
+		for _, v := range s.Values {
+			if !math.IsNaN(v) && int(v) > estimate.Series {
+				estimate.Series = int(v)
+			}
+		}
+	}
+	return estimate, nil
+}
@@ -0,0 +1,258 @@
+// Package capacity estimates the workload of a vmanomaly config and proposes how to shard it
+// between several vmanomaly instances.
+//
+// The workload is measured in cost units per hour: the number of data points processed by models
+// during fit and infer calls, weighted by a relative per-model cost (zscore = 1). Series cardinality
+// of every query is estimated with a cheap count() query, see analyzer.EstimateCardinality.
+// See https://docs.victoriametrics.com/anomaly-detection/scaling-vmanomaly/ for sharding details.
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/analyzer"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmconfig"
+)
+
+// Model kinds, which define how often a model is fit
+const (
+	KindOffline = "offline" // Fit on fit_window every fit_every
+	KindOnline  = "online"  // Fit once on fit_window, then updated incrementally on every infer
+	KindRolling = "rolling" // Fit on inferred data during every infer call
+)
+
+// Fallbacks used when the config does not set the corresponding durations
+const (
+	DefaultStep      = "1m"
+	DefaultFitWindow = "1d"
+)
+
+// ModelCost is the relative cost of processing a single data point by a model
+type ModelCost struct {
+	Kind  string  `json:"kind"`
+	Fit   float64 `json:"fit"`
+	Infer float64 `json:"infer"`
+}
+
+// modelCosts are rough relative costs of built-in models, measured against zscore
+var modelCosts = map[string]ModelCost{
+	"zscore":                    {Kind: KindOffline, Fit: 1, Infer: 1},
+	"mad":                       {Kind: KindOffline, Fit: 2, Infer: 1},
+	"holtwinters":               {Kind: KindOffline, Fit: 20, Infer: 2},
+	"prophet":                   {Kind: KindOffline, Fit: 200, Infer: 20},
+	"isolationforest":           {Kind: KindOffline, Fit: 50, Infer: 5},
+	"isolationforestunivariate": {Kind: KindOffline, Fit: 50, Infer: 5},
+	"auto":                      {Kind: KindOffline, Fit: 1000, Infer: 20},
+	"autotuned":                 {Kind: KindOffline, Fit: 1000, Infer: 20},
+	"zscoreonline":              {Kind: KindOnline, Fit: 1, Infer: 2},
+	"onlinezscore":              {Kind: KindOnline, Fit: 1, Infer: 2},
+	"madonline":                 {Kind: KindOnline, Fit: 2, Infer: 3},
+	"onlinemad":                 {Kind: KindOnline, Fit: 2, Infer: 3},
+	"quantileonline":            {Kind: KindOnline, Fit: 2, Infer: 3},
+	"onlinequantile":            {Kind: KindOnline, Fit: 2, Infer: 3},
+	"rollingquantile":           {Kind: KindRolling, Fit: 0, Infer: 5},
+	"std":                       {Kind: KindRolling, Fit: 0, Infer: 10},
+}
+
+// defaultModelCost is used for custom and unknown model classes
+var defaultModelCost = ModelCost{Kind: KindOffline, Fit: 10, Infer: 2}
+
+// LookupModelCost returns the relative cost of a model class. Both class aliases (e.g. `zscore`)
+// and full class names (e.g. `model.zscore.ZScoreModel`) are supported.
+// The second result is false for unknown classes, in which case a conservative default is returned.
+func LookupModelCost(class string) (ModelCost, bool) {
+	key := class
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		key = key[i+1:]
+	}
+	key = strings.ToLower(strings.ReplaceAll(key, "_", ""))
+	if key != "model" {
+		key = strings.TrimSuffix(key, "model")
+	}
+	cost, ok := modelCosts[key]
+	if !ok {
+		return defaultModelCost, false
+	}
+	return cost, true
+}
+
+// Querier runs datasource queries, it is implemented by *vmanomaly.Client
+type Querier = analyzer.Querier
+
+// Entity is the smallest unit of work moved between shards: a model attached to a single query
+// with all schedulers of the model
+type Entity struct {
+	Model      string    `json:"model"`
+	Class      string    `json:"class"`
+	Query      string    `json:"query"`
+	Schedulers []string  `json:"schedulers"`
+	Series     int       `json:"series"`
+	Cost       ModelCost `json:"model_cost"`
+	// FitsPerHour is the number of fit calls per hour summed over schedulers
+	FitsPerHour float64 `json:"fits_per_hour"`
+	// CostPerHour is the estimated workload in cost units per hour
+	CostPerHour float64 `json:"cost_per_hour"`
+}
+
+// Estimate is the workload of a whole config
+type Estimate struct {
+	Series      map[string]int `json:"series"` // Estimated series per query alias
+	Entities    []Entity       `json:"entities"`
+	CostPerHour float64        `json:"cost_per_hour"`
+	Warnings    []string       `json:"warnings,omitempty"`
+}
+
+// EstimateOptions are options of EstimateWorkload
+type EstimateOptions struct {
+	// Series overrides cardinality estimates per query alias
+	Series map[string]int
+	// DefaultSeries is assumed for queries whose cardinality cannot be estimated, defaults to 1
+	DefaultSeries int
+}
+
+// EstimateWorkload estimates series cardinality of every query of the config and
+// the resulting fit/infer workload of every model and query pair
+func EstimateWorkload(ctx context.Context, q Querier, c *vmconfig.Config, opts EstimateOptions) (*Estimate, error) {
+	if c.Preset != "" {
+		return nil, fmt.Errorf("preset configs cannot be planned, render the preset first")
+	}
+	if opts.DefaultSeries <= 0 {
+		opts.DefaultSeries = 1
+	}
+	est := &Estimate{Series: make(map[string]int, len(c.Reader.Queries))}
+
+	for _, query := range c.Reader.Queries {
+		if n, ok := opts.Series[query.Alias]; ok {
+			est.Series[query.Alias] = n
+			continue
+		}
+		if q == nil {
+			est.Series[query.Alias] = opts.DefaultSeries
+			continue
+		}
+		ce, err := analyzer.EstimateCardinality(ctx, q, query.Expr, stepOf(query), c.Reader.DatasourceURL, c.Reader.TenantID)
+		if err != nil {
+			est.Warnings = append(est.Warnings, fmt.Sprintf("query %s: %v, assuming %d series", query.Alias, err, opts.DefaultSeries))
+			est.Series[query.Alias] = opts.DefaultSeries
+			continue
+		}
+		est.Series[query.Alias] = ce.Series
+	}
+
+	for _, m := range c.Models {
+		cost, ok := LookupModelCost(m.Class)
+		if !ok {
+			est.Warnings = append(est.Warnings, fmt.Sprintf("model %s: unknown class %q, assuming fit cost %g and infer cost %g per point",
+				m.Alias, m.Class, cost.Fit, cost.Infer))
+		}
+		for _, alias := range m.Queries {
+			query, _ := c.Query(alias)
+			e := Entity{
+				Model:      m.Alias,
+				Class:      m.Class,
+				Query:      alias,
+				Schedulers: m.Schedulers,
+				Series:     est.Series[alias],
+				Cost:       cost,
+			}
+			for _, sa := range m.Schedulers {
+				s, _ := c.Scheduler(sa)
+				fits, pointCost, warnings := schedulerCost(s, stepOf(query), cost)
+				for _, w := range warnings {
+					est.Warnings = append(est.Warnings, fmt.Sprintf("scheduler %s: %s", sa, w))
+				}
+				e.FitsPerHour += fits
+				e.CostPerHour += pointCost * float64(e.Series)
+			}
+			est.Entities = append(est.Entities, e)
+			est.CostPerHour += e.CostPerHour
+		}
+	}
+	est.Warnings = dedup(est.Warnings)
+	return est, nil
+}
+
+// schedulerCost returns the number of fits per hour and the per-series cost per hour of a model attached to a scheduler
+func schedulerCost(s vmconfig.Scheduler, step string, cost ModelCost) (float64, float64, []string) {
+	var warnings []string
+	stepDur := mustDuration(step, DefaultStep)
+	inferEvery, ok := duration(s.InferEvery)
+	if !ok {
+		warnings = append(warnings, fmt.Sprintf("infer_every is not set or invalid, assuming query step %s", vmanomaly.FormatDuration(stepDur)))
+		inferEvery = stepDur
+	}
+	fitEvery, ok := duration(s.FitEvery)
+	if !ok {
+		fitEvery = inferEvery
+	}
+	fitWindow, ok := duration(s.FitWindow)
+	if !ok {
+		warnings = append(warnings, fmt.Sprintf("fit_window is not set or invalid, assuming %s", DefaultFitWindow))
+		fitWindow = mustDuration(DefaultFitWindow, DefaultFitWindow)
+	}
+
+	// Every point is inferred once regardless of infer_every
+	inferPoints := float64(time.Hour) / float64(stepDur)
+	fitPoints := float64(fitWindow) / float64(stepDur)
+
+	var fitsPerHour, fitCost float64
+	switch cost.Kind {
+	case KindOnline:
+		// Incremental updates are part of the infer cost, the initial fit is amortized over a day
+		fitsPerHour = 1.0 / 24
+		fitCost = fitPoints * cost.Fit * fitsPerHour
+	case KindRolling:
+		fitsPerHour = float64(time.Hour) / float64(inferEvery)
+	default:
+		fitsPerHour = float64(time.Hour) / float64(fitEvery)
+		fitCost = fitPoints * cost.Fit * fitsPerHour
+	}
+	return fitsPerHour, fitCost + inferPoints*cost.Infer, warnings
+}
+
+func stepOf(q vmconfig.Query) string {
+	if q.Step != "" {
+		return q.Step
+	}
+	return DefaultStep
+}
+
+func duration(s string) (time.Duration, bool) {
+	if s == "" {
+		return 0, false
+	}
+	d, err := vmanomaly.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+func mustDuration(s, fallback string) time.Duration {
+	if d, ok := duration(s); ok {
+		return d
+	}
+	d, _ := duration(fallback)
+	return d
+}
+
+func dedup(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	result := items[:0]
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
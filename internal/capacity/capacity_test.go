@@ -0,0 +1,170 @@
+package capacity
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmconfig"
+)
+
+const testConfig = `
+reader:
+  datasource_url: http://vmsingle:8428
+  sampling_period: 1m
+  queries:
+    cpu: sum(rate(node_cpu_seconds_total[5m])) by (instance)
+    rps: sum(rate(http_requests_total[5m])) by (job)
+    errors:
+      expr: sum(rate(http_errors_total[5m])) by (job)
+      step: 5m
+schedulers:
+  hourly:
+    infer_every: 1m
+    fit_every: 1h
+    fit_window: 7d
+models:
+  zscore:
+    class: zscore
+  prophet:
+    class: model.prophet.ProphetModel
+    queries: [cpu]
+  custom:
+    class: my.custom.Model
+    queries: [errors]
+settings:
+  n_workers: 4
+writer:
+  datasource_url: http://vmsingle:8428
+`
+
+type fakeQuerier map[string]float64
+
+func (f fakeQuerier) Query(_ context.Context, req *vmanomaly.QueryRequest) (map[string]any, error) {
+	for metric, n := range f {
+		if strings.Contains(req.Query, metric) {
+			return map[string]any{"resultType": "matrix", "result": []any{
+				map[string]any{"metric": map[string]any{}, "values": []any{[]any{float64(0), n}}},
+			}}, nil
+		}
+	}
+	return nil, errors.New("unexpected query")
+}
+
+func TestLookupModelCost(t *testing.T) {
+	for class, want := range map[string]string{
+		"zscore":                        KindOffline,
+		"model.zscore.ZScoreModel":      KindOffline,
+		"zscore_online":                 KindOnline,
+		"model.online.OnlineMADModel":   KindOnline,
+		"rolling_quantile":              KindRolling,
+		"model.std.StdModel":            KindRolling,
+		"isolation_forest_univariate":   KindOffline,
+		"model.prophet.ProphetModel":    KindOffline,
+		"model.auto.AutoTunedModel":     KindOffline,
+		"model.holtwinters.HoltWinters": KindOffline,
+	} {
+		cost, ok := LookupModelCost(class)
+		if !ok || cost.Kind != want {
+			t.Errorf("LookupModelCost(%q) = %+v, %v; want kind %s", class, cost, ok, want)
+		}
+	}
+	if _, ok := LookupModelCost("my.custom.Model"); ok {
+		t.Errorf("expected unknown class")
+	}
+}
+
+func TestEstimateWorkload(t *testing.T) {
+	c, err := vmconfig.Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("cannot parse config: %v", err)
+	}
+	est, err := EstimateWorkload(context.Background(), fakeQuerier{"node_cpu": 10, "http_requests": 100}, c, EstimateOptions{})
+	if err != nil {
+		t.Fatalf("EstimateWorkload() error = %v", err)
+	}
+	if est.Series["cpu"] != 10 || est.Series["rps"] != 100 || est.Series["errors"] != 1 {
+		t.Errorf("series = %v", est.Series)
+	}
+	if len(est.Entities) != 5 {
+		t.Fatalf("expected 5 entities, got %+v", est.Entities)
+	}
+	var warnings = strings.Join(est.Warnings, "\n")
+	for _, want := range []string{"query errors", "unknown class"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("expected warning %q, got %v", want, est.Warnings)
+		}
+	}
+
+	// zscore on rps: 100 series * (10080 fit points * 1 fit per hour + 60 inferred points)
+	for _, e := range est.Entities {
+		if e.Model == "zscore" && e.Query == "rps" && e.CostPerHour != 100*(10080+60) {
+			t.Errorf("zscore/rps cost = %v", e.CostPerHour)
+		}
+	}
+
+	est, err = EstimateWorkload(context.Background(), nil, c, EstimateOptions{Series: map[string]int{"cpu": 5}, DefaultSeries: 2})
+	if err != nil {
+		t.Fatalf("EstimateWorkload() error = %v", err)
+	}
+	if est.Series["cpu"] != 5 || est.Series["rps"] != 2 {
+		t.Errorf("series = %v", est.Series)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	c, err := vmconfig.Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("cannot parse config: %v", err)
+	}
+	est, err := EstimateWorkload(context.Background(), nil, c, EstimateOptions{Series: map[string]int{"cpu": 10, "rps": 100, "errors": 50}})
+	if err != nil {
+		t.Fatalf("EstimateWorkload() error = %v", err)
+	}
+
+	plan, err := Split(c, est, SplitOptions{Shards: 3})
+	if err != nil {
+		t.Fatalf("Split() error = %v", err)
+	}
+	if len(plan.Shards) != 3 {
+		t.Fatalf("expected 3 shards, got %d", len(plan.Shards))
+	}
+	entities := 0
+	for _, sh := range plan.Shards {
+		entities += len(sh.Entities)
+		sub, err := vmconfig.FromMap(sh.Config)
+		if err != nil {
+			t.Fatalf("shard %d: invalid sub-config: %v", sh.Index, err)
+		}
+		if got := strings.Join(sub.QueryAliases(), ","); got != strings.Join(sh.Queries, ",") {
+			t.Errorf("shard %d: queries = %s, want %v", sh.Index, got, sh.Queries)
+		}
+		if _, ok := sh.Config["settings"]; !ok {
+			t.Errorf("shard %d: settings section is lost", sh.Index)
+		}
+	}
+	if entities != len(est.Entities) {
+		t.Errorf("shards contain %d entities, want %d", entities, len(est.Entities))
+	}
+	if _, ok := c.Raw["reader"].(map[string]any)["queries"].(map[string]any)["cpu"]; !ok {
+		t.Errorf("original config must not be modified")
+	}
+
+	plan, err = Split(c, est, SplitOptions{Shards: 100})
+	if err != nil {
+		t.Fatalf("Split() error = %v", err)
+	}
+	if len(plan.Shards) != len(est.Entities) {
+		t.Errorf("shards must be capped by the number of entities, got %d", len(plan.Shards))
+	}
+
+	plan, err = Split(c, est, SplitOptions{ShardCapacity: est.CostPerHour / 2.5})
+	if err != nil {
+		t.Fatalf("Split() error = %v", err)
+	}
+	if len(plan.Shards) != 3 {
+		t.Errorf("expected 3 shards derived from capacity, got %d", len(plan.Shards))
+	}
+}
@@ -0,0 +1,127 @@
+package capacity
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmconfig"
+)
+
+// DefaultShardCapacity is the workload in cost units per hour a single vmanomaly instance is assumed to handle,
+// which roughly corresponds to 10k series of zscore refitted hourly on a week of 1m data
+const DefaultShardCapacity = 1e8
+
+// Shard is a sub-config assigned to a single vmanomaly instance
+type Shard struct {
+	Index       int            `json:"index"`
+	Entities    []Entity       `json:"entities"`
+	Queries     []string       `json:"queries"`
+	Models      []string       `json:"models"`
+	Series      int            `json:"series"`
+	CostPerHour float64        `json:"cost_per_hour"`
+	Load        float64        `json:"load"` // CostPerHour relative to the shard capacity
+	Config      map[string]any `json:"config"`
+}
+
+// Plan is a proposed split of a config between shards
+type Plan struct {
+	Shards        []Shard `json:"shards"`
+	CostPerHour   float64 `json:"cost_per_hour"`
+	ShardCapacity float64 `json:"shard_capacity"`
+	// Imbalance is the cost of the most loaded shard relative to the average shard cost, 1 means perfect balance
+	Imbalance float64 `json:"imbalance"`
+}
+
+// SplitOptions are options of Split
+type SplitOptions struct {
+	// Shards is the number of shards, it is derived from ShardCapacity when zero
+	Shards int
+	// ShardCapacity is the workload of a single shard, defaults to DefaultShardCapacity
+	ShardCapacity float64
+}
+
+// Split distributes entities of the estimate between shards, balancing their cost with
+// the longest-processing-time-first heuristic, and builds a standalone sub-config for every shard.
+// The number of shards never exceeds the number of entities.
+func Split(c *vmconfig.Config, est *Estimate, opts SplitOptions) (*Plan, error) {
+	if len(est.Entities) == 0 {
+		return nil, fmt.Errorf("config has no models attached to queries")
+	}
+	if opts.ShardCapacity <= 0 {
+		opts.ShardCapacity = DefaultShardCapacity
+	}
+	n := opts.Shards
+	if n < 0 {
+		return nil, fmt.Errorf("number of shards must not be negative")
+	}
+	if n == 0 {
+		n = max(1, int(math.Ceil(est.CostPerHour/opts.ShardCapacity)))
+	}
+	n = min(n, len(est.Entities))
+
+	entities := slices.Clone(est.Entities)
+	sort.SliceStable(entities, func(i, j int) bool {
+		return entities[i].CostPerHour > entities[j].CostPerHour
+	})
+	shards := make([]Shard, n)
+	for i := range shards {
+		shards[i].Index = i
+	}
+	for _, e := range entities {
+		target := 0
+		for i := range shards {
+			if shards[i].CostPerHour < shards[target].CostPerHour ||
+				(shards[i].CostPerHour == shards[target].CostPerHour && len(shards[i].Entities) < len(shards[target].Entities)) {
+				target = i
+			}
+		}
+		shards[target].Entities = append(shards[target].Entities, e)
+		shards[target].CostPerHour += e.CostPerHour
+	}
+
+	plan := &Plan{CostPerHour: round(est.CostPerHour), ShardCapacity: opts.ShardCapacity}
+	var maxCost float64
+	for i := range shards {
+		sh := &shards[i]
+		cfg, err := subConfig(c, sh.Entities)
+		if err != nil {
+			return nil, fmt.Errorf("shard %d: %w", i, err)
+		}
+		sh.Config = cfg
+		queries := map[string]bool{}
+		models := map[string]bool{}
+		for _, e := range sh.Entities {
+			if !queries[e.Query] {
+				queries[e.Query] = true
+				sh.Series += e.Series
+			}
+			models[e.Model] = true
+		}
+		sh.Queries = slices.Sorted(maps.Keys(queries))
+		sh.Models = slices.Sorted(maps.Keys(models))
+		maxCost = max(maxCost, sh.CostPerHour)
+		sh.CostPerHour = round(sh.CostPerHour)
+		sh.Load = round(sh.CostPerHour / opts.ShardCapacity)
+	}
+	plan.Shards = shards
+	if avg := est.CostPerHour / float64(n); avg > 0 {
+		plan.Imbalance = round(maxCost / avg)
+	} else {
+		plan.Imbalance = 1
+	}
+	return plan, nil
+}
+
+// subConfig builds a standalone config running every model of the entities on its queries with all its schedulers
+func subConfig(c *vmconfig.Config, entities []Entity) (map[string]any, error) {
+	models := map[string]vmconfig.Selection{}
+	for _, e := range entities {
+		sel := models[e.Model]
+		sel.Queries = append(sel.Queries, e.Query)
+		models[e.Model] = sel
+	}
+	return c.Subset(models)
+}
@@ -0,0 +1,331 @@
+// Package changelog parses vmanomaly CHANGELOG.md into structured releases and entries.
+//
+// Releases are `## vX.Y.Z` headings followed by an optional `Released: YYYY-MM-DD` line,
+// notices (quotes and bold lines) and entries like `- FEATURE: ...` with continuation lines.
+package changelog
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Entry kinds used in the changelog
+const (
+	KindFeature        = "FEATURE"
+	KindImprovement    = "IMPROVEMENT"
+	KindBugfix         = "BUGFIX"
+	KindBreakingChange = "BREAKING CHANGE"
+	KindDeprecation    = "DEPRECATION"
+)
+
+// Version is a vmanomaly release version, e.g. v1.22.0-experimental
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string
+}
+
+var versionRe = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?$`)
+
+// ParseVersion parses versions like v1.26.2, 1.26 or v1.0.0-beta
+func ParseVersion(s string) (Version, error) {
+	m := versionRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	v.Pre = m[4]
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 if v is older, equal or newer than o.
+// Pre-release versions are older than the release itself.
+func (v Version) Compare(o Version) int {
+	if c := cmp.Compare(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	default:
+		return strings.Compare(v.Pre, o.Pre)
+	}
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// Entry is a single changelog entry
+type Entry struct {
+	Kind     string `json:"kind"`
+	Text     string `json:"text"`
+	Breaking bool   `json:"breaking,omitempty"` // Breaking change or backward-incompatible behavior
+}
+
+// Release is a changelog section of a single version
+type Release struct {
+	Version Version  `json:"version"`
+	Date    string   `json:"date,omitempty"`
+	Notices []string `json:"notices,omitempty"` // Known issues and important notices
+	Entries []Entry  `json:"entries"`
+}
+
+// Breaking returns breaking entries of the release
+func (r Release) Breaking() []Entry {
+	return r.filter(func(e Entry) bool { return e.Breaking })
+}
+
+// Deprecations returns deprecation entries of the release
+func (r Release) Deprecations() []Entry {
+	return r.filter(func(e Entry) bool { return e.Kind == KindDeprecation })
+}
+
+func (r Release) filter(fn func(Entry) bool) []Entry {
+	var result []Entry
+	for _, e := range r.Entries {
+		if fn(e) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Changelog is a parsed changelog, releases are ordered from the newest to the oldest
+type Changelog struct {
+	Releases []Release `json:"releases"`
+}
+
+var (
+	releaseRe = regexp.MustCompile(`^##\s+(v\S+)\s*$`)
+	entryRe   = regexp.MustCompile(`^-\s+([A-Z][A-Z ]+[A-Z]):\s*(.*)$`)
+)
+
+// kindFixes normalizes misspelled kinds found in the changelog
+var kindFixes = map[string]string{
+	"IMPROVEMEMT": KindImprovement,
+}
+
+// Parse parses changelog markdown
+func Parse(data []byte) (*Changelog, error) {
+	c := &Changelog{}
+	var release *Release
+	var entry *Entry
+	var notice []string
+
+	flushEntry := func() {
+		if entry != nil {
+			entry.Text = strings.TrimSpace(entry.Text)
+			entry.Breaking = entry.Kind == KindBreakingChange || isBreakingText(entry.Text)
+			release.Entries = append(release.Entries, *entry)
+			entry = nil
+		}
+	}
+	flushNotice := func() {
+		if len(notice) > 0 {
+			release.Notices = append(release.Notices, strings.Join(notice, "\n"))
+			notice = nil
+		}
+	}
+	flushRelease := func() {
+		if release != nil {
+			flushEntry()
+			flushNotice()
+			c.Releases = append(c.Releases, *release)
+		}
+	}
+
+	afterBlank := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t")
+		// Unindented text after a blank line ends the entry
+		if entry != nil && afterBlank && line != "" && !strings.HasPrefix(line, " ") {
+			flushEntry()
+		}
+		afterBlank = line == ""
+		if m := releaseRe.FindStringSubmatch(line); m != nil {
+			flushRelease()
+			v, err := ParseVersion(m[1])
+			if err != nil {
+				return nil, err
+			}
+			release = &Release{Version: v}
+			continue
+		}
+		if release == nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "Released:") && release.Date == "" && entry == nil:
+			release.Date = strings.TrimSpace(strings.TrimPrefix(line, "Released:"))
+		case strings.HasPrefix(line, ">") && entry == nil:
+			notice = append(notice, strings.TrimSpace(strings.TrimPrefix(line, ">")))
+		case strings.HasPrefix(line, "- "):
+			flushEntry()
+			flushNotice()
+			entry = &Entry{Text: strings.TrimPrefix(line, "- ")}
+			if m := entryRe.FindStringSubmatch(line); m != nil {
+				entry.Kind = m[1]
+				if fixed, ok := kindFixes[entry.Kind]; ok {
+					entry.Kind = fixed
+				}
+				entry.Text = m[2]
+			}
+		case entry != nil && line != "":
+			entry.Text += "\n" + strings.TrimPrefix(line, "  ")
+		case line == "":
+			flushNotice()
+		case strings.HasPrefix(line, "**") && entry == nil:
+			release.Notices = append(release.Notices, strings.Trim(line, "*()"))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read changelog: %w", err)
+	}
+	flushRelease()
+	if len(c.Releases) == 0 {
+		return nil, fmt.Errorf("no releases found")
+	}
+	return c, nil
+}
+
+func isBreakingText(text string) bool {
+	text = strings.ToLower(text)
+	for _, marker := range []string{"backward-incompatible", "backward incompatible", "breaking change"} {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Latest returns the newest release
+func (c *Changelog) Latest() Release {
+	return c.Releases[0]
+}
+
+// Release returns the release of the given version
+func (c *Changelog) Release(v Version) (Release, bool) {
+	for _, r := range c.Releases {
+		if r.Version.Compare(v) == 0 {
+			return r, true
+		}
+	}
+	return Release{}, false
+}
+
+// Between returns releases newer than from and not newer than to, ordered from the oldest to the newest
+func (c *Changelog) Between(from, to Version) []Release {
+	var result []Release
+	for i := len(c.Releases) - 1; i >= 0; i-- {
+		r := c.Releases[i]
+		if r.Version.Compare(from) > 0 && r.Version.Compare(to) <= 0 {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// Query filters changelog entries, zero fields match everything
+type Query struct {
+	// From excludes releases not newer than the version
+	From *Version
+	// To excludes releases newer than the version
+	To *Version
+	// Keyword is a space-separated list of terms which all must appear in the entry text, case-insensitive
+	Keyword string
+	// Kinds are entry kinds to keep
+	Kinds []string
+	// BreakingOnly keeps breaking entries only
+	BreakingOnly bool
+}
+
+// Search returns releases matching the query from the newest to the oldest,
+// keeping only matching entries. Releases without matching entries are omitted.
+func (c *Changelog) Search(q Query) []Release {
+	terms := strings.Fields(normalizeText(q.Keyword))
+	var result []Release
+	for _, r := range c.Releases {
+		if q.From != nil && r.Version.Compare(*q.From) <= 0 {
+			continue
+		}
+		if q.To != nil && r.Version.Compare(*q.To) > 0 {
+			continue
+		}
+		r.Entries = r.filter(func(e Entry) bool {
+			if q.BreakingOnly && !e.Breaking {
+				return false
+			}
+			if len(q.Kinds) > 0 && !slices.ContainsFunc(q.Kinds, func(k string) bool { return strings.EqualFold(k, e.Kind) }) {
+				return false
+			}
+			text := normalizeText(e.Text)
+			for _, term := range terms {
+				if !strings.Contains(text, term) {
+					return false
+				}
+			}
+			return true
+		})
+		if len(r.Entries) > 0 {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// normalizeText lowercases text and treats underscores and hyphens as spaces,
+// so `fit_window` matches "fit window"
+func normalizeText(s string) string {
+	return strings.NewReplacer("_", " ", "-", " ").Replace(strings.ToLower(s))
+}
+
+var availableFromRe = regexp.MustCompile(`\{\{%\s*available_from\s+"([^"]+)"\s+anomaly\s*%\}\}`)
+
+// AvailableFrom returns versions referenced by `available_from` shortcodes of vmanomaly docs markdown,
+// ordered from the oldest to the newest without duplicates
+func AvailableFrom(markdown string) []Version {
+	var result []Version
+	for _, m := range availableFromRe.FindAllStringSubmatch(markdown, -1) {
+		v, err := ParseVersion(m[1])
+		if err != nil {
+			continue
+		}
+		if !slices.ContainsFunc(result, func(o Version) bool { return o.Compare(v) == 0 }) {
+			result = append(result, v)
+		}
+	}
+	slices.SortFunc(result, Version.Compare)
+	return result
+}
@@ -0,0 +1,178 @@
+package changelog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testChangelog = `---
+title: CHANGELOG
+---
+Please find the changelog below.
+
+## v1.2.0
+Released: 2024-02-01
+
+> There is a known bug in this release.
+> Please upgrade to v1.2.1.
+
+- FEATURE: Added something
+  with a continuation line.
+
+  And a second paragraph.
+
+- BREAKING CHANGE: Removed ARIMA model.
+- IMPROVEMEMT: Typo in kind.
+
+## v1.1.0-beta
+Released: 2024-01-01
+
+**(Experimental Patch Release)**
+
+- IMPROVEMENT: Changed format.
+  > This is an backward-incompatible change, as config changed.
+- DEPRECATION: Old flag is deprecated.
+
+## v1.0.0
+- First public release
+`
+
+func TestParse(t *testing.T) {
+	c, err := Parse([]byte(testChangelog))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(c.Releases) != 3 {
+		t.Fatalf("expected 3 releases, got %d", len(c.Releases))
+	}
+
+	r := c.Latest()
+	if r.Version.String() != "v1.2.0" || r.Date != "2024-02-01" {
+		t.Errorf("latest = %s %s", r.Version, r.Date)
+	}
+	if len(r.Notices) != 1 || !strings.Contains(r.Notices[0], "upgrade to v1.2.1") {
+		t.Errorf("notices = %q", r.Notices)
+	}
+	if len(r.Entries) != 3 {
+		t.Fatalf("entries = %+v", r.Entries)
+	}
+	if r.Entries[0].Kind != KindFeature || !strings.Contains(r.Entries[0].Text, "second paragraph") {
+		t.Errorf("entry 0 = %+v", r.Entries[0])
+	}
+	if r.Entries[2].Kind != KindImprovement {
+		t.Errorf("misspelled kind must be normalized, got %q", r.Entries[2].Kind)
+	}
+	if len(r.Breaking()) != 1 {
+		t.Errorf("breaking = %+v", r.Breaking())
+	}
+
+	beta := c.Releases[1]
+	if beta.Version.Pre != "beta" || len(beta.Notices) != 1 || len(beta.Breaking()) != 1 || len(beta.Deprecations()) != 1 {
+		t.Errorf("beta = %+v", beta)
+	}
+	if first := c.Releases[2]; len(first.Entries) != 1 || first.Entries[0].Kind != "" {
+		t.Errorf("first release = %+v", first)
+	}
+
+	from, _ := ParseVersion("1.0")
+	to, _ := ParseVersion("v1.2.0")
+	between := c.Between(from, to)
+	if len(between) != 2 || between[0].Version.String() != "v1.1.0-beta" {
+		t.Errorf("Between() = %+v", between)
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.26.2", "1.26.2", 0},
+		{"v1.9.0", "v1.10.0", -1},
+		{"v1.22.0-experimental", "v1.22.0", -1},
+		{"v1.22.1", "v1.22.0-experimental", 1},
+		{"v2.0", "v1.99.99", 1},
+	}
+	for _, tt := range tests {
+		a, err := ParseVersion(tt.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseVersion(tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("%s.Compare(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+	if _, err := ParseVersion("latest"); err == nil {
+		t.Error("expected error for invalid version")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	c, err := Parse([]byte(testChangelog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, _ := ParseVersion("v1.0.0")
+	to, _ := ParseVersion("v1.1.0")
+	tests := []struct {
+		name string
+		q    Query
+		want string // versions and number of entries
+	}{
+		{"all", Query{}, "v1.2.0:3 v1.1.0-beta:2 v1.0.0:1"},
+		{"keyword", Query{Keyword: "ARIMA model"}, "v1.2.0:1"},
+		{"underscores", Query{Keyword: "continuation_line"}, "v1.2.0:1"},
+		{"range", Query{From: &from, To: &to}, "v1.1.0-beta:2"},
+		{"kinds", Query{Kinds: []string{"deprecation", "bugfix"}}, "v1.1.0-beta:1"},
+		{"breaking", Query{BreakingOnly: true}, "v1.2.0:1 v1.1.0-beta:1"},
+		{"no match", Query{Keyword: "prophet"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range c.Search(tt.q) {
+				got = append(got, fmt.Sprintf("%s:%d", r.Version, len(r.Entries)))
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("Search() = %q, want %q", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
+
+func TestAvailableFrom(t *testing.T) {
+	md := `### Feature {{% available_from "v1.25.0" anomaly %}}
+text {{% available_from "v1.13.0" anomaly %}} and {{%available_from "v1.25.0" anomaly%}}
+{{% available_from "v1.100.0" %}}`
+	got := AvailableFrom(md)
+	if len(got) != 2 || got[0].String() != "v1.13.0" || got[1].String() != "v1.25.0" {
+		t.Errorf("AvailableFrom() = %v", got)
+	}
+}
+
+func TestParse_Embedded(t *testing.T) {
+	data, err := os.ReadFile("../resources/docs/anomaly-detection/CHANGELOG.md")
+	if err != nil {
+		t.Fatalf("cannot read changelog: %v", err)
+	}
+	c, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	breaking := 0
+	for _, r := range c.Releases {
+		if len(r.Entries) == 0 {
+			t.Errorf("release %s has no entries", r.Version)
+		}
+		breaking += len(r.Breaking())
+	}
+	if breaking < 3 {
+		t.Errorf("expected at least 3 breaking entries, got %d", breaking)
+	}
+}
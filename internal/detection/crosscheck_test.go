@@ -0,0 +1,134 @@
+package detection
+
+import (
+	"cmp"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+// Directories of cross-check cases: results exported from vmanomaly and results of an independent
+// reference computation, which only supplement vmanomaly exports
+const (
+	crossCheckVmanomaly = "vmanomaly"
+	crossCheckReference = "reference"
+)
+
+// crossCheckCase is a detection result exported from vmanomaly, e.g. by running a model with `provide_series`
+// on the series with `fit_window` ending at fit_until and writing anomaly_score, yhat, yhat_lower and yhat_upper
+// of inferred points, or computed by the reference implementation.
+// Values are aligned with points at or after fit_until, nulls are NaN. Tolerance is relative.
+type crossCheckCase struct {
+	VmanomalyVersion string           `json:"vmanomaly_version,omitempty"`
+	Source           string           `json:"source,omitempty"`
+	ModelSpec        map[string]any   `json:"model_spec"`
+	FitUntil         float64          `json:"fit_until"`
+	Tolerance        float64          `json:"tolerance"`
+	Series           vmanomaly.Series `json:"series"`
+	Expected         struct {
+		AnomalyScore []*float64 `json:"anomaly_score"`
+		Yhat         []*float64 `json:"yhat"`
+		YhatLower    []*float64 `json:"yhat_lower"`
+		YhatUpper    []*float64 `json:"yhat_upper"`
+	} `json:"expected"`
+}
+
+// source describes where expected values come from in test errors
+func (c *crossCheckCase) source() string {
+	if c.VmanomalyVersion != "" {
+		return "vmanomaly " + c.VmanomalyVersion
+	}
+	return cmp.Or(c.Source, crossCheckReference)
+}
+
+// TestCrossCheck compares local detection with expected results in testdata/crosscheck.
+// Cases in the vmanomaly directory must have the vmanomaly version they were exported from,
+// every supported model must have a reference case, and models without vmanomaly exports are logged.
+func TestCrossCheck(t *testing.T) {
+	covered := map[string]map[string]bool{crossCheckVmanomaly: {}, crossCheckReference: {}}
+	for dir, classes := range covered {
+		paths, err := filepath.Glob(filepath.Join("testdata", "crosscheck", dir, "*.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range paths {
+			t.Run(dir+"/"+filepath.Base(path), func(t *testing.T) {
+				c := loadCrossCheckCase(t, path)
+				if dir == crossCheckVmanomaly && c.VmanomalyVersion == "" {
+					t.Fatal("vmanomaly_version of the exporting vmanomaly must be set")
+				}
+				classes[crossCheck(t, c)] = true
+			})
+		}
+	}
+	for _, class := range SupportedClasses() {
+		if !covered[crossCheckReference][class] {
+			t.Errorf("no reference cases of %s in testdata/crosscheck/%s", class, crossCheckReference)
+		}
+		if !covered[crossCheckVmanomaly][class] {
+			t.Logf("%s is not cross-checked against vmanomaly, export a case to testdata/crosscheck/%s", class, crossCheckVmanomaly)
+		}
+	}
+}
+
+func loadCrossCheckCase(t *testing.T, path string) *crossCheckCase {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c crossCheckCase
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Tolerance <= 0 {
+		t.Fatal("tolerance must be set")
+	}
+	return &c
+}
+
+// crossCheck compares detection results of the case and returns the model class
+func crossCheck(t *testing.T, c *crossCheckCase) string {
+	t.Helper()
+	spec, _, err := ParseSpec(c.ModelSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tolerance := c.Tolerance
+	r := Detect(spec, []vmanomaly.Series{c.Series}, Options{FitUntil: c.FitUntil})
+	sr := r.Series[0]
+	for _, check := range []struct {
+		name     string
+		got      Values
+		expected []*float64
+	}{
+		{"anomaly_score", sr.AnomalyScore, c.Expected.AnomalyScore},
+		{"yhat", sr.Yhat, c.Expected.Yhat},
+		{"yhat_lower", sr.YhatLower, c.Expected.YhatLower},
+		{"yhat_upper", sr.YhatUpper, c.Expected.YhatUpper},
+	} {
+		if check.expected == nil {
+			continue
+		}
+		if len(check.got) != len(check.expected) {
+			t.Fatalf("%s has %d points, %s has %d", check.name, len(check.got), c.source(), len(check.expected))
+		}
+		for i, want := range check.expected {
+			got := check.got[i]
+			if want == nil {
+				if !math.IsNaN(got) {
+					t.Errorf("%s[%d] = %v, %s has null", check.name, i, got, c.source())
+				}
+				continue
+			}
+			if math.IsNaN(got) || math.Abs(got-*want) > tolerance*max(1, math.Abs(*want)) {
+				t.Errorf("%s[%d] = %v, %s has %v", check.name, i, got, c.source(), *want)
+			}
+		}
+	}
+	return spec.Class
+}
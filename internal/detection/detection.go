@@ -0,0 +1,515 @@
+// Package detection is a pure-Go engine running simple vmanomaly models locally, e.g. for what-if checks
+// or when vmanomaly is unreachable.
+//
+// It implements the zscore, mad, std and rolling_quantile offline and rolling models and the zscore_online,
+// mad_online and quantile_online online models. Outputs follow vmanomaly semantics: yhat is the expected value,
+// [yhat_lower, yhat_upper] is the expected interval and anomaly_score grows from 0 at yhat to 1 at the interval
+// boundaries and above 1 outside of it. The detection_direction, min_dev_from_expected and scale common args
+// are supported. Online models compute quantiles exactly instead of t-digest approximations.
+// See https://docs.victoriametrics.com/anomaly-detection/components/models/
+package detection
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+// Model kinds, which define how fit data is used
+const (
+	KindOffline = "offline" // Fit on fit data, then score inferred points
+	KindOnline  = "online"  // Updated with fit data and then with every inferred point after scoring it
+	KindRolling = "rolling" // Fit data is history of rolling windows over inferred points
+)
+
+// Detection directions of the detection_direction common arg
+const (
+	DirectionBoth  = "both"
+	DirectionAbove = "above_expected"
+	DirectionBelow = "below_expected"
+)
+
+// DefaultThreshold is the anomaly score above which points are reported as anomalies
+const DefaultThreshold = 1.0
+
+// Prediction is the expected value and interval at a point. Yhat is NaN if the model has no estimate,
+// e.g. during the warm-up of a rolling window. Scores of points predicted by not ready models are 0.
+type Prediction struct {
+	Yhat  float64
+	Lower float64
+	Upper float64
+	Ready bool
+}
+
+// Model is a univariate model of a single series
+type Model interface {
+	// Fit fits the model. Online models are updated with fit data and rolling models keep it as history.
+	Fit(ts, y []float64)
+	// Predict returns predictions of inferred points. Online models are updated after every prediction.
+	Predict(ts, y []float64) []Prediction
+}
+
+// Spec is a parsed model spec
+type Spec struct {
+	Class     string     // Class alias, e.g. zscore_online
+	Kind      string     // Model kind: offline, online or rolling
+	Direction string     // Detection direction
+	MinDev    [2]float64 // Minimal deviation from yhat below and above it to get a non-zero score
+	Scale     [2]float64 // Scale of the lower and upper margins of the expected interval
+
+	newModel func() Model
+}
+
+// NewModel returns a new model of the spec
+func (s *Spec) NewModel() Model {
+	return s.newModel()
+}
+
+// modelInfo describes a supported model class
+type modelInfo struct {
+	class string
+	kind  string
+	build func(p *params) (func() Model, error)
+}
+
+// supportedModels are supported models by normalized class names
+var supportedModels = map[string]modelInfo{
+	"zscore":          {class: "zscore", kind: KindOffline, build: buildZScore},
+	"zscoreonline":    {class: "zscore_online", kind: KindOnline, build: buildZScoreOnline},
+	"onlinezscore":    {class: "zscore_online", kind: KindOnline, build: buildZScoreOnline},
+	"mad":             {class: "mad", kind: KindOffline, build: buildMAD},
+	"madonline":       {class: "mad_online", kind: KindOnline, build: buildMADOnline},
+	"onlinemad":       {class: "mad_online", kind: KindOnline, build: buildMADOnline},
+	"std":             {class: "std", kind: KindRolling, build: buildStd},
+	"rollingquantile": {class: "rolling_quantile", kind: KindRolling, build: buildRollingQuantile},
+	"quantileonline":  {class: "quantile_online", kind: KindOnline, build: buildQuantileOnline},
+	"onlinequantile":  {class: "quantile_online", kind: KindOnline, build: buildQuantileOnline},
+}
+
+// SupportedClasses returns class aliases of supported models
+func SupportedClasses() []string {
+	var classes []string
+	for _, info := range supportedModels {
+		if !slices.Contains(classes, info.class) {
+			classes = append(classes, info.class)
+		}
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+// ignoredParams are model spec keys without effect on local detection
+var ignoredParams = []string{"class", "queries", "schedulers", "provide_series"}
+
+// ParseSpec parses a vmanomaly model spec. Both class aliases (e.g. `zscore`) and full class names
+// (e.g. `model.zscore.ZscoreModel`) are supported. Warnings list parameters ignored by the local engine.
+func ParseSpec(modelSpec map[string]any) (*Spec, []string, error) {
+	class, _ := modelSpec["class"].(string)
+	if class == "" {
+		return nil, nil, fmt.Errorf("model_spec.class is required")
+	}
+	info, ok := supportedModels[normalizeClass(class)]
+	if !ok {
+		return nil, nil, fmt.Errorf("model class %q is not supported locally, supported classes: %s", class, strings.Join(SupportedClasses(), ", "))
+	}
+
+	p := &params{m: modelSpec, used: map[string]bool{}}
+	for _, key := range ignoredParams {
+		p.used[key] = true
+	}
+	spec := &Spec{
+		Class:     info.class,
+		Kind:      info.kind,
+		Direction: p.str("detection_direction", DirectionBoth),
+		MinDev:    p.pair("min_dev_from_expected", 0),
+		Scale:     p.pair("scale", 1),
+	}
+	switch spec.Direction {
+	case DirectionBoth, DirectionAbove, DirectionBelow:
+	default:
+		p.errorf("detection_direction must be one of %s, %s or %s, got %q", DirectionBoth, DirectionAbove, DirectionBelow, spec.Direction)
+	}
+	if spec.MinDev[0] < 0 || spec.MinDev[1] < 0 {
+		p.errorf("min_dev_from_expected must be >= 0")
+	}
+	if spec.Scale[0] <= 0 || spec.Scale[1] <= 0 {
+		p.errorf("scale must be > 0")
+	}
+	newModel, err := info.build(p)
+	if err == nil {
+		err = p.err()
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s model spec: %w", info.class, err)
+	}
+	spec.newModel = newModel
+
+	var warnings []string
+	for _, key := range sortedKeys(modelSpec) {
+		if !p.used[key] {
+			warnings = append(warnings, fmt.Sprintf("parameter %q is not supported by local detection and is ignored", key))
+		}
+	}
+	return spec, warnings, nil
+}
+
+// normalizeClass converts a class alias or a full class name to a lookup key, e.g. model.online.OnlineZscoreModel to onlinezscore
+func normalizeClass(class string) string {
+	key := class
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		key = key[i+1:]
+	}
+	key = strings.ToLower(strings.ReplaceAll(key, "_", ""))
+	if key != "model" {
+		key = strings.TrimSuffix(key, "model")
+	}
+	return key
+}
+
+// Score returns the anomaly score of y and the expected interval with scale applied
+func (s *Spec) Score(y float64, p Prediction) (score, lower, upper float64) {
+	lower = p.Yhat - (p.Yhat-p.Lower)*s.Scale[0]
+	upper = p.Yhat + (p.Upper-p.Yhat)*s.Scale[1]
+	if !isFinite(y) || math.IsNaN(p.Yhat) {
+		return math.NaN(), lower, upper
+	}
+	if !p.Ready {
+		return 0, lower, upper
+	}
+
+	dev := y - p.Yhat
+	width := upper - p.Yhat
+	minDev := s.MinDev[1]
+	if dev < 0 {
+		if s.Direction == DirectionAbove {
+			return 0, lower, upper
+		}
+		width = p.Yhat - lower
+		minDev = s.MinDev[0]
+	} else if dev > 0 && s.Direction == DirectionBelow {
+		return 0, lower, upper
+	}
+	dev = math.Abs(dev)
+	if dev == 0 || dev < minDev {
+		return 0, lower, upper
+	}
+	// A degenerate interval, e.g. of a constant series, makes any deviation an anomaly
+	width = max(width, 1e-9*max(math.Abs(p.Yhat), 1))
+	return dev / width, lower, upper
+}
+
+// ============================================================================
+// Detection
+// ============================================================================
+
+// Options are options of Detect
+type Options struct {
+	// FitUntil splits series: points before it fit models and later points are scored.
+	// If zero, offline models are fit on whole series, and all points are scored.
+	FitUntil float64
+	// Threshold is the anomaly score above which points are reported as anomalies, DefaultThreshold if not set
+	Threshold float64
+}
+
+// Values are series values encoded to JSON with null instead of NaN and infinities
+type Values []float64
+
+// MarshalJSON implements json.Marshaler
+func (v Values) MarshalJSON() ([]byte, error) {
+	b := []byte{'['}
+	for i, f := range v {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		if isFinite(f) {
+			b = strconv.AppendFloat(b, f, 'g', -1, 64)
+		} else {
+			b = append(b, "null"...)
+		}
+	}
+	return append(b, ']'), nil
+}
+
+// Anomaly is a point with the anomaly score above the threshold
+type Anomaly struct {
+	Timestamp    float64 `json:"timestamp"`
+	Y            float64 `json:"y"`
+	AnomalyScore float64 `json:"anomaly_score"`
+	Yhat         float64 `json:"yhat"`
+}
+
+// SeriesResult is the detection result of a series, values are aligned with scored timestamps
+type SeriesResult struct {
+	Labels       map[string]string `json:"labels"`
+	Timestamps   []float64         `json:"timestamps"`
+	Y            Values            `json:"y"`
+	AnomalyScore Values            `json:"anomaly_score"`
+	Yhat         Values            `json:"yhat"`
+	YhatLower    Values            `json:"yhat_lower"`
+	YhatUpper    Values            `json:"yhat_upper"`
+	Anomalies    []Anomaly         `json:"anomalies,omitempty"`
+}
+
+// Result is the detection result of all series
+type Result struct {
+	Model     string         `json:"model"`
+	Kind      string         `json:"kind"`
+	Threshold float64        `json:"threshold"`
+	Points    int            `json:"points"`
+	Anomalies int            `json:"anomalies"`
+	Series    []SeriesResult `json:"series"`
+	Warnings  []string       `json:"warnings,omitempty"`
+}
+
+// Detect runs a model of the spec on every series
+func Detect(spec *Spec, series []vmanomaly.Series, opts Options) *Result {
+	threshold := opts.Threshold
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	r := &Result{Model: spec.Class, Kind: spec.Kind, Threshold: threshold, Series: []SeriesResult{}}
+	for _, s := range series {
+		sr, warning := detectSeries(spec, s, opts.FitUntil, threshold)
+		if warning != "" {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("series %s: %s", formatLabels(s.Labels), warning))
+		}
+		r.Points += len(sr.Timestamps)
+		r.Anomalies += len(sr.Anomalies)
+		r.Series = append(r.Series, sr)
+	}
+	return r
+}
+
+func detectSeries(spec *Spec, s vmanomaly.Series, fitUntil, threshold float64) (SeriesResult, string) {
+	ts, y := sortedPoints(s)
+	split := 0
+	if fitUntil > 0 {
+		split = sort.SearchFloat64s(ts, fitUntil)
+	}
+	fitTs, fitY := ts[:split], y[:split]
+	if fitUntil <= 0 && spec.Kind == KindOffline {
+		fitTs, fitY = ts, y
+	}
+
+	var warning string
+	if spec.Kind == KindOffline && countFinite(fitY) == 0 {
+		warning = "no fit data, points are not scored"
+	}
+	m := spec.NewModel()
+	m.Fit(fitTs, fitY)
+	preds := m.Predict(ts[split:], y[split:])
+
+	sr := SeriesResult{Labels: s.Labels, Timestamps: ts[split:], Y: y[split:]}
+	if sr.Labels == nil {
+		sr.Labels = map[string]string{}
+	}
+	for i, p := range preds {
+		score, lower, upper := spec.Score(sr.Y[i], p)
+		sr.AnomalyScore = append(sr.AnomalyScore, score)
+		sr.Yhat = append(sr.Yhat, p.Yhat)
+		sr.YhatLower = append(sr.YhatLower, lower)
+		sr.YhatUpper = append(sr.YhatUpper, upper)
+		if score > threshold {
+			sr.Anomalies = append(sr.Anomalies, Anomaly{Timestamp: sr.Timestamps[i], Y: sr.Y[i], AnomalyScore: score, Yhat: p.Yhat})
+		}
+	}
+	return sr, warning
+}
+
+// TaskResult converts the result to the result of a vmanomaly detection task
+func (r *Result) TaskResult() *vmanomaly.TaskResult {
+	var data map[string]any
+	if b, err := json.Marshal(map[string]any{"series": r.Series}); err == nil {
+		_ = json.Unmarshal(b, &data)
+	}
+	stats := map[string]any{
+		"model":     r.Model,
+		"series":    len(r.Series),
+		"points":    r.Points,
+		"anomalies": r.Anomalies,
+		"threshold": r.Threshold,
+	}
+	if len(r.Warnings) > 0 {
+		stats["warnings"] = r.Warnings
+	}
+	return &vmanomaly.TaskResult{Status: "success", Data: data, Stats: stats}
+}
+
+// sortedPoints returns points of the series sorted by timestamp
+func sortedPoints(s vmanomaly.Series) ([]float64, []float64) {
+	n := min(len(s.Timestamps), len(s.Values))
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return s.Timestamps[idx[a]] < s.Timestamps[idx[b]] })
+	ts := make([]float64, n)
+	y := make([]float64, n)
+	for i, j := range idx {
+		ts[i], y[i] = s.Timestamps[j], s.Values[j]
+	}
+	return ts, y
+}
+
+func formatLabels(labels map[string]string) string {
+	parts := make([]string, 0, len(labels))
+	for _, k := range sortedKeys(labels) {
+		parts = append(parts, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+func countFinite(values []float64) int {
+	n := 0
+	for _, v := range values {
+		if isFinite(v) {
+			n++
+		}
+	}
+	return n
+}
+
+// ============================================================================
+// Parameters
+// ============================================================================
+
+// params reads model spec parameters and tracks used keys and errors
+type params struct {
+	m    map[string]any
+	used map[string]bool
+	errs []string
+}
+
+func (p *params) errorf(format string, args ...any) {
+	p.errs = append(p.errs, fmt.Sprintf(format, args...))
+}
+
+func (p *params) err() error {
+	if len(p.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(p.errs, "; "))
+}
+
+func (p *params) get(key string) (any, bool) {
+	p.used[key] = true
+	v, ok := p.m[key]
+	return v, ok && v != nil
+}
+
+func (p *params) float(key string, def float64) float64 {
+	v, ok := p.get(key)
+	if !ok {
+		return def
+	}
+	f, ok := toFloat(v)
+	if !ok {
+		p.errorf("%s must be a number, got %v", key, v)
+		return def
+	}
+	return f
+}
+
+func (p *params) int(key string, def int) int {
+	f := p.float(key, float64(def))
+	if f != math.Trunc(f) {
+		p.errorf("%s must be an integer, got %v", key, f)
+	}
+	return int(f)
+}
+
+func (p *params) str(key, def string) string {
+	v, ok := p.get(key)
+	if !ok {
+		return def
+	}
+	s, ok := v.(string)
+	if !ok {
+		p.errorf("%s must be a string, got %v", key, v)
+		return def
+	}
+	return s
+}
+
+func (p *params) floats(key string, def []float64) []float64 {
+	v, ok := p.get(key)
+	if !ok {
+		return def
+	}
+	list, ok := v.([]any)
+	if !ok {
+		if fs, ok := v.([]float64); ok {
+			return fs
+		}
+		p.errorf("%s must be a list of numbers, got %v", key, v)
+		return def
+	}
+	fs := make([]float64, 0, len(list))
+	for _, item := range list {
+		f, ok := toFloat(item)
+		if !ok {
+			p.errorf("%s must be a list of numbers, got %v", key, v)
+			return def
+		}
+		fs = append(fs, f)
+	}
+	return fs
+}
+
+// pair reads a number broadcasted to both sides or a list of lower and upper numbers
+func (p *params) pair(key string, def float64) [2]float64 {
+	v, ok := p.m[key]
+	if !ok || v == nil {
+		p.used[key] = true
+		return [2]float64{def, def}
+	}
+	if f, ok := toFloat(v); ok {
+		p.used[key] = true
+		return [2]float64{f, f}
+	}
+	fs := p.floats(key, nil)
+	switch len(fs) {
+	case 1:
+		return [2]float64{fs[0], fs[0]}
+	case 2:
+		return [2]float64{fs[0], fs[1]}
+	}
+	p.errorf("%s must be a number or a list of two numbers, got %v", key, v)
+	return [2]float64{def, def}
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
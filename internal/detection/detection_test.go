@@ -0,0 +1,279 @@
+package detection
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+const eps = 1e-9
+
+func almostEqual(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return math.Abs(a-b) <= eps*max(1, math.Abs(b))
+}
+
+func mustSpec(t *testing.T, modelSpec map[string]any) *Spec {
+	t.Helper()
+	spec, _, err := ParseSpec(modelSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		spec     map[string]any
+		class    string
+		kind     string
+		warnings int
+		err      string
+	}{
+		{spec: map[string]any{"class": "zscore"}, class: "zscore", kind: KindOffline},
+		{spec: map[string]any{"class": "model.online.OnlineZscoreModel", "decay": 0.99}, class: "zscore_online", kind: KindOnline},
+		{spec: map[string]any{"class": "mad_online", "compression": 100.0}, class: "mad_online", kind: KindOnline},
+		{spec: map[string]any{"class": "std", "period": 24.0, "queries": []any{"q1"}}, class: "std", kind: KindRolling},
+		{spec: map[string]any{"class": "quantile_online", "seasonal_interval": "7d", "min_subseason": "1h", "season_starts_from": "2024-01-01"}, class: "quantile_online", kind: KindOnline},
+		{spec: map[string]any{"class": "zscore", "clip_predictions": true, "foo": 1.0}, class: "zscore", kind: KindOffline, warnings: 2},
+		{spec: map[string]any{}, err: "class is required"},
+		{spec: map[string]any{"class": "prophet"}, err: "not supported locally"},
+		{spec: map[string]any{"class": "zscore", "detection_direction": "up"}, err: "detection_direction"},
+		{spec: map[string]any{"class": "zscore", "min_dev_from_expected": []any{1.0, 2.0, 3.0}}, err: "min_dev_from_expected"},
+		{spec: map[string]any{"class": "rolling_quantile", "quantile": 0.9}, err: "window_steps"},
+		{spec: map[string]any{"class": "quantile_online", "seasonal_interval": "1d"}, err: "min_subseason is required"},
+		{spec: map[string]any{"class": "zscore_online", "min_n_samples_seen": 1.5}, err: "integer"},
+	}
+	for _, tt := range tests {
+		spec, warnings, err := ParseSpec(tt.spec)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseSpec(%v) error = %v, want %q", tt.spec, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSpec(%v) error = %v", tt.spec, err)
+			continue
+		}
+		if spec.Class != tt.class || spec.Kind != tt.kind || len(warnings) != tt.warnings {
+			t.Errorf("ParseSpec(%v) = %s %s %v, want %s %s %d warnings", tt.spec, spec.Class, spec.Kind, warnings, tt.class, tt.kind, tt.warnings)
+		}
+	}
+}
+
+func TestSpecScore(t *testing.T) {
+	p := Prediction{Yhat: 10, Lower: 6, Upper: 12, Ready: true}
+	tests := []struct {
+		name string
+		spec map[string]any
+		y    float64
+		want float64
+	}{
+		{"at yhat", nil, 10, 0},
+		{"inside above", nil, 11, 0.5},
+		{"at upper", nil, 12, 1},
+		{"outside below", nil, 2, 2},
+		{"NaN", nil, math.NaN(), math.NaN()},
+		{"above only ignores below", map[string]any{"detection_direction": DirectionAbove}, 2, 0},
+		{"above only scores above", map[string]any{"detection_direction": DirectionAbove}, 14, 2},
+		{"below only ignores above", map[string]any{"detection_direction": DirectionBelow}, 14, 0},
+		{"min dev", map[string]any{"min_dev_from_expected": 5.0}, 14, 0},
+		{"min dev per side", map[string]any{"min_dev_from_expected": []any{5.0, 1.0}}, 14, 2},
+		{"scale", map[string]any{"scale": []any{1.0, 2.0}}, 14, 1},
+	}
+	for _, tt := range tests {
+		modelSpec := map[string]any{"class": "zscore"}
+		for k, v := range tt.spec {
+			modelSpec[k] = v
+		}
+		if got, _, _ := mustSpec(t, modelSpec).Score(tt.y, p); !almostEqual(got, tt.want) {
+			t.Errorf("%s: Score(%v) = %v, want %v", tt.name, tt.y, got, tt.want)
+		}
+	}
+
+	spec := mustSpec(t, map[string]any{"class": "zscore"})
+	if got, _, _ := spec.Score(20, Prediction{Yhat: 10, Lower: 10, Upper: 10, Ready: true}); got <= 1 {
+		t.Errorf("Score() of a degenerate interval = %v, want > 1", got)
+	}
+	if got, _, _ := spec.Score(20, Prediction{Yhat: 10, Lower: 9, Upper: 11}); got != 0 {
+		t.Errorf("Score() of a not ready model = %v, want 0", got)
+	}
+}
+
+func TestModels(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name  string
+		spec  map[string]any
+		fit   []float64
+		infer []float64
+		want  []Prediction
+	}{
+		{
+			name:  "zscore",
+			spec:  map[string]any{"class": "zscore", "z_threshold": 2.0},
+			fit:   []float64{1, 2, 3, 4, 5, nan},
+			infer: []float64{6},
+			want:  []Prediction{{3, 3 - 2*math.Sqrt2, 3 + 2*math.Sqrt2, true}},
+		},
+		{
+			name:  "zscore not fitted",
+			spec:  map[string]any{"class": "zscore"},
+			infer: []float64{6},
+			want:  []Prediction{{nan, nan, nan, false}},
+		},
+		{
+			name:  "zscore_online",
+			spec:  map[string]any{"class": "zscore_online", "z_threshold": 1.0, "min_n_samples_seen": 2.0},
+			infer: []float64{1, 3, 5},
+			want:  []Prediction{{nan, nan, nan, false}, {1, 1, 1, false}, {2, 1, 3, true}},
+		},
+		{
+			name:  "zscore_online decay",
+			spec:  map[string]any{"class": "zscore_online", "z_threshold": 1.0, "min_n_samples_seen": 1.0, "decay": 0.5},
+			fit:   []float64{0, 3},
+			infer: []float64{0},
+			// Weights are 0.5 and 1, so the mean is 2 and the variance is (0.5*4 + 1*1) / 1.5
+			want: []Prediction{{2, 2 - math.Sqrt(2), 2 + math.Sqrt(2), true}},
+		},
+		{
+			name:  "mad",
+			spec:  map[string]any{"class": "mad", "threshold": 2.0},
+			fit:   []float64{1, 2, 3, 4, 100},
+			infer: []float64{7},
+			want:  []Prediction{{3, 1, 5, true}},
+		},
+		{
+			name:  "mad_online",
+			spec:  map[string]any{"class": "mad_online", "threshold": 1.0, "min_n_samples_seen": 4.0},
+			fit:   []float64{1, 2, 3},
+			infer: []float64{4, 0},
+			want:  []Prediction{{2, 1, 3, false}, {2.5, 1.5, 3.5, true}},
+		},
+		{
+			name:  "rolling_quantile",
+			spec:  map[string]any{"class": "rolling_quantile", "quantile": 0.9, "window_steps": 3.0},
+			fit:   []float64{1},
+			infer: []float64{2, 3, 10},
+			want:  []Prediction{{nan, nan, nan, false}, {2, 1.2, 2.8, true}, {3, 2.2, 8.6, true}},
+		},
+		{
+			name:  "std",
+			spec:  map[string]any{"class": "std", "period": 2.0},
+			fit:   []float64{0, 2},
+			infer: []float64{0, 2, 0, 2},
+			want:  []Prediction{{0, 0, 0, true}, {2, 2, 2, true}, {0, 0, 0, true}, {2, 2, 2, true}},
+		},
+		{
+			name:  "quantile_online",
+			spec:  map[string]any{"class": "quantile_online", "quantiles": []any{0.25, 0.5, 0.75}, "iqr_threshold": 1.0, "min_n_samples_seen": 1.0},
+			fit:   []float64{1, 2, 3, 4, 5},
+			infer: []float64{6},
+			want:  []Prediction{{3, 0, 6, true}},
+		},
+	}
+	for _, tt := range tests {
+		m := mustSpec(t, tt.spec).NewModel()
+		ts := func(values []float64, offset int) []float64 {
+			ts := make([]float64, len(values))
+			for i := range ts {
+				ts[i] = float64((offset + i) * 60)
+			}
+			return ts
+		}
+		m.Fit(ts(tt.fit, 0), tt.fit)
+		got := m.Predict(ts(tt.infer, len(tt.fit)), tt.infer)
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: Predict() = %v, want %v", tt.name, got, tt.want)
+		}
+		for i := range got {
+			g, w := got[i], tt.want[i]
+			if !almostEqual(g.Yhat, w.Yhat) || !almostEqual(g.Lower, w.Lower) || !almostEqual(g.Upper, w.Upper) || g.Ready != w.Ready {
+				t.Errorf("%s: Predict()[%d] = %+v, want %+v", tt.name, i, g, w)
+			}
+		}
+	}
+}
+
+func TestQuantileOnline_Seasonal(t *testing.T) {
+	spec := mustSpec(t, map[string]any{
+		"class":              "quantile_online",
+		"quantiles":          []any{0.1, 0.5, 0.9},
+		"seasonal_interval":  "2h",
+		"min_subseason":      "1h",
+		"min_n_samples_seen": 1.0,
+		"global_smoothing":   0.5,
+	})
+	m := spec.NewModel()
+	// Even hours are around 10 and odd hours are around 100
+	m.Fit([]float64{0, 3600, 7200, 10800}, []float64{10, 100, 10, 100})
+	got := m.Predict([]float64{14400, 18000}, []float64{10, 100})
+	// Subseason medians are smoothed towards global medians of 55 and then 10
+	if !almostEqual(got[0].Yhat, 32.5) || !almostEqual(got[1].Yhat, 55) {
+		t.Errorf("Predict() = %+v", got)
+	}
+}
+
+func TestMedianAbsDev(t *testing.T) {
+	tests := []struct {
+		sorted []float64
+		want   float64
+	}{
+		{[]float64{5}, 0},
+		{[]float64{1, 2, 3, 4}, 1},
+		{[]float64{1, 2, 3, 4, 100}, 1},
+		{[]float64{1, 1, 2, 2, 4, 6, 9}, 1},
+	}
+	for _, tt := range tests {
+		if got := medianAbsDev(tt.sorted, quantileSorted(tt.sorted, 0.5)); !almostEqual(got, tt.want) {
+			t.Errorf("medianAbsDev(%v) = %v, want %v", tt.sorted, got, tt.want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	spec := mustSpec(t, map[string]any{"class": "zscore", "z_threshold": 2.0})
+	series := []vmanomaly.Series{{
+		Labels: map[string]string{"job": "api"},
+		// Unsorted points are sorted by timestamp
+		Timestamps: []float64{60, 0, 120, 180, 240, 300, 360},
+		Values:     []float64{2, 1, 3, 4, 5, 20, math.NaN()},
+	}}
+
+	r := Detect(spec, series, Options{FitUntil: 300})
+	if r.Points != 2 || r.Anomalies != 1 || len(r.Series) != 1 {
+		t.Fatalf("Detect() = %+v", r)
+	}
+	sr := r.Series[0]
+	if sr.Timestamps[0] != 300 || sr.Anomalies[0].Timestamp != 300 || !almostEqual(sr.AnomalyScore[0], 17/(2*math.Sqrt2)) {
+		t.Errorf("Detect() series = %+v", sr)
+	}
+
+	// Without FitUntil offline models fit on all points
+	if r := Detect(spec, series, Options{Threshold: 100}); r.Points != 7 || r.Anomalies != 0 {
+		t.Errorf("Detect() without fit split = %+v", r)
+	}
+	// Series without fit data are not scored
+	noFit := []vmanomaly.Series{{Labels: map[string]string{"job": "api"}, Timestamps: []float64{0, 60}, Values: []float64{math.NaN(), 1}}}
+	if r := Detect(spec, noFit, Options{FitUntil: 60}); len(r.Warnings) != 1 || !strings.Contains(r.Warnings[0], `job="api"`) {
+		t.Errorf("Detect() warnings = %v", r.Warnings)
+	}
+
+	tr := r.TaskResult()
+	if tr.Stats["anomalies"] != 1 || tr.Stats["model"] != "zscore" {
+		t.Errorf("TaskResult() stats = %v", tr.Stats)
+	}
+	data, err := json.Marshal(tr.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"anomaly_score":[6.0104076400856545,null]`) {
+		t.Errorf("TaskResult() data = %s", data)
+	}
+}
@@ -0,0 +1,474 @@
+package detection
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+// Formats of inline series
+const (
+	FormatAuto       = "auto"
+	FormatCSV        = "csv"
+	FormatJSON       = "json"
+	FormatPrometheus = "prometheus"
+)
+
+// timestampColumns are names of timestamp columns and keys, the first CSV column is used if none matches
+var timestampColumns = []string{"timestamp", "time", "ts", "_time", "date", "datetime"}
+
+// ParseSeries parses inline series in the format, or detects the format if it is FormatAuto or empty.
+// Supported formats are:
+//   - csv with a header: a timestamp column and either a `value` column with label columns (long format)
+//     or a column per series (wide format);
+//   - json: a Prometheus query response, a list of series objects with `metric` or `labels` and
+//     `values` as [timestamp, value] pairs or `timestamps` and `values` lists, a list of [timestamp, value] pairs,
+//     or a list of point objects with `timestamp`, `value` and label keys;
+//   - prometheus: text exposition format where every sample has a timestamp in milliseconds.
+//
+// Timestamps are Unix seconds, milliseconds (detected by magnitude) or RFC3339 strings.
+func ParseSeries(format, data string) ([]vmanomaly.Series, error) {
+	if format == "" || format == FormatAuto {
+		format = detectFormat(data)
+	}
+	var series []vmanomaly.Series
+	var err error
+	switch format {
+	case FormatCSV:
+		series, err = parseCSV(data)
+	case FormatJSON:
+		series, err = parseJSON(data)
+	case FormatPrometheus:
+		series, err = parsePrometheus(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q, expected %s, %s, %s or %s", format, FormatAuto, FormatCSV, FormatJSON, FormatPrometheus)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s data: %w", format, err)
+	}
+	if len(series) == 0 {
+		return nil, fmt.Errorf("no series in %s data", format)
+	}
+	return series, nil
+}
+
+// detectFormat guesses the format of data by its first meaningful line
+func detectFormat(data string) string {
+	trimmed := strings.TrimSpace(data)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return FormatJSON
+	}
+	for _, line := range strings.Split(trimmed, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.Contains(line, ",") && !strings.Contains(line, "{") {
+			return FormatCSV
+		}
+		break
+	}
+	return FormatPrometheus
+}
+
+// ============================================================================
+// CSV
+// ============================================================================
+
+func parseCSV(data string) ([]vmanomaly.Series, error) {
+	r := csv.NewReader(strings.NewReader(strings.TrimSpace(data)))
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("expected a header and at least one row")
+	}
+	header := rows[0]
+	tsCol, valueCol := 0, -1
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if isTimestampKey(name) {
+			tsCol = i
+		}
+		if name == "value" {
+			valueCol = i
+		}
+	}
+
+	g := newSeriesGroups()
+	for n, row := range rows[1:] {
+		line := n + 2
+		ts, err := parseTimestamp(row[tsCol])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if valueCol >= 0 {
+			// Long format: other columns are labels
+			labels := map[string]string{}
+			for i, name := range header {
+				if i != tsCol && i != valueCol && row[i] != "" {
+					labels[strings.TrimSpace(name)] = row[i]
+				}
+			}
+			v, err := parseValue(row[valueCol])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			g.add(labels, ts, v)
+			continue
+		}
+		// Wide format: every other column is a series, empty cells are missing points
+		for i, name := range header {
+			if i == tsCol || strings.TrimSpace(row[i]) == "" {
+				continue
+			}
+			v, err := parseValue(row[i])
+			if err != nil {
+				return nil, fmt.Errorf("line %d, column %q: %w", line, name, err)
+			}
+			g.add(map[string]string{"__name__": strings.TrimSpace(name)}, ts, v)
+		}
+	}
+	return g.series, nil
+}
+
+// ============================================================================
+// JSON
+// ============================================================================
+
+func parseJSON(data string) ([]vmanomaly.Series, error) {
+	var v any
+	d := json.NewDecoder(strings.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		if _, ok := v["data"]; ok {
+			return vmanomaly.ParseQueryResult(normalizeNumbers(v).(map[string]any))
+		}
+		if _, ok := v["result"]; ok {
+			return vmanomaly.ParseQueryResult(normalizeNumbers(v).(map[string]any))
+		}
+		if _, ok := v["series"]; ok {
+			return parseJSONList(v["series"])
+		}
+		return parseJSONList([]any{v})
+	case []any:
+		return parseJSONList(v)
+	}
+	return nil, fmt.Errorf("expected an object or a list, got %T", v)
+}
+
+func parseJSONList(v any) ([]vmanomaly.Series, error) {
+	items, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a list, got %T", v)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	g := newSeriesGroups()
+	if _, ok := items[0].([]any); ok {
+		// A single series of [timestamp, value] pairs
+		s, err := parsePairs(items)
+		if err != nil {
+			return nil, err
+		}
+		return []vmanomaly.Series{s}, nil
+	}
+	for i, item := range items {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("item #%d: expected an object, got %T", i, item)
+		}
+		if _, ok := obj["values"]; ok {
+			s, err := parseSeriesObject(obj)
+			if err != nil {
+				return nil, fmt.Errorf("series #%d: %w", i, err)
+			}
+			g.series = append(g.series, s)
+			continue
+		}
+		// A point object with label keys
+		var ts, value float64
+		var hasTs, hasValue bool
+		labels := map[string]string{}
+		for key, val := range obj {
+			var err error
+			switch {
+			case isTimestampKey(strings.ToLower(key)):
+				ts, err = parseTimestamp(jsonString(val))
+				hasTs = true
+			case key == "value" || key == "y":
+				value, err = parseValue(jsonString(val))
+				hasValue = true
+			default:
+				labels[key] = jsonString(val)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("point #%d: %w", i, err)
+			}
+		}
+		if !hasTs || !hasValue {
+			return nil, fmt.Errorf("point #%d: expected timestamp and value keys", i)
+		}
+		g.add(labels, ts, value)
+	}
+	return g.series, nil
+}
+
+// parseSeriesObject parses a series with `values` as [timestamp, value] pairs or with a `timestamps` list
+func parseSeriesObject(obj map[string]any) (vmanomaly.Series, error) {
+	labels := map[string]string{}
+	for _, key := range []string{"metric", "labels"} {
+		if m, ok := obj[key].(map[string]any); ok {
+			for k, v := range m {
+				labels[k] = jsonString(v)
+			}
+		}
+	}
+	values, ok := obj["values"].([]any)
+	if !ok {
+		return vmanomaly.Series{}, fmt.Errorf("values must be a list")
+	}
+	rawTs, ok := obj["timestamps"].([]any)
+	if !ok {
+		s, err := parsePairs(values)
+		s.Labels = labels
+		return s, err
+	}
+	if len(rawTs) != len(values) {
+		return vmanomaly.Series{}, fmt.Errorf("%d timestamps and %d values", len(rawTs), len(values))
+	}
+	s := vmanomaly.Series{Labels: labels}
+	for i := range values {
+		ts, err := parseTimestamp(jsonString(rawTs[i]))
+		if err != nil {
+			return s, err
+		}
+		v, err := parseValue(jsonString(values[i]))
+		if err != nil {
+			return s, err
+		}
+		s.Timestamps = append(s.Timestamps, ts)
+		s.Values = append(s.Values, v)
+	}
+	return s, nil
+}
+
+func parsePairs(items []any) (vmanomaly.Series, error) {
+	s := vmanomaly.Series{Labels: map[string]string{}}
+	for i, item := range items {
+		pair, ok := item.([]any)
+		if !ok || len(pair) != 2 {
+			return s, fmt.Errorf("point #%d: expected a [timestamp, value] pair, got %v", i, item)
+		}
+		ts, err := parseTimestamp(jsonString(pair[0]))
+		if err != nil {
+			return s, fmt.Errorf("point #%d: %w", i, err)
+		}
+		v, err := parseValue(jsonString(pair[1]))
+		if err != nil {
+			return s, fmt.Errorf("point #%d: %w", i, err)
+		}
+		s.Timestamps = append(s.Timestamps, ts)
+		s.Values = append(s.Values, v)
+	}
+	return s, nil
+}
+
+// jsonString returns a JSON scalar as a string, nulls are empty
+func jsonString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// normalizeNumbers converts json.Number to float64 for parsers expecting decoded JSON
+func normalizeNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, item := range v {
+			v[k] = normalizeNumbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+	}
+	return v
+}
+
+// ============================================================================
+// Prometheus exposition
+// ============================================================================
+
+func parsePrometheus(data string) ([]vmanomaly.Series, error) {
+	g := newSeriesGroups()
+	for n, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		labels, rest, err := parseMetric(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		fields := strings.Fields(rest)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a value and a timestamp in milliseconds", n+1)
+		}
+		v, err := parseValue(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		ms, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid timestamp %q", n+1, fields[1])
+		}
+		g.add(labels, float64(ms)/1000, v)
+	}
+	return g.series, nil
+}
+
+// parseMetric parses the metric name with labels at the start of the line and returns the rest of the line
+func parseMetric(line string) (map[string]string, string, error) {
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return nil, "", fmt.Errorf("expected a metric name")
+	}
+	labels := map[string]string{"__name__": line[:end]}
+	line = line[end:]
+	if !strings.HasPrefix(line, "{") {
+		return labels, line, nil
+	}
+	line = line[1:]
+	for {
+		line = strings.TrimLeft(line, " \t,")
+		if strings.HasPrefix(line, "}") {
+			return labels, line[1:], nil
+		}
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 || len(line) < eq+2 || line[eq+1] != '"' {
+			return nil, "", fmt.Errorf("expected label=\"value\" in %q", line)
+		}
+		name := strings.TrimSpace(line[:eq])
+		value, n, err := unquoteLabelValue(line[eq+1:])
+		if err != nil {
+			return nil, "", err
+		}
+		labels[name] = value
+		line = line[eq+1+n:]
+	}
+}
+
+// unquoteLabelValue unquotes the quoted label value at the start of s and returns its length in s
+func unquoteLabelValue(s string) (string, int, error) {
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return sb.String(), i + 1, nil
+		case '\\':
+			if i+1 == len(s) {
+				break
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			default:
+				sb.WriteByte(s[i])
+			}
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated label value in %q", s)
+}
+
+// ============================================================================
+// Helpers
+// ============================================================================
+
+// seriesGroups groups points by labels keeping the order series appear in
+type seriesGroups struct {
+	series []vmanomaly.Series
+	index  map[string]int
+}
+
+func newSeriesGroups() *seriesGroups {
+	return &seriesGroups{index: map[string]int{}}
+}
+
+func (g *seriesGroups) add(labels map[string]string, ts, v float64) {
+	key := formatLabels(labels)
+	i, ok := g.index[key]
+	if !ok {
+		i = len(g.series)
+		g.index[key] = i
+		g.series = append(g.series, vmanomaly.Series{Labels: labels})
+	}
+	g.series[i].Timestamps = append(g.series[i].Timestamps, ts)
+	g.series[i].Values = append(g.series[i].Values, v)
+}
+
+func isTimestampKey(key string) bool {
+	for _, name := range timestampColumns {
+		if key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTimestamp parses Unix seconds, Unix milliseconds or an RFC3339 or `2006-01-02 15:04:05` UTC time
+func parseTimestamp(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if f, err := strconv.ParseFloat(s, 64); err == nil && isFinite(f) {
+		// Unix seconds stay below 1e11 until year 5138
+		if math.Abs(f) >= 1e11 {
+			f /= 1000
+		}
+		return f, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return float64(t.UnixNano()) / 1e9, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid timestamp %q, expected Unix seconds, milliseconds or RFC3339", s)
+}
+
+// parseValue parses a sample value, empty values and nulls are NaN
+func parseValue(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "null") {
+		return math.NaN(), nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
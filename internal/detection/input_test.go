@@ -0,0 +1,121 @@
+package detection
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSeries(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		data       string
+		labels     []map[string]string
+		timestamps []float64 // Of the first series
+		values     []float64 // Of the first series
+	}{
+		{
+			name:       "csv long",
+			data:       "timestamp,host,value\n1700000000,a,1\n1700000060,a,2\n1700000000,b,3\n",
+			labels:     []map[string]string{{"host": "a"}, {"host": "b"}},
+			timestamps: []float64{1700000000, 1700000060},
+			values:     []float64{1, 2},
+		},
+		{
+			name:       "csv wide with RFC3339 and missing cells",
+			data:       "time,cpu,mem\n2023-11-14T22:13:20Z,1,\n2023-11-14T22:14:20Z,2,5\n",
+			labels:     []map[string]string{{"__name__": "cpu"}, {"__name__": "mem"}},
+			timestamps: []float64{1700000000, 1700000060},
+			values:     []float64{1, 2},
+		},
+		{
+			name:       "json pairs in milliseconds",
+			data:       `[[1700000000000, 1], [1700000060000, "2"]]`,
+			labels:     []map[string]string{{}},
+			timestamps: []float64{1700000000, 1700000060},
+			values:     []float64{1, 2},
+		},
+		{
+			name:       "json series objects",
+			data:       `[{"labels": {"job": "api"}, "timestamps": [1700000000, 1700000060], "values": [1, null]}]`,
+			labels:     []map[string]string{{"job": "api"}},
+			timestamps: []float64{1700000000, 1700000060},
+			values:     []float64{1, math.NaN()},
+		},
+		{
+			name:       "json points",
+			data:       `[{"ts": "2023-11-14 22:13:20", "value": 1, "job": "api"}, {"ts": 1700000060, "value": 2, "job": "api"}]`,
+			labels:     []map[string]string{{"job": "api"}},
+			timestamps: []float64{1700000000, 1700000060},
+			values:     []float64{1, 2},
+		},
+		{
+			name:       "json query response",
+			data:       `{"status": "success", "data": {"resultType": "matrix", "result": [{"metric": {"job": "api"}, "values": [[1700000000, "1"]]}]}}`,
+			labels:     []map[string]string{{"job": "api"}},
+			timestamps: []float64{1700000000},
+			values:     []float64{1},
+		},
+		{
+			name:       "json task result data",
+			data:       `{"series": [{"labels": {"job": "api"}, "timestamps": [1700000000], "values": [1]}]}`,
+			labels:     []map[string]string{{"job": "api"}},
+			timestamps: []float64{1700000000},
+			values:     []float64{1},
+		},
+		{
+			name:       "prometheus",
+			data:       "# TYPE up gauge\nup{job=\"api\",path=\"a\\\"b\"} 1 1700000000000\nup{job=\"api\",path=\"a\\\"b\"} 0 1700000060000\nup 1 1700000000000\n",
+			labels:     []map[string]string{{"__name__": "up", "job": "api", "path": `a"b`}, {"__name__": "up"}},
+			timestamps: []float64{1700000000, 1700000060},
+			values:     []float64{1, 0},
+		},
+	}
+	for _, tt := range tests {
+		series, err := ParseSeries(tt.format, tt.data)
+		if err != nil {
+			t.Errorf("%s: ParseSeries() error = %v", tt.name, err)
+			continue
+		}
+		var labels []map[string]string
+		for _, s := range series {
+			labels = append(labels, s.Labels)
+		}
+		if !reflect.DeepEqual(labels, tt.labels) {
+			t.Errorf("%s: labels = %v, want %v", tt.name, labels, tt.labels)
+			continue
+		}
+		if !reflect.DeepEqual(series[0].Timestamps, tt.timestamps) {
+			t.Errorf("%s: timestamps = %v, want %v", tt.name, series[0].Timestamps, tt.timestamps)
+		}
+		for i, v := range series[0].Values {
+			if !almostEqual(v, tt.values[i]) {
+				t.Errorf("%s: values = %v, want %v", tt.name, series[0].Values, tt.values)
+				break
+			}
+		}
+	}
+}
+
+func TestParseSeries_Errors(t *testing.T) {
+	tests := []struct {
+		format string
+		data   string
+		err    string
+	}{
+		{"", "up{job=\"api\"} 1\n", "timestamp in milliseconds"},
+		{"", "timestamp,value\nyesterday,1\n", "invalid timestamp"},
+		{"csv", "timestamp,value\n", "at least one row"},
+		{"json", `[{"value": 1}]`, "expected timestamp and value"},
+		{"json", `[]`, "no series"},
+		{"xml", "<series/>", "unsupported format"},
+		{"prometheus", "up{job=\"api} 1 1700000000000", "unterminated"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseSeries(tt.format, tt.data); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseSeries(%q, %q) error = %v, want %q", tt.format, tt.data, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,563 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+// Defaults of model parameters, matching vmanomaly
+const (
+	defaultZThreshold   = 2.5
+	defaultMADThreshold = 2.5
+	defaultMinSamples   = 16
+	defaultQuantile     = 0.9
+)
+
+// ============================================================================
+// Z-score
+// ============================================================================
+
+func buildZScore(p *params) (func() Model, error) {
+	z := p.float("z_threshold", defaultZThreshold)
+	if z <= 0 {
+		return nil, fmt.Errorf("z_threshold must be > 0")
+	}
+	return func() Model { return &zscoreModel{z: z} }, nil
+}
+
+// zscoreModel predicts the mean of fit data with the interval of z_threshold standard deviations
+type zscoreModel struct {
+	z        float64
+	mean, sd float64
+	fitted   bool
+}
+
+func (m *zscoreModel) Fit(_, y []float64) {
+	mean, sd, n := meanStd(y)
+	m.mean, m.sd, m.fitted = mean, sd, n > 0
+}
+
+func (m *zscoreModel) Predict(ts, _ []float64) []Prediction {
+	p := notFitted()
+	if m.fitted {
+		p = Prediction{Yhat: m.mean, Lower: m.mean - m.z*m.sd, Upper: m.mean + m.z*m.sd, Ready: true}
+	}
+	return repeat(p, len(ts))
+}
+
+func buildZScoreOnline(p *params) (func() Model, error) {
+	z := p.float("z_threshold", defaultZThreshold)
+	minSamples := p.int("min_n_samples_seen", defaultMinSamples)
+	decay := p.float("decay", 1)
+	if z <= 0 {
+		return nil, fmt.Errorf("z_threshold must be > 0")
+	}
+	if decay <= 0 || decay > 1 {
+		return nil, fmt.Errorf("decay must be in (0, 1]")
+	}
+	return func() Model { return &zscoreOnlineModel{z: z, minSamples: minSamples, decay: decay} }, nil
+}
+
+// zscoreOnlineModel is the z-score model with mean and variance updated by every point,
+// weights of older points are multiplied by decay on every update
+type zscoreOnlineModel struct {
+	z          float64
+	minSamples int
+	decay      float64
+
+	n       int
+	weight  float64
+	mean    float64
+	sqDiffs float64
+}
+
+func (m *zscoreOnlineModel) update(y float64) {
+	if !isFinite(y) {
+		return
+	}
+	m.n++
+	m.weight = m.decay*m.weight + 1
+	d := y - m.mean
+	m.mean += d / m.weight
+	m.sqDiffs = m.decay*m.sqDiffs + d*(y-m.mean)
+}
+
+func (m *zscoreOnlineModel) Fit(_, y []float64) {
+	for _, v := range y {
+		m.update(v)
+	}
+}
+
+func (m *zscoreOnlineModel) Predict(ts, y []float64) []Prediction {
+	preds := make([]Prediction, len(ts))
+	for i := range ts {
+		preds[i] = notFitted()
+		if m.n > 0 {
+			sd := math.Sqrt(max(m.sqDiffs/m.weight, 0))
+			preds[i] = Prediction{Yhat: m.mean, Lower: m.mean - m.z*sd, Upper: m.mean + m.z*sd, Ready: m.n >= m.minSamples}
+		}
+		m.update(y[i])
+	}
+	return preds
+}
+
+// ============================================================================
+// MAD
+// ============================================================================
+
+func buildMAD(p *params) (func() Model, error) {
+	threshold := p.float("threshold", defaultMADThreshold)
+	if threshold <= 0 {
+		return nil, fmt.Errorf("threshold must be > 0")
+	}
+	return func() Model { return &madModel{threshold: threshold} }, nil
+}
+
+// madModel predicts the median of fit data with the interval of threshold median absolute deviations
+type madModel struct {
+	threshold float64
+	pred      Prediction
+}
+
+func (m *madModel) Fit(_, y []float64) {
+	m.pred = notFitted()
+	sorted := sortedFinite(y)
+	if len(sorted) > 0 {
+		m.pred = madPrediction(sorted, m.threshold)
+	}
+}
+
+func (m *madModel) Predict(ts, _ []float64) []Prediction {
+	return repeat(m.pred, len(ts))
+}
+
+func buildMADOnline(p *params) (func() Model, error) {
+	threshold := p.float("threshold", defaultMADThreshold)
+	minSamples := p.int("min_n_samples_seen", defaultMinSamples)
+	// Quantiles are exact, so the t-digest compression has no effect
+	p.used["compression"] = true
+	if threshold <= 0 {
+		return nil, fmt.Errorf("threshold must be > 0")
+	}
+	return func() Model { return &madOnlineModel{threshold: threshold, minSamples: minSamples} }, nil
+}
+
+// madOnlineModel is the MAD model with the median and MAD updated by every point
+type madOnlineModel struct {
+	threshold  float64
+	minSamples int
+	sorted     []float64
+}
+
+func (m *madOnlineModel) Fit(_, y []float64) {
+	for _, v := range y {
+		m.sorted = insertSorted(m.sorted, v)
+	}
+}
+
+func (m *madOnlineModel) Predict(ts, y []float64) []Prediction {
+	preds := make([]Prediction, len(ts))
+	for i := range ts {
+		preds[i] = notFitted()
+		if len(m.sorted) > 0 {
+			preds[i] = madPrediction(m.sorted, m.threshold)
+			preds[i].Ready = len(m.sorted) >= m.minSamples
+		}
+		m.sorted = insertSorted(m.sorted, y[i])
+	}
+	return preds
+}
+
+func madPrediction(sorted []float64, threshold float64) Prediction {
+	med := quantileSorted(sorted, 0.5)
+	mad := medianAbsDev(sorted, med)
+	return Prediction{Yhat: med, Lower: med - threshold*mad, Upper: med + threshold*mad, Ready: true}
+}
+
+// medianAbsDev returns the median of absolute deviations of sorted values from med
+// by merging deviations below and above med, which are sorted too
+func medianAbsDev(sorted []float64, med float64) float64 {
+	n := len(sorted)
+	i := sort.SearchFloat64s(sorted, med) - 1 // Deviations of values below med grow to the left
+	j := i + 1
+	var prev, cur float64
+	for k := 0; k <= n/2; k++ {
+		prev = cur
+		if j >= n || (i >= 0 && med-sorted[i] <= sorted[j]-med) {
+			cur = med - sorted[i]
+			i--
+		} else {
+			cur = sorted[j] - med
+			j++
+		}
+	}
+	if n%2 == 0 {
+		return (prev + cur) / 2
+	}
+	return cur
+}
+
+// ============================================================================
+// Seasonal decomposition
+// ============================================================================
+
+func buildStd(p *params) (func() Model, error) {
+	period := p.int("period", 1)
+	z := p.float("z_threshold", defaultZThreshold)
+	if period < 1 {
+		return nil, fmt.Errorf("period must be >= 1")
+	}
+	if z <= 0 {
+		return nil, fmt.Errorf("z_threshold must be > 0")
+	}
+	return func() Model { return &stdModel{period: period, z: z} }, nil
+}
+
+// stdModel is the rolling model decomposing fit and inferred data to a trailing moving average trend,
+// a seasonal component of the period and residuals. It predicts the trend with the seasonal component
+// with the interval of z_threshold standard deviations of residuals.
+type stdModel struct {
+	period  int
+	z       float64
+	history []float64
+}
+
+func (m *stdModel) Fit(_, y []float64) {
+	m.history = append(m.history[:0], y...)
+}
+
+func (m *stdModel) Predict(ts, y []float64) []Prediction {
+	full := append(append([]float64(nil), m.history...), y...)
+	n := len(full)
+
+	// Trailing moving average as in statsmodels seasonal_decompose(two_sided=False),
+	// the filter of even periods has half weights at ends
+	filt := make([]float64, m.period)
+	for i := range filt {
+		filt[i] = 1 / float64(m.period)
+	}
+	if m.period%2 == 0 {
+		filt = append(filt, 0)
+		filt[0], filt[m.period] = 0.5/float64(m.period), 0.5/float64(m.period)
+	}
+	trend := make([]float64, n)
+	for t := range trend {
+		trend[t] = math.NaN()
+		if t < len(filt)-1 {
+			continue
+		}
+		var sum float64
+		for k, w := range filt {
+			sum += w * full[t-k]
+		}
+		trend[t] = sum
+	}
+
+	detrended := make([]float64, n)
+	for t := range full {
+		detrended[t] = full[t] - trend[t]
+	}
+	seasonal := make([]float64, m.period)
+	var seasonalMean float64
+	for phase := range seasonal {
+		var values []float64
+		for t := phase; t < n; t += m.period {
+			values = append(values, detrended[t])
+		}
+		seasonal[phase], _, _ = meanStd(values)
+		if math.IsNaN(seasonal[phase]) {
+			seasonal[phase] = 0
+		}
+		seasonalMean += seasonal[phase] / float64(m.period)
+	}
+	resid := make([]float64, n)
+	for t := range detrended {
+		resid[t] = detrended[t] - (seasonal[t%m.period] - seasonalMean)
+	}
+	_, sd, _ := meanStd(resid)
+
+	preds := make([]Prediction, len(ts))
+	for i := range ts {
+		t := len(m.history) + i
+		yhat := trend[t] + seasonal[t%m.period] - seasonalMean
+		if math.IsNaN(yhat) {
+			preds[i] = notFitted()
+			continue
+		}
+		preds[i] = Prediction{Yhat: yhat, Lower: yhat - m.z*sd, Upper: yhat + m.z*sd, Ready: !math.IsNaN(sd)}
+	}
+	return preds
+}
+
+// ============================================================================
+// Rolling quantile
+// ============================================================================
+
+func buildRollingQuantile(p *params) (func() Model, error) {
+	quantile := p.float("quantile", defaultQuantile)
+	window := p.int("window_steps", 0)
+	if quantile < 0.5 || quantile > 1 {
+		return nil, fmt.Errorf("quantile must be in [0.5, 1]")
+	}
+	if window < 1 {
+		return nil, fmt.Errorf("window_steps must be >= 1")
+	}
+	return func() Model { return &rollingQuantileModel{quantile: quantile, window: window} }, nil
+}
+
+// rollingQuantileModel predicts the median of the last window_steps points including the current one,
+// with the interval from the 1-quantile to the quantile of them
+type rollingQuantileModel struct {
+	quantile float64
+	window   int
+	history  []float64
+}
+
+func (m *rollingQuantileModel) Fit(_, y []float64) {
+	m.history = append(m.history[:0], y...)
+}
+
+func (m *rollingQuantileModel) Predict(ts, y []float64) []Prediction {
+	full := append(append([]float64(nil), m.history...), y...)
+	preds := make([]Prediction, len(ts))
+	for i := range ts {
+		t := len(m.history) + i
+		preds[i] = notFitted()
+		if t+1 < m.window {
+			continue
+		}
+		window := sortedFinite(full[t+1-m.window : t+1])
+		if len(window) < m.window {
+			continue
+		}
+		preds[i] = Prediction{
+			Yhat:  quantileSorted(window, 0.5),
+			Lower: quantileSorted(window, 1-m.quantile),
+			Upper: quantileSorted(window, m.quantile),
+			Ready: true,
+		}
+	}
+	return preds
+}
+
+// ============================================================================
+// Online quantile
+// ============================================================================
+
+func buildQuantileOnline(p *params) (func() Model, error) {
+	quantiles := p.floats("quantiles", []float64{0.01, 0.5, 0.99})
+	iqrThreshold := p.float("iqr_threshold", 0)
+	seasonalInterval := p.str("seasonal_interval", "")
+	minSubseason := p.str("min_subseason", "")
+	smoothing := p.float("global_smoothing", 0)
+	seasonStart := p.str("season_starts_from", "")
+	minSamples := p.int("min_n_samples_seen", defaultMinSamples)
+	p.used["compression"] = true
+	p.used["use_transform"] = true
+
+	if len(quantiles) != 3 || !(0 < quantiles[0] && quantiles[0] < quantiles[1] && quantiles[1] < quantiles[2] && quantiles[2] < 1) {
+		return nil, fmt.Errorf("quantiles must be 3 ascending values in (0, 1), got %v", quantiles)
+	}
+	if iqrThreshold < 0 {
+		return nil, fmt.Errorf("iqr_threshold must be >= 0")
+	}
+	if smoothing < 0 || smoothing > 1 {
+		return nil, fmt.Errorf("global_smoothing must be in [0, 1]")
+	}
+
+	m := quantileOnlineModel{
+		quantiles:    quantiles,
+		iqrThreshold: iqrThreshold,
+		smoothing:    smoothing,
+		minSamples:   minSamples,
+	}
+	if seasonalInterval != "" {
+		if minSubseason == "" {
+			return nil, fmt.Errorf("min_subseason is required with seasonal_interval")
+		}
+		season, err := vmanomaly.ParseDuration(seasonalInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seasonal_interval: %w", err)
+		}
+		subseason, err := vmanomaly.ParseDuration(minSubseason)
+		if err != nil {
+			return nil, fmt.Errorf("invalid min_subseason: %w", err)
+		}
+		if subseason <= 0 || season < subseason || season%subseason != 0 {
+			return nil, fmt.Errorf("seasonal_interval must be a multiple of min_subseason")
+		}
+		m.season, m.subseason = season.Seconds(), subseason.Seconds()
+	}
+	if seasonStart != "" {
+		start, err := parseSeasonStart(seasonStart)
+		if err != nil {
+			return nil, err
+		}
+		m.seasonStart = float64(start.Unix())
+	}
+	return func() Model {
+		model := m
+		return &model
+	}, nil
+}
+
+// quantileOnlineModel predicts the median of seen points with the interval between the lower and upper quantiles,
+// widened by iqr_threshold interquartile ranges. With seasonal_interval, quantiles are computed over points
+// of the same subseason, e.g. the same hour of a week, and smoothed towards global quantiles with global_smoothing.
+type quantileOnlineModel struct {
+	quantiles    []float64
+	iqrThreshold float64
+	smoothing    float64
+	minSamples   int
+	season       float64 // Seconds, 0 for no seasonality
+	subseason    float64
+	seasonStart  float64 // Unix timestamp, season_starts_from or 1970-01-01
+
+	global  []float64
+	buckets map[int][]float64
+}
+
+func (m *quantileOnlineModel) bucket(ts float64) int {
+	if m.season == 0 {
+		return 0
+	}
+	offset := math.Mod(ts-m.seasonStart, m.season)
+	if offset < 0 {
+		offset += m.season
+	}
+	return int(offset / m.subseason)
+}
+
+func (m *quantileOnlineModel) update(ts, y float64) {
+	if !isFinite(y) {
+		return
+	}
+	m.global = insertSorted(m.global, y)
+	if m.season > 0 {
+		if m.buckets == nil {
+			m.buckets = map[int][]float64{}
+		}
+		b := m.bucket(ts)
+		m.buckets[b] = insertSorted(m.buckets[b], y)
+	}
+}
+
+// quantile returns the quantile of the bucket smoothed towards the global quantile
+func (m *quantileOnlineModel) quantile(bucket []float64, q float64) float64 {
+	global := quantileSorted(m.global, q)
+	if m.season == 0 || len(bucket) == 0 {
+		return global
+	}
+	return (1-m.smoothing)*quantileSorted(bucket, q) + m.smoothing*global
+}
+
+func (m *quantileOnlineModel) Fit(ts, y []float64) {
+	for i := range ts {
+		m.update(ts[i], y[i])
+	}
+}
+
+func (m *quantileOnlineModel) Predict(ts, y []float64) []Prediction {
+	preds := make([]Prediction, len(ts))
+	for i := range ts {
+		preds[i] = notFitted()
+		if len(m.global) > 0 {
+			bucket := m.buckets[m.bucket(ts[i])]
+			lower, yhat, upper := m.quantile(bucket, m.quantiles[0]), m.quantile(bucket, m.quantiles[1]), m.quantile(bucket, m.quantiles[2])
+			if m.iqrThreshold > 0 {
+				iqr := m.quantile(bucket, 0.75) - m.quantile(bucket, 0.25)
+				lower -= m.iqrThreshold * iqr
+				upper += m.iqrThreshold * iqr
+			}
+			preds[i] = Prediction{Yhat: yhat, Lower: lower, Upper: upper, Ready: len(m.global) >= m.minSamples}
+		}
+		m.update(ts[i], y[i])
+	}
+	return preds
+}
+
+func parseSeasonStart(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid season_starts_from %q, expected a date like 2024-01-01 or an RFC3339 timestamp", s)
+}
+
+// ============================================================================
+// Helpers
+// ============================================================================
+
+func notFitted() Prediction {
+	nan := math.NaN()
+	return Prediction{Yhat: nan, Lower: nan, Upper: nan}
+}
+
+func repeat(p Prediction, n int) []Prediction {
+	preds := make([]Prediction, n)
+	for i := range preds {
+		preds[i] = p
+	}
+	return preds
+}
+
+// meanStd returns the mean and the population standard deviation of finite values and their count
+func meanStd(values []float64) (mean, sd float64, n int) {
+	var sum float64
+	for _, v := range values {
+		if isFinite(v) {
+			sum += v
+			n++
+		}
+	}
+	if n == 0 {
+		return math.NaN(), math.NaN(), 0
+	}
+	mean = sum / float64(n)
+	var sq float64
+	for _, v := range values {
+		if isFinite(v) {
+			sq += (v - mean) * (v - mean)
+		}
+	}
+	return mean, math.Sqrt(sq / float64(n)), n
+}
+
+func sortedFinite(values []float64) []float64 {
+	sorted := make([]float64, 0, len(values))
+	for _, v := range values {
+		if isFinite(v) {
+			sorted = append(sorted, v)
+		}
+	}
+	sort.Float64s(sorted)
+	return sorted
+}
+
+// insertSorted inserts a finite value keeping values sorted
+func insertSorted(sorted []float64, v float64) []float64 {
+	if !isFinite(v) {
+		return sorted
+	}
+	i := sort.SearchFloat64s(sorted, v)
+	sorted = append(sorted, 0)
+	copy(sorted[i+1:], sorted[i:])
+	sorted[i] = v
+	return sorted
+}
+
+// quantileSorted returns the quantile of sorted values with linear interpolation, as numpy and pandas do by default
+func quantileSorted(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := min(lo+1, len(sorted)-1)
+	return sorted[lo] + (pos-float64(lo))*(sorted[hi]-sorted[lo])
+}
@@ -0,0 +1,69 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// commandBatchSize is the maximum number of texts passed to a single command run
+const commandBatchSize = 64
+
+// CommandEmbedder embeds texts with a local command, e.g. a script running a sentence-transformers model.
+// The command reads {"texts": ["..."]} from stdin and writes {"embeddings": [[0.1, ...]]} to stdout,
+// one vector per text in the same order.
+type CommandEmbedder struct {
+	// Command is the command line, it is split into arguments by spaces
+	Command string
+	// ModelName identifies the model, the command line is used if empty
+	ModelName string
+}
+
+// Model implements Embedder
+func (e CommandEmbedder) Model() string {
+	if e.ModelName != "" {
+		return e.ModelName
+	}
+	return e.Command
+}
+
+// Embed implements Embedder
+func (e CommandEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	args := strings.Fields(e.Command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("embedder command is empty")
+	}
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += commandBatchSize {
+		batch := texts[start:min(start+commandBatchSize, len(texts))]
+		input, err := json.Marshal(map[string][]string{"texts": batch})
+		if err != nil {
+			return nil, err
+		}
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("error running embedder command: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		var output struct {
+			Embeddings [][]float32 `json:"embeddings"`
+		}
+		if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+			return nil, fmt.Errorf("error parsing embedder command output: %w", err)
+		}
+		if len(output.Embeddings) != len(batch) {
+			return nil, fmt.Errorf("embedder command returned %d embeddings for %d texts", len(output.Embeddings), len(batch))
+		}
+		for _, v := range output.Embeddings {
+			Normalize(v)
+			vectors = append(vectors, v)
+		}
+	}
+	return vectors, nil
+}
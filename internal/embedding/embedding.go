@@ -0,0 +1,110 @@
+// Package embedding provides text embedders and helpers for vector search over documentation.
+//
+// Embedders run locally: the built-in HashEmbedder needs no model files, and CommandEmbedder
+// delegates to a local process (e.g. a script running a sentence-transformers model), so no network
+// is needed at runtime. Vectors of document chunks can be computed offline and shipped as a Store.
+package embedding
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"slices"
+)
+
+// Embedder converts texts to vectors. Vectors of texts with similar meaning must have a high cosine similarity.
+type Embedder interface {
+	// Model identifies the embedding model, vectors of different models are not comparable
+	Model() string
+	// Embed returns a vector for every text
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Normalize scales v to the unit length in place, so the cosine similarity of normalized vectors is their dot product
+func Normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+}
+
+// Dot returns the dot product of vectors, it is 0 for vectors of different dimensions
+func Dot(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+// Key returns the key of a text in a Store, it changes whenever the text changes
+func Key(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:12])
+}
+
+// Ranked is an item of a ranking with its score
+type Ranked struct {
+	ID    string
+	Score float64
+}
+
+// DefaultRRFK is the rank constant of reciprocal rank fusion from the original paper
+const DefaultRRFK = 60
+
+// Fuse merges rankings of IDs ordered from the best to the worst with reciprocal rank fusion:
+// the score of an ID is the sum of 1/(k+rank) over rankings it appears in. Ties are ordered by ID.
+func Fuse(k int, rankings ...[]string) []Ranked {
+	scores := map[string]float64{}
+	for _, ranking := range rankings {
+		for i, id := range ranking {
+			scores[id] += 1 / float64(k+i+1)
+		}
+	}
+	fused := make([]Ranked, 0, len(scores))
+	for id, score := range scores {
+		fused = append(fused, Ranked{ID: id, Score: score})
+	}
+	slices.SortFunc(fused, func(a, b Ranked) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.ID, b.ID))
+	})
+	return fused
+}
+
+// Match is a vector similar to a query
+type Match struct {
+	Index int // Index of the vector
+	Score float64
+}
+
+// Nearest returns up to n vectors most similar to the normalized query with positive similarity, ordered by similarity
+func Nearest(query []float32, vectors [][]float32, n int) []Match {
+	var matches []Match
+	for i, v := range vectors {
+		if sim := Dot(query, v); sim > 0 {
+			matches = append(matches, Match{Index: i, Score: sim})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b Match) int { return cmp.Compare(b.Score, a.Score) })
+	return matches[:min(n, len(matches))]
+}
+
+// New returns a CommandEmbedder running the command, or the built-in HashEmbedder if the command is empty
+func New(command, model string) Embedder {
+	if command == "" {
+		return HashEmbedder{}
+	}
+	return CommandEmbedder{Command: command, ModelName: model}
+}
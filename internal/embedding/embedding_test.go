@@ -0,0 +1,156 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestHashEmbedder(t *testing.T) {
+	e := HashEmbedder{}
+	vectors, err := e.Embed(context.Background(), []string{
+		"How to silence alerts during maintenance",
+		"Silencing alerting rules while maintaining servers",
+		"Prophet model seasonality parameters",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors[0]) != DefaultHashDim {
+		t.Fatalf("dim = %d, want %d", len(vectors[0]), DefaultHashDim)
+	}
+	similar, different := Dot(vectors[0], vectors[1]), Dot(vectors[0], vectors[2])
+	if similar <= different {
+		t.Errorf("similarity of related texts %.3f must be greater than of unrelated %.3f", similar, different)
+	}
+	if self := Dot(vectors[0], vectors[0]); self < 0.999 || self > 1.001 {
+		t.Errorf("vectors must be normalized, got squared norm %.3f", self)
+	}
+	if e.Model() != "hash-v1-512" || (HashEmbedder{Dim: 64}).Model() != "hash-v1-64" {
+		t.Errorf("unexpected model names %s", e.Model())
+	}
+}
+
+func TestFuse(t *testing.T) {
+	fused := Fuse(DefaultRRFK, []string{"a", "b", "c"}, []string{"c", "d", "a"})
+	var ids []string
+	for _, r := range fused {
+		ids = append(ids, r.ID)
+	}
+	// a: 1/61+1/63, c: 1/63+1/61, ties are ordered by ID
+	if want := []string{"a", "c", "b", "d"}; !slices.Equal(ids, want) {
+		t.Errorf("Fuse() = %v, want %v", ids, want)
+	}
+	if fused[2].Score != 1.0/62 {
+		t.Errorf("score of b = %v, want %v", fused[2].Score, 1.0/62)
+	}
+}
+
+func TestNearest(t *testing.T) {
+	vectors := [][]float32{{1, 0}, {0, 1}, {0.6, 0.8}, {-1, 0}}
+	got := Nearest([]float32{1, 0}, vectors, 5)
+	var indexes []int
+	for _, m := range got {
+		indexes = append(indexes, m.Index)
+	}
+	if want := []int{0, 2}; !slices.Equal(indexes, want) {
+		t.Errorf("Nearest() = %v, want %v", indexes, want)
+	}
+}
+
+// countingEmbedder counts embedded texts
+type countingEmbedder struct {
+	HashEmbedder
+	embedded int
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.embedded += len(texts)
+	return e.HashEmbedder.Embed(ctx, texts)
+}
+
+func TestEmbedAll(t *testing.T) {
+	e := &countingEmbedder{}
+	texts := []string{"one", "two", "three"}
+	vectors, _ := e.HashEmbedder.Embed(context.Background(), texts[:2])
+	stores := []*Store{
+		{Model: "other", Vectors: map[string][]float32{Key("three"): {1}}},
+		NewStore(e.Model(), texts[:2], vectors),
+	}
+
+	got, computed, err := EmbedAll(context.Background(), e, texts, stores...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !computed || e.embedded != 1 {
+		t.Errorf("only the text missing in stores of the model must be embedded, embedded %d", e.embedded)
+	}
+	if len(got) != 3 || len(got[2]) != DefaultHashDim {
+		t.Errorf("unexpected vectors %v", got)
+	}
+
+	dir := t.TempDir()
+	if s, err := ReadStore(os.DirFS(dir), StoreFile); err != nil || s != nil {
+		t.Errorf("ReadStore() of a missing file = %v, %v", s, err)
+	}
+	if err := NewStore(e.Model(), texts, got).WriteFile(filepath.Join(dir, StoreFile)); err != nil {
+		t.Fatal(err)
+	}
+	s, err := ReadStore(os.DirFS(dir), StoreFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.embedded = 0
+	if _, computed, err := EmbedAll(context.Background(), e, texts, s); err != nil || computed || e.embedded != 0 {
+		t.Errorf("all vectors must be read from the store, computed %v, err %v", computed, err)
+	}
+}
+
+// TestHelperEmbedder is run as the embedder command by TestCommandEmbedder
+func TestHelperEmbedder(t *testing.T) {
+	if os.Getenv("EMBEDDING_HELPER") != "1" {
+		return
+	}
+	var input struct {
+		Texts []string `json:"texts"`
+	}
+	if err := json.NewDecoder(os.Stdin).Decode(&input); err != nil {
+		os.Exit(1)
+	}
+	embeddings := make([][]float32, len(input.Texts))
+	for i, text := range input.Texts {
+		embeddings[i] = []float32{float32(len(text)), 0, 3}
+	}
+	_ = json.NewEncoder(os.Stdout).Encode(map[string]any{"embeddings": embeddings})
+	os.Exit(0)
+}
+
+func TestCommandEmbedder(t *testing.T) {
+	t.Setenv("EMBEDDING_HELPER", "1")
+	e := CommandEmbedder{Command: fmt.Sprintf("%s -test.run=^TestHelperEmbedder$", os.Args[0]), ModelName: "test"}
+	texts := make([]string, commandBatchSize+1)
+	for i := range texts {
+		texts[i] = "abcd"
+	}
+	vectors, err := e.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != len(texts) {
+		t.Fatalf("got %d vectors for %d texts", len(vectors), len(texts))
+	}
+	if want := []float32{0.8, 0, 0.6}; !slices.Equal(vectors[0], want) {
+		t.Errorf("vector = %v, want normalized %v", vectors[0], want)
+	}
+	if e.Model() != "test" {
+		t.Errorf("Model() = %s", e.Model())
+	}
+
+	if _, err := (CommandEmbedder{Command: "false"}).Embed(context.Background(), []string{"x"}); err == nil {
+		t.Error("expected error of a failing command")
+	}
+}
@@ -0,0 +1,101 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// DefaultHashDim is the default dimension of HashEmbedder vectors
+const DefaultHashDim = 512
+
+// HashEmbedder embeds texts with feature hashing of word stems, word bigrams and character trigrams.
+// It needs no model files and is deterministic, but captures only lexical similarity:
+// different word forms and misspellings are close, synonyms are not. Use CommandEmbedder for semantic models.
+type HashEmbedder struct {
+	Dim int // Vector dimension, DefaultHashDim if 0
+}
+
+// Model implements Embedder
+func (e HashEmbedder) Model() string {
+	return fmt.Sprintf("hash-v1-%d", e.dim())
+}
+
+// Embed implements Embedder
+func (e HashEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e HashEmbedder) dim() int {
+	if e.Dim <= 0 {
+		return DefaultHashDim
+	}
+	return e.Dim
+}
+
+func (e HashEmbedder) embed(text string) []float32 {
+	v := make([]float32, e.dim())
+	add := func(feature string, weight float32) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(feature))
+		sum := h.Sum64()
+		// The highest bit picks the sign to keep hash collisions unbiased
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		v[sum%uint64(len(v))] += weight
+	}
+	words := tokenize(text)
+	for i, w := range words {
+		add("w:"+w, 1)
+		if i > 0 {
+			add("b:"+words[i-1]+" "+w, 0.5)
+		}
+		padded := "^" + w + "$"
+		for j := 0; j+3 <= len(padded); j++ {
+			add("t:"+padded[j:j+3], 0.25)
+		}
+	}
+	Normalize(v)
+	return v
+}
+
+// tokenize splits text into lowercase word stems without stop words.
+// Code identifiers like fit_window are split into words.
+func tokenize(text string) []string {
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if stopWords[w] {
+			continue
+		}
+		words = append(words, stem(w))
+	}
+	return words
+}
+
+// stem strips common English suffixes, it is much cruder than a real stemmer, but is enough for feature hashing
+func stem(w string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s", "ly"} {
+		if len(w) > len(suffix)+2 && strings.HasSuffix(w, suffix) {
+			return strings.TrimSuffix(w, suffix)
+		}
+	}
+	return w
+}
+
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`a an and are as at be but by can do does for from how i if in into is it its
+		my no not of on or so that the their then there these this to was we what when where which who why will with you your`) {
+		stopWords[w] = true
+	}
+}
@@ -0,0 +1,111 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// StoreFile is the name of the file with precomputed embeddings in a doc set directory
+const StoreFile = "embeddings.json"
+
+// Store holds precomputed vectors of texts keyed by Key of the text
+type Store struct {
+	Model   string               `json:"model"`
+	Vectors map[string][]float32 `json:"vectors"`
+}
+
+// NewStore returns a store of vectors of texts computed with the model
+func NewStore(model string, texts []string, vectors [][]float32) *Store {
+	s := &Store{Model: model, Vectors: make(map[string][]float32, len(texts))}
+	for i, text := range texts {
+		s.Vectors[Key(text)] = vectors[i]
+	}
+	return s
+}
+
+// ReadStore reads a store from fsys. It returns nil without an error if the file does not exist.
+func ReadStore(fsys fs.FS, name string) (*Store, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s Store
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("error parsing embeddings %s: %w", name, err)
+	}
+	return &s, nil
+}
+
+// WriteFile writes the store to a file atomically
+func (s *Store) WriteFile(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// EmbedAll returns vectors of texts, taking them from stores of the embedder model if possible
+// and computing the rest with the embedder. It also returns whether any vector was computed.
+func EmbedAll(ctx context.Context, e Embedder, texts []string, stores ...*Store) ([][]float32, bool, error) {
+	vectors := make([][]float32, len(texts))
+	var missing []int
+	for i, text := range texts {
+		key := Key(text)
+		for _, s := range stores {
+			if s == nil || s.Model != e.Model() {
+				continue
+			}
+			if v, ok := s.Vectors[key]; ok {
+				vectors[i] = v
+				break
+			}
+		}
+		if vectors[i] == nil {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return vectors, false, nil
+	}
+	batch := make([]string, len(missing))
+	for j, i := range missing {
+		batch[j] = texts[i]
+	}
+	computed, err := e.Embed(ctx, batch)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(computed) != len(batch) {
+		return nil, false, fmt.Errorf("embedder returned %d vectors for %d texts", len(computed), len(batch))
+	}
+	for j, i := range missing {
+		vectors[i] = computed[j]
+	}
+	return vectors, true, nil
+}
@@ -0,0 +1,378 @@
+package grafana
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/analyzer"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmalert"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmconfig"
+)
+
+// Defaults of DashboardOptions
+const (
+	DefaultTitle          = "vmanomaly: anomaly detection results"
+	DefaultUID            = "vmanomaly-results"
+	DefaultDatasourceType = "prometheus"
+)
+
+// datasourceVar is the name of the datasource variable used by all panels
+const datasourceVar = "ds"
+
+// DashboardOptions configure GenerateDashboard
+type DashboardOptions struct {
+	Title string
+	UID   string
+	// DatasourceType is the Grafana datasource plugin, e.g. prometheus or victoriametrics-metrics-datasource
+	DatasourceType string
+	// Threshold is the default anomaly score threshold of statistics and panel threshold lines
+	Threshold float64
+	// Job is the regexp matching `job` label of scraped vmanomaly self-monitoring metrics
+	Job string
+	// SkipSelfMonitoring disables the self-monitoring row
+	SkipSelfMonitoring bool
+}
+
+// modelSeries are series produced by vmanomaly models besides anomaly_score
+var modelSeries = []string{"y", "yhat", "yhat_lower", "yhat_upper"}
+
+// GenerateDashboard builds a Grafana dashboard for results of a vmanomaly config:
+// global anomaly score statistics, a row per query alias with the actual value, predicted
+// yhat band and anomaly_score, and a row with vmanomaly self-monitoring metrics
+func GenerateDashboard(c *vmconfig.Config, opts DashboardOptions) (*Dashboard, error) {
+	if c.Preset != "" {
+		return nil, fmt.Errorf("config uses %q preset, which comes with its own dashboard", c.Preset)
+	}
+	if opts.Title == "" {
+		opts.Title = DefaultTitle
+	}
+	if opts.UID == "" {
+		opts.UID = DefaultUID
+	}
+	if opts.DatasourceType == "" {
+		opts.DatasourceType = DefaultDatasourceType
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = vmalert.DefaultThreshold
+	}
+	if opts.Job == "" {
+		opts.Job = vmalert.DefaultJobSelector
+	}
+
+	b := &dashboardBuilder{
+		config: c,
+		opts:   opts,
+		ds:     &DatasourceRef{Type: opts.DatasourceType, UID: "${" + datasourceVar + "}"},
+	}
+	b.queryLabel, _ = c.Writer.QueryLabel("")
+
+	d := &Dashboard{
+		UID:           opts.UID,
+		Title:         opts.Title,
+		Description:   "Anomaly scores, predictions and self-monitoring of vmanomaly",
+		Tags:          []string{"vmanomaly", "anomaly-detection"},
+		Editable:      true,
+		Refresh:       "1m",
+		SchemaVersion: SchemaVersion,
+		Time:          TimeRange{From: "now-6h", To: "now"},
+		Templating:    Templating{List: b.variables()},
+		Annotations:   Annotations{List: []any{}},
+	}
+	d.Panels = append(d.Panels, b.overviewRow()...)
+	for _, alias := range c.QueryAliases() {
+		d.Panels = append(d.Panels, b.queryRow(alias)...)
+	}
+	if !opts.SkipSelfMonitoring {
+		d.Panels = append(d.Panels, b.selfMonitoringRow())
+	}
+
+	if err := d.Validate(); err != nil {
+		return nil, fmt.Errorf("generated dashboard is invalid: %w", err)
+	}
+	return d, nil
+}
+
+type dashboardBuilder struct {
+	config     *vmconfig.Config
+	opts       DashboardOptions
+	ds         *DatasourceRef
+	queryLabel string
+	nextID     int
+	y          int
+}
+
+func (b *dashboardBuilder) panel(typ, title string, w, h, x int) Panel {
+	b.nextID++
+	return Panel{
+		ID:         b.nextID,
+		Type:       typ,
+		Title:      title,
+		GridPos:    GridPos{W: w, H: h, X: x, Y: b.y},
+		Datasource: b.ds,
+	}
+}
+
+func (b *dashboardBuilder) row(title string) Panel {
+	p := b.panel("row", title, 24, 1, 0)
+	p.Datasource = nil
+	b.y++
+	return p
+}
+
+func (b *dashboardBuilder) variables() []Variable {
+	score := b.config.Writer.MetricName("anomaly_score")
+	extra := b.extraMatchers()
+	queryAliases := fmt.Sprintf("label_values(%s{%s}, %s)", score, extra, b.queryLabel)
+	modelAliases := fmt.Sprintf("label_values(%s{%s}, model_alias)",
+		score, b.joinMatchers(fmt.Sprintf(`%s=~"$%s"`, b.queryLabel, b.queryLabel), extra))
+	all := &Current{Text: "All", Value: "$__all"}
+	return []Variable{
+		{
+			Name:    datasourceVar,
+			Label:   "Datasource",
+			Type:    "datasource",
+			Query:   b.opts.DatasourceType,
+			Current: &Current{Text: "default", Value: "default"},
+		},
+		{
+			Name:       b.queryLabel,
+			Label:      "Query alias",
+			Type:       "query",
+			Datasource: b.ds,
+			Query:      queryAliases,
+			Definition: queryAliases,
+			Refresh:    2,
+			Multi:      true,
+			IncludeAll: true,
+			Current:    all,
+		},
+		{
+			Name:       "model_alias",
+			Label:      "Model alias",
+			Type:       "query",
+			Datasource: b.ds,
+			Query:      modelAliases,
+			Definition: modelAliases,
+			Refresh:    2,
+			Multi:      true,
+			IncludeAll: true,
+			Current:    all,
+		},
+		{
+			Name:    "threshold",
+			Label:   "Anomaly score threshold",
+			Type:    "textbox",
+			Query:   formatFloat(b.opts.Threshold),
+			Current: &Current{Text: formatFloat(b.opts.Threshold), Value: formatFloat(b.opts.Threshold)},
+		},
+	}
+}
+
+// extraMatchers returns label matchers of constant labels added by writer.metric_format
+func (b *dashboardBuilder) extraMatchers() string {
+	extra := b.config.Writer.ExtraLabels()
+	var matchers []string
+	for _, name := range slices.Sorted(maps.Keys(extra)) {
+		matchers = append(matchers, fmt.Sprintf("%s=%q", name, extra[name]))
+	}
+	return strings.Join(matchers, ", ")
+}
+
+func (b *dashboardBuilder) joinMatchers(matchers ...string) string {
+	var nonEmpty []string
+	for _, m := range matchers {
+		if m != "" {
+			nonEmpty = append(nonEmpty, m)
+		}
+	}
+	return strings.Join(nonEmpty, ", ")
+}
+
+// selector returns a selector of a produced series filtered by dashboard variables,
+// or by the given query alias if it is set
+func (b *dashboardBuilder) selector(series, alias string) string {
+	queryMatcher := fmt.Sprintf(`%s=~"$%s"`, b.queryLabel, b.queryLabel)
+	if alias != "" {
+		label, value := b.config.Writer.QueryLabel(alias)
+		queryMatcher = fmt.Sprintf("%s=%q", label, value)
+	}
+	return fmt.Sprintf("%s{%s}", b.config.Writer.MetricName(series),
+		b.joinMatchers(queryMatcher, `model_alias=~"$model_alias"`, b.extraMatchers()))
+}
+
+// overviewRow contains global anomaly score statistics across selected query and model aliases
+func (b *dashboardBuilder) overviewRow() []Panel {
+	score := b.selector("anomaly_score", "")
+	panels := []Panel{b.row("Overview")}
+
+	anomalous := b.panel("stat", "Anomalous series", 6, 4, 0)
+	anomalous.Description = "Number of series with anomaly score above $threshold"
+	anomalous.Targets = []Target{{RefID: "A", Expr: fmt.Sprintf("count(%s > $threshold) or vector(0)", score), Instant: true}}
+	anomalous.FieldConfig = thresholdsConfig("", 1)
+
+	share := b.panel("stat", "Anomalous share", 6, 4, 6)
+	share.Description = "Share of series with anomaly score above $threshold"
+	share.Targets = []Target{{RefID: "A", Expr: fmt.Sprintf("(count(%s > $threshold) or vector(0)) / count(%s)", score, score), Instant: true}}
+	share.FieldConfig = thresholdsConfig("percentunit", 0.05)
+
+	maxScore := b.panel("stat", "Max anomaly score", 6, 4, 12)
+	maxScore.Targets = []Target{{RefID: "A", Expr: fmt.Sprintf("max(%s)", score), Instant: true}}
+	maxScore.FieldConfig = thresholdsConfig("", b.opts.Threshold)
+
+	series := b.panel("stat", "Series analyzed", 6, 4, 18)
+	series.Targets = []Target{{RefID: "A", Expr: fmt.Sprintf("count(%s)", score), Instant: true}}
+	b.y += 4
+
+	top := b.panel("timeseries", "Top 10 anomaly scores", 24, 8, 0)
+	top.Description = "Highest anomaly scores by query and model alias"
+	top.Targets = []Target{{
+		RefID:        "A",
+		Expr:         fmt.Sprintf("topk(10, max by (%s, model_alias) (%s))", b.queryLabel, score),
+		LegendFormat: fmt.Sprintf("{{%s}} / {{model_alias}}", b.queryLabel),
+	}}
+	top.FieldConfig = scoreFieldConfig(b.opts.Threshold)
+	b.y += 8
+
+	return append(panels, anomalous, share, maxScore, series, top)
+}
+
+// queryRow contains panels of a single query alias: actual value with the predicted band and anomaly score
+func (b *dashboardBuilder) queryRow(alias string) []Panel {
+	q, _ := b.config.Query(alias)
+	panels := []Panel{b.row("Query: " + alias)}
+
+	legend := fmt.Sprintf("{{model_alias}} %s", legendLabels(q.Expr))
+	values := b.panel("timeseries", alias+": actual vs expected", 12, 8, 0)
+	values.Description = fmt.Sprintf("Actual value y and predicted yhat with [yhat_lower, yhat_upper] band. Query: %s", q.Expr)
+	for i, series := range modelSeries {
+		if !b.provides(alias, series) {
+			continue
+		}
+		values.Targets = append(values.Targets, Target{
+			RefID:        string(rune('A' + i)),
+			Expr:         b.selector(series, alias),
+			LegendFormat: strings.TrimSpace(series + " " + legend),
+		})
+	}
+	values.FieldConfig = bandFieldConfig()
+
+	score := b.panel("timeseries", alias+": anomaly score", 12, 8, 12)
+	score.Description = "Anomaly score, values above the threshold are anomalies"
+	score.Targets = []Target{{RefID: "A", Expr: b.selector("anomaly_score", alias), LegendFormat: strings.TrimSpace(legend)}}
+	score.FieldConfig = scoreFieldConfig(b.opts.Threshold)
+	b.y += 8
+
+	if len(values.Targets) == 0 {
+		// models of the query produce anomaly scores only
+		score.GridPos.W, score.GridPos.X = 24, 0
+		return append(panels, score)
+	}
+	return append(panels, values, score)
+}
+
+// provides reports whether any model attached to the query produces the series.
+// Models without provide_series produce all series they support.
+func (b *dashboardBuilder) provides(alias, series string) bool {
+	for _, m := range b.config.Models {
+		if !slices.Contains(m.Queries, alias) {
+			continue
+		}
+		if len(m.ProvideSeries) == 0 || slices.Contains(m.ProvideSeries, series) {
+			return true
+		}
+	}
+	return false
+}
+
+// legendLabels returns legend placeholders for labels of the outermost `by (...)` clause of a query
+func legendLabels(expr string) string {
+	var parts []string
+	for _, label := range analyzer.Analyze(expr, "").GroupBy {
+		parts = append(parts, fmt.Sprintf("{{%s}}", label))
+	}
+	return strings.Join(parts, " ")
+}
+
+// selfMonitoringRow contains vmanomaly self-monitoring metrics,
+// see https://docs.victoriametrics.com/anomaly-detection/components/monitoring/
+func (b *dashboardBuilder) selfMonitoringRow() Panel {
+	row := b.row("Self-monitoring")
+	row.Collapsed = true
+	job := fmt.Sprintf("job=~%q", b.opts.Job)
+	by := "model_alias, scheduler_alias, stage"
+
+	type panelDef struct {
+		title, expr, legend, unit string
+	}
+	defs := []panelDef{
+		{"Model runs", fmt.Sprintf("sum(rate(vmanomaly_model_runs{%s}[$__rate_interval])) by (%s)", job, by), "{{model_alias}} {{scheduler_alias}} {{stage}}", "ops"},
+		{"Skipped model runs", fmt.Sprintf("sum(increase(vmanomaly_model_runs_skipped{%s}[$__rate_interval])) by (%s)", job, by), "{{model_alias}} {{scheduler_alias}} {{stage}}", "short"},
+		{"Model run errors", fmt.Sprintf("sum(increase(vmanomaly_model_run_errors{%s}[$__rate_interval])) by (%s)", job, by), "{{model_alias}} {{scheduler_alias}} {{stage}}", "short"},
+		{"Model run duration p95", fmt.Sprintf("histogram_quantile(0.95, sum(rate(vmanomaly_model_run_duration_seconds_bucket{%s}[$__rate_interval])) by (le, %s))", job, by), "{{model_alias}} {{scheduler_alias}} {{stage}}", "s"},
+		{"Active models", fmt.Sprintf("sum(vmanomaly_models_active{%s}) by (model_alias, scheduler_alias)", job), "{{model_alias}} {{scheduler_alias}}", "short"},
+		{"Uptime", fmt.Sprintf("time() - process_start_time_seconds{%s}", job), "{{instance}}", "s"},
+	}
+	for i, def := range defs {
+		p := b.panel("timeseries", def.title, 12, 8, (i%2)*12)
+		p.Targets = []Target{{RefID: "A", Expr: def.expr, LegendFormat: def.legend}}
+		p.FieldConfig = &FieldConfig{Defaults: map[string]any{"unit": def.unit}, Overrides: []Override{}}
+		row.Panels = append(row.Panels, p)
+		if i%2 == 1 {
+			b.y += 8
+		}
+	}
+	return row
+}
+
+func thresholdsConfig(unit string, red float64) *FieldConfig {
+	defaults := map[string]any{
+		"thresholds": map[string]any{
+			"mode": "absolute",
+			"steps": []map[string]any{
+				{"color": "green", "value": nil},
+				{"color": "red", "value": red},
+			},
+		},
+	}
+	if unit != "" {
+		defaults["unit"] = unit
+	}
+	return &FieldConfig{Defaults: defaults, Overrides: []Override{}}
+}
+
+// scoreFieldConfig draws the threshold as a dashed line
+func scoreFieldConfig(threshold float64) *FieldConfig {
+	fc := thresholdsConfig("", threshold)
+	fc.Defaults["min"] = 0
+	fc.Defaults["custom"] = map[string]any{
+		"thresholdsStyle": map[string]any{"mode": "dashed"},
+	}
+	return fc
+}
+
+// bandFieldConfig renders yhat_lower and yhat_upper as a dashed band around yhat
+func bandFieldConfig() *FieldConfig {
+	fc := &FieldConfig{Defaults: map[string]any{"custom": map[string]any{"lineWidth": 1}}}
+	for _, series := range []string{"yhat_lower", "yhat_upper"} {
+		fc.Overrides = append(fc.Overrides, Override{
+			Matcher: Matcher{ID: "byRegexp", Options: "^" + series + " .*"},
+			Properties: []Property{
+				{ID: "custom.lineStyle", Value: map[string]any{"fill": "dash", "dash": []int{10, 10}}},
+				{ID: "custom.fillOpacity", Value: 10},
+				{ID: "color", Value: map[string]any{"mode": "fixed", "fixedColor": "gray"}},
+			},
+		})
+	}
+	fc.Overrides = append(fc.Overrides, Override{
+		Matcher:    Matcher{ID: "byRegexp", Options: "^y .*"},
+		Properties: []Property{{ID: "custom.lineWidth", Value: 2}},
+	})
+	return fc
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
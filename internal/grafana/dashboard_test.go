@@ -0,0 +1,112 @@
+package grafana
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmconfig"
+)
+
+const testConfig = `
+reader:
+  sampling_period: 1m
+  queries:
+    cpu: sum(rate(node_cpu_seconds_total[5m])) by (mode)
+    rps: sum(rate(http_requests_total[5m]))
+schedulers:
+  s1:
+    infer_every: 1m
+models:
+  zscore:
+    class: zscore
+    queries: [rps]
+    provide_series: [anomaly_score]
+  prophet:
+    class: prophet
+    queries: [cpu]
+writer:
+  metric_format:
+    __name__: vmanomaly_$VAR
+    for: $QUERY_KEY
+    env: prod
+`
+
+func TestGenerateDashboard(t *testing.T) {
+	c, err := vmconfig.Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("cannot parse config: %v", err)
+	}
+	d, err := GenerateDashboard(c, DashboardOptions{Threshold: 2})
+	if err != nil {
+		t.Fatalf("GenerateDashboard() error = %v", err)
+	}
+
+	var names []string
+	for _, v := range d.Templating.List {
+		names = append(names, v.Name)
+	}
+	if strings.Join(names, ",") != "ds,for,model_alias,threshold" {
+		t.Errorf("variables = %v", names)
+	}
+
+	panels := map[string]Panel{}
+	for _, p := range d.Panels {
+		panels[p.Title] = p
+	}
+	for _, title := range []string{"Overview", "Query: cpu", "Query: rps", "Self-monitoring", "cpu: actual vs expected", "cpu: anomaly score", "rps: anomaly score"} {
+		if _, ok := panels[title]; !ok {
+			t.Errorf("panel %q is missing", title)
+		}
+	}
+	if _, ok := panels["rps: actual vs expected"]; ok {
+		t.Errorf("rps models provide anomaly_score only, values panel must be skipped")
+	}
+
+	cpu := panels["cpu: actual vs expected"]
+	if len(cpu.Targets) != 4 {
+		t.Fatalf("expected y, yhat, yhat_lower and yhat_upper targets, got %+v", cpu.Targets)
+	}
+	if want := `vmanomaly_yhat{for="cpu", model_alias=~"$model_alias", env="prod"}`; cpu.Targets[1].Expr != want {
+		t.Errorf("yhat expr = %q, want %q", cpu.Targets[1].Expr, want)
+	}
+	if want := "yhat {{model_alias}} {{mode}}"; cpu.Targets[1].LegendFormat != want {
+		t.Errorf("legend = %q, want %q", cpu.Targets[1].LegendFormat, want)
+	}
+	if n := len(panels["Self-monitoring"].Panels); n != 6 {
+		t.Errorf("self-monitoring row has %d panels, want 6", n)
+	}
+
+	if _, err := json.Marshal(d); err != nil {
+		t.Errorf("cannot marshal dashboard: %v", err)
+	}
+}
+
+func TestDashboardValidate(t *testing.T) {
+	d := &Dashboard{
+		UID:           "bad uid",
+		SchemaVersion: SchemaVersion,
+		Templating:    Templating{List: []Variable{{Name: "job", Type: "query"}, {Name: "job", Type: "unknown"}}},
+		Panels: []Panel{
+			{ID: 1, Type: "timeseries", GridPos: GridPos{W: 20, H: 8, X: 10}, Targets: []Target{
+				{RefID: "A", Expr: `up{job="$job"}`},
+				{RefID: "A", Expr: `sum(rate(x[5m])`},
+				{RefID: "B", Expr: `up{instance="$instance"}`},
+			}},
+			{ID: 1, Type: "piechart", GridPos: GridPos{W: 24, H: 8}},
+		},
+	}
+	err := d.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{
+		"title must be set", "uid", "duplicate variable", "unknown variable type", "must fit into 24 columns",
+		"refId", "invalid expression", "undefined variable $instance", "must be positive and unique",
+		"unknown panel type", "has no queries",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package grafana builds Grafana dashboards for vmanomaly results and validates their structure.
+//
+// Only the subset of the dashboard JSON model used by generated dashboards is described here,
+// see https://grafana.com/docs/grafana/latest/dashboards/build-dashboards/view-dashboard-json-model/
+package grafana
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/analyzer"
+)
+
+// SchemaVersion is the dashboard schema version of generated dashboards
+const SchemaVersion = 39
+
+// Dashboard is a Grafana dashboard
+type Dashboard struct {
+	UID           string      `json:"uid"`
+	Title         string      `json:"title"`
+	Description   string      `json:"description,omitempty"`
+	Tags          []string    `json:"tags,omitempty"`
+	Editable      bool        `json:"editable"`
+	Refresh       string      `json:"refresh,omitempty"`
+	SchemaVersion int         `json:"schemaVersion"`
+	Time          TimeRange   `json:"time"`
+	Templating    Templating  `json:"templating"`
+	Annotations   Annotations `json:"annotations"`
+	Panels        []Panel     `json:"panels"`
+}
+
+// TimeRange is the default time range of a dashboard
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Templating contains dashboard variables
+type Templating struct {
+	List []Variable `json:"list"`
+}
+
+// Annotations contains dashboard annotation queries
+type Annotations struct {
+	List []any `json:"list"`
+}
+
+// Variable is a dashboard template variable
+type Variable struct {
+	Name       string         `json:"name"`
+	Label      string         `json:"label,omitempty"`
+	Type       string         `json:"type"` // datasource|query|textbox|constant|custom
+	Query      string         `json:"query"`
+	Datasource *DatasourceRef `json:"datasource,omitempty"`
+	Definition string         `json:"definition,omitempty"`
+	Refresh    int            `json:"refresh,omitempty"` // 1 - on dashboard load, 2 - on time range change
+	Multi      bool           `json:"multi,omitempty"`
+	IncludeAll bool           `json:"includeAll,omitempty"`
+	Current    *Current       `json:"current,omitempty"`
+	Hide       int            `json:"hide,omitempty"`
+}
+
+// Current is the selected value of a variable
+type Current struct {
+	Text  any `json:"text"`
+	Value any `json:"value"`
+}
+
+// DatasourceRef references a datasource by type and UID, UID may be a variable, e.g. ${ds}
+type DatasourceRef struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+// GridPos is a panel position in the 24 columns wide dashboard grid
+type GridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Panel is a dashboard panel or a row
+type Panel struct {
+	ID          int            `json:"id"`
+	Type        string         `json:"type"`
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	GridPos     GridPos        `json:"gridPos"`
+	Datasource  *DatasourceRef `json:"datasource,omitempty"`
+	Targets     []Target       `json:"targets,omitempty"`
+	FieldConfig *FieldConfig   `json:"fieldConfig,omitempty"`
+	Options     map[string]any `json:"options,omitempty"`
+	Collapsed   bool           `json:"collapsed,omitempty"`
+	Panels      []Panel        `json:"panels,omitempty"` // Panels of a collapsed row
+}
+
+// Target is a panel query
+type Target struct {
+	RefID        string         `json:"refId"`
+	Expr         string         `json:"expr"`
+	LegendFormat string         `json:"legendFormat,omitempty"`
+	Datasource   *DatasourceRef `json:"datasource,omitempty"`
+	Instant      bool           `json:"instant,omitempty"`
+}
+
+// FieldConfig configures how panel fields are displayed
+type FieldConfig struct {
+	Defaults  map[string]any `json:"defaults"`
+	Overrides []Override     `json:"overrides"`
+}
+
+// Override changes field properties of fields selected by the matcher
+type Override struct {
+	Matcher    Matcher    `json:"matcher"`
+	Properties []Property `json:"properties"`
+}
+
+// Matcher selects fields, e.g. {id: byFrameRefID, options: B}
+type Matcher struct {
+	ID      string `json:"id"`
+	Options any    `json:"options"`
+}
+
+// Property is a field property set by an override
+type Property struct {
+	ID    string `json:"id"`
+	Value any    `json:"value"`
+}
+
+var (
+	knownPanelTypes    = []string{"row", "timeseries", "stat", "gauge", "bargauge", "table", "text", "heatmap"}
+	knownVariableTypes = []string{"datasource", "query", "textbox", "constant", "custom", "interval"}
+	// builtinVariables are provided by Grafana
+	builtinVariables = []string{
+		"__interval", "__interval_ms", "__rate_interval", "__range", "__range_s", "__range_ms",
+		"__dashboard", "__from", "__to", "__name", "__org", "__user", "__timeFilter",
+	}
+
+	uidRe      = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,40}$`)
+	variableRe = regexp.MustCompile(`\$\{?([a-zA-Z_][a-zA-Z0-9_]*)`)
+)
+
+// Validate checks the dashboard structure: required fields, unique panel IDs and variable names,
+// panel types, grid positions, query refIds, MetricsQL syntax of queries and references to variables
+func (d *Dashboard) Validate() error {
+	var errs []error
+	addErr := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if strings.TrimSpace(d.Title) == "" {
+		addErr("title must be set")
+	}
+	if d.UID != "" && !uidRe.MatchString(d.UID) {
+		addErr("uid %q must be up to 40 letters, digits, '-' or '_'", d.UID)
+	}
+	if d.SchemaVersion <= 0 {
+		addErr("schemaVersion must be positive")
+	}
+
+	variables := slices.Clone(builtinVariables)
+	for i, v := range d.Templating.List {
+		path := fmt.Sprintf("templating.list[%d]", i)
+		switch {
+		case v.Name == "":
+			addErr("%s: name must be set", path)
+		case slices.Contains(variables, v.Name):
+			addErr("%s: duplicate variable %q", path, v.Name)
+		}
+		if !slices.Contains(knownVariableTypes, v.Type) {
+			addErr("%s: unknown variable type %q", path, v.Type)
+		}
+		variables = append(variables, v.Name)
+	}
+
+	ids := map[int]bool{}
+	var validatePanels func(panels []Panel, path string)
+	validatePanels = func(panels []Panel, path string) {
+		for i, p := range panels {
+			panelPath := fmt.Sprintf("%s[%d]", path, i)
+			if p.ID <= 0 || ids[p.ID] {
+				addErr("%s: panel id %d must be positive and unique", panelPath, p.ID)
+			}
+			ids[p.ID] = true
+			if !slices.Contains(knownPanelTypes, p.Type) {
+				addErr("%s: unknown panel type %q", panelPath, p.Type)
+			}
+			if g := p.GridPos; g.W <= 0 || g.H <= 0 || g.X < 0 || g.Y < 0 || g.X+g.W > 24 {
+				addErr("%s: gridPos %+v must fit into 24 columns", panelPath, g)
+			}
+			if p.Type == "row" {
+				if len(p.Targets) > 0 {
+					addErr("%s: rows cannot have queries", panelPath)
+				}
+				validatePanels(p.Panels, panelPath+".panels")
+				continue
+			}
+			if len(p.Panels) > 0 {
+				addErr("%s: only rows can contain panels", panelPath)
+			}
+			if p.Type != "text" && len(p.Targets) == 0 {
+				addErr("%s: panel %q has no queries", panelPath, p.Title)
+			}
+			refIDs := map[string]bool{}
+			for j, t := range p.Targets {
+				targetPath := fmt.Sprintf("%s.targets[%d]", panelPath, j)
+				if t.RefID == "" || refIDs[t.RefID] {
+					addErr("%s: refId %q must be set and unique within the panel", targetPath, t.RefID)
+				}
+				refIDs[t.RefID] = true
+				if _, _, err := analyzer.Parse(t.Expr); err != nil {
+					addErr("%s: invalid expression %q: %s", targetPath, t.Expr, err)
+				}
+				for _, name := range referencedVariables(t.Expr) {
+					if !slices.Contains(variables, name) {
+						addErr("%s: expression references undefined variable $%s", targetPath, name)
+					}
+				}
+			}
+		}
+	}
+	validatePanels(d.Panels, "panels")
+
+	return errors.Join(errs...)
+}
+
+func referencedVariables(s string) []string {
+	var names []string
+	for _, m := range variableRe.FindAllStringSubmatch(s, -1) {
+		if !slices.Contains(names, m[1]) {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
@@ -0,0 +1,306 @@
+// Package k8s renders Kubernetes deployment manifests for vmanomaly: a VMAnomaly custom resource
+// with an accompanying VMRule for the VictoriaMetrics operator, or values.yaml of the
+// victoria-metrics-anomaly Helm chart.
+//
+// See https://docs.victoriametrics.com/operator/resources/vmanomaly/ and
+// https://github.com/VictoriaMetrics/helm-charts/tree/master/charts/victoria-metrics-anomaly
+package k8s
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmalert"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmconfig"
+)
+
+// Defaults of Options
+const (
+	DefaultName             = "vmanomaly"
+	DefaultLicenseSecretKey = "license-key"
+	DefaultStorageSize      = "1Gi"
+	DefaultSamplingPeriod   = "1m"
+)
+
+// Options are deployment choices for vmanomaly
+type Options struct {
+	Name      string
+	Namespace string
+	Replicas  int
+	// Shards splits models between vmanomaly instances, see
+	// https://docs.victoriametrics.com/anomaly-detection/scaling-vmanomaly/
+	Shards    int
+	Resources Resources
+	// LicenseSecret is the name of the secret holding the license key under LicenseSecretKey
+	LicenseSecret    string
+	LicenseSecretKey string
+	// Persistence enables a persistent volume and settings.restore_state,
+	// so models and their training data survive restarts
+	Persistence  bool
+	StorageSize  string
+	StorageClass string
+	// Rules are vmalert rules deployed as VMRule, nil skips VMRule
+	Rules *vmalert.RuleFile
+}
+
+// Resources are container resource requests and limits, e.g. cpu: 500m, memory: 512Mi
+type Resources struct {
+	Requests map[string]string `yaml:"requests,omitempty" json:"requests,omitempty"`
+	Limits   map[string]string `yaml:"limits,omitempty" json:"limits,omitempty"`
+}
+
+func (r Resources) empty() bool {
+	return len(r.Requests) == 0 && len(r.Limits) == 0
+}
+
+// ObjectMeta is metadata of a Kubernetes object
+type ObjectMeta struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+// VMAnomaly is the VMAnomaly custom resource of the VictoriaMetrics operator
+type VMAnomaly struct {
+	APIVersion string        `yaml:"apiVersion"`
+	Kind       string        `yaml:"kind"`
+	Metadata   ObjectMeta    `yaml:"metadata"`
+	Spec       VMAnomalySpec `yaml:"spec"`
+}
+
+// VMAnomalySpec is the spec of VMAnomaly. Reader and writer connection settings take precedence
+// over the same settings in ConfigRawYaml
+type VMAnomalySpec struct {
+	ReplicaCount  int             `yaml:"replicaCount,omitempty"`
+	ShardCount    int             `yaml:"shardCount,omitempty"`
+	License       *License        `yaml:"license,omitempty"`
+	Reader        VMAnomalyReader `yaml:"reader"`
+	Writer        VMAnomalyWriter `yaml:"writer"`
+	ConfigRawYaml string          `yaml:"configRawYaml"`
+	Resources     *Resources      `yaml:"resources,omitempty"`
+	Storage       *StorageSpec    `yaml:"storage,omitempty"`
+	Monitoring    *MonitoringSpec `yaml:"monitoring,omitempty"`
+}
+
+// License references the license key secret
+type License struct {
+	KeyRef SecretKeyRef `yaml:"keyRef"`
+}
+
+// SecretKeyRef selects a key of a secret
+type SecretKeyRef struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+// VMAnomalyReader are reader connection settings
+type VMAnomalyReader struct {
+	DatasourceURL  string `yaml:"datasourceURL"`
+	SamplingPeriod string `yaml:"samplingPeriod"`
+	TenantID       string `yaml:"tenantID,omitempty"`
+}
+
+// VMAnomalyWriter are writer connection settings
+type VMAnomalyWriter struct {
+	DatasourceURL string `yaml:"datasourceURL"`
+	TenantID      string `yaml:"tenantID,omitempty"`
+}
+
+// StorageSpec configures a persistent volume claim template
+type StorageSpec struct {
+	VolumeClaimTemplate VolumeClaimTemplate `yaml:"volumeClaimTemplate"`
+}
+
+// VolumeClaimTemplate is a persistent volume claim template
+type VolumeClaimTemplate struct {
+	Spec VolumeClaimSpec `yaml:"spec"`
+}
+
+// VolumeClaimSpec is a persistent volume claim spec
+type VolumeClaimSpec struct {
+	AccessModes      []string  `yaml:"accessModes"`
+	StorageClassName string    `yaml:"storageClassName,omitempty"`
+	Resources        Resources `yaml:"resources"`
+}
+
+// MonitoringSpec configures the self-monitoring endpoint scraped by the operator
+type MonitoringSpec struct {
+	Pull PullSpec `yaml:"pull"`
+}
+
+// PullSpec configures the metrics port
+type PullSpec struct {
+	Port string `yaml:"port"`
+}
+
+// VMRule is the VMRule custom resource of the VictoriaMetrics operator
+type VMRule struct {
+	APIVersion string     `yaml:"apiVersion"`
+	Kind       string     `yaml:"kind"`
+	Metadata   ObjectMeta `yaml:"metadata"`
+	Spec       VMRuleSpec `yaml:"spec"`
+}
+
+// VMRuleSpec contains vmalert rule groups
+type VMRuleSpec struct {
+	Groups []vmalert.Group `yaml:"groups"`
+}
+
+// nameRe matches valid Kubernetes object names (RFC 1123 DNS label)
+var nameRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+func (o *Options) normalize() error {
+	if o.Name == "" {
+		o.Name = DefaultName
+	}
+	if len(o.Name) > 63 || !nameRe.MatchString(o.Name) {
+		return fmt.Errorf("invalid name %q: must consist of lower case alphanumeric characters or '-'", o.Name)
+	}
+	if o.Namespace != "" && !nameRe.MatchString(o.Namespace) {
+		return fmt.Errorf("invalid namespace %q", o.Namespace)
+	}
+	if o.Replicas < 0 || o.Shards < 0 {
+		return fmt.Errorf("replicas and shards must not be negative")
+	}
+	if o.LicenseSecret != "" && o.LicenseSecretKey == "" {
+		o.LicenseSecretKey = DefaultLicenseSecretKey
+	}
+	if o.Persistence && o.StorageSize == "" {
+		o.StorageSize = DefaultStorageSize
+	}
+	return nil
+}
+
+// rawConfig returns the config passed to vmanomaly, enabling state restoration for persistent deployments
+func rawConfig(c *vmconfig.Config, opts Options) map[string]any {
+	raw := maps.Clone(c.Raw)
+	if opts.Persistence {
+		settings, _ := raw["settings"].(map[string]any)
+		settings = maps.Clone(settings)
+		if settings == nil {
+			settings = map[string]any{}
+		}
+		settings["restore_state"] = true
+		raw["settings"] = settings
+	}
+	return raw
+}
+
+// GenerateOperatorManifests renders VMAnomaly and, if rules are set, VMRule manifests as a multi-document YAML
+func GenerateOperatorManifests(c *vmconfig.Config, opts Options) (string, error) {
+	if err := opts.normalize(); err != nil {
+		return "", err
+	}
+	if c.Reader.DatasourceURL == "" || c.Writer.DatasourceURL == "" {
+		return "", fmt.Errorf("reader.datasource_url and writer.datasource_url must be set for VMAnomaly")
+	}
+	samplingPeriod := c.Reader.SamplingPeriod
+	if samplingPeriod == "" {
+		samplingPeriod = DefaultSamplingPeriod
+	}
+	if _, err := vmanomaly.ParseDuration(samplingPeriod); err != nil {
+		return "", fmt.Errorf("invalid reader.sampling_period %q: %w", samplingPeriod, err)
+	}
+
+	configRaw, err := marshal(rawConfig(c, opts))
+	if err != nil {
+		return "", err
+	}
+	labels := map[string]string{"app.kubernetes.io/name": "vmanomaly", "app.kubernetes.io/instance": opts.Name}
+	cr := VMAnomaly{
+		APIVersion: "operator.victoriametrics.com/v1",
+		Kind:       "VMAnomaly",
+		Metadata:   ObjectMeta{Name: opts.Name, Namespace: opts.Namespace, Labels: labels},
+		Spec: VMAnomalySpec{
+			ReplicaCount: opts.Replicas,
+			ShardCount:   opts.Shards,
+			Reader: VMAnomalyReader{
+				DatasourceURL:  c.Reader.DatasourceURL,
+				SamplingPeriod: samplingPeriod,
+				TenantID:       c.Reader.TenantID,
+			},
+			Writer: VMAnomalyWriter{
+				DatasourceURL: c.Writer.DatasourceURL,
+				TenantID:      c.Writer.TenantID,
+			},
+			ConfigRawYaml: configRaw,
+			Monitoring:    &MonitoringSpec{Pull: PullSpec{Port: "8490"}},
+		},
+	}
+	if opts.LicenseSecret != "" {
+		cr.Spec.License = &License{KeyRef: SecretKeyRef{Name: opts.LicenseSecret, Key: opts.LicenseSecretKey}}
+	}
+	if !opts.Resources.empty() {
+		cr.Spec.Resources = &opts.Resources
+	}
+	if opts.Persistence {
+		cr.Spec.Storage = &StorageSpec{VolumeClaimTemplate: VolumeClaimTemplate{Spec: VolumeClaimSpec{
+			AccessModes:      []string{"ReadWriteOnce"},
+			StorageClassName: opts.StorageClass,
+			Resources:        Resources{Requests: map[string]string{"storage": opts.StorageSize}},
+		}}}
+	}
+
+	docs := []any{cr}
+	if opts.Rules != nil {
+		docs = append(docs, VMRule{
+			APIVersion: "operator.victoriametrics.com/v1beta1",
+			Kind:       "VMRule",
+			Metadata:   ObjectMeta{Name: opts.Name + "-alerts", Namespace: opts.Namespace, Labels: labels},
+			Spec:       VMRuleSpec{Groups: opts.Rules.Groups},
+		})
+	}
+	return marshal(docs...)
+}
+
+// GenerateHelmValues renders values.yaml for the victoria-metrics-anomaly Helm chart
+func GenerateHelmValues(c *vmconfig.Config, opts Options) (string, error) {
+	if err := opts.normalize(); err != nil {
+		return "", err
+	}
+	values := map[string]any{
+		"config": rawConfig(c, opts),
+	}
+	if opts.Replicas > 0 {
+		values["replicaCount"] = opts.Replicas
+	}
+	if opts.Shards > 0 {
+		values["shardsCount"] = opts.Shards
+	}
+	if opts.LicenseSecret != "" {
+		values["license"] = map[string]any{
+			"secret": map[string]any{"name": opts.LicenseSecret, "key": opts.LicenseSecretKey},
+		}
+	}
+	if !opts.Resources.empty() {
+		values["resources"] = opts.Resources
+	}
+	if opts.Persistence {
+		pv := map[string]any{"enabled": true, "size": opts.StorageSize}
+		if opts.StorageClass != "" {
+			pv["storageClassName"] = opts.StorageClass
+		}
+		values["persistentVolume"] = pv
+	}
+	return marshal(values)
+}
+
+func marshal(docs ...any) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return "", fmt.Errorf("cannot marshal manifest: %w", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("cannot marshal manifest: %w", err)
+	}
+	return buf.String(), nil
+}
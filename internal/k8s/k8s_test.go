@@ -0,0 +1,127 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmalert"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmconfig"
+)
+
+const testConfig = `
+reader:
+  datasource_url: http://vmsingle:8428
+  sampling_period: 1m
+  queries:
+    rps: sum(rate(http_requests_total[5m]))
+schedulers:
+  s1:
+    infer_every: 1m
+    fit_every: 1h
+    fit_window: 7d
+models:
+  zscore:
+    class: zscore
+writer:
+  datasource_url: http://vmsingle:8428
+`
+
+func mustParseConfig(t *testing.T) *vmconfig.Config {
+	t.Helper()
+	c, err := vmconfig.Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("cannot parse config: %v", err)
+	}
+	return c
+}
+
+func TestGenerateOperatorManifests(t *testing.T) {
+	c := mustParseConfig(t)
+	rules, err := vmalert.GenerateRules(c, vmalert.GenerateOptions{})
+	if err != nil {
+		t.Fatalf("GenerateRules() error = %v", err)
+	}
+	out, err := GenerateOperatorManifests(c, Options{
+		Namespace:     "monitoring",
+		Replicas:      1,
+		Shards:        2,
+		Resources:     Resources{Requests: map[string]string{"cpu": "500m", "memory": "512Mi"}},
+		LicenseSecret: "vmanomaly-license",
+		Persistence:   true,
+		Rules:         rules,
+	})
+	if err != nil {
+		t.Fatalf("GenerateOperatorManifests() error = %v", err)
+	}
+
+	dec := yaml.NewDecoder(strings.NewReader(out))
+	var cr VMAnomaly
+	if err := dec.Decode(&cr); err != nil {
+		t.Fatalf("cannot decode VMAnomaly: %v", err)
+	}
+	if cr.Kind != "VMAnomaly" || cr.Metadata.Name != DefaultName || cr.Spec.ShardCount != 2 {
+		t.Errorf("unexpected VMAnomaly: %+v", cr)
+	}
+	if cr.Spec.License == nil || cr.Spec.License.KeyRef.Key != DefaultLicenseSecretKey {
+		t.Errorf("license = %+v", cr.Spec.License)
+	}
+	if cr.Spec.Storage == nil || cr.Spec.Storage.VolumeClaimTemplate.Spec.Resources.Requests["storage"] != DefaultStorageSize {
+		t.Errorf("storage = %+v", cr.Spec.Storage)
+	}
+	if cr.Spec.Reader.DatasourceURL != "http://vmsingle:8428" || cr.Spec.Reader.SamplingPeriod != "1m" {
+		t.Errorf("reader = %+v", cr.Spec.Reader)
+	}
+	raw, err := vmconfig.Parse([]byte(cr.Spec.ConfigRawYaml))
+	if err != nil {
+		t.Fatalf("configRawYaml is not a valid config: %v", err)
+	}
+	if settings, _ := raw.Raw["settings"].(map[string]any); settings["restore_state"] != true {
+		t.Errorf("restore_state must be enabled with persistence, got settings %v", settings)
+	}
+	if _, ok := c.Raw["settings"]; ok {
+		t.Errorf("original config must not be modified")
+	}
+
+	var rule VMRule
+	if err := dec.Decode(&rule); err != nil {
+		t.Fatalf("cannot decode VMRule: %v", err)
+	}
+	if rule.Kind != "VMRule" || rule.Metadata.Name != "vmanomaly-alerts" || len(rule.Spec.Groups) != 2 {
+		t.Errorf("unexpected VMRule: %+v", rule)
+	}
+}
+
+func TestGenerateOperatorManifests_Errors(t *testing.T) {
+	c := mustParseConfig(t)
+	if _, err := GenerateOperatorManifests(c, Options{Name: "Bad_Name"}); err == nil {
+		t.Error("expected error for invalid name")
+	}
+	c.Writer.DatasourceURL = ""
+	if _, err := GenerateOperatorManifests(c, Options{}); err == nil {
+		t.Error("expected error for missing writer datasource_url")
+	}
+}
+
+func TestGenerateHelmValues(t *testing.T) {
+	out, err := GenerateHelmValues(mustParseConfig(t), Options{Shards: 3, Persistence: true, StorageClass: "fast"})
+	if err != nil {
+		t.Fatalf("GenerateHelmValues() error = %v", err)
+	}
+	var values map[string]any
+	if err := yaml.Unmarshal([]byte(out), &values); err != nil {
+		t.Fatalf("cannot decode values: %v", err)
+	}
+	if values["shardsCount"] != 3 {
+		t.Errorf("shardsCount = %v", values["shardsCount"])
+	}
+	pv, _ := values["persistentVolume"].(map[string]any)
+	if pv["enabled"] != true || pv["storageClassName"] != "fast" {
+		t.Errorf("persistentVolume = %v", pv)
+	}
+	config, _ := values["config"].(map[string]any)
+	if _, ok := config["models"]; !ok {
+		t.Errorf("config is missing models: %v", config)
+	}
+}
@@ -0,0 +1,362 @@
+// Package logsql contains helpers for LogsQL queries used by vmanomaly's VictoriaLogs reader (VLogsReader).
+//
+// VLogsReader reads data from the /select/logsql/stats_query_range endpoint, so every query must
+// contain a `| stats` pipe with numeric stats functions. The helpers here do a lightweight,
+// dependency-free analysis of such queries: they locate the stats pipe, extract `by (...)` labels
+// and result names, check stats functions against the list supported by vmanomaly and infer
+// the query step from `_time:<step>` buckets.
+package logsql
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+// AllowedStatsFunctions is the list of stats pipe functions supported by VLogsReader
+// (see "Valid stats functions" section of the reader docs). All of them return numeric values.
+var AllowedStatsFunctions = []string{
+	"avg",
+	"count",
+	"count_empty",
+	"count_uniq",
+	"count_uniq_hash",
+	"max",
+	"median",
+	"min",
+	"quantile",
+	"rate",
+	"rate_sum",
+	"sum",
+	"sum_len",
+}
+
+// Severity levels of analysis issues
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue describes a problem found in a LogsQL query
+type Issue struct {
+	Severity string `json:"severity"` // error|warning
+	Message  string `json:"message"`
+}
+
+// StatsFunc describes a single function call of the stats pipe
+type StatsFunc struct {
+	Name       string `json:"name"`                  // Function name, e.g. "count"
+	Args       string `json:"args,omitempty"`        // Raw function arguments
+	Condition  string `json:"condition,omitempty"`   // Raw `if (...)` condition, if any
+	ResultName string `json:"result_name,omitempty"` // Result field name set via `as`
+}
+
+// Analysis is the result of analyzing a LogsQL query
+type Analysis struct {
+	Query        string      `json:"query"`
+	Filter       string      `json:"filter"`                  // Part of the query before the first pipe
+	By           []string    `json:"by,omitempty"`            // Labels from `stats by (...)` excluding `_time` buckets
+	Functions    []StatsFunc `json:"functions,omitempty"`     // Stats functions
+	TimeBucket   string      `json:"time_bucket,omitempty"`   // `_time:<step>` bucket from `stats by (...)`
+	InferredStep string      `json:"inferred_step,omitempty"` // Step inferred from `_time:` bucket
+	SeriesNames  []string    `json:"series_names,omitempty"`  // Names of the produced series (result names)
+	Issues       []Issue     `json:"issues,omitempty"`
+}
+
+// Valid reports whether the analysis found no errors
+func (a *Analysis) Valid() bool {
+	for _, issue := range a.Issues {
+		if issue.Severity == SeverityError {
+			return false
+		}
+	}
+	return true
+}
+
+// Errors returns messages of all issues with error severity
+func (a *Analysis) Errors() []string {
+	var errs []string
+	for _, issue := range a.Issues {
+		if issue.Severity == SeverityError {
+			errs = append(errs, issue.Message)
+		}
+	}
+	return errs
+}
+
+func (a *Analysis) addIssue(severity, format string, args ...any) {
+	a.Issues = append(a.Issues, Issue{Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
+
+// Analyze parses a LogsQL query and checks that it is usable by VLogsReader.
+// Analyze never fails: all problems are reported as issues of the returned analysis.
+func Analyze(query string) *Analysis {
+	a := &Analysis{Query: query}
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		a.addIssue(SeverityError, "query is empty")
+		return a
+	}
+
+	pipes, err := splitTopLevel(query, '|')
+	if err != nil {
+		a.addIssue(SeverityError, "%s", err)
+		return a
+	}
+	a.Filter = strings.TrimSpace(pipes[0])
+	if a.Filter == "" {
+		a.addIssue(SeverityError, "query must start with a filter, use `*` to match all logs")
+	}
+	if strings.Contains(a.Filter, "_time:") {
+		a.addIssue(SeverityWarning, "`_time:` filter is overridden by vmanomaly, which sets the time range from fit_window and infer_every; remove it from the query")
+	}
+
+	statsIdx := -1
+	for i := 1; i < len(pipes); i++ {
+		if isStatsPipe(pipes[i]) {
+			statsIdx = i
+		}
+	}
+	if statsIdx < 0 {
+		a.addIssue(SeverityError, "query must contain a `| stats` pipe, e.g. `%s | stats count() as logs`", a.Filter)
+		return a
+	}
+	if statsIdx != len(pipes)-1 {
+		a.addIssue(SeverityWarning, "pipes after `| stats` are applied to the calculated stats and may drop or rename the produced series")
+	}
+
+	a.parseStatsPipe(strings.TrimSpace(pipes[statsIdx]))
+	return a
+}
+
+// InferStep returns the step of the `_time:<step>` bucket of the stats pipe, if any
+func InferStep(query string) (string, bool) {
+	a := Analyze(query)
+	return a.InferredStep, a.InferredStep != ""
+}
+
+func isStatsPipe(pipe string) bool {
+	pipe = strings.TrimSpace(pipe)
+	if !strings.HasPrefix(pipe, "stats") {
+		return false
+	}
+	rest := pipe[len("stats"):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '(' || rest[0] == '\n' || rest[0] == '\t'
+}
+
+func (a *Analysis) parseStatsPipe(pipe string) {
+	body := strings.TrimSpace(strings.TrimPrefix(pipe, "stats"))
+
+	if strings.HasPrefix(body, "by") {
+		rest := strings.TrimSpace(body[len("by"):])
+		if strings.HasPrefix(rest, "(") {
+			body = rest
+		}
+	}
+	if strings.HasPrefix(body, "(") {
+		end := matchingParen(body, 0)
+		if end < 0 {
+			a.addIssue(SeverityError, "unbalanced parentheses in `stats by (...)` clause")
+			return
+		}
+		a.parseBy(body[1:end])
+		body = strings.TrimSpace(body[end+1:])
+	}
+
+	if body == "" {
+		a.addIssue(SeverityError, "`| stats` pipe must contain at least one stats function")
+		return
+	}
+
+	calls, err := splitTopLevel(body, ',')
+	if err != nil {
+		a.addIssue(SeverityError, "%s", err)
+		return
+	}
+	resultNames := make(map[string]bool, len(calls))
+	for _, call := range calls {
+		fn, ok := a.parseStatsFunc(strings.TrimSpace(call))
+		if !ok {
+			continue
+		}
+		a.Functions = append(a.Functions, fn)
+		if fn.ResultName == "" {
+			continue
+		}
+		if resultNames[fn.ResultName] {
+			a.addIssue(SeverityError, "duplicate result name %q in `| stats` pipe", fn.ResultName)
+		}
+		resultNames[fn.ResultName] = true
+		a.SeriesNames = append(a.SeriesNames, fn.ResultName)
+	}
+}
+
+func (a *Analysis) parseBy(by string) {
+	fields, err := splitTopLevel(by, ',')
+	if err != nil {
+		a.addIssue(SeverityError, "%s", err)
+		return
+	}
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.HasPrefix(field, "_time:") {
+			a.By = append(a.By, field)
+			continue
+		}
+
+		bucket := strings.TrimSpace(strings.TrimPrefix(field, "_time:"))
+		if i := strings.Index(bucket, " offset "); i >= 0 {
+			bucket = strings.TrimSpace(bucket[:i])
+		}
+		a.TimeBucket = bucket
+		if _, err := vmanomaly.ParseDuration(bucket); err != nil {
+			a.addIssue(SeverityError, "invalid `_time:` bucket %q: %s", bucket, err)
+			continue
+		}
+		a.InferredStep = bucket
+		a.addIssue(SeverityWarning, "`_time:%s` bucket is replaced by the reader step; use step=%q and remove the bucket from `by (...)`", bucket, bucket)
+	}
+	if slices.Contains(a.By, "_time") {
+		a.addIssue(SeverityError, "`_time` without a bucket in `by (...)` produces a series per log entry; use `_time:<step>` or drop it")
+	}
+}
+
+func (a *Analysis) parseStatsFunc(call string) (StatsFunc, bool) {
+	var fn StatsFunc
+
+	open := strings.IndexByte(call, '(')
+	if open <= 0 {
+		a.addIssue(SeverityError, "cannot parse stats function %q, expected `func(args) as name`", call)
+		return fn, false
+	}
+	fn.Name = strings.TrimSpace(call[:open])
+	end := matchingParen(call, open)
+	if end < 0 {
+		a.addIssue(SeverityError, "unbalanced parentheses in stats function %q", call)
+		return fn, false
+	}
+	fn.Args = strings.TrimSpace(call[open+1 : end])
+	rest := strings.TrimSpace(call[end+1:])
+
+	if strings.HasPrefix(rest, "if") {
+		cond := strings.TrimSpace(rest[len("if"):])
+		if strings.HasPrefix(cond, "(") {
+			condEnd := matchingParen(cond, 0)
+			if condEnd < 0 {
+				a.addIssue(SeverityError, "unbalanced parentheses in `if (...)` condition of %q", call)
+				return fn, false
+			}
+			fn.Condition = strings.TrimSpace(cond[1:condEnd])
+			rest = strings.TrimSpace(cond[condEnd+1:])
+		}
+	}
+
+	rest = strings.TrimSpace(strings.TrimPrefix(rest, "as "))
+	fn.ResultName = strings.Trim(rest, "\"'`")
+
+	if !slices.Contains(AllowedStatsFunctions, fn.Name) {
+		a.addIssue(SeverityError, "stats function %q is not supported by vmanomaly; supported functions: %s", fn.Name, strings.Join(AllowedStatsFunctions, ", "))
+	}
+	if fn.Name == "quantile" && fn.Args == "" {
+		a.addIssue(SeverityError, "`quantile` requires a phi argument, e.g. quantile(0.9, duration)")
+	}
+	if fn.ResultName == "" {
+		a.addIssue(SeverityWarning, "stats function %q has no result name; add `as <name>` to get a stable series name", fn.Name)
+	}
+	return fn, true
+}
+
+// ValidateStep checks the step passed to VLogsReader against the `_time:` bucket of the query, if any
+func (a *Analysis) ValidateStep(step string) {
+	if step == "" {
+		return
+	}
+	stepDur, err := vmanomaly.ParseDuration(step)
+	if err != nil {
+		a.addIssue(SeverityError, "invalid step %q: %s", step, err)
+		return
+	}
+	if stepDur < time.Second {
+		a.addIssue(SeverityWarning, "step %q is below 1s, VictoriaLogs stats are calculated with second precision", step)
+	}
+	if a.InferredStep == "" {
+		return
+	}
+	if bucketDur, _ := vmanomaly.ParseDuration(a.InferredStep); bucketDur != stepDur {
+		a.addIssue(SeverityWarning, "step %q differs from `_time:%s` bucket of the query", step, a.InferredStep)
+	}
+}
+
+// splitTopLevel splits s by sep ignoring separators inside quotes and parentheses
+func splitTopLevel(s string, sep byte) ([]string, error) {
+	var (
+		parts []string
+		depth int
+		quote byte
+		start int
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unexpected `)` at position %d", i)
+			}
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quoted string")
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses")
+	}
+	return append(parts, s[start:]), nil
+}
+
+// matchingParen returns the index of the parenthesis closing the one at position open, or -1
+func matchingParen(s string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,176 @@
+package logsql
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantValid    bool
+		wantBy       []string
+		wantFuncs    []string
+		wantSeries   []string
+		wantStep     string
+		wantWarnings bool
+	}{
+		{
+			name:       "simple count",
+			query:      "error | stats count() as errors",
+			wantValid:  true,
+			wantFuncs:  []string{"count"},
+			wantSeries: []string{"errors"},
+		},
+		{
+			name:       "by clause",
+			query:      `_stream:{app="api"} | stats by (host, path) avg(duration) as avg_duration`,
+			wantValid:  true,
+			wantBy:     []string{"host", "path"},
+			wantFuncs:  []string{"avg"},
+			wantSeries: []string{"avg_duration"},
+		},
+		{
+			name:       "multiple quantiles",
+			query:      "* | stats quantile(0.5, d) p50, quantile(0.99, d) p99",
+			wantValid:  true,
+			wantFuncs:  []string{"quantile", "quantile"},
+			wantSeries: []string{"p50", "p99"},
+		},
+		{
+			name:         "time bucket infers step",
+			query:        "* | stats by (_time:5m, host) count() as logs",
+			wantValid:    true,
+			wantBy:       []string{"host"},
+			wantFuncs:    []string{"count"},
+			wantSeries:   []string{"logs"},
+			wantStep:     "5m",
+			wantWarnings: true,
+		},
+		{
+			name:       "conditional count",
+			query:      "* | stats by (app) count() if (level:error) as errors, count() as total",
+			wantValid:  true,
+			wantBy:     []string{"app"},
+			wantFuncs:  []string{"count", "count"},
+			wantSeries: []string{"errors", "total"},
+		},
+		{
+			name:      "unsupported function",
+			query:     "* | stats values(host) as hosts",
+			wantValid: false,
+			wantFuncs: []string{"values"},
+		},
+		{
+			name:      "missing stats pipe",
+			query:     "error | limit 10",
+			wantValid: false,
+		},
+		{
+			name:      "empty query",
+			query:     "  ",
+			wantValid: false,
+		},
+		{
+			name:      "unbalanced parentheses",
+			query:     "* | stats by (host count() as logs",
+			wantValid: false,
+		},
+		{
+			name:      "duplicate result names",
+			query:     "* | stats count() as x, sum(n) as x",
+			wantValid: false,
+			wantFuncs: []string{"count", "sum"},
+		},
+		{
+			name:         "time filter warning",
+			query:        "_time:5m error | stats count() as errors",
+			wantValid:    true,
+			wantFuncs:    []string{"count"},
+			wantSeries:   []string{"errors"},
+			wantWarnings: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Analyze(tt.query)
+			if a.Valid() != tt.wantValid {
+				t.Fatalf("Valid() = %v, want %v (issues: %+v)", a.Valid(), tt.wantValid, a.Issues)
+			}
+			if !slices.Equal(a.By, tt.wantBy) {
+				t.Errorf("By = %v, want %v", a.By, tt.wantBy)
+			}
+			var funcs []string
+			for _, fn := range a.Functions {
+				funcs = append(funcs, fn.Name)
+			}
+			if !slices.Equal(funcs, tt.wantFuncs) {
+				t.Errorf("Functions = %v, want %v", funcs, tt.wantFuncs)
+			}
+			if tt.wantValid && !slices.Equal(a.SeriesNames, tt.wantSeries) {
+				t.Errorf("SeriesNames = %v, want %v", a.SeriesNames, tt.wantSeries)
+			}
+			if a.InferredStep != tt.wantStep {
+				t.Errorf("InferredStep = %q, want %q", a.InferredStep, tt.wantStep)
+			}
+			hasWarnings := len(a.Issues) > len(a.Errors())
+			if tt.wantValid && hasWarnings != tt.wantWarnings {
+				t.Errorf("warnings = %v, want %v (issues: %+v)", hasWarnings, tt.wantWarnings, a.Issues)
+			}
+		})
+	}
+}
+
+func TestAnalysis_ValidateStep(t *testing.T) {
+	a := Analyze("* | stats by (_time:5m) count() as logs")
+	a.ValidateStep("1m")
+	found := false
+	for _, issue := range a.Issues {
+		if strings.Contains(issue.Message, "differs") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected step mismatch warning, got %+v", a.Issues)
+	}
+
+	a = Analyze("* | stats count() as logs")
+	a.ValidateStep("bad")
+	if a.Valid() {
+		t.Error("expected invalid step to produce an error")
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	for _, tmpl := range Templates() {
+		t.Run(tmpl.Name, func(t *testing.T) {
+			query, err := tmpl.Render(nil)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if a := Analyze(query); !a.Valid() {
+				t.Errorf("rendered query %q is invalid: %v", query, a.Errors())
+			}
+		})
+	}
+
+	tmpl, ok := GetTemplate("error_rate")
+	if !ok {
+		t.Fatal("error_rate template not found")
+	}
+	query, err := tmpl.Render(map[string]string{"filter": `_stream:{app="api"}`, "by": "host"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := `_stream:{app="api"} level:error | stats by (host) rate() as error_rate`
+	if query != want {
+		t.Errorf("Render() = %q, want %q", query, want)
+	}
+
+	if _, err := tmpl.Render(map[string]string{"unknown": "x"}); err == nil {
+		t.Error("expected error for unknown parameter")
+	}
+}
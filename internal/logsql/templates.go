@@ -0,0 +1,100 @@
+package logsql
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Template is a parametrized LogsQL query for a common log-based anomaly detection scenario
+type Template struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Query       string            `json:"query"`      // LogsQL query with {{param}} placeholders
+	Params      map[string]string `json:"params"`     // Parameter descriptions
+	Defaults    map[string]string `json:"defaults"`   // Default parameter values
+	ModelSpec   map[string]any    `json:"model_spec"` // Recommended model specification
+	Step        string            `json:"step"`       // Recommended step
+}
+
+var templates = []Template{
+	{
+		Name:        "error_rate",
+		Description: "Per-second rate of error logs grouped by the given fields. Detects bursts of errors (above expected only).",
+		Query:       "{{filter}} {{error_filter}} | stats by ({{by}}) rate() as error_rate",
+		Params: map[string]string{
+			"filter":       "Stream or field filter selecting the logs, e.g. `_stream:{app=\"api\"}`",
+			"error_filter": "Filter matching error logs",
+			"by":           "Comma-separated fields to group by",
+		},
+		Defaults: map[string]string{
+			"filter":       "*",
+			"error_filter": "level:error",
+			"by":           "_stream",
+		},
+		ModelSpec: map[string]any{
+			"class":                 "zscore_online",
+			"z_threshold":           3.0,
+			"detection_direction":   "above_expected",
+			"min_dev_from_expected": 0.1,
+		},
+		Step: "1m",
+	},
+	{
+		Name:        "log_volume",
+		Description: "Number of logs per step grouped by the given fields. Detects unexpected drops (e.g. a silent service) and spikes of log volume.",
+		Query:       "{{filter}} | stats by ({{by}}) count() as log_volume",
+		Params: map[string]string{
+			"filter": "Stream or field filter selecting the logs, e.g. `_stream:{app=\"api\"}`",
+			"by":     "Comma-separated fields to group by",
+		},
+		Defaults: map[string]string{
+			"filter": "*",
+			"by":     "_stream",
+		},
+		ModelSpec: map[string]any{
+			"class":               "quantile_online",
+			"quantiles":           []any{0.01, 0.5, 0.99},
+			"seasonal_interval":   "1d",
+			"detection_direction": "both",
+			"data_range":          []any{0, "inf"},
+		},
+		Step: "5m",
+	},
+}
+
+// Templates returns all available LogsQL templates sorted by name
+func Templates() []Template {
+	result := slices.Clone(templates)
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// GetTemplate returns a template by its name
+func GetTemplate(name string) (Template, bool) {
+	for _, t := range templates {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+// Render substitutes template parameters, falling back to defaults for missing ones
+func (t Template) Render(params map[string]string) (string, error) {
+	for name := range params {
+		if _, ok := t.Params[name]; !ok {
+			return "", fmt.Errorf("unknown parameter %q for template %q", name, t.Name)
+		}
+	}
+	query := t.Query
+	for name := range t.Params {
+		value, ok := params[name]
+		if !ok || strings.TrimSpace(value) == "" {
+			value = t.Defaults[name]
+		}
+		query = strings.ReplaceAll(query, "{{"+name+"}}", value)
+	}
+	return query, nil
+}
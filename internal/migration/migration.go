@@ -0,0 +1,236 @@
+// Package migration plans vmanomaly upgrades for stateful deployments (settings.restore_state).
+//
+// A plan combines the versions of the running service and its stored state, the embedded changelog
+// and the compatibility checks of vmanomaly: it lists intermediate versions worth stopping at,
+// breaking changes and known issues on the way, model aliases which lose their state and
+// the cost of refitting them on their fit_window.
+package migration
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/capacity"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/changelog"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmconfig"
+)
+
+// Checker checks compatibility of the stored state with a version, it is implemented by *vmanomaly.Client
+type Checker interface {
+	Compatibility(ctx context.Context, versionTo *string) (*vmanomaly.CompatibilityCheckResponse, error)
+}
+
+// Step is an upgrade to a single version
+type Step struct {
+	Version string `json:"version"`
+	Reason  string `json:"reason"`
+	// Releases are all releases passed by this step
+	Releases        []string          `json:"releases"`
+	Breaking        []changelog.Entry `json:"breaking,omitempty"`
+	Deprecations    []changelog.Entry `json:"deprecations,omitempty"`
+	KnownIssues     []string          `json:"known_issues,omitempty"`
+	Compatible      *bool             `json:"compatible,omitempty"` // Result of vmanomaly compatibility check, nil if unavailable
+	DropEverything  bool              `json:"drop_everything,omitempty"`
+	ModelsToPurge   []string          `json:"models_to_purge,omitempty"`
+	PurgeReaderData bool              `json:"purge_reader_data,omitempty"`
+}
+
+// StateLoss is a model alias whose state is lost during the upgrade
+type StateLoss struct {
+	Model string `json:"model"`
+	Class string `json:"class,omitempty"`
+	Step  string `json:"step"` // Version at which the state is lost
+	// FitWindow is the longest fit_window of the model schedulers, the model produces no
+	// anomaly scores until it is refit on that much data
+	FitWindow string `json:"fit_window,omitempty"`
+	// FitPoints is the number of points per series read for the refit, summed over queries
+	FitPoints int `json:"fit_points,omitempty"`
+	// Series is the estimated number of series summed over queries
+	Series int `json:"series,omitempty"`
+	// RefitCost is the one-time refit workload in capacity cost units (points weighted by model cost)
+	RefitCost float64 `json:"refit_cost,omitempty"`
+}
+
+// Plan is an ordered upgrade plan
+type Plan struct {
+	RuntimeVersion string      `json:"runtime_version,omitempty"`
+	StoredVersion  string      `json:"stored_version,omitempty"`
+	From           string      `json:"from"`
+	To             string      `json:"to"`
+	Steps          []Step      `json:"steps"`
+	StateLoss      []StateLoss `json:"state_loss,omitempty"`
+	RefitCost      float64     `json:"refit_cost,omitempty"`
+	Warnings       []string    `json:"warnings,omitempty"`
+}
+
+// Options are inputs of Build
+type Options struct {
+	// From is the version of the stored state or the running service
+	From changelog.Version
+	// To is the target version
+	To changelog.Version
+	// Config is the deployed config, used for refit costs, optional
+	Config *vmconfig.Config
+	// Series is the estimated number of series per query alias, optional
+	Series map[string]int
+}
+
+// Build builds an upgrade plan. Intermediate steps are the latest patch releases (not newer than the target)
+// of every minor version which contains breaking changes or deprecations, so config changes can be applied
+// and verified one at a time. If checker is set, compatibility of the stored state is checked for every step.
+func Build(ctx context.Context, cl *changelog.Changelog, checker Checker, opts Options) (*Plan, error) {
+	if opts.To.Compare(opts.From) <= 0 {
+		return nil, fmt.Errorf("target version %s must be newer than %s", opts.To, opts.From)
+	}
+	plan := &Plan{From: opts.From.String(), To: opts.To.String()}
+	releases := cl.Between(opts.From, opts.To)
+	if _, ok := cl.Release(opts.To); !ok {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("target version %s is not found in the embedded changelog", opts.To))
+	}
+
+	stops := stopVersions(releases, opts.To)
+	next := 0
+	for _, stop := range stops {
+		step := Step{Version: stop.String()}
+		for next < len(releases) && releases[next].Version.Compare(stop) <= 0 {
+			r := releases[next]
+			step.Releases = append(step.Releases, r.Version.String())
+			step.Breaking = append(step.Breaking, r.Breaking()...)
+			step.Deprecations = append(step.Deprecations, r.Deprecations()...)
+			next++
+		}
+		if r, ok := cl.Release(stop); ok {
+			step.KnownIssues = r.Notices
+		}
+		switch {
+		case stop.Compare(opts.To) == 0:
+			step.Reason = "target version"
+		case len(step.Breaking) > 0:
+			step.Reason = "apply and verify config changes for breaking changes before going further"
+		default:
+			step.Reason = "apply deprecated options replacements before going further"
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+
+	lost := map[string]string{}
+	if checker != nil {
+		for i := range plan.Steps {
+			step := &plan.Steps[i]
+			version := strings.TrimPrefix(step.Version, "v")
+			result, err := checker.Compatibility(ctx, &version)
+			if err != nil {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("cannot check compatibility with %s: %v", step.Version, err))
+				continue
+			}
+			if plan.StoredVersion == "" && result.StoredVersion != nil {
+				plan.StoredVersion = *result.StoredVersion
+			}
+			if !result.GlobalCheck.HasState {
+				continue
+			}
+			step.Compatible = &result.GlobalCheck.IsCompatible
+			step.DropEverything = result.GlobalCheck.DropEverything
+			if a := result.ComponentAssessment; a != nil {
+				step.ModelsToPurge = a.ModelsToPurge
+				step.PurgeReaderData = a.ShouldPurgeReaderData
+			}
+			var aliases []string
+			if step.DropEverything && opts.Config != nil {
+				for _, m := range opts.Config.Models {
+					aliases = append(aliases, m.Alias)
+				}
+			} else {
+				aliases = step.ModelsToPurge
+			}
+			for _, alias := range aliases {
+				if _, ok := lost[alias]; !ok {
+					lost[alias] = step.Version
+				}
+			}
+			if step.DropEverything && opts.Config == nil {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("all state is dropped at %s, pass the config to list affected models", step.Version))
+			}
+		}
+	}
+
+	for _, alias := range slices.Sorted(maps.Keys(lost)) {
+		loss := StateLoss{Model: alias, Step: lost[alias]}
+		if opts.Config != nil {
+			refitCost(&loss, opts.Config, opts.Series)
+		}
+		plan.RefitCost += loss.RefitCost
+		plan.StateLoss = append(plan.StateLoss, loss)
+	}
+	return plan, nil
+}
+
+// stopVersions returns versions to stop at on the way to the target
+func stopVersions(releases []changelog.Release, to changelog.Version) []changelog.Version {
+	var stops []changelog.Version
+	for i, r := range releases {
+		if len(r.Breaking()) == 0 && len(r.Deprecations()) == 0 {
+			continue
+		}
+		// Stop at the latest patch of the same minor version
+		stop := r.Version
+		for _, later := range releases[i+1:] {
+			if later.Version.Major == stop.Major && later.Version.Minor == stop.Minor && later.Version.Pre == "" {
+				stop = later.Version
+			}
+		}
+		if len(stops) == 0 || stops[len(stops)-1].Compare(stop) != 0 {
+			stops = append(stops, stop)
+		}
+	}
+	if len(stops) == 0 || stops[len(stops)-1].Compare(to) != 0 {
+		stops = slices.DeleteFunc(stops, func(v changelog.Version) bool { return v.Compare(to) >= 0 })
+		stops = append(stops, to)
+	}
+	return stops
+}
+
+// refitCost fills refit details of a model from the config
+func refitCost(loss *StateLoss, c *vmconfig.Config, series map[string]int) {
+	m, ok := c.Model(loss.Model)
+	if !ok {
+		return
+	}
+	loss.Class = m.Class
+	cost, _ := capacity.LookupModelCost(m.Class)
+
+	var fitWindow time.Duration
+	for _, alias := range m.Schedulers {
+		s, _ := c.Scheduler(alias)
+		if d, err := vmanomaly.ParseDuration(s.FitWindow); err == nil && d > fitWindow {
+			fitWindow = d
+		}
+	}
+	if fitWindow == 0 || cost.Kind == capacity.KindRolling {
+		// Rolling models keep no state between infer calls
+		return
+	}
+	loss.FitWindow = vmanomaly.FormatDuration(fitWindow)
+	for _, alias := range m.Queries {
+		q, _ := c.Query(alias)
+		step, err := vmanomaly.ParseDuration(q.Step)
+		if err != nil || step <= 0 {
+			step, _ = vmanomaly.ParseDuration(capacity.DefaultStep)
+		}
+		points := int(fitWindow / step)
+		n := max(series[alias], 1)
+		loss.FitPoints += points
+		loss.Series += n
+		loss.RefitCost += float64(points*n) * max(cost.Fit, 1)
+	}
+}
+
+// RuntimeVersion extracts the vmanomaly version from its build info
+func RuntimeVersion(buildInfo map[string]any) string {
+	return vmanomaly.BuildVersion(buildInfo)
+}
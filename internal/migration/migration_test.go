@@ -0,0 +1,167 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/changelog"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmconfig"
+)
+
+const testChangelog = `
+## v1.4.0
+Released: 2024-04-01
+
+> Known issue with online models.
+
+- FEATURE: New model.
+
+## v1.3.1
+- BUGFIX: Fixed state.
+
+## v1.3.0
+- BREAKING CHANGE: Changed state format of prophet.
+
+## v1.2.0
+- DEPRECATION: Old reader arg.
+
+## v1.1.0
+- FEATURE: Something.
+`
+
+const testConfig = `
+reader:
+  datasource_url: http://vmsingle:8428
+  sampling_period: 1m
+  queries:
+    cpu: sum(rate(node_cpu_seconds_total[5m])) by (instance)
+    rps:
+      expr: sum(rate(http_requests_total[5m])) by (job)
+      step: 5m
+schedulers:
+  daily:
+    infer_every: 1m
+    fit_every: 1d
+    fit_window: 7d
+models:
+  prophet:
+    class: prophet
+  rolling:
+    class: rolling_quantile
+    quantile: 0.9
+writer:
+  datasource_url: http://vmsingle:8428
+`
+
+type fakeChecker map[string]*vmanomaly.CompatibilityCheckResponse
+
+func (f fakeChecker) Compatibility(_ context.Context, versionTo *string) (*vmanomaly.CompatibilityCheckResponse, error) {
+	if r, ok := f[*versionTo]; ok {
+		return r, nil
+	}
+	return nil, errors.New("unknown version")
+}
+
+func mustVersion(t *testing.T, s string) changelog.Version {
+	t.Helper()
+	v, err := changelog.ParseVersion(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestBuild(t *testing.T) {
+	cl, err := changelog.Parse([]byte(testChangelog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := vmconfig.Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := "1.1.0"
+	checker := fakeChecker{
+		"1.2.0": {StoredVersion: &stored, GlobalCheck: vmanomaly.GlobalCompatibilityCheck{HasState: true, IsCompatible: true}},
+		"1.3.1": {
+			StoredVersion:       &stored,
+			GlobalCheck:         vmanomaly.GlobalCompatibilityCheck{HasState: true},
+			ComponentAssessment: &vmanomaly.ComponentCompatibilityAssessment{ModelsToPurge: []string{"prophet", "rolling"}},
+		},
+	}
+
+	plan, err := Build(context.Background(), cl, checker, Options{
+		From:   mustVersion(t, stored),
+		To:     mustVersion(t, "v1.4.0"),
+		Config: cfg,
+		Series: map[string]int{"cpu": 10},
+	})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	var versions []string
+	for _, s := range plan.Steps {
+		versions = append(versions, s.Version)
+	}
+	if got := strings.Join(versions, ","); got != "v1.2.0,v1.3.1,v1.4.0" {
+		t.Fatalf("steps = %s", got)
+	}
+	if s := plan.Steps[1]; len(s.Breaking) != 1 || strings.Join(s.Releases, ",") != "v1.3.0,v1.3.1" || s.Compatible == nil || *s.Compatible {
+		t.Errorf("step 1 = %+v", s)
+	}
+	if s := plan.Steps[2]; len(s.KnownIssues) != 1 || s.Reason != "target version" {
+		t.Errorf("step 2 = %+v", s)
+	}
+	if plan.StoredVersion != stored {
+		t.Errorf("stored version = %q", plan.StoredVersion)
+	}
+	if len(plan.Warnings) != 1 || !strings.Contains(plan.Warnings[0], "v1.4.0") {
+		t.Errorf("warnings = %q", plan.Warnings)
+	}
+
+	if len(plan.StateLoss) != 2 {
+		t.Fatalf("state loss = %+v", plan.StateLoss)
+	}
+	prophet := plan.StateLoss[0]
+	// 7d of 1m points for 10 cpu series and 7d of 5m points for a single rps series, prophet fit costs 200
+	wantCost := float64(7*24*60*10+7*24*12) * 200
+	if prophet.Model != "prophet" || prophet.Step != "v1.3.1" || prophet.FitWindow != "7d" || prophet.Series != 11 || prophet.RefitCost != wantCost {
+		t.Errorf("prophet = %+v, want refit cost %v", prophet, wantCost)
+	}
+	if rolling := plan.StateLoss[1]; rolling.RefitCost != 0 || rolling.FitWindow != "" {
+		t.Errorf("rolling models must not need a refit, got %+v", rolling)
+	}
+	if plan.RefitCost != wantCost {
+		t.Errorf("refit cost = %v", plan.RefitCost)
+	}
+}
+
+func TestBuild_Errors(t *testing.T) {
+	cl, err := changelog.Parse([]byte(testChangelog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Build(context.Background(), cl, nil, Options{From: mustVersion(t, "1.4.0"), To: mustVersion(t, "1.3.0")}); err == nil {
+		t.Error("expected error for downgrade")
+	}
+	plan, err := Build(context.Background(), cl, nil, Options{From: mustVersion(t, "1.3.0"), To: mustVersion(t, "1.4.0")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 1 || plan.Steps[0].Version != "v1.4.0" || len(plan.StateLoss) != 0 {
+		t.Errorf("plan = %+v", plan)
+	}
+}
+
+func TestRuntimeVersion(t *testing.T) {
+	if v := RuntimeVersion(map[string]any{"vmanomaly": "v1.26.2", "vmui": "v1"}); v != "v1.26.2" {
+		t.Errorf("RuntimeVersion() = %q", v)
+	}
+	if v := RuntimeVersion(map[string]any{"version": "1.0.0"}); v != "1.0.0" {
+		t.Errorf("RuntimeVersion() = %q", v)
+	}
+}
@@ -0,0 +1,238 @@
+package params
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Catalog is a set of documented parameters of vmanomaly components
+type Catalog struct {
+	Params []Param
+}
+
+// Query selects parameters of a catalog. Empty fields match everything.
+type Query struct {
+	Component string
+	// Class is a full class name (e.g. model.zscore.ZscoreModel), its alias (zscore) or the last part of the name (ZscoreModel).
+	// Parameters common to all classes of the component are matched too.
+	Class string
+	// Name is the exact parameter name, it is matched case-insensitively
+	Name string
+	// Keyword is matched as a substring of parameter names and descriptions
+	Keyword string
+}
+
+// NewCatalog returns a catalog of parameters parsed from component docs keyed by component name
+func NewCatalog(docs map[string]string) *Catalog {
+	c := &Catalog{}
+	for _, component := range Components {
+		if markdown, ok := docs[component]; ok {
+			c.Params = append(c.Params, Parse(component, markdown)...)
+		}
+	}
+	return c
+}
+
+// Lookup returns parameters matching the query in docs order
+func (c *Catalog) Lookup(q Query) []Param {
+	// Common parameters are matched only in components having the class
+	classComponents := map[string]bool{}
+	if q.Class != "" {
+		for _, p := range c.Params {
+			if p.Class != "" && MatchClass(p, q.Class) {
+				classComponents[p.Component] = true
+			}
+		}
+	}
+	var result []Param
+	for _, p := range c.Params {
+		if q.Component != "" && !strings.EqualFold(p.Component, q.Component) {
+			continue
+		}
+		if q.Class != "" && (p.Class == "" && !classComponents[p.Component] || p.Class != "" && !MatchClass(p, q.Class)) {
+			continue
+		}
+		result = append(result, p)
+	}
+	return Filter(result, q)
+}
+
+// ResolveClass returns the full class name and aliases of a class of the component
+// given by its full name, alias or the last part of the name
+func (c *Catalog) ResolveClass(component, class string) (string, []string, bool) {
+	for _, p := range c.Params {
+		if p.Class != "" && (component == "" || strings.EqualFold(p.Component, component)) && MatchClass(p, class) {
+			return p.Class, p.Aliases, true
+		}
+	}
+	return "", nil, false
+}
+
+// Classes returns full class names of the component in docs order
+func (c *Catalog) Classes(component string) []string {
+	var classes []string
+	for _, p := range c.Params {
+		if p.Class != "" && strings.EqualFold(p.Component, component) && !slices.Contains(classes, p.Class) {
+			classes = append(classes, p.Class)
+		}
+	}
+	return classes
+}
+
+// MatchClass returns whether the parameter belongs to the class given by its full name, alias or the last part of the name
+func MatchClass(p Param, class string) bool {
+	if strings.EqualFold(p.Class, class) || strings.EqualFold(p.Class[strings.LastIndex(p.Class, ".")+1:], class) {
+		return true
+	}
+	for _, alias := range p.Aliases {
+		if strings.EqualFold(alias, class) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns parameters matching the name and the keyword of the query
+func Filter(params []Param, q Query) []Param {
+	keyword := strings.ToLower(q.Keyword)
+	var result []Param
+	for _, p := range params {
+		if q.Name != "" && !strings.EqualFold(p.Name, q.Name) {
+			continue
+		}
+		if keyword != "" && !strings.Contains(strings.ToLower(p.Name), keyword) && !strings.Contains(strings.ToLower(p.Description), keyword) {
+			continue
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// MergeSchema merges parameters of a model class with its JSON schema returned by vmanomaly.
+// The schema is the source of truth for types and defaults: documented defaults which differ
+// are kept in DocsDefault. Schema properties missing in docs are appended with SourceSchema.
+func MergeSchema(params []Param, class string, aliases []string, schema map[string]any) []Param {
+	props, _ := schema["properties"].(map[string]any)
+	if len(props) == 0 {
+		return params
+	}
+	required := map[string]bool{}
+	if list, ok := schema["required"].([]any); ok {
+		for _, r := range list {
+			if name, ok := r.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	result := slices.Clone(params)
+	for _, name := range names {
+		prop, _ := props[name].(map[string]any)
+		typ := schemaType(prop)
+		def, hasDefault := prop["default"]
+		description, _ := prop["description"].(string)
+
+		i := slices.IndexFunc(result, func(p Param) bool { return p.Name == name })
+		if i < 0 {
+			p := Param{
+				Component:   ComponentModels,
+				Class:       class,
+				Aliases:     aliases,
+				Name:        name,
+				Type:        typ,
+				Optional:    !required[name],
+				Description: description,
+				Source:      SourceSchema,
+			}
+			if hasDefault {
+				p.Default = formatDefault(def)
+			}
+			result = append(result, p)
+			continue
+		}
+
+		p := &result[i]
+		p.Source = SourceBoth
+		if typ != "" {
+			p.Type = typ
+		}
+		if p.Description == "" {
+			p.Description = description
+		}
+		if hasDefault {
+			value := formatDefault(def)
+			if p.Default != "" && !equalDefaults(p.Default, value) {
+				p.DocsDefault = p.Default
+			}
+			p.Default = value
+		}
+	}
+	return result
+}
+
+// schemaType returns the type of a JSON schema property, union types are joined with " | "
+func schemaType(prop map[string]any) string {
+	if t, ok := prop["type"].(string); ok {
+		if items, ok := prop["items"].(map[string]any); ok && t == "array" {
+			if it := schemaType(items); it != "" {
+				return fmt.Sprintf("array[%s]", it)
+			}
+		}
+		return t
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		variants, ok := prop[key].([]any)
+		if !ok {
+			continue
+		}
+		var types []string
+		for _, v := range variants {
+			if m, ok := v.(map[string]any); ok {
+				if t := schemaType(m); t != "" && !slices.Contains(types, t) {
+					types = append(types, t)
+				}
+			}
+		}
+		return strings.Join(types, " | ")
+	}
+	return ""
+}
+
+// formatDefault formats a default value of a JSON schema: strings as is, other values as JSON
+func formatDefault(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// equalDefaults compares documented and schema defaults ignoring case, quotes and number formatting
+func equalDefaults(a, b string) bool {
+	norm := func(s string) string {
+		s = strings.ToLower(strings.Trim(strings.TrimSpace(s), `"'`))
+		if s == "none" {
+			return "null"
+		}
+		return s
+	}
+	a, b = norm(a), norm(b)
+	if a == b {
+		return true
+	}
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	return errA == nil && errB == nil && fa == fb
+}
@@ -0,0 +1,109 @@
+package params
+
+import (
+	"testing"
+)
+
+func TestCatalogLookup(t *testing.T) {
+	c := NewCatalog(map[string]string{
+		ComponentModels:    modelsDoc,
+		ComponentScheduler: schedulerDoc,
+		ComponentSettings:  settingsDoc,
+	})
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string // component/class/name of found params
+	}{
+		{
+			name:  "alias includes common args",
+			query: Query{Component: ComponentModels, Class: "zscore"},
+			want:  []string{"models//min_dev_from_expected", "models/model.zscore.ZscoreModel/class", "models/model.zscore.ZscoreModel/z_threshold"},
+		},
+		{
+			name:  "common args of other components are not matched",
+			query: Query{Class: "periodic"},
+			want:  []string{"scheduler/scheduler.periodic.PeriodicScheduler/fit_window", "scheduler/scheduler.periodic.PeriodicScheduler/tz"},
+		},
+		{
+			name:  "full class name and exact name",
+			query: Query{Class: "model.online.OnlineMADModel", Name: "Compression"},
+			want:  []string{"models/model.online.OnlineMADModel/compression"},
+		},
+		{
+			name:  "short class name",
+			query: Query{Class: "PeriodicScheduler", Name: "tz"},
+			want:  []string{"scheduler/scheduler.periodic.PeriodicScheduler/tz"},
+		},
+		{
+			name:  "name in all components",
+			query: Query{Name: "class"},
+			want:  []string{"models/model.zscore.ZscoreModel/class", "models/model.online.OnlineMADModel/class"},
+		},
+		{
+			name:  "keyword",
+			query: Query{Keyword: "timezone"},
+			want:  []string{"scheduler/scheduler.periodic.PeriodicScheduler/tz"},
+		},
+		{
+			name:  "unknown class",
+			query: Query{Component: ComponentModels, Class: "prophet", Name: "z_threshold"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.Lookup(tt.query)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Lookup() returned %d params, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, p := range got {
+				if id := p.Component + "/" + p.Class + "/" + p.Name; id != tt.want[i] {
+					t.Errorf("param %d = %s, want %s", i, id, tt.want[i])
+				}
+			}
+		})
+	}
+
+	class, aliases, ok := c.ResolveClass(ComponentModels, "mad_online")
+	if !ok || class != "model.online.OnlineMADModel" || len(aliases) != 1 {
+		t.Errorf("ResolveClass() = %s, %v, %v", class, aliases, ok)
+	}
+	if classes := c.Classes(ComponentModels); len(classes) != 2 {
+		t.Errorf("Classes() = %v", classes)
+	}
+}
+
+func TestMergeSchema(t *testing.T) {
+	c := NewCatalog(map[string]string{ComponentModels: modelsDoc})
+	docs := c.Lookup(Query{Component: ComponentModels, Class: "zscore"})
+	schema := map[string]any{
+		"properties": map[string]any{
+			"z_threshold":           map[string]any{"type": "number", "default": 3.0},
+			"min_dev_from_expected": map[string]any{"anyOf": []any{map[string]any{"type": "number"}, map[string]any{"type": "array", "items": map[string]any{"type": "number"}}}, "default": 0},
+			"provide_series":        map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Series to write"},
+		},
+		"required": []any{"provide_series"},
+	}
+
+	merged := MergeSchema(docs, "model.zscore.ZscoreModel", []string{"zscore"}, schema)
+	byName := map[string]Param{}
+	for _, p := range merged {
+		byName[p.Name] = p
+	}
+	if p := byName["z_threshold"]; p.Source != SourceBoth || p.Default != "3" || p.DocsDefault != "2.5" || p.Type != "number" {
+		t.Errorf("z_threshold = %+v", p)
+	}
+	if p := byName["min_dev_from_expected"]; p.Default != "0" || p.DocsDefault != "" || p.Type != "number | array[number]" {
+		t.Errorf("min_dev_from_expected = %+v", p)
+	}
+	if p := byName["provide_series"]; p.Source != SourceSchema || p.Optional || p.Class != "model.zscore.ZscoreModel" || p.Description != "Series to write" {
+		t.Errorf("provide_series = %+v", p)
+	}
+	if p := byName["class"]; p.Source != SourceDocs {
+		t.Errorf("class must keep docs source, got %+v", p)
+	}
+	if len(docs) != 3 || docs[1].Source != SourceDocs {
+		t.Error("MergeSchema() must not modify the input")
+	}
+}
@@ -0,0 +1,490 @@
+// Package params extracts a catalog of vmanomaly config parameters from component documentation
+// (components/models.md, reader.md, writer.md, scheduler.md and settings.md).
+//
+// Parameters are documented in three ways, which are all supported:
+//   - bullet lists like "* `z_threshold` (float, optional) - ... Defaults to `2.5`." used for models and per-query reader args;
+//   - HTML tables with Parameter, Type, Example and Description columns used for reader, writer and schedulers;
+//   - prose sections like "The `n_workers` argument allows ..." used for common model args and settings.
+//
+// Docs-derived defaults and types are best-effort, MergeSchema overrides them with the live model schema.
+package params
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Components of vmanomaly config with documented parameters, they match the names of docs files
+const (
+	ComponentModels    = "models"
+	ComponentReader    = "reader"
+	ComponentWriter    = "writer"
+	ComponentScheduler = "scheduler"
+	ComponentSettings  = "settings"
+)
+
+// Components are all components with documented parameters
+var Components = []string{ComponentModels, ComponentReader, ComponentWriter, ComponentScheduler, ComponentSettings}
+
+// Sources of parameter info
+const (
+	SourceDocs   = "docs"
+	SourceSchema = "schema"
+	SourceBoth   = "docs+schema"
+)
+
+// Param is a config parameter of a vmanomaly component
+type Param struct {
+	Component string `json:"component"`
+	// Class is the full class name (e.g. model.zscore.ZscoreModel) the parameter belongs to,
+	// it is empty for parameters common to all classes of the component
+	Class   string   `json:"class,omitempty"`
+	Aliases []string `json:"class_aliases,omitempty"`
+	// Section is the heading path of the docs section describing the parameter
+	Section     string `json:"section,omitempty"`
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Optional    bool   `json:"optional,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Example     string `json:"example,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Since is the vmanomaly version the parameter is available since
+	Since  string `json:"since,omitempty"`
+	Source string `json:"source"`
+	// DocsDefault is the default from docs if it differs from the default of the live schema
+	DocsDefault string `json:"docs_default,omitempty"`
+}
+
+var (
+	shortcodeRe  = regexp.MustCompile(`\{\{%.*?%\}\}`)
+	availableRe  = regexp.MustCompile(`\{\{%\s*available_from\s+["“]v?([\d.]+)["”][^%]*%\}\}`)
+	introducedRe = regexp.MustCompile(`Introduced in \[v?(\d+\.\d+\.\d+)\]`)
+	linkRe       = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	tagRe        = regexp.MustCompile(`<[^>]+>`)
+	spaceRe      = regexp.MustCompile(`\s+`)
+	classRe      = regexp.MustCompile(`["\x60]((?:model|reader|writer|scheduler|monitoring)\.\w+\.[A-Z]\w*)["\x60]`)
+	classAliasRe = regexp.MustCompile("(?:\\bor|must be)\\s+[\"`]+([a-z_]+)[\"`]+")
+	// configAliasRe matches config examples like `class: "periodic" # or class: "scheduler.periodic.PeriodicScheduler"`
+	configAliasRe = regexp.MustCompile(`class:\s*"([a-z_]+)".*class:\s*"([\w.]+)"`)
+	bulletRe      = regexp.MustCompile("^\\s{0,3}[*-]\\s+`(\\w+)`\\s*((?:\\{\\{%[^%]*%\\}\\})?)\\s*\\(([^)]*)\\)\\s*(?:,\\s*(optional))?\\s*[-:–]?\\s*(.*)$")
+	proseNameRe   = regexp.MustCompile("`(\\w+)`\\s*((?:\\{\\{%[^%]*%\\}\\})?)\\s*(?:arg|argument|parameter)\\b|(?:arg|argument|parameter)\\s+`(\\w+)`\\s*((?:\\{\\{%[^%]*%\\}\\})?)")
+	proseTypeRe   = regexp.MustCompile("^\\s*\\(`([^`]+)`\\)")
+	tableRe       = regexp.MustCompile(`(?s)<table[^>]*>(.*?)</table>`)
+	headerCellRe  = regexp.MustCompile(`(?s)<th[^>]*>(.*?)</th>`)
+	rowRe         = regexp.MustCompile(`(?s)<tr[^>]*>(.*?)</tr>`)
+	dataCellRe    = regexp.MustCompile(`(?s)<td([^>]*)>(.*?)</td>`)
+	rowspanRe     = regexp.MustCompile(`rowspan=["']?(\d+)`)
+	codeNameRe    = regexp.MustCompile("`(\\w+)`")
+	defaultRegexs = []*regexp.Regexp{
+		regexp.MustCompile("(?i)by default,?[^.]*?\\bis set to\\s+`([^`]+)`"),
+		regexp.MustCompile("(?i)set to\\s+`([^`]+)`\\s+by default"),
+		regexp.MustCompile("(?i)\\(default:\\s*`?([^`)]+)`?\\)"),
+		regexp.MustCompile("(?i)(?:^|[.,;:)]\\s*|\\bit\\s+|\\bis\\s+)defaults? to\\s+`([^`]+)`"),
+		regexp.MustCompile("(?i)default(?: value)? is(?: set to)?\\s+`?([^\\s`,;]+?)`?(?:[\\s.,;]|$)"),
+		regexp.MustCompile("(?i)(?:^|[.,;:)]\\s*|\\bit\\s+|\\bis\\s+)defaults? to\\s+([^\\s,;]+?)\\.?(?:[\\s,;]|$)"),
+		regexp.MustCompile("(?i)by default,?\\s+(?:(`[^`]+`)(?:[.,;)]|$)|(\\([^)]*\\)|'[^']+'|not set|true|false|-?\\d+(?:\\.\\d+)?)\\.?(?:[\\s,;)]|$))"),
+		regexp.MustCompile(`(?i)default value\s*[-:]\s*"?([^"\s.,;]+)"?`),
+		regexp.MustCompile("(?i)the default [\\w ]*?\\((`[^`]+`)\\)"),
+	}
+)
+
+// section is a part of a markdown document under a heading
+type section struct {
+	level  int
+	path   []string // Heading texts from the top level
+	parent int      // Index of the parent section, -1 for top level sections
+	body   []string
+	class  string // Class declared in the section or named by its heading
+	alias  string
+}
+
+// Parse extracts parameters of a component from its markdown documentation
+func Parse(component, markdown string) []Param {
+	sections := splitSections(markdown)
+	classes := fileClasses(markdown)
+
+	var params []Param
+	owners := []int{} // Section index of every param
+	for i, s := range sections {
+		text := strings.Join(s.body, "\n")
+		found := parseBullets(s.body)
+		found = append(found, parseTables(text)...)
+		if len(found) == 0 && isProseSection(component, sections, i) {
+			if p, ok := parseProse(s, text); ok {
+				found = append(found, p)
+			}
+		}
+		for _, p := range found {
+			if p.Name == "class" {
+				if m := classRe.FindStringSubmatch(p.Description + " " + p.Example); m != nil {
+					sections[i].class = m[1]
+					if a := classAliasRe.FindStringSubmatch(p.Description + " " + p.Example); a != nil {
+						sections[i].alias = a[1]
+					}
+				}
+			}
+			p.Component = component
+			p.Section = strings.Join(s.path, " > ")
+			p.Source = SourceDocs
+			params = append(params, p)
+			owners = append(owners, i)
+		}
+	}
+	for i := range sections {
+		if sections[i].class == "" && len(sections[i].path) > 0 {
+			sections[i].class = classByHeading(sections[i].path[len(sections[i].path)-1], classes)
+		}
+	}
+
+	aliases := map[string]string{}
+	for _, m := range configAliasRe.FindAllStringSubmatch(markdown, -1) {
+		aliases[m[2]] = m[1]
+	}
+	for _, s := range sections {
+		if s.alias != "" {
+			aliases[s.class] = s.alias
+		}
+	}
+
+	for i := range params {
+		for s := owners[i]; s >= 0; s = sections[s].parent {
+			if sections[s].class == "" {
+				continue
+			}
+			params[i].Class = sections[s].class
+			if alias, ok := aliases[sections[s].class]; ok {
+				params[i].Aliases = []string{alias}
+			}
+			break
+		}
+	}
+	return params
+}
+
+// splitSections splits markdown into sections by headings, skipping front matter and fenced code blocks
+func splitSections(markdown string) []section {
+	sections := []section{{parent: -1}}
+	var stack []int // Indexes of open sections
+	inCode, inFrontMatter := false, strings.HasPrefix(markdown, "---")
+	for i, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if inFrontMatter {
+			if i > 0 && trimmed == "---" {
+				inFrontMatter = false
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+		}
+		if inCode || !strings.HasPrefix(line, "#") {
+			cur := &sections[len(sections)-1]
+			cur.body = append(cur.body, line)
+			continue
+		}
+		level := len(line) - len(strings.TrimLeft(line, "#"))
+		for len(stack) > 0 && sections[stack[len(stack)-1]].level >= level {
+			stack = stack[:len(stack)-1]
+		}
+		s := section{level: level, parent: -1}
+		if len(stack) > 0 {
+			s.parent = stack[len(stack)-1]
+			s.path = slices.Clone(sections[s.parent].path)
+		}
+		s.path = append(s.path, cleanText(strings.Trim(trimmed, "# ")))
+		sections = append(sections, s)
+		stack = append(stack, len(sections)-1)
+	}
+	return sections
+}
+
+// fileClasses returns class names mentioned in markdown
+func fileClasses(markdown string) []string {
+	var classes []string
+	for _, m := range classRe.FindAllStringSubmatch(markdown, -1) {
+		if !slices.Contains(classes, m[1]) {
+			classes = append(classes, m[1])
+		}
+	}
+	return classes
+}
+
+// classByHeading returns the class named by a heading, e.g. "Periodic scheduler" names scheduler.periodic.PeriodicScheduler
+// and "MAD (Median Absolute Deviation)" names model.mad.MADModel. The longest matching class name wins.
+func classByHeading(heading string, classes []string) string {
+	normalized := normalizeName(heading)
+	best, bestLen := "", 0
+	for _, class := range classes {
+		name := normalizeName(class[strings.LastIndex(class, ".")+1:])
+		if strings.HasPrefix(normalized, name) && len(name) > bestLen {
+			best, bestLen = class, len(name)
+		}
+		for _, suffix := range []string{"model", "scheduler", "reader", "writer"} {
+			if short := strings.TrimSuffix(name, suffix); short != name && short != "" && strings.HasPrefix(normalized, short) && len(short) > bestLen {
+				best, bestLen = class, len(short)
+			}
+		}
+	}
+	return best
+}
+
+// normalizeName lowercases s and removes everything except letters and digits
+func normalizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, strings.ToLower(s))
+}
+
+// isProseSection returns whether parameters of the section are described in prose:
+// common model args and settings
+func isProseSection(component string, sections []section, i int) bool {
+	s := sections[i]
+	switch component {
+	case ComponentModels:
+		return s.parent >= 0 && strings.EqualFold(last(sections[s.parent].path), "Common args")
+	case ComponentSettings:
+		return s.level == 2
+	}
+	return false
+}
+
+func last(path []string) string {
+	if len(path) == 0 {
+		return ""
+	}
+	return path[len(path)-1]
+}
+
+// parseBullets parses parameters listed as "* `name` (type, optional) - description"
+func parseBullets(lines []string) []Param {
+	var params []Param
+	for _, line := range lines {
+		m := bulletRe.FindStringSubmatch(line)
+		if m == nil || strings.HasSuffix(m[1], "_") {
+			// Names ending with "_" are model properties
+			continue
+		}
+		typ, optional := parseType(m[3])
+		p := Param{
+			Name:        m[1],
+			Type:        typ,
+			Optional:    optional || m[4] != "",
+			Description: cleanText(m[5]),
+			Since:       since(m[2] + " " + m[5]),
+			Default:     findDefault(m[5]),
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+// parseTables parses parameters of HTML tables with a Parameter column
+func parseTables(text string) []Param {
+	var params []Param
+	for _, table := range tableRe.FindAllStringSubmatch(text, -1) {
+		var columns []string
+		for _, th := range headerCellRe.FindAllStringSubmatch(table[1], -1) {
+			columns = append(columns, strings.ToLower(cleanText(th[1])))
+		}
+		if !slices.Contains(columns, "parameter") {
+			continue
+		}
+		type span struct {
+			value string
+			rows  int
+		}
+		spans := map[int]*span{}
+		for _, row := range rowRe.FindAllStringSubmatch(table[1], -1) {
+			rawCells := dataCellRe.FindAllStringSubmatch(row[1], -1)
+			if len(rawCells) == 0 {
+				continue
+			}
+			// Insert values of cells spanning from previous rows
+			cells := make([]string, 0, len(columns))
+			next, continued := 0, false
+			for col := range columns {
+				if sp := spans[col]; sp != nil && sp.rows > 0 {
+					cells = append(cells, sp.value)
+					sp.rows--
+					continued = continued || columns[col] == "parameter"
+					continue
+				}
+				if next >= len(rawCells) {
+					cells = append(cells, "")
+					continue
+				}
+				cell := rawCells[next]
+				next++
+				cells = append(cells, cell[2])
+				if m := rowspanRe.FindStringSubmatch(cell[1]); m != nil {
+					n, _ := strconv.Atoi(m[1])
+					spans[col] = &span{value: cell[2], rows: n - 1}
+				}
+			}
+
+			p := Param{}
+			for col, name := range columns {
+				raw := cells[col]
+				switch name {
+				case "parameter":
+					if m := codeNameRe.FindStringSubmatch(raw); m != nil {
+						p.Name = m[1]
+					}
+					p.Since = since(raw)
+				case "type":
+					p.Type, p.Optional = parseType(cleanText(raw))
+				case "example":
+					p.Example = cleanText(raw)
+				case "description":
+					p.Description = cleanText(raw)
+					p.Default = findDefault(raw)
+					p.Since = cmp.Or(p.Since, since(raw))
+				}
+			}
+			switch {
+			case p.Name == "":
+			case continued && len(params) > 0:
+				// Rows of a parameter cell spanning several rows describe alternative formats of the same parameter
+				prev := &params[len(params)-1]
+				if p.Type != "" && !strings.Contains(prev.Type, p.Type) {
+					prev.Type += " | " + p.Type
+				}
+				if p.Example != "" && !strings.Contains(prev.Example, p.Example) {
+					prev.Example += "; " + p.Example
+				}
+			default:
+				params = append(params, p)
+			}
+		}
+	}
+	return params
+}
+
+// parseProse extracts a parameter described by a prose section, e.g. "The `n_workers` argument allows ..."
+// or a section named after the parameter ("Logger Levels" for logger_levels).
+func parseProse(s section, text string) (Param, bool) {
+	prose := withoutCode(text)
+	var p Param
+	if m := proseNameRe.FindStringSubmatchIndex(prose); m != nil {
+		// The name is either before or after the "argument" word
+		nameAt, codeAt := 2, 4
+		if m[2] < 0 {
+			nameAt, codeAt = 6, 8
+		}
+		name, code, end := prose[m[nameAt]:m[nameAt+1]], "", m[1]
+		if m[codeAt] >= 0 {
+			code = prose[m[codeAt]:m[codeAt+1]]
+		}
+		p.Name = name
+		p.Since = since(code)
+		if t := proseTypeRe.FindStringSubmatch(prose[end:]); t != nil {
+			p.Type = t[1]
+		}
+		p.Description = cleanText(paragraphAt(prose, m[0]))
+		if p.Since == "" {
+			p.Since = since(paragraphAt(prose, m[0]))
+		}
+	} else {
+		name := strings.ReplaceAll(strings.ToLower(last(s.path)), " ", "_")
+		if !strings.Contains(text, name) {
+			return p, false
+		}
+		p.Name = name
+		para := firstParagraph(prose)
+		p.Description = cleanText(para)
+		p.Since = since(para)
+	}
+	p.Default = findDefault(prose)
+	return p, p.Name != ""
+}
+
+// withoutCode removes fenced code blocks from markdown
+func withoutCode(text string) string {
+	var b strings.Builder
+	inCode := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if !inCode {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// paragraphAt returns the paragraph containing the byte offset
+func paragraphAt(text string, offset int) string {
+	start := strings.LastIndex(text[:offset], "\n\n")
+	end := strings.Index(text[offset:], "\n\n")
+	if end < 0 {
+		end = len(text) - offset
+	}
+	return text[start+1 : offset+end]
+}
+
+// firstParagraph returns the first paragraph of text which is not a quote
+func firstParagraph(text string) string {
+	for _, para := range strings.Split(text, "\n\n") {
+		if para = strings.TrimSpace(para); para != "" && !strings.HasPrefix(para, ">") {
+			return para
+		}
+	}
+	return ""
+}
+
+// parseType splits a documented type like "float, optional" or "str, Optional" into the type and optionality
+func parseType(s string) (string, bool) {
+	optional := false
+	var parts []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if strings.EqualFold(part, "optional") {
+			optional = true
+			continue
+		}
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", "), optional
+}
+
+// since returns the version from the first available_from shortcode or "Introduced in" note of text
+func since(text string) string {
+	if m := availableRe.FindStringSubmatch(text); m != nil {
+		return "v" + m[1]
+	}
+	if m := introducedRe.FindStringSubmatch(text); m != nil {
+		return "v" + m[1]
+	}
+	return ""
+}
+
+// findDefault returns the default value mentioned in a description, e.g. "Defaults to `2.5`" or "By default 100"
+func findDefault(text string) string {
+	text = cleanText(text)
+	for _, re := range defaultRegexs {
+		if m := re.FindStringSubmatch(text); m != nil {
+			return strings.Trim(cmp.Or(m[1:]...), "`'\"*")
+		}
+	}
+	return ""
+}
+
+// cleanText removes HTML tags, shortcodes and link targets from markdown and collapses whitespace
+func cleanText(s string) string {
+	s = tagRe.ReplaceAllString(s, " ")
+	s = shortcodeRe.ReplaceAllString(s, "")
+	s = linkRe.ReplaceAllString(s, "$1")
+	s = strings.ReplaceAll(s, "**", "")
+	return strings.TrimSpace(spaceRe.ReplaceAllString(s, " "))
+}
@@ -0,0 +1,172 @@
+package params
+
+import (
+	"testing"
+)
+
+const modelsDoc = `---
+title: Models
+---
+# Models
+
+## Common args
+
+### Minimal deviation from expected
+
+` + "`min_dev_from_expected`{{% available_from \"v1.13.0\" anomaly %}} argument reduces false positives. By default, if this parameter is not set, it is set to `0`." + `
+
+` + "```yaml\nmin_dev_from_expected: 5.0  # by default resolved to `10`\n```" + `
+
+## Built-in Models
+
+### Z-score
+
+* ` + "`class` (string) - model class name `\"model.zscore.ZscoreModel\"` (or `zscore` with class alias support{{% available_from \"v1.13.0\" anomaly %}})" + `
+* ` + "`z_threshold` (float, optional) - standard score for calculation boundaries. Defaults to `2.5`." + `
+* ` + "`n_samples_seen_` (int) - model property" + `
+
+### Online MAD
+
+* ` + "`class` (string) - model class name `\"model.online.OnlineMADModel\"` (or `mad_online`)" + `
+* ` + "`compression` (int, optional) - the compression parameter. By default 100." + `
+`
+
+const schedulerDoc = `# Scheduler
+
+` + "```yaml\nscheduler:\n  class: \"periodic\" # or class: \"scheduler.periodic.PeriodicScheduler\" until v1.13.0\n```" + `
+
+## Periodic scheduler
+
+### Parameters
+
+<table class="params">
+    <thead>
+        <tr>
+            <th>Parameter</th>
+            <th>Type</th>
+            <th>Example</th>
+            <th>Description</th>
+        </tr>
+    </thead>
+    <tbody>
+        <tr>
+            <td rowspan="2">
+
+<span style="white-space: nowrap;">` + "`fit_window`" + `</span>
+            </td>
+            <td>str</td>
+            <td>` + "`\"14d\"`" + `</td>
+            <td>What time range to use for training the models.</td>
+        </tr>
+        <tr>
+            <td>int</td>
+            <td>` + "`1209600`" + `</td>
+            <td>What time range to use for training the models.</td>
+        </tr>
+        <tr>
+            <td>` + "`tz`{{% available_from \"v1.18.5\" anomaly %}}" + `</td>
+            <td>str, <span>Optional</span></td>
+            <td>` + "`America/New_York`" + `</td>
+            <td>Defines the local timezone. Defaults to ` + "`UTC`" + ` if no timezone is provided.</td>
+        </tr>
+    </tbody>
+</table>
+`
+
+const settingsDoc = `# Settings
+
+## Parallelization
+
+The ` + "`n_workers`" + ` argument allows you to specify the number of workers. It's set to ` + "`1`" + ` by default.
+
+## Logger Levels
+
+{{% available_from "v1.25.3" anomaly %}} Service supports per-component logger levels.
+
+` + "```yaml\nsettings:\n  logger_levels:\n    reader: debug\n```" + `
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		component string
+		doc       string
+		want      []Param
+	}{
+		{
+			name:      "bullets and prose",
+			component: ComponentModels,
+			doc:       modelsDoc,
+			want: []Param{
+				{Name: "min_dev_from_expected", Section: "Models > Common args > Minimal deviation from expected", Default: "0", Since: "v1.13.0"},
+				{Name: "class", Class: "model.zscore.ZscoreModel", Aliases: []string{"zscore"}, Type: "string", Since: "v1.13.0"},
+				{Name: "z_threshold", Class: "model.zscore.ZscoreModel", Aliases: []string{"zscore"}, Type: "float", Optional: true, Default: "2.5"},
+				{Name: "class", Class: "model.online.OnlineMADModel", Aliases: []string{"mad_online"}, Type: "string"},
+				{Name: "compression", Class: "model.online.OnlineMADModel", Aliases: []string{"mad_online"}, Type: "int", Optional: true, Default: "100"},
+			},
+		},
+		{
+			name:      "tables",
+			component: ComponentScheduler,
+			doc:       schedulerDoc,
+			want: []Param{
+				{Name: "fit_window", Class: "scheduler.periodic.PeriodicScheduler", Aliases: []string{"periodic"}, Type: "str | int", Example: "`\"14d\"`; `1209600`"},
+				{Name: "tz", Class: "scheduler.periodic.PeriodicScheduler", Aliases: []string{"periodic"}, Type: "str", Optional: true, Default: "UTC", Since: "v1.18.5", Example: "`America/New_York`"},
+			},
+		},
+		{
+			name:      "settings",
+			component: ComponentSettings,
+			doc:       settingsDoc,
+			want: []Param{
+				{Name: "n_workers", Default: "1"},
+				{Name: "logger_levels", Since: "v1.25.3"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.component, tt.doc)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Parse() returned %d params, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				if g.Component != tt.component || g.Source != SourceDocs {
+					t.Errorf("param %s: component %q, source %q", g.Name, g.Component, g.Source)
+				}
+				if g.Name != w.Name || g.Class != w.Class || g.Type != w.Type || g.Optional != w.Optional ||
+					g.Default != w.Default || g.Since != w.Since || (w.Example != "" && g.Example != w.Example) ||
+					(w.Section != "" && g.Section != w.Section) || len(g.Aliases) != len(w.Aliases) ||
+					(len(w.Aliases) > 0 && g.Aliases[0] != w.Aliases[0]) {
+					t.Errorf("param %d = %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestFindDefault(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Standard score. Defaults to `2.5`.", "2.5"},
+		{"Ratio of folds. Defaults to 3 (3/4 of the data for training).", "3"},
+		{"The quantiles to estimate. By default (0.01, 0.5, 0.99).", "(0.01, 0.5, 0.99)"},
+		{"Start date. By default '1970-01-01'.", "1970-01-01"},
+		{"Contamination. Default value - \"auto\"", "auto"},
+		{"Number of jobs (default: `1`)", "1"},
+		{"The default value is set to 1ms, which should help.", "1ms"},
+		{"By default, `restore_state` is set to `false`, meaning fresh start.", "false"},
+		{"By default, the timezone defaults to `UTC`.", ""},
+		{"By default, `y` values outside the range trigger an anomaly.", ""},
+		{"Overrides the default anomaly score (`1.01`) of such points.", "1.01"},
+	}
+	for _, tt := range tests {
+		if got := findDefault(tt.text); got != tt.want {
+			t.Errorf("findDefault(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Package presets contains vmanomaly preset definitions, which allow to run vmanomaly with
+// a minimal config (preset name and datasources) and come with premade vmalert rules.
+//
+// See https://docs.victoriametrics.com/anomaly-detection/presets/ for details.
+package presets
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmalert"
+)
+
+// Parameter names accepted by presets
+const (
+	ParamDatasourceURL    = "datasource_url"
+	ParamTenantID         = "tenant_id"
+	ParamWriterURL        = "writer_url"
+	ParamWriterTenantID   = "writer_tenant_id"
+	ParamAnomalyThreshold = "anomaly_threshold"
+)
+
+// Param describes a preset parameter
+type Param struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required,omitempty"`
+	Default     string `json:"default,omitempty"`
+}
+
+// Indicator is a query monitored by a preset, it becomes the `for` label of produced anomaly scores
+type Indicator struct {
+	Name        string   `json:"name"`
+	Metrics     []string `json:"metrics"`
+	Description string   `json:"description"`
+	By          []string `json:"by,omitempty"` // Labels identifying a series besides instance
+}
+
+// Preset is a vmanomaly preset
+type Preset struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	DocsURL     string      `json:"docs_url"`
+	AssetsURL   string      `json:"assets_url,omitempty"`
+	Params      []Param     `json:"params,omitempty"`
+	Indicators  []Indicator `json:"indicators,omitempty"`
+}
+
+// Rendered is a preset rendered with user parameters
+type Rendered struct {
+	Config map[string]any    `json:"config"`
+	Rules  *vmalert.RuleFile `json:"rules,omitempty"`
+}
+
+var presets = []Preset{
+	{
+		Name:        "ui",
+		Description: "Runs vmanomaly in UI mode to explore data and backtest anomaly detection configurations interactively",
+		DocsURL:     "https://docs.victoriametrics.com/anomaly-detection/presets/#ui",
+	},
+	{
+		Name:        "node-exporter",
+		Description: "Detects anomalies in key node_exporter metrics (CPU, page faults, context switches, network errors and throughput, disk latency) with predefined queries, models and schedulers",
+		DocsURL:     "https://docs.victoriametrics.com/anomaly-detection/presets/#node-exporter",
+		AssetsURL:   "https://github.com/VictoriaMetrics/VictoriaMetrics/tree/master/deployment/docker/vmanomaly/vmanomaly-node-exporter-preset/",
+		Params: []Param{
+			{Name: ParamDatasourceURL, Description: "VictoriaMetrics URL to read node_exporter metrics from", Required: true},
+			{Name: ParamTenantID, Description: "Tenant ID of the source for cluster version of VictoriaMetrics, e.g. 0:0"},
+			{Name: ParamWriterURL, Description: "VictoriaMetrics URL to write anomaly scores to. Defaults to datasource_url"},
+			{Name: ParamWriterTenantID, Description: "Tenant ID of the destination. Defaults to tenant_id"},
+			{Name: ParamAnomalyThreshold, Description: "Anomaly score threshold of generated alerts", Default: "1.0"},
+		},
+		Indicators: []Indicator{
+			{Name: "page_faults", Metrics: []string{"node_vmstat_pgmajfault"}, Description: "Major page faults requiring data to be loaded from disk"},
+			{Name: "context_switch", Metrics: []string{"node_context_switches_total"}, Description: "Context switches across all CPUs"},
+			{Name: "cpu_seconds_total", Metrics: []string{"node_cpu_seconds_total"}, Description: "CPU time by processing mode", By: []string{"mode"}},
+			{Name: "host_network_receive_errors", Metrics: []string{"node_network_receive_errs_total", "node_network_receive_packets_total"}, Description: "Errors while receiving packets on network interfaces"},
+			{Name: "host_network_transmit_errors", Metrics: []string{"node_network_transmit_errs_total", "node_network_transmit_packets_total"}, Description: "Errors while transmitting packets on network interfaces"},
+			{Name: "receive_bytes", Metrics: []string{"node_network_receive_bytes_total"}, Description: "Bytes received on network interfaces"},
+			{Name: "transmit_bytes", Metrics: []string{"node_network_transmit_bytes_total"}, Description: "Bytes transmitted on network interfaces"},
+			{Name: "read_latency", Metrics: []string{"node_disk_read_time_seconds_total", "node_disk_reads_completed_total"}, Description: "Disk read latency"},
+			{Name: "write_latency", Metrics: []string{"node_disk_write_time_seconds_total", "node_disk_writes_completed_total"}, Description: "Disk write latency"},
+		},
+	},
+}
+
+// List returns all available presets sorted by name
+func List() []Preset {
+	result := slices.Clone(presets)
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Get returns a preset by its name
+func Get(name string) (Preset, bool) {
+	for _, p := range presets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// Render builds vmanomaly config and vmalert rules of the preset with given parameters,
+// falling back to defaults for missing ones
+func (p Preset) Render(params map[string]string) (*Rendered, error) {
+	values := make(map[string]string, len(p.Params))
+	for name, value := range params {
+		if !slices.ContainsFunc(p.Params, func(param Param) bool { return param.Name == name }) {
+			return nil, fmt.Errorf("unknown parameter %q for preset %q", name, p.Name)
+		}
+		values[name] = strings.TrimSpace(value)
+	}
+	for _, param := range p.Params {
+		if values[param.Name] == "" {
+			values[param.Name] = param.Default
+		}
+		if param.Required && values[param.Name] == "" {
+			return nil, fmt.Errorf("parameter %q is required for preset %q", param.Name, p.Name)
+		}
+	}
+
+	config := map[string]any{"preset": p.Name}
+	if p.Name != "node-exporter" {
+		return &Rendered{Config: config}, nil
+	}
+
+	if values[ParamWriterURL] == "" {
+		values[ParamWriterURL] = values[ParamDatasourceURL]
+	}
+	if values[ParamWriterTenantID] == "" {
+		values[ParamWriterTenantID] = values[ParamTenantID]
+	}
+	config["reader"] = datasource(values[ParamDatasourceURL], values[ParamTenantID])
+	config["writer"] = datasource(values[ParamWriterURL], values[ParamWriterTenantID])
+
+	threshold, err := strconv.ParseFloat(values[ParamAnomalyThreshold], 64)
+	if err != nil || threshold <= 0 {
+		return nil, fmt.Errorf("invalid %s %q: must be a positive number", ParamAnomalyThreshold, values[ParamAnomalyThreshold])
+	}
+	return &Rendered{Config: config, Rules: p.rules(threshold)}, nil
+}
+
+func datasource(url, tenantID string) map[string]any {
+	section := map[string]any{"datasource_url": url}
+	if tenantID != "" {
+		section["tenant_id"] = tenantID
+	}
+	return section
+}
+
+// rules returns vmalert rules firing when all models and schedulers of the preset agree
+// that a series of an indicator is anomalous
+func (p Preset) rules(threshold float64) *vmalert.RuleFile {
+	group := vmalert.Group{Name: fmt.Sprintf("vmanomaly-%s-preset", p.Name)}
+	for _, ind := range p.Indicators {
+		by := append([]string{"for", "instance"}, ind.By...)
+		group.Rules = append(group.Rules, vmalert.Rule{
+			Alert: vmalert.CamelCase(ind.Name) + "Anomaly",
+			Expr: fmt.Sprintf(`min by (%s) (anomaly_score{preset=%q, for=%q}) > %s`,
+				strings.Join(by, ", "), p.Name, ind.Name, strconv.FormatFloat(threshold, 'f', -1, 64)),
+			// node-exporter preset infers every minute, require a few consecutive anomalous points
+			For:    "5m",
+			Labels: map[string]string{"severity": "warning", "preset": p.Name},
+			Annotations: map[string]string{
+				"summary":     fmt.Sprintf("Anomaly in %s on {{ $labels.instance }}", ind.Name),
+				"description": fmt.Sprintf("%s (%s) is anomalous according to all models of the %s preset. Anomaly score: {{ $value }}", ind.Description, strings.Join(ind.Metrics, ", "), p.Name),
+			},
+		})
+	}
+	return &vmalert.RuleFile{Groups: []vmalert.Group{group}}
+}
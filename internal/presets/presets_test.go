@@ -0,0 +1,71 @@
+package presets
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmalert"
+)
+
+func TestRender(t *testing.T) {
+	p, ok := Get("node-exporter")
+	if !ok {
+		t.Fatal("node-exporter preset not found")
+	}
+
+	if _, err := p.Render(nil); err == nil {
+		t.Error("expected error for missing datasource_url")
+	}
+	if _, err := p.Render(map[string]string{ParamDatasourceURL: "http://vm:8428", "foo": "bar"}); err == nil {
+		t.Error("expected error for unknown parameter")
+	}
+	if _, err := p.Render(map[string]string{ParamDatasourceURL: "http://vm:8428", ParamAnomalyThreshold: "-1"}); err == nil {
+		t.Error("expected error for invalid threshold")
+	}
+
+	r, err := p.Render(map[string]string{ParamDatasourceURL: "http://vm:8428", ParamTenantID: "0:0", ParamAnomalyThreshold: "1.5"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if r.Config["preset"] != "node-exporter" {
+		t.Errorf("preset = %v", r.Config["preset"])
+	}
+	writer := r.Config["writer"].(map[string]any)
+	if writer["datasource_url"] != "http://vm:8428" || writer["tenant_id"] != "0:0" {
+		t.Errorf("writer should default to reader datasource, got %v", writer)
+	}
+
+	if len(r.Rules.Groups) != 1 || len(r.Rules.Groups[0].Rules) != len(p.Indicators) {
+		t.Fatalf("expected one rule per indicator, got %+v", r.Rules)
+	}
+	rule := r.Rules.Groups[0].Rules[0]
+	if rule.Alert != "PageFaultsAnomaly" || !strings.HasSuffix(rule.Expr, "> 1.5") {
+		t.Errorf("unexpected rule %+v", rule)
+	}
+
+	out, err := r.Rules.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	parsed, err := vmalert.Parse([]byte(out))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if parsed.Groups[0].Rules[2].Expr != r.Rules.Groups[0].Rules[2].Expr {
+		t.Errorf("rules do not survive YAML round trip:\n%s", out)
+	}
+}
+
+func TestRender_UI(t *testing.T) {
+	p, ok := Get("ui")
+	if !ok {
+		t.Fatal("ui preset not found")
+	}
+	r, err := p.Render(nil)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if len(r.Config) != 1 || r.Rules != nil {
+		t.Errorf("unexpected ui preset render: %+v", r)
+	}
+}
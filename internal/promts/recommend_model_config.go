@@ -167,11 +167,12 @@ You have access to powerful MCP tools that integrate with vmanomaly. **ALWAYS us
    - Essential for understanding configuration options
    - Use this before configuring ANY model
 
-3. **search_docs** (query: string, limit?: number)
+3. **search_docs** (query: string, limit?: number, max_bytes?: number)
    - Search vmanomaly documentation for specific guidance
    - Examples: "prophet seasonality", "online models", "fit_window configuration"
-   - Returns: Relevant documentation chunks with context
+   - Returns: Ranked section snippets with heading paths and section URIs
    - Use when you need specific implementation details
+   - Use **get_doc_section** (uri: string) to read a full section from the results
 
 **Phase 3: Configuration**
 4. **validate_model_config** (model_spec: object)
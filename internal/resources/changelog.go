@@ -0,0 +1,25 @@
+package resources
+
+import (
+	"fmt"
+	"io/fs"
+	"sync"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/changelog"
+)
+
+// ChangelogPath is the path of vmanomaly changelog in the embedded docs
+const ChangelogPath = "docs/anomaly-detection/CHANGELOG.md"
+
+// Changelog returns the parsed embedded vmanomaly changelog, it is parsed once
+var Changelog = sync.OnceValues(func() (*changelog.Changelog, error) {
+	data, err := fs.ReadFile(DocsDir, ChangelogPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading changelog: %w", err)
+	}
+	c, err := changelog.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing changelog: %w", err)
+	}
+	return c, nil
+})
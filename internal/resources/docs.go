@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"io/fs"
 	"log"
-	"path/filepath"
+	"maps"
+	"path"
+	"slices"
 	"strings"
+	"sync"
 
-	"github.com/blevesearch/bleve/v2"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
@@ -22,73 +24,77 @@ const (
 	maxMarkdownDescriptionSize = 4096
 )
 
+var contents = map[string]mcp.ResourceContents{}
+
 var (
-	searchIndex bleve.Index
-	resources   map[string]mcp.Resource
-	contents    map[string]mcp.ResourceContents
+	registeredMu   sync.Mutex
+	docsServer     *server.MCPServer
+	registeredURIs []string
 )
 
-// RegisterDocsResources initializes and registers all documentation resources
-func RegisterDocsResources(s *server.MCPServer) {
-	var err error
-	mapping := bleve.NewIndexMapping()
-	if searchIndex, err = bleve.NewMemOnly(mapping); err != nil {
-		log.Fatal(fmt.Errorf("error creating index: %w", err))
+// RegisterDocsResources registers documentation resources of the active doc set,
+// they are re-registered when extra docs are reloaded
+func RegisterDocsResources(s *server.MCPServer) error {
+	if err := ensureDocSets(); err != nil {
+		return err
+	}
+	set := activeDocSet()
+	if err := set.build(); err != nil {
+		return fmt.Errorf("error building docs index: %w", err)
 	}
+	registeredMu.Lock()
+	docsServer = s
+	registeredMu.Unlock()
+	n := syncRegisteredResources(set)
+	log.Printf("Registered %d documentation resources of %s docs\n", n, set.Version)
+	return nil
+}
 
-	docFiles, err := ListDocFiles()
-	if err != nil {
-		log.Fatal(fmt.Errorf("error listing docs files: %w", err))
-	}
-	resources = make(map[string]mcp.Resource, len(docFiles))
-	contents = make(map[string]mcp.ResourceContents, len(docFiles))
-	for _, docFile := range docFiles {
-		resourceURI := fmt.Sprintf("%s%s#%d", docsURIPrefix, docFile.Path, docFile.ChunkNum)
-		resource := mcp.NewResource(
-			resourceURI,
-			docFile.Name,
-			mcp.WithMIMEType("text/markdown"),
-			mcp.WithResourceDescription(docFile.Content[:min(len(docFile.Content), maxMarkdownDescriptionSize)]),
-		)
-		s.AddResource(resource, docResourcesHandler)
-		resources[resourceURI] = resource
-		contents[resourceURI] = mcp.TextResourceContents{
-			URI:      resourceURI,
-			MIMEType: "text/markdown",
-			Text:     docFile.Content,
-		}
-		if err = searchIndex.Index(resourceURI, docFile); err != nil {
-			log.Fatal(fmt.Errorf("error indexing file %s: %w", docFile.Path, err))
+// syncRegisteredResources replaces registered documentation resources with resources of the set
+// and returns their number. It does nothing if resources are not registered.
+func syncRegisteredResources(set *DocSet) int {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+	if docsServer == nil {
+		return 0
+	}
+	resources := set.Resources()
+	current := make(map[string]bool, len(resources))
+	serverResources := make([]server.ServerResource, 0, len(resources))
+	for _, r := range resources {
+		current[r.URI] = true
+		serverResources = append(serverResources, server.ServerResource{Resource: r, Handler: docResourcesHandler})
+	}
+	var removed []string
+	for _, uri := range registeredURIs {
+		if !current[uri] {
+			removed = append(removed, uri)
 		}
 	}
-	log.Printf("Registered %d documentation resources\n", len(docFiles))
+	if len(removed) > 0 {
+		docsServer.DeleteResources(removed...)
+	}
+	docsServer.AddResources(serverResources...)
+	registeredURIs = slices.Collect(maps.Keys(current))
+	return len(resources)
 }
 
-// SearchDocResources searches documentation using full-text search
-func SearchDocResources(query string, limit int) ([]mcp.Resource, error) {
-	searchQuery := bleve.NewMatchQuery(query)
-	searchQuery.Fuzziness = 1
-	searchRequest := bleve.NewSearchRequest(searchQuery)
-	searchRequest.Size = limit
-	searchResults, err := searchIndex.Search(searchRequest)
+// SearchDocResources searches documentation of the given version using full-text or hybrid search.
+// Version is resolved with SelectDocSet, the empty version means the active doc set.
+// It returns hits ordered by score and the version of the searched doc set.
+func SearchDocResources(ctx context.Context, query, version string, limit int) ([]DocHit, string, error) {
+	set, err := SelectDocSet(version)
 	if err != nil {
-		return nil, fmt.Errorf("error searching index: %w", err)
+		return nil, "", err
 	}
-	if searchResults.Total == 0 {
-		return nil, fmt.Errorf("no results found for query: %s", query)
+	if err := set.build(); err != nil {
+		return nil, "", err
 	}
-	results := make([]mcp.Resource, 0)
-	for _, hit := range searchResults.Hits {
-		if len(results) >= limit {
-			break
-		}
-		resource, ok := resources[hit.ID]
-		if !ok {
-			continue
-		}
-		results = append(results, resource)
+	results, err := set.search(ctx, query, limit)
+	if err != nil {
+		return nil, "", err
 	}
-	return results, nil
+	return results, set.Version.String(), nil
 }
 
 // docResourcesHandler handles ReadResource requests for documentation
@@ -100,13 +106,18 @@ func docResourcesHandler(_ context.Context, rrr mcp.ReadResourceRequest) ([]mcp.
 	return []mcp.ResourceContents{content}, nil
 }
 
-// GetDocResourceContent retrieves cached resource content by URI
+// GetDocResourceContent retrieves cached resource content by URI, images are read from doc sets on request
 func GetDocResourceContent(uri string) (mcp.ResourceContents, error) {
+	contentsMu.RLock()
 	content, ok := contents[uri]
-	if !ok {
-		return nil, fmt.Errorf("resource not found: %s", uri)
+	contentsMu.RUnlock()
+	if ok {
+		return content, nil
 	}
-	return content, nil
+	if content, ok, err := getDocImageContent(uri); ok {
+		return content, err
+	}
+	return nil, fmt.Errorf("resource not found: %s", uri)
 }
 
 // GetDocFileContent reads a file from the embedded filesystem
@@ -124,57 +135,46 @@ type DocFileInfo struct {
 	ChunkNum int    `json:"chunk_num"`
 	Content  string `json:"content"`
 	Name     string `json:"name"`
+	Headings string `json:"headings"` // Headings of the chunk, one per line
+	Source   string `json:"source"`   // SourceUpstream or the name of the extra docs source
 }
 
-// ListDocFiles scans the embedded filesystem and chunks all markdown files
+// ListDocFiles scans the embedded filesystem and chunks all markdown files of the latest docs
 func ListDocFiles() ([]DocFileInfo, error) {
+	return listDocFiles(DocsDir, "docs", "docs")
+}
+
+// listDocFiles chunks all markdown files under root of fsys, paths of chunks are relative to root
+// and prefixed with prefix. Versioned doc sets under root are skipped.
+func listDocFiles(fsys fs.FS, root, prefix string) ([]DocFileInfo, error) {
 	docs := make([]DocFileInfo, 0)
 
 	// Walk the docs directory
-	err := fs.WalkDir(DocsDir, "docs", func(path string, d fs.DirEntry, err error) error {
+	err := fs.WalkDir(fsys, root, func(filePath string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
+		if d.IsDir() && filePath == path.Join(root, versionsDir) {
+			return fs.SkipDir
+		}
 
 		// Only process markdown files
-		if d.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".md") {
+		if d.IsDir() || !strings.HasSuffix(strings.ToLower(filePath), ".md") {
 			return nil
 		}
 
-		content, err := GetDocFileContent(path)
+		data, err := fs.ReadFile(fsys, filePath)
 		if err != nil {
-			return fmt.Errorf("error reading file %s: %w", path, err)
+			return fmt.Errorf("error reading file %s: %w", filePath, err)
 		}
+		content := string(data)
+		docPath := path.Join(prefix, strings.TrimPrefix(strings.TrimPrefix(filePath, root), "/"))
 
-		chunks, err := splitMarkdown(content)
+		chunks, err := chunkDocFile(docPath, content, SourceUpstream)
 		if err != nil {
-			return fmt.Errorf("error splitting file %s: %w", path, err)
-		}
-
-		for chunkNum, chunkContent := range chunks {
-			name := ""
-			for line := range strings.Lines(chunkContent) {
-				if strings.TrimSpace(line) == "" {
-					continue
-				}
-				if !strings.HasPrefix(line, "#") {
-					break
-				}
-				title := strings.TrimSpace(strings.Trim(line, "# "))
-				name = fmt.Sprintf("%s / %s", name, title)
-			}
-			name = strings.Trim(name, "/ ")
-			if name == "" {
-				name = filepath.Base(path)
-			}
-
-			docs = append(docs, DocFileInfo{
-				Path:     path,
-				ChunkNum: chunkNum,
-				Content:  chunkContent,
-				Name:     name,
-			})
+			return fmt.Errorf("error splitting file %s: %w", filePath, err)
 		}
+		docs = append(docs, chunks...)
 
 		return nil
 	})
@@ -185,3 +185,44 @@ func ListDocFiles() ([]DocFileInfo, error) {
 
 	return docs, nil
 }
+
+// chunkDocFile splits markdown content of a doc file into chunks named after their headings
+func chunkDocFile(docPath, content, source string) ([]DocFileInfo, error) {
+	chunks, err := splitMarkdown(content)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]DocFileInfo, 0, len(chunks))
+	for chunkNum, chunkContent := range chunks {
+		name := ""
+		for line := range strings.Lines(chunkContent) {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if !strings.HasPrefix(line, "#") {
+				break
+			}
+			title := strings.TrimSpace(strings.Trim(line, "# "))
+			name = fmt.Sprintf("%s / %s", name, title)
+		}
+		name = strings.Trim(name, "/ ")
+		if name == "" {
+			name = path.Base(docPath)
+		}
+
+		docs = append(docs, DocFileInfo{
+			Path:     docPath,
+			ChunkNum: chunkNum,
+			Content:  chunkContent,
+			Name:     name,
+			Headings: chunkHeadings(chunkContent),
+			Source:   source,
+		})
+	}
+	return docs, nil
+}
+
+// docResourceURI returns the resource URI of a documentation chunk
+func docResourceURI(d DocFileInfo) string {
+	return fmt.Sprintf("%s%s#%d", docsURIPrefix, d.Path, d.ChunkNum)
+}
@@ -0,0 +1,246 @@
+package resources
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SourceUpstream is the source of the upstream vmanomaly documentation
+const SourceUpstream = "upstream"
+
+// extraFile is a markdown file of extra documentation
+type extraFile struct {
+	path    string // Path relative to the source root
+	content string
+	source  string
+}
+
+var (
+	extraMu          sync.RWMutex
+	extraPaths       []string
+	extraFiles       []extraFile
+	extraFingerprint string
+	extraGeneration  int // Incremented on every reload of extra docs
+)
+
+// currentExtraDocs returns loaded extra docs and their generation
+func currentExtraDocs() ([]extraFile, int) {
+	extraMu.RLock()
+	defer extraMu.RUnlock()
+	return extraFiles, extraGeneration
+}
+
+// setExtraDocs loads extra docs from paths, which are directories or tarballs (.tar, .tar.gz or .tgz).
+// Files of later paths replace files with the same path of earlier ones.
+func setExtraDocs(paths []string) error {
+	fp, err := fingerprint(paths)
+	if err != nil {
+		return err
+	}
+	files, err := loadExtraDocs(paths)
+	if err != nil {
+		return err
+	}
+	extraMu.Lock()
+	defer extraMu.Unlock()
+	extraPaths = paths
+	extraFiles = files
+	extraFingerprint = fp
+	extraGeneration++
+	return nil
+}
+
+// ReloadExtraDocs reloads extra docs if their files changed and re-indexes the active doc set.
+// It returns true if docs were reloaded.
+func ReloadExtraDocs() (bool, error) {
+	extraMu.RLock()
+	paths, oldFingerprint := extraPaths, extraFingerprint
+	extraMu.RUnlock()
+	if len(paths) == 0 {
+		return false, nil
+	}
+	fp, err := fingerprint(paths)
+	if err != nil {
+		return false, err
+	}
+	if fp == oldFingerprint {
+		return false, nil
+	}
+	if err := setExtraDocs(paths); err != nil {
+		return false, err
+	}
+	// Other doc sets are re-indexed lazily on the next search
+	if set := activeDocSet(); set != nil {
+		if err := set.build(); err != nil {
+			return true, err
+		}
+		syncRegisteredResources(set)
+	}
+	return true, nil
+}
+
+// WatchExtraDocs polls extra docs for changes every interval and re-indexes them until ctx is done
+func WatchExtraDocs(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := ReloadExtraDocs()
+			if err != nil {
+				log.Printf("error reloading extra docs: %v\n", err)
+				continue
+			}
+			if reloaded {
+				log.Printf("Reloaded extra docs\n")
+			}
+		}
+	}
+}
+
+func loadExtraDocs(paths []string) ([]extraFile, error) {
+	byPath := map[string]int{}
+	var files []extraFile
+	for _, p := range paths {
+		loaded, err := readExtraDocs(p)
+		if err != nil {
+			return nil, fmt.Errorf("error loading extra docs from %s: %w", p, err)
+		}
+		for _, f := range loaded {
+			if i, ok := byPath[f.path]; ok {
+				files[i] = f
+				continue
+			}
+			byPath[f.path] = len(files)
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// readExtraDocs reads markdown files of a directory or a tarball
+func readExtraDocs(p string) ([]extraFile, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	source := sourceName(p)
+	if info.IsDir() {
+		var files []extraFile
+		fsys := os.DirFS(p)
+		err := fs.WalkDir(fsys, ".", func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !isMarkdown(filePath) {
+				return nil
+			}
+			data, err := fs.ReadFile(fsys, filePath)
+			if err != nil {
+				return err
+			}
+			files = append(files, extraFile{path: filePath, content: string(data), source: source})
+			return nil
+		})
+		return files, err
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(p, ".gz") || strings.HasSuffix(p, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	var files []extraFile
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := path.Clean(strings.TrimPrefix(h.Name, "./"))
+		if h.Typeflag != tar.TypeReg || !isMarkdown(name) {
+			continue
+		}
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("invalid path in archive: %s", h.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, extraFile{path: name, content: string(data), source: source})
+	}
+	return files, nil
+}
+
+// fingerprint returns a hash of names, sizes and modification times of files under paths
+func fingerprint(paths []string) (string, error) {
+	h := sha256.New()
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", p, info.Size(), info.ModTime().UnixNano())
+		if !info.IsDir() {
+			continue
+		}
+		err = fs.WalkDir(os.DirFS(p), ".", func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !isMarkdown(filePath) {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s\x00%d\x00%d\n", filePath, info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// sourceName returns the name of an extra docs source: base name of its path without archive extensions
+func sourceName(p string) string {
+	name := path.Base(strings.ReplaceAll(p, "\\", "/"))
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar"} {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
+}
+
+func isMarkdown(p string) bool {
+	return strings.HasSuffix(strings.ToLower(p), ".md")
+}
@@ -0,0 +1,124 @@
+package resources
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func writeTarball(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtraDocs(t *testing.T) {
+	dir := t.TempDir()
+	runbooks := filepath.Join(dir, "runbooks")
+	if err := os.MkdirAll(filepath.Join(runbooks, "anomaly-detection"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	runbook := filepath.Join(runbooks, "cpu.md")
+	if err := os.WriteFile(runbook, []byte("# CPU runbook\n\nRestart the kartoffelservice.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Replaces the upstream FAQ
+	if err := os.WriteFile(filepath.Join(runbooks, "anomaly-detection", "FAQ.md"), []byte("# FAQ\n\nOur own FAQ.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dir, "guides.tar.gz")
+	writeTarball(t, archive, map[string]string{"./team/conventions.md": "# Conventions\n\nAlways use the blumenkohl preset.\n", "notes.txt": "skipped"})
+
+	t.Cleanup(func() {
+		if err := InitDocs(DocsOptions{}); err != nil {
+			t.Error(err)
+		}
+	})
+	if err := InitDocs(DocsOptions{ExtraDocs: []string{runbooks, archive}}); err != nil {
+		t.Fatalf("InitDocs() error = %v", err)
+	}
+
+	for query, want := range map[string]string{
+		"kartoffelservice": "docs://docs/cpu.md#0",
+		"blumenkohl":       "docs://docs/team/conventions.md#0",
+	} {
+		rs, _, err := SearchDocResources(context.Background(), query, "", 10)
+		if err != nil {
+			t.Fatalf("SearchDocResources(%q) error = %v", query, err)
+		}
+		if len(rs) != 1 || rs[0].Resource.URI != want {
+			t.Errorf("SearchDocResources(%q) = %+v, want %s", query, rs, want)
+		}
+	}
+	content, err := GetDocResourceContent("docs://docs/anomaly-detection/FAQ.md#0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := content.(mcp.TextResourceContents)
+	if !strings.Contains(text.Text, "Our own FAQ") || text.Meta["source"] != "runbooks" {
+		t.Errorf("FAQ is not replaced: %+v", text)
+	}
+	if _, err := GetDocResourceContent("docs://docs/anomaly-detection/FAQ.md#1"); err == nil {
+		t.Error("upstream FAQ chunks must be removed")
+	}
+
+	if reloaded, err := ReloadExtraDocs(); err != nil || reloaded {
+		t.Fatalf("ReloadExtraDocs() = %v, %v, want no reload", reloaded, err)
+	}
+	if err := os.WriteFile(runbook, []byte("# CPU runbook\n\nScale the rosenkohlservice.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the modification time changes on filesystems with coarse timestamps
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(runbook, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := ReloadExtraDocs(); err != nil || !reloaded {
+		t.Fatalf("ReloadExtraDocs() = %v, %v, want reload", reloaded, err)
+	}
+	if _, _, err := SearchDocResources(context.Background(), "rosenkohlservice", "", 10); err != nil {
+		t.Errorf("changed docs are not re-indexed: %v", err)
+	}
+	if _, _, err := SearchDocResources(context.Background(), "kartoffelservice", "", 10); err == nil {
+		t.Error("old content must be removed from the index")
+	}
+}
+
+func TestSourceName(t *testing.T) {
+	for p, want := range map[string]string{
+		"/opt/runbooks":       "runbooks",
+		"/opt/guides.tar.gz":  "guides",
+		"guides.tgz":          "guides",
+		"/opt/docs/guide.tar": "guide",
+	} {
+		if got := sourceName(p); got != want {
+			t.Errorf("sourceName(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package resources
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// imageMIMETypes are MIME types of documentation images by file extension
+var imageMIMETypes = map[string]string{
+	".webp": "image/webp",
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".svg":  "image/svg+xml",
+}
+
+// imageRefRe matches markdown image references, e.g. ![vmanomaly-components](vmanomaly-components.webp)
+var imageRefRe = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)`)
+
+// docImage is an image file of a doc set
+type docImage struct {
+	fsys     fs.FS
+	path     string // Path of the file in fsys
+	mimeType string
+}
+
+var (
+	imagesMu sync.RWMutex
+	// images are images of built doc sets by resource URI, they are read on request
+	images = map[string]docImage{}
+)
+
+// listDocImages returns image resources under root of fsys, paths in URIs are relative to root and prefixed with prefix.
+// Resources are described with alt texts and chunk names of their references in docFiles.
+func listDocImages(fsys fs.FS, root, prefix string, docFiles []DocFileInfo) (map[string]mcp.Resource, map[string]docImage, error) {
+	type reference struct{ alt, chunk string }
+	refs := map[string]reference{}
+	for _, d := range docFiles {
+		for _, m := range imageRefRe.FindAllStringSubmatch(d.Content, -1) {
+			if strings.Contains(m[2], "://") {
+				continue
+			}
+			imagePath := path.Join(path.Dir(d.Path), m[2])
+			if _, ok := refs[imagePath]; !ok {
+				refs[imagePath] = reference{alt: m[1], chunk: d.Name}
+			}
+		}
+	}
+
+	resources := map[string]mcp.Resource{}
+	files := map[string]docImage{}
+	err := fs.WalkDir(fsys, root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && filePath == path.Join(root, versionsDir) {
+			return fs.SkipDir
+		}
+		mimeType, ok := imageMIMETypes[strings.ToLower(path.Ext(filePath))]
+		if d.IsDir() || !ok {
+			return nil
+		}
+		docPath := path.Join(prefix, strings.TrimPrefix(strings.TrimPrefix(filePath, root), "/"))
+		uri := docsURIPrefix + docPath
+		description := fmt.Sprintf("Documentation image %s", path.Base(docPath))
+		if ref, ok := refs[docPath]; ok {
+			description = fmt.Sprintf("Documentation image %q of %s", ref.alt, ref.chunk)
+		}
+		resources[uri] = mcp.NewResource(uri, path.Base(docPath), mcp.WithMIMEType(mimeType), mcp.WithResourceDescription(description))
+		files[uri] = docImage{fsys: fsys, path: filePath, mimeType: mimeType}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error walking docs directory: %w", err)
+	}
+	return resources, files, nil
+}
+
+// getDocImageContent reads an image resource of a built doc set
+func getDocImageContent(uri string) (mcp.ResourceContents, bool, error) {
+	imagesMu.RLock()
+	img, ok := images[uri]
+	imagesMu.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+	data, err := fs.ReadFile(img.fsys, img.path)
+	if err != nil {
+		return nil, true, fmt.Errorf("error reading image %s: %w", img.path, err)
+	}
+	return mcp.BlobResourceContents{
+		URI:      uri,
+		MIMEType: img.mimeType,
+		Blob:     base64.StdEncoding.EncodeToString(data),
+	}, true, nil
+}
@@ -0,0 +1,277 @@
+package resources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/token/porter"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/registry"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	// docsAnalyzerName is an English analyzer which keeps code identifiers like `fit_window`
+	// or `model.zscore.ZScoreModel` as tokens and also indexes their parts
+	docsAnalyzerName = "vmanomaly_docs"
+	// codePartsFilterName is a token filter emitting parts of code identifiers along with the identifiers
+	codePartsFilterName = "vmanomaly_code_parts"
+
+	// indexMappingVersion must be changed on every change of the index mapping or analysis
+	// to invalidate cached indexes
+	indexMappingVersion = "1"
+)
+
+// Field boosts of documentation search, matches in chunk names and headings rank higher than in body text
+const (
+	nameBoost     = 3
+	headingsBoost = 2
+	contentBoost  = 1
+)
+
+func init() {
+	if err := registry.RegisterTokenFilter(codePartsFilterName, func(map[string]any, *registry.Cache) (analysis.TokenFilter, error) {
+		return codePartsFilter{}, nil
+	}); err != nil {
+		panic(err)
+	}
+	if err := registry.RegisterAnalyzer(docsAnalyzerName, docsAnalyzerConstructor); err != nil {
+		panic(err)
+	}
+}
+
+func docsAnalyzerConstructor(_ map[string]any, cache *registry.Cache) (analysis.Analyzer, error) {
+	tokenizer, err := cache.TokenizerNamed(unicode.Name)
+	if err != nil {
+		return nil, err
+	}
+	var filters []analysis.TokenFilter
+	for _, name := range []string{en.PossessiveName, lowercase.Name, codePartsFilterName, en.StopName, porter.Name} {
+		filter, err := cache.TokenFilterNamed(name)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return &analysis.DefaultAnalyzer{Tokenizer: tokenizer, TokenFilters: filters}, nil
+}
+
+// codePartsFilter keeps code identifiers joined with `_`, `.` or `-` and adds their parts at the same position,
+// so `fit_window` matches both `fit_window` and `window`. The identifier itself is marked as keyword
+// to protect it from stemming.
+type codePartsFilter struct{}
+
+func (codePartsFilter) Filter(input analysis.TokenStream) analysis.TokenStream {
+	output := make(analysis.TokenStream, 0, len(input))
+	for _, token := range input {
+		output = append(output, token)
+		term := string(token.Term)
+		if !strings.ContainsAny(term, "_.-") {
+			continue
+		}
+		token.KeyWord = true
+		offset := 0
+		for _, part := range strings.FieldsFunc(term, func(r rune) bool { return r == '_' || r == '.' || r == '-' }) {
+			i := strings.Index(term[offset:], part) + offset
+			offset = i + len(part)
+			output = append(output, &analysis.Token{
+				Start:    token.Start + i,
+				End:      token.Start + offset,
+				Term:     []byte(part),
+				Position: token.Position,
+				Type:     token.Type,
+			})
+		}
+	}
+	return output
+}
+
+// newDocsIndexMapping returns the index mapping of documentation chunks
+func newDocsIndexMapping() mapping.IndexMapping {
+	text := func() *mapping.FieldMapping {
+		f := bleve.NewTextFieldMapping()
+		f.Analyzer = docsAnalyzerName
+		f.Store = true
+		f.IncludeTermVectors = true
+		return f
+	}
+	keywordField := bleve.NewTextFieldMapping()
+	keywordField.Analyzer = keyword.Name
+	keywordField.Store = false
+	keywordField.IncludeTermVectors = false
+	keywordField.IncludeInAll = false
+	ignored := bleve.NewNumericFieldMapping()
+	ignored.Index = false
+	ignored.Store = false
+
+	doc := bleve.NewDocumentMapping()
+	doc.AddFieldMappingsAt("name", text())
+	doc.AddFieldMappingsAt("headings", text())
+	doc.AddFieldMappingsAt("content", text())
+	doc.AddFieldMappingsAt("path", keywordField)
+	doc.AddFieldMappingsAt("source", keywordField)
+	doc.AddFieldMappingsAt("chunk_num", ignored)
+
+	im := bleve.NewIndexMapping()
+	im.DefaultAnalyzer = docsAnalyzerName
+	im.DefaultMapping = doc
+	return im
+}
+
+// newDocsQuery returns a fuzzy match query over chunk names, headings and content with field boosts
+func newDocsQuery(text string) query.Query {
+	match := func(field string, boost float64) query.Query {
+		q := bleve.NewMatchQuery(text)
+		q.SetField(field)
+		q.SetBoost(boost)
+		q.Fuzziness = 1
+		return q
+	}
+	return bleve.NewDisjunctionQuery(
+		match("name", nameBoost),
+		match("headings", headingsBoost),
+		match("content", contentBoost),
+	)
+}
+
+// newDocsIndex creates an index of documentation chunks. If cacheDir is set, the index is stored there
+// under a name derived from the hash of chunks and reused as long as the docs do not change.
+func newDocsIndex(docFiles []DocFileInfo, cacheDir, name string) (bleve.Index, error) {
+	if cacheDir == "" {
+		index, err := bleve.NewMemOnly(newDocsIndexMapping())
+		if err != nil {
+			return nil, fmt.Errorf("error creating index: %w", err)
+		}
+		if err := indexDocFiles(index, docFiles); err != nil {
+			_ = index.Close()
+			return nil, err
+		}
+		return index, nil
+	}
+
+	prefix := name + "-"
+	indexPath := filepath.Join(cacheDir, prefix+docsHash(docFiles))
+	if _, err := os.Stat(indexPath); err == nil {
+		index, err := bleve.Open(indexPath)
+		if err == nil {
+			return index, nil
+		}
+		log.Printf("rebuilding broken docs index cache %s: %v\n", indexPath, err)
+	}
+	removeCachedIndexes(cacheDir, prefix)
+
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating docs index cache dir: %w", err)
+	}
+	tmpPath, err := os.MkdirTemp(cacheDir, prefix+"tmp-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating docs index cache dir: %w", err)
+	}
+	// bleve requires a non-existing directory
+	_ = os.Remove(tmpPath)
+	index, err := bleve.New(tmpPath, newDocsIndexMapping())
+	if err != nil {
+		_ = os.RemoveAll(tmpPath)
+		return nil, fmt.Errorf("error creating index: %w", err)
+	}
+	err = indexDocFiles(index, docFiles)
+	if closeErr := index.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, indexPath)
+	}
+	if err != nil {
+		_ = os.RemoveAll(tmpPath)
+		return nil, fmt.Errorf("error caching docs index: %w", err)
+	}
+	return bleve.Open(indexPath)
+}
+
+// indexDocFiles indexes chunks in a single batch
+func indexDocFiles(index bleve.Index, docFiles []DocFileInfo) error {
+	batch := index.NewBatch()
+	for _, docFile := range docFiles {
+		if err := batch.Index(docResourceURI(docFile), docFile); err != nil {
+			return fmt.Errorf("error indexing file %s: %w", docFile.Path, err)
+		}
+	}
+	if err := index.Batch(batch); err != nil {
+		return fmt.Errorf("error indexing docs: %w", err)
+	}
+	return nil
+}
+
+// docsHash returns the hash of chunks and the index mapping version
+func docsHash(docFiles []DocFileInfo) string {
+	h := sha256.New()
+	h.Write([]byte(indexMappingVersion))
+	for _, d := range docFiles {
+		for _, s := range []string{d.Path, strconv.Itoa(d.ChunkNum), d.Name, d.Headings, d.Source, d.Content} {
+			h.Write([]byte(strconv.Itoa(len(s))))
+			h.Write([]byte{0})
+			h.Write([]byte(s))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// removeCachedIndexes removes cached indexes with the prefix
+func removeCachedIndexes(cacheDir, prefix string) {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), prefix) {
+			if err := os.RemoveAll(filepath.Join(cacheDir, e.Name())); err != nil {
+				log.Printf("error removing stale docs index cache %s: %v\n", e.Name(), err)
+			}
+		}
+	}
+}
+
+var (
+	shortcodeRe = regexp.MustCompile(`\{\{%.*?%\}\}`)
+	linkRe      = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+)
+
+// headingText returns the text of a markdown heading line without shortcodes and link targets
+func headingText(line string) string {
+	text := shortcodeRe.ReplaceAllString(strings.Trim(strings.TrimSpace(line), "# "), "")
+	return strings.TrimSpace(linkRe.ReplaceAllString(text, "$1"))
+}
+
+// chunkHeadings returns headings of a markdown chunk without shortcodes, one per line.
+// Lines in fenced code blocks are skipped, since they are often comments.
+func chunkHeadings(content string) string {
+	var headings []string
+	inCode := false
+	for line := range strings.Lines(content) {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode || !strings.HasPrefix(line, "#") {
+			continue
+		}
+		if h := headingText(line); h != "" {
+			headings = append(headings, h)
+		}
+	}
+	return strings.Join(headings, "\n")
+}
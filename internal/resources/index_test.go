@@ -0,0 +1,204 @@
+package resources
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/embedding"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+)
+
+func TestDocsAnalyzer(t *testing.T) {
+	im := newDocsIndexMapping().(*mapping.IndexMappingImpl)
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"fit_window", []string{"fit_window", "fit", "window"}},
+		{"model.zscore.ZScoreModel", []string{"model.zscore.zscoremodel", "model", "zscore", "zscoremodel"}},
+		{"The models' windows", []string{"model", "window"}},
+	}
+	for _, tt := range tests {
+		tokens, err := im.AnalyzeText(docsAnalyzerName, []byte(tt.text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, token := range tokens {
+			got = append(got, string(token.Term))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("AnalyzeText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+var testDocFiles = []DocFileInfo{
+	{Path: "docs/a.md", Name: "Schedulers", Content: "# Schedulers\n\nSchedulers define when models are fit. The fit_window arg sets the data range.", Source: SourceUpstream},
+	{Path: "docs/b.md", Name: "Models", Content: "# Models\n\nModels mention window sizes only in passing.", Source: SourceUpstream},
+	{Path: "docs/c.md", Name: "Fit window", Headings: "Fit window", Content: "# Fit window\n\nHow much data is used.", Source: SourceUpstream},
+}
+
+func TestNewDocsIndex_Search(t *testing.T) {
+	index, err := newDocsIndex(testDocFiles, "", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+
+	req := bleve.NewSearchRequest(newDocsQuery("fit window"))
+	req.Highlight = bleve.NewHighlight()
+	req.Highlight.AddField("content")
+	res, err := index.Search(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Hits) < 2 || res.Hits[0].ID != docResourceURI(testDocFiles[2]) {
+		t.Fatalf("heading match must rank first, got %v", res.Hits)
+	}
+	for _, hit := range res.Hits {
+		if hit.ID == docResourceURI(testDocFiles[0]) && len(hit.Fragments["content"]) == 0 {
+			t.Errorf("no highlighted fragments for %s", hit.ID)
+		}
+	}
+}
+
+func TestNewDocsIndex_Cache(t *testing.T) {
+	dir := t.TempDir()
+	index, err := newDocsIndex(testDocFiles, dir, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Close(); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected a single cached index, got %d", len(entries))
+	}
+	cached := entries[0].Name()
+
+	// Unchanged docs reuse the cache
+	index, err = newDocsIndex(testDocFiles, dir, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := index.DocCount(); n != uint64(len(testDocFiles)) {
+		t.Errorf("cached index has %d docs", n)
+	}
+	if err := index.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changed docs replace the cache
+	index, err = newDocsIndex(testDocFiles[:1], dir, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+	entries, _ = os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() == cached {
+		t.Errorf("stale index is not replaced: %v", entries)
+	}
+}
+
+func TestChunkHeadings(t *testing.T) {
+	content := "# Title {{% available_from \"v1.2.0\" anomaly %}}\n\ntext\n\n```yaml\n# comment\n```\n## Sub ##\n"
+	if got := chunkHeadings(content); got != "Title\nSub" {
+		t.Errorf("chunkHeadings() = %q", got)
+	}
+}
+
+// conceptEmbedder is a toy semantic embedder: every dimension is a concept expressed by any of its words
+type conceptEmbedder struct {
+	embedded *int
+}
+
+var testConcepts = [][]string{
+	{"night", "quiet hours", "maintenance window"},
+	{"stop", "suppress", "silence"},
+}
+
+func (conceptEmbedder) Model() string { return "concepts" }
+
+func (e conceptEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	*e.embedded += len(texts)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(testConcepts))
+		for dim, words := range testConcepts {
+			for _, w := range words {
+				if strings.Contains(strings.ToLower(text), w) {
+					vectors[i][dim] = 1
+				}
+			}
+		}
+		embedding.Normalize(vectors[i])
+	}
+	return vectors, nil
+}
+
+func TestHybridSearch(t *testing.T) {
+	extra := t.TempDir()
+	if err := os.WriteFile(filepath.Join(extra, "maintenance.md"), []byte("# Maintenance windows\n\nSuppress notifications during quiet hours.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	const (
+		query = "how do I stop alerts at night"
+		want  = "docs://docs/maintenance.md#0"
+	)
+	found := func(rs []DocHit) bool {
+		return slices.ContainsFunc(rs, func(h DocHit) bool { return h.Resource.URI == want })
+	}
+	t.Cleanup(func() {
+		if err := InitDocs(DocsOptions{}); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if err := InitDocs(DocsOptions{ExtraDocs: []string{extra}}); err != nil {
+		t.Fatal(err)
+	}
+	rs, _, err := SearchDocResources(context.Background(), query, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found(rs) {
+		t.Fatalf("full-text search must not find the paraphrased doc")
+	}
+
+	embedded := 0
+	cacheDir := t.TempDir()
+	opts := DocsOptions{ExtraDocs: []string{extra}, IndexCacheDir: cacheDir, Embedder: conceptEmbedder{embedded: &embedded}}
+	if err := InitDocs(opts); err != nil {
+		t.Fatal(err)
+	}
+	rs, _, err = SearchDocResources(context.Background(), query, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found(rs) {
+		t.Errorf("hybrid search must find the paraphrased doc, got %d hits", len(rs))
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, activeDocSet().Version.String()+"-"+embedding.StoreFile)); err != nil {
+		t.Errorf("embeddings are not cached: %v", err)
+	}
+
+	// Chunk embeddings are read from the cache, only the query is embedded
+	embedded = 0
+	if err := InitDocs(opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := SearchDocResources(context.Background(), query, "", 10); err != nil {
+		t.Fatal(err)
+	}
+	if embedded != 1 {
+		t.Errorf("embedded %d texts, want only the query", embedded)
+	}
+}
@@ -0,0 +1,135 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/params"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// URIs and URI templates of resources with live state of the connected vmanomaly
+const (
+	BuildInfoURI             = "vmanomaly://buildinfo"
+	LimitsURI                = "vmanomaly://limits"
+	ModelSchemaURITemplate   = "vmanomaly://models/{class}/schema"
+	TaskURITemplate          = "vmanomaly://tasks/{task_id}"
+	CompatibilityURITemplate = "vmanomaly://compatibility{?version_to}"
+)
+
+// liveFunc fetches live state for a resource from URI template arguments
+type liveFunc func(ctx context.Context, args map[string]string) (any, error)
+
+// RegisterLiveResources registers resources and resource templates with live state of the connected vmanomaly,
+// so clients can attach it as context without a tool call. State is fetched on every read.
+func RegisterLiveResources(s *server.MCPServer, client vmanomaly.API) {
+	s.AddResource(
+		mcp.NewResource(BuildInfoURI, "vmanomaly build info",
+			mcp.WithResourceDescription("Version and build information of the connected vmanomaly"),
+			mcp.WithMIMEType("application/json"),
+		),
+		liveResourceHandler(func(ctx context.Context, _ map[string]string) (any, error) {
+			return client.GetBuildInfo(ctx)
+		}),
+	)
+	s.AddResource(
+		mcp.NewResource(LimitsURI, "vmanomaly detection limits",
+			mcp.WithResourceDescription("Concurrency limits of anomaly detection tasks of the connected vmanomaly: maximum, running and available task slots"),
+			mcp.WithMIMEType("application/json"),
+		),
+		liveResourceHandler(func(ctx context.Context, _ map[string]string) (any, error) {
+			return client.GetDetectionLimits(ctx)
+		}),
+	)
+
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate(ModelSchemaURITemplate, "vmanomaly model schema",
+			mcp.WithTemplateDescription("JSON schema of model parameters. class is a model alias (e.g. zscore, prophet) or a full class name (e.g. model.zscore.ZscoreModel)"),
+			mcp.WithTemplateMIMEType("application/json"),
+		),
+		liveTemplateHandler(func(ctx context.Context, args map[string]string) (any, error) {
+			return client.GetModelSchema(ctx, modelAlias(args["class"]))
+		}),
+	)
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate(TaskURITemplate, "vmanomaly detection task",
+			mcp.WithTemplateDescription("Status, progress and results of an anomaly detection task by its ID"),
+			mcp.WithTemplateMIMEType("application/json"),
+		),
+		liveTemplateHandler(func(ctx context.Context, args map[string]string) (any, error) {
+			return client.GetTaskStatus(ctx, args["task_id"])
+		}),
+	)
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate(CompatibilityURITemplate, "vmanomaly state compatibility",
+			mcp.WithTemplateDescription("Compatibility of persisted vmanomaly state with the runtime version or version_to"),
+			mcp.WithTemplateMIMEType("application/json"),
+		),
+		liveTemplateHandler(func(ctx context.Context, args map[string]string) (any, error) {
+			var versionTo *string
+			if v := args["version_to"]; v != "" {
+				versionTo = &v
+			}
+			return client.Compatibility(ctx, versionTo)
+		}),
+	)
+}
+
+// liveResourceHandler returns a handler of a resource with live state
+func liveResourceHandler(fetch liveFunc) server.ResourceHandlerFunc {
+	return func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return readLive(ctx, req, fetch)
+	}
+}
+
+// liveTemplateHandler returns a handler of a resource template with live state
+func liveTemplateHandler(fetch liveFunc) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return readLive(ctx, req, fetch)
+	}
+}
+
+// readLive fetches live state with arguments of the request and returns it as JSON
+func readLive(ctx context.Context, req mcp.ReadResourceRequest, fetch liveFunc) ([]mcp.ResourceContents, error) {
+	args := make(map[string]string, len(req.Params.Arguments))
+	for name, value := range req.Params.Arguments {
+		// Template variables are matched as lists of values
+		switch v := value.(type) {
+		case []string:
+			if len(v) > 0 {
+				args[name] = v[0]
+			}
+		case string:
+			args[name] = v
+		}
+	}
+	result, err := fetch(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", req.Params.URI, err)
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding %s: %w", req.Params.URI, err)
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      req.Params.URI,
+		MIMEType: "application/json",
+		Text:     string(data),
+	}}, nil
+}
+
+// modelAlias returns the alias of a documented model class given by its full name, other classes are returned as is
+func modelAlias(class string) string {
+	catalog, err := ParamCatalog()
+	if err != nil {
+		return class
+	}
+	if full, aliases, ok := catalog.ResolveClass(params.ComponentModels, class); ok && full == class && len(aliases) > 0 {
+		return aliases[0]
+	}
+	return class
+}
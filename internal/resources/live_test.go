@@ -0,0 +1,108 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// readResource reads a resource of the server with a JSON-RPC request
+func readResource(t *testing.T, s *server.MCPServer, uri string) (mcp.ReadResourceResult, string) {
+	t.Helper()
+	req := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "resources/read", "params": {"uri": %q}}`, uri)
+	msg := s.HandleMessage(context.Background(), []byte(req))
+	switch resp := msg.(type) {
+	case mcp.JSONRPCResponse:
+		result, ok := resp.Result.(mcp.ReadResourceResult)
+		if !ok {
+			t.Fatalf("unexpected result %T", resp.Result)
+		}
+		return result, ""
+	case mcp.JSONRPCError:
+		return mcp.ReadResourceResult{}, resp.Error.Message
+	}
+	t.Fatalf("unexpected response %T", msg)
+	return mcp.ReadResourceResult{}, ""
+}
+
+func TestLiveResources(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/server/buildinfo":
+			_, _ = w.Write([]byte(`{"version": "v1.25.3"}`))
+		case "/api/v1/model/schema":
+			_ = json.NewEncoder(w).Encode(map[string]any{"title": r.URL.Query().Get("model_class")})
+		case "/api/v1/anomaly_detection/tasks/abc-123":
+			_, _ = w.Write([]byte(`{"task_id": "abc-123", "status": "running"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	s := server.NewMCPServer("test", "v0.0.0", server.WithResourceCapabilities(true, false))
+	RegisterLiveResources(s, vmanomaly.NewClient(srv.URL, "", nil))
+
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"vmanomaly://buildinfo", `"version": "v1.25.3"`},
+		{"vmanomaly://models/prophet/schema", `"title": "prophet"`},
+		{"vmanomaly://models/model.zscore.ZscoreModel/schema", `"title": "zscore"`},
+		{"vmanomaly://tasks/abc-123", `"status": "running"`},
+	}
+	for _, tt := range tests {
+		result, errMsg := readResource(t, s, tt.uri)
+		if errMsg != "" {
+			t.Errorf("reading %s: %s", tt.uri, errMsg)
+			continue
+		}
+		text, ok := result.Contents[0].(mcp.TextResourceContents)
+		if !ok || text.URI != tt.uri || text.MIMEType != "application/json" || !strings.Contains(text.Text, tt.want) {
+			t.Errorf("%s = %+v, want JSON with %s", tt.uri, result.Contents[0], tt.want)
+		}
+	}
+
+	for _, uri := range []string{"vmanomaly://compatibility", "vmanomaly://compatibility?version_to=v1.26.0"} {
+		if _, errMsg := readResource(t, s, uri); !strings.Contains(errMsg, "error reading "+uri) {
+			t.Errorf("%s must match the template and report the API error, got %q", uri, errMsg)
+		}
+	}
+}
+
+func TestDocImages(t *testing.T) {
+	s := server.NewMCPServer("test", "v0.0.0", server.WithResourceCapabilities(true, false))
+	if err := RegisterDocsResources(s); err != nil {
+		t.Fatal(err)
+	}
+	uri := "docs://docs/anomaly-detection/components/autotune.webp"
+	var found *mcp.Resource
+	for _, r := range activeDocSet().Resources() {
+		if r.URI == uri {
+			found = &r
+		}
+	}
+	if found == nil || found.MIMEType != "image/webp" || !strings.Contains(found.Description, "vmanomaly-autotune-schema") {
+		t.Fatalf("image resource = %+v", found)
+	}
+
+	result, errMsg := readResource(t, s, uri)
+	if errMsg != "" {
+		t.Fatal(errMsg)
+	}
+	blob, ok := result.Contents[0].(mcp.BlobResourceContents)
+	if !ok || blob.MIMEType != "image/webp" || !strings.HasPrefix(blob.Blob, "UklGR") {
+		t.Errorf("image content must be a base64 encoded webp, got %T", result.Contents[0])
+	}
+}
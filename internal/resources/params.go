@@ -0,0 +1,26 @@
+package resources
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sync"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/params"
+)
+
+// ComponentsDocsDir is the directory of vmanomaly component docs in the embedded docs
+const ComponentsDocsDir = "docs/anomaly-detection/components"
+
+// ParamCatalog returns the catalog of parameters documented in the embedded component docs, it is parsed once
+var ParamCatalog = sync.OnceValues(func() (*params.Catalog, error) {
+	docs := make(map[string]string, len(params.Components))
+	for _, component := range params.Components {
+		data, err := fs.ReadFile(DocsDir, path.Join(ComponentsDocsDir, component+".md"))
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s docs: %w", component, err)
+		}
+		docs[component] = string(data)
+	}
+	return params.NewCatalog(docs), nil
+})
@@ -0,0 +1,26 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/params"
+)
+
+func TestParamCatalog(t *testing.T) {
+	c, err := ParamCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, component := range params.Components {
+		if len(c.Lookup(params.Query{Component: component})) == 0 {
+			t.Errorf("no parameters of %s are found in embedded docs", component)
+		}
+	}
+	found := c.Lookup(params.Query{Class: "zscore", Name: "z_threshold"})
+	if len(found) != 1 || found[0].Default != "2.5" || found[0].Class != "model.zscore.ZscoreModel" {
+		t.Errorf("z_threshold of zscore = %+v", found)
+	}
+	if found := c.Lookup(params.Query{Class: "periodic", Name: "fit_every"}); len(found) != 1 {
+		t.Errorf("fit_every of periodic scheduler = %+v", found)
+	}
+}
@@ -0,0 +1,275 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// sectionURISeparator separates the chunk number and the section number in section URIs,
+	// e.g. docs://docs/anomaly-detection/FAQ.md#3.1 is the second section of the fourth chunk
+	sectionURISeparator = "."
+	// DefaultSnippetSize is the default maximum size of section snippets in bytes
+	DefaultSnippetSize = 600
+	// headingMatchWeight is the weight of query terms matched in section headings relative to the body
+	headingMatchWeight = 2
+)
+
+// Section is a part of a documentation chunk under a single heading
+type Section struct {
+	URI string `json:"uri"`
+	// HeadingPath are headings from the document title to the section heading
+	HeadingPath []string `json:"heading_path"`
+	Content     string   `json:"content"`
+	Source      string   `json:"source"`
+}
+
+// SectionHit is the best matching section of a documentation search hit
+type SectionHit struct {
+	Section
+	// Score is the score of the chunk the section belongs to, see DocHit
+	Score float64 `json:"score"`
+	// Snippet is the most relevant part of the section content
+	Snippet string `json:"snippet"`
+}
+
+// SearchDocSections searches documentation like SearchDocResources, but returns the best matching section
+// of every hit with a snippet of up to snippetSize bytes
+func SearchDocSections(ctx context.Context, query, version string, limit, snippetSize int) ([]SectionHit, string, error) {
+	hits, docsVersion, err := SearchDocResources(ctx, query, version, limit)
+	if err != nil {
+		return nil, docsVersion, err
+	}
+	queryTerms := analyzeTerms(query)
+	results := make([]SectionHit, 0, len(hits))
+	for _, hit := range hits {
+		sections, err := DocSections(hit.Resource.URI)
+		if err != nil {
+			continue
+		}
+		best, bestScore := 0, 0
+		for i, s := range sections {
+			if score := sectionScore(s, queryTerms); score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		results = append(results, SectionHit{
+			Section: sections[best],
+			Score:   hit.Score,
+			Snippet: snippet(sections[best].Content, queryTerms, snippetSize),
+		})
+	}
+	return results, docsVersion, nil
+}
+
+// DocSections splits a documentation chunk into sections
+func DocSections(chunkURI string) ([]Section, error) {
+	content, err := GetDocResourceContent(chunkURI)
+	if err != nil {
+		return nil, err
+	}
+	text, ok := content.(mcp.TextResourceContents)
+	if !ok {
+		return nil, fmt.Errorf("resource %s is not a text", chunkURI)
+	}
+	source, _ := text.Meta["source"].(string)
+	parts := splitSections(text.Text)
+	sections := make([]Section, 0, len(parts))
+	for i, p := range parts {
+		sections = append(sections, Section{
+			URI:         chunkURI + sectionURISeparator + strconv.Itoa(i),
+			HeadingPath: p.headingPath,
+			Content:     p.content,
+			Source:      source,
+		})
+	}
+	return sections, nil
+}
+
+// GetDocSection returns a section by its URI. A chunk URI returns the whole chunk as a single section.
+func GetDocSection(uri string) (Section, error) {
+	chunkURI, num, isSection := parseSectionURI(uri)
+	sections, err := DocSections(chunkURI)
+	if err != nil {
+		return Section{}, err
+	}
+	if !isSection {
+		content, _ := GetDocResourceContent(chunkURI)
+		text, _ := content.(mcp.TextResourceContents)
+		return Section{URI: chunkURI, HeadingPath: sections[0].HeadingPath, Content: text.Text, Source: sections[0].Source}, nil
+	}
+	if num >= len(sections) {
+		return Section{}, fmt.Errorf("section not found: %s", uri)
+	}
+	return sections[num], nil
+}
+
+// parseSectionURI splits a section URI into the chunk URI and the section number.
+// It returns false if uri is a chunk URI.
+func parseSectionURI(uri string) (string, int, bool) {
+	hash := strings.LastIndex(uri, "#")
+	if hash < 0 {
+		return uri, 0, false
+	}
+	chunk, section, ok := strings.Cut(uri[hash+1:], sectionURISeparator)
+	if !ok {
+		return uri, 0, false
+	}
+	num, err := strconv.Atoi(section)
+	if err != nil || num < 0 {
+		return uri, 0, false
+	}
+	return uri[:hash+1] + chunk, num, true
+}
+
+// sectionPart is a section of markdown content
+type sectionPart struct {
+	headingPath []string
+	content     string
+}
+
+// splitSections splits markdown content into sections. A section starts with a heading following non-heading
+// content, so consecutive headings (e.g. the heading hierarchy prepended to chunks) belong to the same section.
+// Headings in fenced code blocks are ignored.
+func splitSections(content string) []sectionPart {
+	type heading struct {
+		level int
+		text  string
+	}
+	var (
+		stack   []heading
+		parts   []sectionPart
+		current strings.Builder
+		hasBody bool
+		inCode  bool
+	)
+	path := func() []string {
+		p := make([]string, 0, len(stack))
+		for _, h := range stack {
+			p = append(p, h.text)
+		}
+		return p
+	}
+	flush := func() {
+		if strings.TrimSpace(current.String()) != "" {
+			parts = append(parts, sectionPart{headingPath: path(), content: current.String()})
+		}
+		current.Reset()
+		hasBody = false
+	}
+	for line := range strings.Lines(content) {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+		}
+		if !inCode && strings.HasPrefix(line, "#") {
+			if hasBody {
+				flush()
+			}
+			level := len(line) - len(strings.TrimLeft(line, "#"))
+			for len(stack) > 0 && stack[len(stack)-1].level >= level {
+				stack = stack[:len(stack)-1]
+			}
+			if text := headingText(line); text != "" {
+				stack = append(stack, heading{level: level, text: text})
+			}
+			current.WriteString(line)
+			continue
+		}
+		if trimmed != "" {
+			hasBody = true
+		}
+		current.WriteString(line)
+	}
+	flush()
+	if len(parts) == 0 {
+		parts = append(parts, sectionPart{content: content})
+	}
+	return parts
+}
+
+// docsAnalyzer returns the analyzer of documentation search, it is used to match query terms in sections
+var docsAnalyzer = sync.OnceValue(func() analysis.Analyzer {
+	return newDocsIndexMapping().(*mapping.IndexMappingImpl).AnalyzerNamed(docsAnalyzerName)
+})
+
+// analyzeTerms returns distinct terms of text produced by the documentation analyzer
+func analyzeTerms(text string) map[string]bool {
+	terms := map[string]bool{}
+	for _, token := range docsAnalyzer().Analyze([]byte(text)) {
+		terms[string(token.Term)] = true
+	}
+	return terms
+}
+
+// sectionScore returns the weighted number of query terms found in the section
+func sectionScore(s Section, queryTerms map[string]bool) int {
+	contentTerms := analyzeTerms(s.Content)
+	headingTerms := analyzeTerms(strings.Join(s.HeadingPath, "\n"))
+	score := 0
+	for term := range queryTerms {
+		if contentTerms[term] {
+			score++
+		}
+		if headingTerms[term] {
+			score += headingMatchWeight
+		}
+	}
+	return score
+}
+
+// snippet returns up to size bytes of section content starting from the line matching most query terms.
+// Cut parts are marked with "…".
+func snippet(content string, queryTerms map[string]bool, size int) string {
+	lines := strings.SplitAfter(content, "\n")
+	best, bestScore := 0, 0
+	for i, line := range lines {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		score := 0
+		for term := range analyzeTerms(line) {
+			if queryTerms[term] {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	// Skip leading headings, they are returned as the heading path
+	if bestScore == 0 {
+		for best < len(lines)-1 && (strings.HasPrefix(lines[best], "#") || strings.TrimSpace(lines[best]) == "") {
+			best++
+		}
+	}
+	text := strings.TrimSpace(strings.Join(lines[best:], ""))
+	if best > 0 && bestScore > 0 {
+		text = "…" + text
+	}
+	return Truncate(text, size)
+}
+
+// Truncate cuts text to at most size bytes at a rune boundary, marking the cut with "…"
+func Truncate(text string, size int) string {
+	if len(text) <= size {
+		return text
+	}
+	const ellipsis = "…"
+	if size <= len(ellipsis) {
+		return ""
+	}
+	cut := size - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + ellipsis
+}
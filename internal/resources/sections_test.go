@@ -0,0 +1,103 @@
+package resources
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitSections(t *testing.T) {
+	content := "# Models\n## [Prophet](https://facebook.github.io/prophet/)\n\nIntro.\n\n### Args {{% available_from \"v1.18.0\" anomaly %}}\n\n```yaml\n# not a heading\n```\n\n## Online\n\nText.\n"
+	parts := splitSections(content)
+	want := [][]string{
+		{"Models", "Prophet"},
+		{"Models", "Prophet", "Args"},
+		{"Models", "Online"},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("got %d sections, want %d: %+v", len(parts), len(want), parts)
+	}
+	for i, p := range parts {
+		if !slices.Equal(p.headingPath, want[i]) {
+			t.Errorf("section %d heading path = %q, want %q", i, p.headingPath, want[i])
+		}
+	}
+	if !strings.Contains(parts[1].content, "# not a heading") {
+		t.Errorf("code block must stay in its section: %q", parts[1].content)
+	}
+	if got := strings.Join([]string{parts[0].content, parts[1].content, parts[2].content}, ""); got != content {
+		t.Errorf("sections must cover the whole content, got %q", got)
+	}
+}
+
+func TestParseSectionURI(t *testing.T) {
+	tests := []struct {
+		uri       string
+		chunk     string
+		section   int
+		isSection bool
+	}{
+		{"docs://docs/a/FAQ.md#3.1", "docs://docs/a/FAQ.md#3", 1, true},
+		{"docs://docs/a/FAQ.md#3", "docs://docs/a/FAQ.md#3", 0, false},
+		{"docs://docs/a/FAQ.md#3.x", "docs://docs/a/FAQ.md#3.x", 0, false},
+	}
+	for _, tt := range tests {
+		chunk, section, isSection := parseSectionURI(tt.uri)
+		if chunk != tt.chunk || section != tt.section || isSection != tt.isSection {
+			t.Errorf("parseSectionURI(%q) = %q, %d, %v", tt.uri, chunk, section, isSection)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		size int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"0123456789", 7, "0123…"},
+		{"ääää", 6, "ä…"},
+		{"long text", 2, ""},
+	} {
+		if got := Truncate(tt.text, tt.size); got != tt.want || len(got) > tt.size {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.text, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestSearchDocSections(t *testing.T) {
+	hits, _, err := SearchDocSections(context.Background(), "prophet seasonality", "", 5, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) == 0 {
+		t.Fatal("no hits")
+	}
+	for _, h := range hits {
+		if len(h.Snippet) > 300 || len(h.HeadingPath) == 0 {
+			t.Errorf("unexpected hit %s: %q %q", h.URI, h.HeadingPath, h.Snippet)
+		}
+	}
+	if !strings.Contains(strings.ToLower(strings.Join(hits[0].HeadingPath, " ")+hits[0].Snippet), "prophet") {
+		t.Errorf("first hit does not match the query: %+v", hits[0])
+	}
+
+	section, err := GetDocSection(hits[0].URI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := strings.TrimPrefix(hits[0].Snippet, "…")
+	if section.URI != hits[0].URI || !strings.Contains(section.Content, start[:min(len(start), 40)]) {
+		t.Errorf("GetDocSection() = %+v", section)
+	}
+	chunkURI, _, _ := parseSectionURI(hits[0].URI)
+	chunk, err := GetDocSection(chunkURI)
+	if err != nil || len(chunk.Content) < len(section.Content) {
+		t.Errorf("GetDocSection(%s) = %+v, %v", chunkURI, chunk, err)
+	}
+	if _, err := GetDocSection(chunkURI + ".1000"); err == nil {
+		t.Error("expected error for a missing section")
+	}
+}
@@ -0,0 +1,470 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/changelog"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/embedding"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// versionsDir is the directory of versioned doc sets inside the embedded docs,
+// every subdirectory is named after a vmanomaly version and has the same layout as docs
+const versionsDir = "versions"
+
+// DocsOptions are options of documentation resources
+type DocsOptions struct {
+	// Version is the vmanomaly version to pick the doc set for, the latest docs are used if empty
+	Version string
+	// VersionsDir is an optional directory with extra doc sets in subdirectories named after vmanomaly versions,
+	// e.g. VersionsDir/v1.24.0/anomaly-detection/README.md
+	VersionsDir string
+	// ExtraDocs are directories or tarballs (.tar, .tar.gz or .tgz) with extra markdown indexed alongside every doc set.
+	// Files with the same path as upstream docs (e.g. anomaly-detection/FAQ.md) replace them.
+	ExtraDocs []string
+	// IndexCacheDir is an optional directory to cache search indexes in, indexes are rebuilt when docs change
+	IndexCacheDir string
+	// Embedder enables hybrid search: full-text ranking is fused with vector similarity of chunk embeddings.
+	// Vectors are read from embeddings.json of the doc set or IndexCacheDir if they were computed with the same model,
+	// the rest are computed on indexing. Only full-text search is used if nil.
+	Embedder embedding.Embedder
+}
+
+// hybridCandidates is the minimum number of candidates of each ranking fused in hybrid search
+const hybridCandidates = 50
+
+// DocSet is a documentation snapshot of a single vmanomaly version
+type DocSet struct {
+	Version changelog.Version `json:"version"`
+	Source  string            `json:"source"` // "embedded" or the directory the set is loaded from
+
+	fsys   fs.FS
+	root   string // Directory of the set in fsys
+	prefix string // Path prefix of the set in resource URIs
+
+	buildMu    sync.Mutex // Serializes builds, cached indexes can't be opened twice
+	mu         sync.RWMutex
+	built      bool
+	generation int // Generation of extra docs the set is built with
+	index      bleve.Index
+	resources  map[string]mcp.Resource
+	vectorURIs []string    // Resource URIs of vectors
+	vectors    [][]float32 // Normalized chunk embeddings, empty if hybrid search is disabled
+}
+
+// builtDocSet is the result of indexing a doc set
+type builtDocSet struct {
+	index      bleve.Index
+	resources  map[string]mcp.Resource
+	contents   map[string]mcp.ResourceContents
+	images     map[string]docImage
+	vectorURIs []string
+	vectors    [][]float32
+}
+
+var (
+	docSetsMu     sync.Mutex
+	docSets       []*DocSet // Ordered from the newest to the oldest
+	activeSet     *DocSet
+	indexCacheDir string
+	docsEmbedder  embedding.Embedder
+	contentsMu    sync.RWMutex
+)
+
+// InitDocs loads the embedded doc sets and doc sets from opts.VersionsDir and activates the one matching opts.Version.
+// Docs are initialized with default options on the first use if InitDocs is not called.
+func InitDocs(opts DocsOptions) error {
+	cl, err := Changelog()
+	if err != nil {
+		return err
+	}
+	if err := setExtraDocs(opts.ExtraDocs); err != nil {
+		return err
+	}
+	sets := map[changelog.Version]*DocSet{}
+	sets[cl.Latest().Version] = &DocSet{Version: cl.Latest().Version, Source: "embedded", fsys: DocsDir, root: "docs", prefix: "docs"}
+	addVersions := func(fsys fs.FS, root, source string) error {
+		entries, err := fs.ReadDir(fsys, root)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+			v, err := changelog.ParseVersion(e.Name())
+			if err != nil {
+				log.Printf("skipping docs directory %s: %v\n", e.Name(), err)
+				continue
+			}
+			dir := path.Join(root, e.Name())
+			sets[v] = &DocSet{Version: v, Source: source, fsys: fsys, root: dir, prefix: path.Join("docs", versionsDir, v.String())}
+		}
+		return nil
+	}
+	if _, err := fs.Stat(DocsDir, path.Join("docs", versionsDir)); err == nil {
+		if err := addVersions(DocsDir, path.Join("docs", versionsDir), "embedded"); err != nil {
+			return fmt.Errorf("error reading embedded doc versions: %w", err)
+		}
+	}
+	if opts.VersionsDir != "" {
+		if err := addVersions(os.DirFS(opts.VersionsDir), ".", opts.VersionsDir); err != nil {
+			return fmt.Errorf("error reading doc versions from %s: %w", opts.VersionsDir, err)
+		}
+	}
+
+	list := make([]*DocSet, 0, len(sets))
+	for _, set := range sets {
+		list = append(list, set)
+	}
+	slices.SortFunc(list, func(a, b *DocSet) int { return b.Version.Compare(a.Version) })
+
+	docSetsMu.Lock()
+	defer docSetsMu.Unlock()
+	for _, set := range docSets {
+		set.close()
+	}
+	docSets = list
+	activeSet = list[0]
+	indexCacheDir = opts.IndexCacheDir
+	docsEmbedder = opts.Embedder
+	if opts.Version != "" {
+		v, err := changelog.ParseVersion(opts.Version)
+		if err != nil {
+			return fmt.Errorf("invalid docs version: %w", err)
+		}
+		activeSet = pickDocSet(list, v)
+	}
+	return nil
+}
+
+// ensureDocSets initializes doc sets with defaults if they are not initialized yet
+func ensureDocSets() error {
+	docSetsMu.Lock()
+	initialized := docSets != nil
+	docSetsMu.Unlock()
+	if initialized {
+		return nil
+	}
+	return InitDocs(DocsOptions{})
+}
+
+// pickDocSet returns the newest set not newer than v or the oldest set if all sets are newer
+func pickDocSet(sets []*DocSet, v changelog.Version) *DocSet {
+	for _, set := range sets {
+		if set.Version.Compare(v) <= 0 {
+			return set
+		}
+	}
+	return sets[len(sets)-1]
+}
+
+// indexOptions returns the index cache dir and the embedder of doc sets
+func indexOptions() (string, embedding.Embedder) {
+	docSetsMu.Lock()
+	defer docSetsMu.Unlock()
+	return indexCacheDir, docsEmbedder
+}
+
+func activeDocSet() *DocSet {
+	docSetsMu.Lock()
+	defer docSetsMu.Unlock()
+	return activeSet
+}
+
+// DocSets returns available doc sets from the newest to the oldest
+func DocSets() ([]*DocSet, error) {
+	if err := ensureDocSets(); err != nil {
+		return nil, err
+	}
+	docSetsMu.Lock()
+	defer docSetsMu.Unlock()
+	return slices.Clone(docSets), nil
+}
+
+// SelectDocSet returns the doc set for a vmanomaly version: the newest set not newer than the version,
+// or the oldest set if all sets are newer. The empty version selects the active set and "latest" the newest one.
+func SelectDocSet(version string) (*DocSet, error) {
+	if err := ensureDocSets(); err != nil {
+		return nil, err
+	}
+	docSetsMu.Lock()
+	defer docSetsMu.Unlock()
+	switch version {
+	case "":
+		return activeSet, nil
+	case "latest":
+		return docSets[0], nil
+	}
+	v, err := changelog.ParseVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	return pickDocSet(docSets, v), nil
+}
+
+// build indexes documentation of the set with the current extra docs unless it is already done
+func (s *DocSet) build() error {
+	s.buildMu.Lock()
+	defer s.buildMu.Unlock()
+	extras, generation := currentExtraDocs()
+	s.mu.RLock()
+	upToDate := s.built && s.generation == generation
+	s.mu.RUnlock()
+	if upToDate {
+		return nil
+	}
+
+	b, err := s.doBuild(extras)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	oldIndex, oldResources := s.index, s.resources
+	s.index, s.resources, s.vectorURIs, s.vectors = b.index, b.resources, b.vectorURIs, b.vectors
+	s.generation, s.built = generation, true
+	s.mu.Unlock()
+	if oldIndex != nil {
+		_ = oldIndex.Close()
+	}
+
+	contentsMu.Lock()
+	imagesMu.Lock()
+	defer contentsMu.Unlock()
+	defer imagesMu.Unlock()
+	for uri := range oldResources {
+		delete(contents, uri)
+		delete(images, uri)
+	}
+	maps.Copy(contents, b.contents)
+	maps.Copy(images, b.images)
+	return nil
+}
+
+// ErrNoResults is returned by documentation search if nothing matches the query
+var ErrNoResults = errors.New("no results found for query")
+
+// DocHit is a documentation search hit
+type DocHit struct {
+	Resource mcp.Resource
+	// Score is the full-text relevance score, or the reciprocal rank fusion score in hybrid search
+	Score float64
+	// Fragments are highlighted fragments of the chunk content matching the query
+	Fragments []string
+}
+
+// search searches the set index. If the set has embeddings, full-text and vector rankings are fused
+// with reciprocal rank fusion.
+func (s *DocSet) search(ctx context.Context, text string, limit int) ([]DocHit, error) {
+	var queryVector []float32
+	s.mu.RLock()
+	hybrid := len(s.vectors) > 0
+	s.mu.RUnlock()
+	if _, embedder := indexOptions(); hybrid && embedder != nil {
+		vectors, err := embedder.Embed(ctx, []string{text})
+		if err != nil {
+			log.Printf("error embedding docs query, falling back to full-text search: %v\n", err)
+		} else {
+			queryVector = vectors[0]
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	size := limit
+	if queryVector != nil {
+		size = max(limit, hybridCandidates)
+	}
+	searchRequest := bleve.NewSearchRequest(newDocsQuery(text))
+	searchRequest.Size = size
+	searchRequest.Highlight = bleve.NewHighlight()
+	searchRequest.Highlight.AddField("content")
+	searchResults, err := s.index.Search(searchRequest)
+	if err != nil {
+		return nil, fmt.Errorf("error searching index: %w", err)
+	}
+
+	var ranked []embedding.Ranked
+	fragments := make(map[string][]string, len(searchResults.Hits))
+	if queryVector == nil {
+		for _, hit := range searchResults.Hits {
+			ranked = append(ranked, embedding.Ranked{ID: hit.ID, Score: hit.Score})
+			fragments[hit.ID] = hit.Fragments["content"]
+		}
+	} else {
+		textIDs := make([]string, 0, len(searchResults.Hits))
+		for _, hit := range searchResults.Hits {
+			textIDs = append(textIDs, hit.ID)
+			fragments[hit.ID] = hit.Fragments["content"]
+		}
+		var vectorIDs []string
+		for _, m := range embedding.Nearest(queryVector, s.vectors, size) {
+			vectorIDs = append(vectorIDs, s.vectorURIs[m.Index])
+		}
+		ranked = embedding.Fuse(embedding.DefaultRRFK, textIDs, vectorIDs)
+	}
+	if len(ranked) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoResults, text)
+	}
+
+	results := make([]DocHit, 0, min(limit, len(ranked)))
+	for _, r := range ranked {
+		if len(results) >= limit {
+			break
+		}
+		resource, ok := s.resources[r.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, DocHit{Resource: resource, Score: r.Score, Fragments: fragments[r.ID]})
+	}
+	return results, nil
+}
+
+// close closes the set index
+func (s *DocSet) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index != nil {
+		_ = s.index.Close()
+		s.index, s.built = nil, false
+	}
+}
+
+// Resources returns documentation resources of the set
+func (s *DocSet) Resources() []mcp.Resource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Collect(maps.Values(s.resources))
+}
+
+func (s *DocSet) doBuild(extras []extraFile) (*builtDocSet, error) {
+	docFiles, err := listDocFiles(s.fsys, s.root, s.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("error listing docs files of %s: %w", s.Version, err)
+	}
+	if len(extras) > 0 {
+		replaced := map[string]bool{}
+		var extraDocs []DocFileInfo
+		for _, f := range extras {
+			docPath := path.Join(s.prefix, f.path)
+			chunks, err := chunkDocFile(docPath, f.content, f.source)
+			if err != nil {
+				return nil, fmt.Errorf("error splitting file %s of %s: %w", f.path, f.source, err)
+			}
+			replaced[docPath] = true
+			extraDocs = append(extraDocs, chunks...)
+		}
+		docFiles = slices.DeleteFunc(docFiles, func(d DocFileInfo) bool { return replaced[d.Path] })
+		docFiles = append(docFiles, extraDocs...)
+	}
+
+	cacheDir, embedder := indexOptions()
+	index, err := newDocsIndex(docFiles, cacheDir, s.Version.String())
+	if err != nil {
+		return nil, err
+	}
+	b := &builtDocSet{
+		index:     index,
+		resources: make(map[string]mcp.Resource, len(docFiles)),
+		contents:  make(map[string]mcp.ResourceContents, len(docFiles)),
+	}
+	for _, docFile := range docFiles {
+		resourceURI := docResourceURI(docFile)
+		resource := mcp.NewResource(
+			resourceURI,
+			docFile.Name,
+			mcp.WithMIMEType("text/markdown"),
+			mcp.WithResourceDescription(docFile.Content[:min(len(docFile.Content), maxMarkdownDescriptionSize)]),
+		)
+		resource.Meta = &mcp.Meta{AdditionalFields: map[string]any{"source": docFile.Source}}
+		b.resources[resourceURI] = resource
+		b.contents[resourceURI] = mcp.TextResourceContents{
+			Meta:     map[string]any{"source": docFile.Source},
+			URI:      resourceURI,
+			MIMEType: "text/markdown",
+			Text:     docFile.Content,
+		}
+	}
+
+	imageResources, images, err := listDocImages(s.fsys, s.root, s.prefix, docFiles)
+	if err != nil {
+		return nil, fmt.Errorf("error listing docs images of %s: %w", s.Version, err)
+	}
+	maps.Copy(b.resources, imageResources)
+	b.images = images
+
+	if embedder != nil {
+		// Hybrid search is an improvement over full-text search, so the set is still usable without embeddings
+		vectors, err := s.embed(embedder, docFiles, cacheDir)
+		if err != nil {
+			log.Printf("error embedding %s docs, using full-text search only: %v\n", s.Version, err)
+		} else {
+			b.vectors = vectors
+			for _, docFile := range docFiles {
+				b.vectorURIs = append(b.vectorURIs, docResourceURI(docFile))
+			}
+		}
+	}
+	return b, nil
+}
+
+// embed returns embeddings of chunks. Embeddings are taken from embeddings.json of the set and the cache dir
+// if possible, computed embeddings are saved to the cache dir.
+func (s *DocSet) embed(embedder embedding.Embedder, docFiles []DocFileInfo, cacheDir string) ([][]float32, error) {
+	texts := make([]string, len(docFiles))
+	for i, docFile := range docFiles {
+		texts[i] = docFile.Name + "\n\n" + docFile.Content
+	}
+	shipped, err := embedding.ReadStore(s.fsys, path.Join(s.root, embedding.StoreFile))
+	if err != nil {
+		return nil, err
+	}
+	var cached *embedding.Store
+	cacheFile := s.Version.String() + "-" + embedding.StoreFile
+	if cacheDir != "" {
+		if cached, err = embedding.ReadStore(os.DirFS(cacheDir), cacheFile); err != nil {
+			log.Printf("ignoring broken embeddings cache: %v\n", err)
+		}
+	}
+	vectors, computed, err := embedding.EmbedAll(context.Background(), embedder, texts, shipped, cached)
+	if err != nil {
+		return nil, err
+	}
+	if computed && cacheDir != "" {
+		if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+			return nil, fmt.Errorf("error creating docs index cache dir: %w", err)
+		}
+		if err := embedding.NewStore(embedder.Model(), texts, vectors).WriteFile(filepath.Join(cacheDir, cacheFile)); err != nil {
+			log.Printf("error caching embeddings: %v\n", err)
+		}
+	}
+	return vectors, nil
+}
+
+// BuildIndexes builds search indexes of all doc sets, it is used to fill IndexCacheDir in advance
+func BuildIndexes() error {
+	sets, err := DocSets()
+	if err != nil {
+		return err
+	}
+	for _, set := range sets {
+		if err := set.build(); err != nil {
+			return fmt.Errorf("error building index of %s docs: %w", set.Version, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package resources
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDocSets(t *testing.T) {
+	dir := t.TempDir()
+	docDir := filepath.Join(dir, "v1.10.0", "anomaly-detection")
+	if err := os.MkdirAll(docDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(docDir, "README.md"), []byte("# Old docs\n\nThe legacy zwiebelmodel parameter.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "drafts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := InitDocs(DocsOptions{}); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if err := InitDocs(DocsOptions{Version: "v1.12.3", VersionsDir: dir}); err != nil {
+		t.Fatalf("InitDocs() error = %v", err)
+	}
+	sets, err := DocSets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sets) != 2 || sets[1].Version.String() != "v1.10.0" || sets[1].Source != dir {
+		t.Fatalf("DocSets() = %+v", sets)
+	}
+
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"", "v1.10.0"},
+		{"1.11", "v1.10.0"},
+		{"v1.0.0", "v1.10.0"},
+		{"latest", sets[0].Version.String()},
+		{"v99.0.0", sets[0].Version.String()},
+	}
+	for _, tt := range tests {
+		set, err := SelectDocSet(tt.version)
+		if err != nil {
+			t.Fatalf("SelectDocSet(%q) error = %v", tt.version, err)
+		}
+		if set.Version.String() != tt.want {
+			t.Errorf("SelectDocSet(%q) = %s, want %s", tt.version, set.Version, tt.want)
+		}
+	}
+
+	rs, version, err := SearchDocResources(context.Background(), "zwiebelmodel", "", 10)
+	if err != nil {
+		t.Fatalf("SearchDocResources() error = %v", err)
+	}
+	if version != "v1.10.0" || len(rs) != 1 || !strings.HasPrefix(rs[0].Resource.URI, "docs://docs/versions/v1.10.0/anomaly-detection/README.md") {
+		t.Errorf("SearchDocResources() = %v %+v", version, rs)
+	}
+	if _, err := GetDocResourceContent(rs[0].Resource.URI); err != nil {
+		t.Errorf("GetDocResourceContent() error = %v", err)
+	}
+	if _, _, err := SearchDocResources(context.Background(), "zwiebelmodel", "latest", 10); err == nil {
+		t.Error("expected no results in the latest docs")
+	}
+}
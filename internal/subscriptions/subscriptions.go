@@ -0,0 +1,270 @@
+// Package subscriptions implements MCP resource subscriptions for task resources (vmanomaly://tasks/{task_id}).
+//
+// Subscribed tasks are polled with GetTaskStatus in the background, and sessions subscribed to a task receive
+// notifications/resources/updated when its status or progress changes. Polling stops when a task finishes.
+//
+// mcp-go does not route resources/subscribe and resources/unsubscribe requests, so they are intercepted
+// by transports before the MCP server: see Manager.Stdio and Manager.Middleware.
+package subscriptions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+// TaskURIPrefix is the prefix of subscribable task resource URIs
+const TaskURIPrefix = "vmanomaly://tasks/"
+
+// Defaults of Options
+const (
+	DefaultPollInterval  = 5 * time.Second
+	DefaultMaxPerSession = 10
+	DefaultMaxPollRate   = 5
+)
+
+// finalStatuses are statuses of finished tasks, they are not polled anymore
+var finalStatuses = []string{"done", "error", "canceled"}
+
+// ErrSessionGone is returned by NotifyFunc if the session does not exist anymore, its subscriptions are removed
+var ErrSessionGone = errors.New("session does not exist")
+
+// StatusFunc returns the status of a task
+type StatusFunc func(ctx context.Context, taskID string) (*vmanomaly.AnomalyDetectionTaskStatus, error)
+
+// NotifyFunc sends notifications/resources/updated for the resource URI to the session
+type NotifyFunc func(sessionID, uri string) error
+
+// Options are limits of subscriptions
+type Options struct {
+	// PollInterval is the interval between polls of every subscribed task
+	PollInterval time.Duration
+	// MaxPerSession is the maximum number of subscriptions of a session
+	MaxPerSession int
+	// MaxPollRate is the maximum number of task status requests per second of all sessions
+	MaxPollRate float64
+}
+
+// taskState is the last seen state of a subscribed task
+type taskState struct {
+	status   string
+	progress int
+	finished bool
+}
+
+// Manager tracks subscriptions of sessions and polls subscribed tasks
+type Manager struct {
+	status StatusFunc
+	notify NotifyFunc
+	opts   Options
+
+	mu       sync.Mutex
+	sessions map[string]map[string]bool // Session ID -> subscribed task IDs
+	tasks    map[string]*taskState      // Task ID -> last seen state
+}
+
+// NewManager returns a subscription manager, zero options are replaced with defaults
+func NewManager(status StatusFunc, notify NotifyFunc, opts Options) *Manager {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.MaxPerSession <= 0 {
+		opts.MaxPerSession = DefaultMaxPerSession
+	}
+	if opts.MaxPollRate <= 0 {
+		opts.MaxPollRate = DefaultMaxPollRate
+	}
+	return &Manager{
+		status:   status,
+		notify:   notify,
+		opts:     opts,
+		sessions: map[string]map[string]bool{},
+		tasks:    map[string]*taskState{},
+	}
+}
+
+// taskID returns the task ID of a task resource URI
+func taskID(uri string) (string, error) {
+	id, ok := strings.CutPrefix(uri, TaskURIPrefix)
+	if !ok || id == "" || strings.ContainsAny(id, "/?#") {
+		return "", fmt.Errorf("only task resources (%s{task_id}) support subscriptions, got %q", TaskURIPrefix, uri)
+	}
+	return id, nil
+}
+
+// Subscribe subscribes the session to updates of a task resource. The current task state is fetched
+// to check the task exists and to detect following changes.
+func (m *Manager) Subscribe(ctx context.Context, sessionID, uri string) error {
+	id, err := taskID(uri)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	subscribed := m.sessions[sessionID]
+	if subscribed[id] {
+		m.mu.Unlock()
+		return nil
+	}
+	if len(subscribed) >= m.opts.MaxPerSession {
+		m.mu.Unlock()
+		return fmt.Errorf("too many subscriptions: a session can subscribe to at most %d tasks", m.opts.MaxPerSession)
+	}
+	_, known := m.tasks[id]
+	m.mu.Unlock()
+
+	var state *taskState
+	if !known {
+		status, err := m.status(ctx, id)
+		if err != nil {
+			return fmt.Errorf("cannot get status of task %s: %w", id, err)
+		}
+		state = newTaskState(status)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sessions[sessionID] == nil {
+		m.sessions[sessionID] = map[string]bool{}
+	}
+	m.sessions[sessionID][id] = true
+	if _, ok := m.tasks[id]; !ok && state != nil {
+		m.tasks[id] = state
+	}
+	return nil
+}
+
+// Unsubscribe removes a subscription of the session, unknown subscriptions are ignored
+func (m *Manager) Unsubscribe(sessionID, uri string) error {
+	id, err := taskID(uri)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions[sessionID], id)
+	if len(m.sessions[sessionID]) == 0 {
+		delete(m.sessions, sessionID)
+	}
+	m.forgetUnsubscribed()
+	return nil
+}
+
+// RemoveSession removes all subscriptions of the session
+func (m *Manager) RemoveSession(sessionID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, sessionID)
+	m.forgetUnsubscribed()
+}
+
+// forgetUnsubscribed removes states of tasks without subscribers. m.mu must be held.
+func (m *Manager) forgetUnsubscribed() {
+	for id := range m.tasks {
+		if len(m.subscribers(id)) == 0 {
+			delete(m.tasks, id)
+		}
+	}
+}
+
+// subscribers returns sorted IDs of sessions subscribed to the task. m.mu must be held.
+func (m *Manager) subscribers(id string) []string {
+	var sessions []string
+	for sessionID, tasks := range m.sessions {
+		if tasks[id] {
+			sessions = append(sessions, sessionID)
+		}
+	}
+	slices.Sort(sessions)
+	return sessions
+}
+
+// Subscriptions returns the number of subscriptions of the session
+func (m *Manager) Subscriptions(sessionID string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sessions[sessionID])
+}
+
+// Run polls subscribed tasks every poll interval until ctx is done
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Poll(ctx)
+		}
+	}
+}
+
+// Poll fetches statuses of unfinished subscribed tasks, spacing requests according to the poll rate limit,
+// and notifies subscribers of changed tasks
+func (m *Manager) Poll(ctx context.Context) {
+	m.mu.Lock()
+	var ids []string
+	for id, state := range m.tasks {
+		if !state.finished {
+			ids = append(ids, id)
+		}
+	}
+	m.mu.Unlock()
+	slices.Sort(ids)
+
+	spacing := time.Duration(float64(time.Second) / m.opts.MaxPollRate)
+	for i, id := range ids {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(spacing):
+			}
+		}
+		status, err := m.status(ctx, id)
+		if err != nil {
+			slog.Debug("Failed to poll subscribed task", "task_id", id, "error", err)
+			continue
+		}
+		m.update(id, newTaskState(status))
+	}
+}
+
+// update stores the new state of the task and notifies subscribers if it changed
+func (m *Manager) update(id string, next *taskState) {
+	m.mu.Lock()
+	prev, ok := m.tasks[id]
+	if !ok || (prev.status == next.status && prev.progress == next.progress) {
+		m.mu.Unlock()
+		return
+	}
+	m.tasks[id] = next
+	sessions := m.subscribers(id)
+	m.mu.Unlock()
+
+	for _, sessionID := range sessions {
+		err := m.notify(sessionID, TaskURIPrefix+id)
+		if errors.Is(err, ErrSessionGone) {
+			m.RemoveSession(sessionID)
+			continue
+		}
+		if err != nil {
+			slog.Debug("Failed to notify subscriber", "session_id", sessionID, "task_id", id, "error", err)
+		}
+	}
+}
+
+func newTaskState(status *vmanomaly.AnomalyDetectionTaskStatus) *taskState {
+	return &taskState{
+		status:   status.Status,
+		progress: status.Progress,
+		finished: slices.Contains(finalStatuses, status.Status),
+	}
+}
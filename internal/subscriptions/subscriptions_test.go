@@ -0,0 +1,318 @@
+package subscriptions
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// fakeTasks serves task statuses and records notifications
+type fakeTasks struct {
+	mu       sync.Mutex
+	tasks    map[string]vmanomaly.AnomalyDetectionTaskStatus
+	polls    map[string]int
+	notified []string // session/uri
+	gone     map[string]bool
+}
+
+func newFakeTasks() *fakeTasks {
+	return &fakeTasks{
+		tasks: map[string]vmanomaly.AnomalyDetectionTaskStatus{},
+		polls: map[string]int{},
+		gone:  map[string]bool{},
+	}
+}
+
+func (f *fakeTasks) set(id, status string, progress int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tasks[id] = vmanomaly.AnomalyDetectionTaskStatus{TaskID: id, Status: status, Progress: progress}
+}
+
+func (f *fakeTasks) status(_ context.Context, id string) (*vmanomaly.AnomalyDetectionTaskStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.polls[id]++
+	task, ok := f.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("task %s not found", id)
+	}
+	return &task, nil
+}
+
+func (f *fakeTasks) notify(sessionID, uri string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.gone[sessionID] {
+		return ErrSessionGone
+	}
+	f.notified = append(f.notified, sessionID+"/"+uri)
+	return nil
+}
+
+func (f *fakeTasks) takeNotified() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	notified := f.notified
+	f.notified = nil
+	return notified
+}
+
+func TestManager(t *testing.T) {
+	f := newFakeTasks()
+	f.set("t1", "running", 10)
+	f.set("t2", "running", 0)
+	m := NewManager(f.status, f.notify, Options{MaxPerSession: 2, MaxPollRate: 1000})
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		session string
+		uri     string
+		wantErr string
+	}{
+		{name: "task", session: "a", uri: "vmanomaly://tasks/t1"},
+		{name: "same task of another session", session: "b", uri: "vmanomaly://tasks/t1"},
+		{name: "repeated subscription", session: "a", uri: "vmanomaly://tasks/t1"},
+		{name: "second task", session: "a", uri: "vmanomaly://tasks/t2"},
+		{name: "session limit", session: "a", uri: "vmanomaly://tasks/t3", wantErr: "too many subscriptions"},
+		{name: "unknown task", session: "b", uri: "vmanomaly://tasks/t3", wantErr: "cannot get status of task t3"},
+		{name: "not a task", session: "b", uri: "vmanomaly://buildinfo", wantErr: "only task resources"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.Subscribe(ctx, tt.session, tt.uri)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Subscribe() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Subscribe() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Unchanged tasks are not notified
+	m.Poll(ctx)
+	if got := f.takeNotified(); len(got) != 0 {
+		t.Fatalf("unexpected notifications: %v", got)
+	}
+
+	f.set("t1", "running", 50)
+	f.set("t2", "done", 100)
+	m.Poll(ctx)
+	want := []string{"a/vmanomaly://tasks/t1", "b/vmanomaly://tasks/t1", "a/vmanomaly://tasks/t2"}
+	if got := f.takeNotified(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("notifications = %v, want %v", got, want)
+	}
+
+	// Finished tasks are not polled anymore
+	polls := f.polls["t2"]
+	m.Poll(ctx)
+	if f.polls["t2"] != polls {
+		t.Error("finished task must not be polled")
+	}
+
+	// Sessions which are gone are removed on notification
+	f.gone["b"] = true
+	f.set("t1", "canceled", 50)
+	m.Poll(ctx)
+	if got := f.takeNotified(); len(got) != 1 || got[0] != "a/vmanomaly://tasks/t1" {
+		t.Fatalf("notifications = %v", got)
+	}
+	if n := m.Subscriptions("b"); n != 0 {
+		t.Errorf("gone session has %d subscriptions", n)
+	}
+
+	if err := m.Unsubscribe("a", "vmanomaly://tasks/t1"); err != nil {
+		t.Fatalf("Unsubscribe() error = %v", err)
+	}
+	if n := m.Subscriptions("a"); n != 1 {
+		t.Errorf("Subscriptions() = %d after unsubscribe, want 1", n)
+	}
+	m.RemoveSession("a")
+	if n := len(m.tasks); n != 0 {
+		t.Errorf("%d tasks are tracked without subscribers", n)
+	}
+}
+
+func TestPollRate(t *testing.T) {
+	f := newFakeTasks()
+	m := NewManager(f.status, f.notify, Options{MaxPollRate: 50})
+	for _, id := range []string{"t1", "t2", "t3"} {
+		f.set(id, "running", 0)
+		if err := m.Subscribe(context.Background(), "a", TaskURIPrefix+id); err != nil {
+			t.Fatalf("Subscribe() error = %v", err)
+		}
+	}
+	start := time.Now()
+	m.Poll(context.Background())
+	// 3 polls at 50 per second are spaced by 2 intervals of 20ms
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("3 polls took %s, want at least 40ms", elapsed)
+	}
+}
+
+func TestHandleMessage(t *testing.T) {
+	f := newFakeTasks()
+	f.set("t1", "running", 0)
+	m := NewManager(f.status, f.notify, Options{})
+
+	tests := []struct {
+		name      string
+		session   string
+		message   string
+		handled   bool
+		wantError int
+	}{
+		{name: "subscribe", session: "a", message: `{"jsonrpc":"2.0","id":1,"method":"resources/subscribe","params":{"uri":"vmanomaly://tasks/t1"}}`, handled: true},
+		{name: "unsubscribe", session: "a", message: `{"jsonrpc":"2.0","id":2,"method":"resources/unsubscribe","params":{"uri":"vmanomaly://tasks/t1"}}`, handled: true},
+		{name: "unknown task", session: "a", message: `{"jsonrpc":"2.0","id":3,"method":"resources/subscribe","params":{"uri":"vmanomaly://tasks/t2"}}`, handled: true, wantError: mcp.INVALID_PARAMS},
+		{name: "no session", message: `{"jsonrpc":"2.0","id":4,"method":"resources/subscribe","params":{"uri":"vmanomaly://tasks/t1"}}`, handled: true, wantError: mcp.INVALID_REQUEST},
+		{name: "other method", session: "a", message: `{"jsonrpc":"2.0","id":5,"method":"resources/read","params":{"uri":"vmanomaly://tasks/t1"}}`},
+		{name: "not json", session: "a", message: `resources/subscribe`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, ok := m.HandleMessage(context.Background(), tt.session, []byte(tt.message))
+			if ok != tt.handled {
+				t.Fatalf("HandleMessage() handled = %v, want %v", ok, tt.handled)
+			}
+			if !ok {
+				return
+			}
+			rpcErr, isErr := resp.(mcp.JSONRPCError)
+			if isErr != (tt.wantError != 0) || (isErr && rpcErr.Error.Code != tt.wantError) {
+				t.Errorf("HandleMessage() = %+v, want error code %d", resp, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestStdio(t *testing.T) {
+	f := newFakeTasks()
+	f.set("t1", "running", 0)
+	release := make(chan struct{})
+	status := func(ctx context.Context, id string) (*vmanomaly.AnomalyDetectionTaskStatus, error) {
+		<-release
+		return f.status(ctx, id)
+	}
+	m := NewManager(status, f.notify, Options{})
+
+	input := `{"jsonrpc":"2.0","id":1,"method":"ping"}` + "\n" +
+		`{"jsonrpc":"2.0","id":2,"method":"resources/subscribe","params":{"uri":"vmanomaly://tasks/t1"}}` + "\n" +
+		`{"jsonrpc":"2.0","id":3,"method":"resources/read","params":{"uri":"vmanomaly://tasks/t1"}}` + "\n" +
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"x","arguments":{"text":"{\"method\":\"resources/subscribe\"}"}}}` + "\n"
+	outR, outW := io.Pipe()
+	in, _ := m.Stdio(context.Background(), strings.NewReader(input), outW)
+
+	// Other messages are passed while the subscription waits for the task status
+	passed, err := io.ReadAll(in)
+	if err != nil {
+		t.Fatalf("cannot read input: %v", err)
+	}
+	if strings.Contains(string(passed), `"method":"resources/subscribe"`) || strings.Count(string(passed), "\n") != 3 {
+		t.Errorf("unexpected input passed to the server: %s", passed)
+	}
+	close(release)
+	resp, err := bufio.NewReader(outR).ReadString('\n')
+	if err != nil || !strings.Contains(resp, `"id":2,"result":{}`) {
+		t.Errorf("unexpected subscription response: %s, %v", resp, err)
+	}
+	if m.Subscriptions("stdio") != 1 {
+		t.Error("stdio session is not subscribed")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	f := newFakeTasks()
+	f.set("t1", "running", 0)
+	m := NewManager(f.status, f.notify, Options{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(append([]byte("next:"), body...))
+	})
+	srv := httptest.NewServer(m.Middleware(next))
+	defer srv.Close()
+
+	post := func(body string) string {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(body))
+		req.Header.Set(server.HeaderKeySessionID, "s1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return string(data)
+	}
+
+	ping := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+	if got := post(ping); got != "next:"+ping {
+		t.Errorf("request is not passed to the server: %s", got)
+	}
+	got := post(`{"jsonrpc":"2.0","id":2,"method":"resources/subscribe","params":{"uri":"vmanomaly://tasks/t1"}}`)
+	var resp map[string]any
+	if err := json.Unmarshal([]byte(got), &resp); err != nil || resp["result"] == nil {
+		t.Errorf("unexpected subscription response: %s", got)
+	}
+	if m.Subscriptions("s1") != 1 {
+		t.Error("session is not subscribed")
+	}
+}
+
+func TestSSEMiddleware(t *testing.T) {
+	f := newFakeTasks()
+	f.set("t1", "running", 0)
+	m := NewManager(f.status, f.notify, Options{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	var sent []any
+	send := func(sessionID string, event any) error {
+		if sessionID != "s1" {
+			return fmt.Errorf("unknown session %s", sessionID)
+		}
+		sent = append(sent, event)
+		return nil
+	}
+	srv := httptest.NewServer(m.SSEMiddleware(next, send))
+	defer srv.Close()
+
+	post := func(body string) int {
+		resp, err := http.Post(srv.URL+"/message?sessionId=s1", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post(`{"jsonrpc":"2.0","id":1,"method":"ping"}`); code != http.StatusTeapot {
+		t.Errorf("request is not passed to the server, status %d", code)
+	}
+	if code := post(`{"jsonrpc":"2.0","id":2,"method":"resources/subscribe","params":{"uri":"vmanomaly://tasks/t1"}}`); code != http.StatusAccepted {
+		t.Errorf("unexpected status of subscription request %d", code)
+	}
+	if len(sent) != 1 {
+		t.Fatalf("expected a response on the event stream, got %v", sent)
+	}
+	if data, _ := json.Marshal(sent[0]); !strings.Contains(string(data), `"id":2,"result":{}`) {
+		t.Errorf("unexpected subscription response: %s", data)
+	}
+	if m.Subscriptions("s1") != 1 {
+		t.Error("session is not subscribed")
+	}
+}
@@ -0,0 +1,196 @@
+package subscriptions
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Methods of subscription requests, they are not handled by mcp-go
+const (
+	MethodSubscribe   = "resources/subscribe"
+	MethodUnsubscribe = "resources/unsubscribe"
+)
+
+// maxRequestSize limits the size of HTTP request bodies read by Middleware
+const maxRequestSize = 10 << 20
+
+// stdioQueueSize is the number of subscription requests of the stdio transport waiting to be handled
+const stdioQueueSize = 64
+
+// request is a JSON-RPC request of subscription methods
+type request struct {
+	ID     mcp.RequestId `json:"id"`
+	Method string        `json:"method"`
+	Params struct {
+		URI string `json:"uri"`
+	} `json:"params"`
+}
+
+// parseRequest decodes a raw JSON-RPC message and reports whether it is a subscribe or unsubscribe request
+func parseRequest(raw []byte) (request, bool) {
+	var req request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return req, false
+	}
+	return req, req.Method == MethodSubscribe || req.Method == MethodUnsubscribe
+}
+
+// HandleMessage handles a raw JSON-RPC subscribe or unsubscribe request of the session.
+// The second value is false for other messages, they must be passed to the MCP server.
+func (m *Manager) HandleMessage(ctx context.Context, sessionID string, raw []byte) (mcp.JSONRPCMessage, bool) {
+	req, ok := parseRequest(raw)
+	if !ok {
+		return nil, false
+	}
+	return m.handleRequest(ctx, sessionID, req), true
+}
+
+func (m *Manager) handleRequest(ctx context.Context, sessionID string, req request) mcp.JSONRPCMessage {
+	if sessionID == "" {
+		return mcp.NewJSONRPCError(req.ID, mcp.INVALID_REQUEST, "subscriptions require a session", nil)
+	}
+	if req.Params.URI == "" {
+		return mcp.NewJSONRPCError(req.ID, mcp.INVALID_PARAMS, "uri is required", nil)
+	}
+	var err error
+	if req.Method == MethodSubscribe {
+		err = m.Subscribe(ctx, sessionID, req.Params.URI)
+	} else {
+		err = m.Unsubscribe(sessionID, req.Params.URI)
+	}
+	if err != nil {
+		return mcp.NewJSONRPCError(req.ID, mcp.INVALID_PARAMS, err.Error(), nil)
+	}
+	return mcp.NewJSONRPCResultResponse(req.ID, mcp.EmptyResult{})
+}
+
+// lockedWriter serializes writes of the stdio server and of subscription responses
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// Stdio wraps input and output of the stdio transport: subscription requests are removed from the input
+// and answered directly to the output, other lines are passed to the returned reader unchanged.
+// The session of the stdio transport is "stdio".
+func (m *Manager) Stdio(ctx context.Context, in io.Reader, out io.Writer) (io.Reader, io.Writer) {
+	const sessionID = "stdio"
+	pr, pw := io.Pipe()
+	w := &lockedWriter{w: out}
+
+	// Subscription requests are handled in order by a separate goroutine, so the first status request
+	// of a subscription to a slow vmanomaly does not hold other messages of the session
+	reqs := make(chan request, stdioQueueSize)
+	go func() {
+		for req := range reqs {
+			writeResponse(w, m.handleRequest(ctx, sessionID, req))
+		}
+	}()
+	go func() {
+		defer close(reqs)
+		reader := bufio.NewReader(in)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				if req, ok := parseRequest(line); ok {
+					reqs <- req
+				} else if _, werr := pw.Write(line); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	return pr, w
+}
+
+// writeResponse writes a JSON-RPC response as a single line
+func writeResponse(w io.Writer, resp mcp.JSONRPCMessage) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		slog.Error("Failed to encode subscription response", "error", err)
+		return
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		slog.Error("Failed to write subscription response", "error", err)
+	}
+}
+
+// Middleware answers subscription requests of the streamable HTTP transport, sessions are identified
+// by the Mcp-Session-Id header. Other requests are passed to next.
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return m.intercept(next, func(w http.ResponseWriter, r *http.Request, req request) {
+		resp := m.handleRequest(r.Context(), r.Header.Get(server.HeaderKeySessionID), req)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			slog.Error("Failed to write subscription response", "error", err)
+		}
+	})
+}
+
+// SSEMiddleware answers subscription requests sent to the message endpoint of the SSE transport, sessions are
+// identified by the sessionId query parameter. Like the SSE transport does, the request is accepted with
+// 202 Accepted and the response is sent to the event stream of the session with send, e.g. SSEServer.SendEventToSession.
+// Other requests are passed to next.
+func (m *Manager) SSEMiddleware(next http.Handler, send func(sessionID string, event any) error) http.Handler {
+	return m.intercept(next, func(w http.ResponseWriter, r *http.Request, req request) {
+		sessionID := r.URL.Query().Get("sessionId")
+		resp := m.handleRequest(r.Context(), sessionID, req)
+		w.WriteHeader(http.StatusAccepted)
+		if err := send(sessionID, resp); err != nil {
+			slog.Error("Failed to send subscription response", "session", sessionID, "error", err)
+		}
+	})
+}
+
+// intercept passes subscription requests posted to the handler to handle and other requests to next
+func (m *Manager) intercept(next http.Handler, handle func(w http.ResponseWriter, r *http.Request, req request)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+		_ = r.Body.Close()
+		if err != nil {
+			http.Error(w, "cannot read request body", http.StatusBadRequest)
+			return
+		}
+		if req, ok := parseRequest(body); ok {
+			handle(w, r, req)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ServerNotifier returns a NotifyFunc sending notifications/resources/updated to sessions of the MCP server
+func ServerNotifier(s *server.MCPServer) NotifyFunc {
+	return func(sessionID, uri string) error {
+		err := s.SendNotificationToSpecificClient(sessionID, string(mcp.MethodNotificationResourceUpdated), map[string]any{"uri": uri})
+		if errors.Is(err, server.ErrSessionNotFound) {
+			return ErrSessionGone
+		}
+		return err
+	}
+}
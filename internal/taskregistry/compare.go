@@ -0,0 +1,121 @@
+package taskregistry
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// maxCompareDepth limits nesting of compared result objects, deeper values are compared as a whole
+const maxCompareDepth = 4
+
+// maxListValues is the maximum length of differing lists whose values are returned
+const maxListValues = 10
+
+// Difference is a field with different values in two tasks
+type Difference struct {
+	Field string   `json:"field"`
+	A     any      `json:"a"`
+	B     any      `json:"b"`
+	Delta *float64 `json:"delta,omitempty"` // B - A for numbers
+}
+
+// Comparison lists differences of requests and results of two tasks
+type Comparison struct {
+	TaskA              string       `json:"task_a"`
+	TaskB              string       `json:"task_b"`
+	StatusA            string       `json:"status_a"`
+	StatusB            string       `json:"status_b"`
+	RequestDifferences []Difference `json:"request_differences"`
+	ResultDifferences  []Difference `json:"result_differences"`
+	Notes              []string     `json:"notes,omitempty"`
+}
+
+// Compare returns differences of requests and results of two tasks. Nested objects are compared by field,
+// arrays are compared by length (len(field)) and, if lengths are equal, by value.
+func Compare(a, b *Record) Comparison {
+	c := Comparison{
+		TaskA:              a.TaskID,
+		TaskB:              b.TaskID,
+		StatusA:            a.Status,
+		StatusB:            b.Status,
+		RequestDifferences: []Difference{},
+		ResultDifferences:  []Difference{},
+	}
+	c.RequestDifferences = diff(c.RequestDifferences, "", toJSONValue(a.Request), toJSONValue(b.Request), 0)
+	switch {
+	case a.Result == nil || b.Result == nil:
+		for _, rec := range []*Record{a, b} {
+			if rec.Result == nil {
+				c.Notes = append(c.Notes, fmt.Sprintf("task %s has no cached result (status %q)", rec.TaskID, rec.Status))
+			}
+		}
+	default:
+		c.ResultDifferences = diff(c.ResultDifferences, "", toJSONValue(a.Result), toJSONValue(b.Result), 0)
+	}
+	return c
+}
+
+// toJSONValue returns v decoded from its JSON encoding, so values of both tasks have the same types
+func toJSONValue(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil
+	}
+	return decoded
+}
+
+// diff appends differences of JSON values a and b at path to diffs
+func diff(diffs []Difference, path string, a, b any, depth int) []Difference {
+	ma, aIsMap := a.(map[string]any)
+	mb, bIsMap := b.(map[string]any)
+	if aIsMap && bIsMap && depth < maxCompareDepth {
+		keys := make([]string, 0, len(ma)+len(mb))
+		for k := range ma {
+			keys = append(keys, k)
+		}
+		for k := range mb {
+			if _, ok := ma[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			field := k
+			if path != "" {
+				field = path + "." + k
+			}
+			diffs = diff(diffs, field, ma[k], mb[k], depth+1)
+		}
+		return diffs
+	}
+
+	la, aIsList := a.([]any)
+	lb, bIsList := b.([]any)
+	if aIsList && bIsList && len(la) != len(lb) {
+		delta := float64(len(lb) - len(la))
+		return append(diffs, Difference{Field: "len(" + path + ")", A: len(la), B: len(lb), Delta: &delta})
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return diffs
+	}
+	if aIsList && bIsList && len(la) > maxListValues {
+		// Long lists, e.g. series points, are not copied to the output
+		summary := fmt.Sprintf("%d items", len(la))
+		return append(diffs, Difference{Field: path, A: summary, B: summary + ", differ"})
+	}
+	d := Difference{Field: path, A: a, B: b}
+	if na, ok := a.(float64); ok {
+		if nb, ok := b.(float64); ok {
+			delta := nb - na
+			d.Delta = &delta
+		}
+	}
+	return append(diffs, d)
+}
@@ -0,0 +1,41 @@
+package taskregistry
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+func TestCompare(t *testing.T) {
+	a := &Record{
+		TaskID:  "t1",
+		Status:  "done",
+		Request: &vmanomaly.AnomalyDetectionTaskRequest{Query: "up", Step: "1m", ModelSpec: map[string]any{"class": "zscore", "z_threshold": 2.5}},
+		Result:  &vmanomaly.TaskResult{Status: "success", Stats: map[string]any{"anomalies": 4, "series": 2}, Data: map[string]any{"scores": make([]any, 20)}},
+	}
+	b := &Record{
+		TaskID:  "t2",
+		Status:  "done",
+		Request: &vmanomaly.AnomalyDetectionTaskRequest{Query: "up", Step: "1m", ModelSpec: map[string]any{"class": "zscore", "z_threshold": 3}},
+		Result:  &vmanomaly.TaskResult{Status: "success", Stats: map[string]any{"anomalies": 1, "series": 2}, Data: map[string]any{"scores": make([]any, 30)}},
+	}
+
+	c := Compare(a, b)
+	if len(c.RequestDifferences) != 1 || c.RequestDifferences[0].Field != "model_spec.z_threshold" || *c.RequestDifferences[0].Delta != 0.5 {
+		t.Errorf("RequestDifferences = %+v", c.RequestDifferences)
+	}
+	want := map[string]float64{"len(data.scores)": 10, "stats.anomalies": -3}
+	if len(c.ResultDifferences) != len(want) {
+		t.Fatalf("ResultDifferences = %+v", c.ResultDifferences)
+	}
+	for _, d := range c.ResultDifferences {
+		if delta, ok := want[d.Field]; !ok || d.Delta == nil || *d.Delta != delta {
+			t.Errorf("unexpected difference %+v", d)
+		}
+	}
+
+	b.Result, b.Status = nil, "running"
+	if c := Compare(a, b); len(c.ResultDifferences) != 0 || len(c.Notes) != 1 {
+		t.Errorf("tasks without results must be noted, got %+v", c)
+	}
+}
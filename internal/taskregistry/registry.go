@@ -0,0 +1,200 @@
+// Package taskregistry keeps a local history of anomaly detection tasks created through the MCP server:
+// their requests, submitting sessions, timestamps and final results. vmanomaly forgets tasks and their
+// results on restarts, the registry keeps them to re-open, compare and re-run past tasks.
+package taskregistry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+// DefaultMaxTasks is the default number of records kept in the registry
+const DefaultMaxTasks = 500
+
+// finalStatuses are statuses of finished tasks, their records are not updated anymore
+var finalStatuses = []string{"done", "error", "canceled"}
+
+// Record is a task created through the MCP server
+type Record struct {
+	TaskID     string                                 `json:"task_id"`
+	Label      string                                 `json:"label,omitempty"`
+	SessionID  string                                 `json:"session_id,omitempty"`
+	RerunOf    string                                 `json:"rerun_of,omitempty"` // ID of the task this one re-runs
+	Request    *vmanomaly.AnomalyDetectionTaskRequest `json:"request"`
+	Status     string                                 `json:"status"`
+	Progress   int                                    `json:"progress"`
+	Message    string                                 `json:"message,omitempty"`
+	Error      *string                                `json:"error,omitempty"`
+	Result     *vmanomaly.TaskResult                  `json:"result,omitempty"` // Final result, cached when the task is done
+	CreatedAt  time.Time                              `json:"created_at"`
+	UpdatedAt  time.Time                              `json:"updated_at"`
+	FinishedAt *time.Time                             `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the task reached a final status
+func (r *Record) Finished() bool {
+	return slices.Contains(finalStatuses, r.Status)
+}
+
+// TaskStatus returns the recorded state in the shape of vmanomaly task status
+func (r *Record) TaskStatus() *vmanomaly.AnomalyDetectionTaskStatus {
+	startedAt := r.CreatedAt.Format(time.RFC3339)
+	return &vmanomaly.AnomalyDetectionTaskStatus{
+		TaskID:     r.TaskID,
+		Status:     r.Status,
+		Progress:   r.Progress,
+		Message:    r.Message,
+		StartedAt:  &startedAt,
+		UpdatedAt:  r.UpdatedAt.Format(time.RFC3339),
+		ResultData: r.Result,
+		Error:      r.Error,
+	}
+}
+
+// clone returns a deep copy of the record
+func (r *Record) clone() *Record {
+	data, err := json.Marshal(r)
+	if err != nil {
+		panic(fmt.Errorf("BUG: cannot encode task record: %w", err))
+	}
+	c := &Record{}
+	if err := json.Unmarshal(data, c); err != nil {
+		panic(fmt.Errorf("BUG: cannot decode task record: %w", err))
+	}
+	return c
+}
+
+// Filter selects records of List, empty fields match all records
+type Filter struct {
+	Label     string
+	Status    string
+	SessionID string
+	Limit     int
+}
+
+// Registry records tasks in a store
+type Registry struct {
+	store    Store
+	maxTasks int
+	now      func() time.Time
+
+	// mu serializes read-modify-write updates of records
+	mu sync.Mutex
+}
+
+// New returns a registry keeping at most maxTasks records in store, the oldest records are removed first
+func New(store Store, maxTasks int) *Registry {
+	if maxTasks <= 0 {
+		maxTasks = DefaultMaxTasks
+	}
+	return &Registry{store: store, maxTasks: maxTasks, now: time.Now}
+}
+
+// Add records a created task
+func (r *Registry) Add(rec *Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now().UTC()
+	rec.CreatedAt = now
+	rec.UpdatedAt = now
+	if rec.Status == "" {
+		rec.Status = "running"
+	}
+	if err := r.store.Put(rec); err != nil {
+		return fmt.Errorf("cannot record task %s: %w", rec.TaskID, err)
+	}
+	return r.prune()
+}
+
+// prune removes the oldest records over the limit. r.mu must be held.
+func (r *Registry) prune() error {
+	records, err := r.store.List()
+	if err != nil {
+		return err
+	}
+	for _, rec := range records[min(len(records), r.maxTasks):] {
+		if err := r.store.Delete(rec.TaskID); err != nil {
+			return fmt.Errorf("cannot remove task %s: %w", rec.TaskID, err)
+		}
+	}
+	return nil
+}
+
+// Task returns the record of a task by its ID or ErrNotFound
+func (r *Registry) Task(taskID string) (*Record, error) {
+	return r.store.Get(taskID)
+}
+
+// Get returns a record by task ID or, if there is no such task, the most recent record with the label
+func (r *Registry) Get(ref string) (*Record, error) {
+	rec, err := r.Task(ref)
+	if !errors.Is(err, ErrNotFound) {
+		return rec, err
+	}
+	records, err := r.List(Filter{Label: ref, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no task with ID or label %q in the registry", ref)
+	}
+	return records[0], nil
+}
+
+// List returns records matching the filter, the most recently created first
+func (r *Registry) List(f Filter) ([]*Record, error) {
+	records, err := r.store.List()
+	if err != nil {
+		return nil, err
+	}
+	matched := records[:0]
+	for _, rec := range records {
+		if (f.Label == "" || rec.Label == f.Label) && (f.Status == "" || rec.Status == f.Status) &&
+			(f.SessionID == "" || rec.SessionID == f.SessionID) {
+			matched = append(matched, rec)
+		}
+	}
+	if f.Limit > 0 && len(matched) > f.Limit {
+		matched = matched[:f.Limit]
+	}
+	return matched, nil
+}
+
+// Update records the status of a task fetched from vmanomaly, the result is cached when the task finishes.
+// Finished and unknown tasks are not updated, the returned record is nil for unknown tasks.
+func (r *Registry) Update(status *vmanomaly.AnomalyDetectionTaskStatus) (*Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec, err := r.store.Get(status.TaskID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil || rec.Finished() {
+		return rec, err
+	}
+	now := r.now().UTC()
+	rec.Status = status.Status
+	rec.Progress = status.Progress
+	rec.Message = status.Message
+	rec.Error = status.Error
+	rec.UpdatedAt = now
+	if rec.Finished() {
+		rec.Result = status.ResultData
+		rec.FinishedAt = &now
+	}
+	if err := r.store.Put(rec); err != nil {
+		return nil, fmt.Errorf("cannot update task %s: %w", rec.TaskID, err)
+	}
+	return rec, nil
+}
+
+// Close closes the store
+func (r *Registry) Close() error {
+	return r.store.Close()
+}
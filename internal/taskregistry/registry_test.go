@@ -0,0 +1,119 @@
+package taskregistry
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+func TestRegistry(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return NewMemoryStore() },
+		"bolt": func(t *testing.T) Store {
+			s, err := OpenBoltStore(filepath.Join(t.TempDir(), "tasks.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			r := New(newStore(t), 3)
+			defer r.Close()
+			now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			r.now = func() time.Time {
+				now = now.Add(time.Minute)
+				return now
+			}
+
+			for _, rec := range []*Record{
+				{TaskID: "t1", Label: "zscore", SessionID: "a", Request: &vmanomaly.AnomalyDetectionTaskRequest{Query: "up"}},
+				{TaskID: "t2", Label: "zscore", SessionID: "b"},
+				{TaskID: "t3", Label: "mad", SessionID: "a"},
+			} {
+				if err := r.Add(rec); err != nil {
+					t.Fatalf("Add() error = %v", err)
+				}
+			}
+
+			if rec, err := r.Get("zscore"); err != nil || rec.TaskID != "t2" {
+				t.Errorf("Get(label) must return the most recent task, got %+v, %v", rec, err)
+			}
+			if rec, err := r.Get("t1"); err != nil || rec.Request.Query != "up" || rec.Status != "running" {
+				t.Errorf("Get(id) = %+v, %v", rec, err)
+			}
+			if _, err := r.Get("prophet"); err == nil {
+				t.Error("expected error for unknown task")
+			}
+			if recs, _ := r.List(Filter{SessionID: "a"}); len(recs) != 2 || recs[0].TaskID != "t3" {
+				t.Errorf("List(session) = %+v", recs)
+			}
+
+			// Results are cached when tasks finish, finished tasks are not updated anymore
+			result := &vmanomaly.TaskResult{Status: "success", Stats: map[string]any{"anomalies": 3.0}}
+			if rec, err := r.Update(&vmanomaly.AnomalyDetectionTaskStatus{TaskID: "t1", Status: "running", Progress: 50}); err != nil || rec.Progress != 50 || rec.Result != nil {
+				t.Errorf("Update(running) = %+v, %v", rec, err)
+			}
+			if rec, err := r.Update(&vmanomaly.AnomalyDetectionTaskStatus{TaskID: "t1", Status: "done", Progress: 100, ResultData: result}); err != nil || rec.FinishedAt == nil || rec.Result == nil {
+				t.Errorf("Update(done) = %+v, %v", rec, err)
+			}
+			if rec, _ := r.Update(&vmanomaly.AnomalyDetectionTaskStatus{TaskID: "t1", Status: "error"}); rec.Status != "done" {
+				t.Errorf("finished task must not be updated, got status %s", rec.Status)
+			}
+			if rec, err := r.Update(&vmanomaly.AnomalyDetectionTaskStatus{TaskID: "unknown", Status: "done"}); rec != nil || err != nil {
+				t.Errorf("Update(unknown) = %+v, %v", rec, err)
+			}
+			if status := mustTask(t, r, "t1").TaskStatus(); status.ResultData.Stats["anomalies"] != 3.0 {
+				t.Errorf("TaskStatus() = %+v", status)
+			}
+
+			// The oldest task is removed over the limit
+			if err := r.Add(&Record{TaskID: "t4"}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.Task("t1"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("the oldest task must be pruned, got %v", err)
+			}
+			if recs, _ := r.List(Filter{}); len(recs) != 3 {
+				t.Errorf("List() returned %d tasks, want 3", len(recs))
+			}
+		})
+	}
+}
+
+func TestBoltStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	s, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(s, 0)
+	if err := r.Add(&Record{TaskID: "t1", Label: "nightly"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if rec, err := New(s, 0).Get("nightly"); err != nil || rec.TaskID != "t1" {
+		t.Errorf("task must survive reopening, got %+v, %v", rec, err)
+	}
+}
+
+func mustTask(t *testing.T, r *Registry, id string) *Record {
+	t.Helper()
+	rec, err := r.Task(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
@@ -0,0 +1,166 @@
+package taskregistry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrNotFound is returned by stores for unknown task IDs
+var ErrNotFound = errors.New("task is not in the registry")
+
+// Store persists task records
+type Store interface {
+	// Put creates or replaces the record with the same task ID
+	Put(rec *Record) error
+	// Get returns the record of a task or ErrNotFound
+	Get(taskID string) (*Record, error)
+	// List returns all records, the most recently created first
+	List() ([]*Record, error)
+	// Delete removes the record of a task, unknown tasks are ignored
+	Delete(taskID string) error
+	// Close releases resources of the store
+	Close() error
+}
+
+// sortRecords sorts records by creation time, the most recent first
+func sortRecords(records []*Record) {
+	slices.SortStableFunc(records, func(a, b *Record) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+}
+
+// MemoryStore keeps records in memory, they are lost on restart
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string]*Record
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[string]*Record{}}
+}
+
+func (s *MemoryStore) Put(rec *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[rec.TaskID] = rec.clone()
+	return nil
+}
+
+func (s *MemoryStore) Get(taskID string) (*Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rec, ok := s.records[taskID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return rec.clone(), nil
+}
+
+func (s *MemoryStore) List() ([]*Record, error) {
+	s.mu.RLock()
+	records := make([]*Record, 0, len(s.records))
+	for _, rec := range s.records {
+		records = append(records, rec.clone())
+	}
+	s.mu.RUnlock()
+	sortRecords(records)
+	return records, nil
+}
+
+func (s *MemoryStore) Delete(taskID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, taskID)
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// tasksBucket is the bbolt bucket with JSON-encoded records by task ID
+var tasksBucket = []byte("tasks")
+
+// BoltStore keeps records in a bbolt database file, so they survive restarts
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens or creates a bbolt database file. The file is locked while the store is open.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("cannot open task registry %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(tasksBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("cannot initialize task registry %s: %w", path, err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) Put(rec *Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("cannot encode task %s: %w", rec.TaskID, err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).Put([]byte(rec.TaskID), data)
+	})
+}
+
+func (s *BoltStore) Get(taskID string) (*Record, error) {
+	var rec *Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(tasksBucket).Get([]byte(taskID))
+		if data == nil {
+			return ErrNotFound
+		}
+		rec = &Record{}
+		return json.Unmarshal(data, rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (s *BoltStore) List() ([]*Record, error) {
+	var records []*Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).ForEach(func(k, v []byte) error {
+			rec := &Record{}
+			if err := json.Unmarshal(v, rec); err != nil {
+				return fmt.Errorf("cannot decode task %s: %w", k, err)
+			}
+			records = append(records, rec)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortRecords(records)
+	return records, nil
+}
+
+func (s *BoltStore) Delete(taskID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).Delete([]byte(taskID))
+	})
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -3,7 +3,9 @@ package tools
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmalert"
 	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -20,7 +22,23 @@ type GenerateAlertRuleArgs struct {
 	InferEvery       string  `json:"infer_every,omitempty" jsonschema:"description=Inference cadence (defaults to step value)"`
 }
 
-func RegisterAlertTools(s *server.MCPServer, client *vmanomaly.Client) {
+type GenerateConfigAlertRulesArgs struct {
+	Config             map[string]any     `json:"config,omitempty" jsonschema:"description=Complete vmanomaly configuration object (reader/schedulers/models/writer). Either config or config_yaml must be set"`
+	ConfigYAML         string             `json:"config_yaml,omitempty" jsonschema:"description=Complete vmanomaly configuration as YAML text. Either config or config_yaml must be set"`
+	AnomalyThreshold   float64            `json:"anomaly_threshold,omitempty" jsonschema:"description=Default anomaly score threshold (default: 1.0)"`
+	Thresholds         map[string]float64 `json:"thresholds,omitempty" jsonschema:"description=Per-query anomaly score thresholds by query alias from reader.queries (e.g. {\"cpu\": 2.5})"`
+	ForPeriods         float64            `json:"for_periods,omitempty" jsonschema:"description=Number of consecutive inference runs (infer_every) the score must stay above threshold before firing. Negative disables 'for:' (default: 2)"`
+	GroupName          string             `json:"group_name,omitempty" jsonschema:"description=Name of the anomaly alerts rule group (default: 'vmanomaly-anomalies')"`
+	Job                string             `json:"job,omitempty" jsonschema:"description=Regexp matching job label of vmanomaly self-monitoring metrics (default: '.*vmanomaly.*')"`
+	SkipSelfMonitoring bool               `json:"skip_self_monitoring,omitempty" jsonschema:"description=Do not add 'TooManyRestarts' and 'SkippedModelRunsDetected' self-monitoring alerts"`
+}
+
+type ValidateAlertRulesArgs struct {
+	RulesYAML string `json:"rules_yaml" jsonschema:"required,description=vmalert rules file content in YAML (groups with alerting and recording rules)"`
+	TestsYAML string `json:"tests_yaml,omitempty" jsonschema:"description=Optional vmalert-tool unit test file in YAML with input_series and alert_rule_test/metricsql_expr_test cases. rule_files are ignored: rules_yaml is tested"`
+}
+
+func RegisterAlertTools(s *server.MCPServer, client vmanomaly.API) {
 	generateAlertRuleTool := mcp.NewTool(
 		"vmanomaly_generate_alert_rule",
 		mcp.WithDescription("Generate a VMAlert rule YAML configuration for anomaly score alerting. Creates a production-ready vmalert rule that triggers when anomaly_score exceeds the threshold. Use this to set up alerting for anomalies detected by vmanomaly."),
@@ -33,9 +51,35 @@ func RegisterAlertTools(s *server.MCPServer, client *vmanomaly.Client) {
 		mcp.WithInputSchema[GenerateAlertRuleArgs](),
 	)
 	s.AddTool(generateAlertRuleTool, mcp.NewTypedToolHandler(handleGenerateAlertRule(client)))
+
+	generateConfigAlertRulesTool := mcp.NewTool(
+		"vmanomaly_generate_config_alert_rules",
+		mcp.WithDescription("Generate a complete vmalert rules file for a whole vmanomaly config. Creates one alerting rule per model/query alias pair matching anomaly scores by query alias ('for' label) and 'model_alias' label, honoring writer.metric_format. Supports per-query thresholds; 'for:' durations are derived from infer_every of the model schedulers. Also adds 'TooManyRestarts' and 'SkippedModelRunsDetected' self-monitoring alerts. Works offline without calling vmanomaly."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Generate VMAlert Rules for Config",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[GenerateConfigAlertRulesArgs](),
+	)
+	s.AddTool(generateConfigAlertRulesTool, mcp.NewTypedToolHandler(handleGenerateConfigAlertRules()))
+
+	validateAlertRulesTool := mcp.NewTool(
+		"vmanomaly_validate_alert_rules",
+		mcp.WithDescription("Validate a vmalert rules file offline before deploying it. Checks group and rule structure, durations, MetricsQL expressions, label names and label/annotation templates. Optionally runs vmalert-tool style unit tests: synthetic input series (e.g. values: '0.5x10 3x5') with expected firing alerts at given eval times and expected expression results, evaluated by a local evaluator covering selectors, rate/increase/changes, basic *_over_time rollups, sum/min/max/avg/count and arithmetic, comparison and and/or/unless operators. Use it to prove generated anomaly_score alerts fire on a synthetic anomaly."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Validate VMAlert Rules",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[ValidateAlertRulesArgs](),
+	)
+	s.AddTool(validateAlertRulesTool, mcp.NewTypedToolHandler(handleValidateAlertRules()))
 }
 
-func handleGenerateAlertRule(client *vmanomaly.Client) func(ctx context.Context, req mcp.CallToolRequest, args GenerateAlertRuleArgs) (*mcp.CallToolResult, error) {
+func handleGenerateAlertRule(client vmanomaly.API) func(ctx context.Context, req mcp.CallToolRequest, args GenerateAlertRuleArgs) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest, args GenerateAlertRuleArgs) (*mcp.CallToolResult, error) {
 		alertReq := &vmanomaly.AlertRuleRequest{
 			Step:  args.Step,
@@ -67,3 +111,74 @@ func handleGenerateAlertRule(client *vmanomaly.Client) func(ctx context.Context,
 		return mcp.NewToolResultText(resultMsg), nil
 	}
 }
+
+func handleGenerateConfigAlertRules() func(ctx context.Context, req mcp.CallToolRequest, args GenerateConfigAlertRulesArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args GenerateConfigAlertRulesArgs) (*mcp.CallToolResult, error) {
+		cfg, err := parseConfigArg(args.Config, args.ConfigYAML)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid config: %v", err)), nil
+		}
+
+		rules, err := vmalert.GenerateRules(cfg, vmalert.GenerateOptions{
+			GroupName:          args.GroupName,
+			Threshold:          args.AnomalyThreshold,
+			Thresholds:         args.Thresholds,
+			ForPeriods:         int(args.ForPeriods),
+			Job:                args.Job,
+			SkipSelfMonitoring: args.SkipSelfMonitoring,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to generate alert rules: %v", err)), nil
+		}
+		yamlRules, err := rules.Marshal()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to format alert rules: %v", err)), nil
+		}
+
+		var names []string
+		for _, r := range rules.Groups[0].Rules {
+			names = append(names, r.Name())
+		}
+		resultMsg := fmt.Sprintf("Generated %d anomaly alert(s) for %d model(s) and %d query alias(es): %s\n\n```yaml\n%s```\n\nSave this to a .yaml file and configure vmalert to load it with -rule flag.",
+			len(names), len(cfg.Models), len(cfg.Reader.Queries), strings.Join(names, ", "), yamlRules)
+		return mcp.NewToolResultText(resultMsg), nil
+	}
+}
+
+func handleValidateAlertRules() func(ctx context.Context, req mcp.CallToolRequest, args ValidateAlertRulesArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args ValidateAlertRulesArgs) (*mcp.CallToolResult, error) {
+		rules, report := vmalert.Validate([]byte(args.RulesYAML))
+
+		var sb strings.Builder
+		if report.Valid() {
+			sb.WriteString(fmt.Sprintf("Rules are valid: %d group(s), %d rule(s).\n", report.Groups, report.Rules))
+		} else {
+			sb.WriteString(fmt.Sprintf("Rules are INVALID: %d group(s), %d rule(s).\n", report.Groups, report.Rules))
+		}
+		for _, issue := range report.Issues {
+			sb.WriteString(fmt.Sprintf("- [%s] %s: %s\n", issue.Severity, issue.Path, issue.Message))
+		}
+
+		if args.TestsYAML != "" {
+			sb.WriteString("\n")
+			if rules == nil {
+				sb.WriteString("Unit tests skipped: rules cannot be parsed.\n")
+			} else {
+				tests, err := vmalert.ParseUnitTests([]byte(args.TestsYAML))
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("Invalid unit tests: %v", err)), nil
+				}
+				result := vmalert.RunUnitTests(rules, tests)
+				if result.Passed() {
+					sb.WriteString(fmt.Sprintf("Unit tests PASSED: %d check(s).\n", result.Tests))
+				} else {
+					sb.WriteString(fmt.Sprintf("Unit tests FAILED: %d failure(s), %d check(s) run.\n", result.Failed, result.Tests))
+				}
+				for _, failure := range result.Failures {
+					sb.WriteString("- " + failure + "\n")
+				}
+			}
+		}
+		return mcp.NewToolResultText(strings.TrimSpace(sb.String())), nil
+	}
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/analyzer"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ============================================================================
+// Query Analysis Tool Arguments (Struct-based schemas)
+// ============================================================================
+
+// AnalyzeQueryArgs defines arguments for analyze_query tool
+type AnalyzeQueryArgs struct {
+	Query          string  `json:"query" jsonschema:"required,description=MetricsQL/PromQL query to analyze e.g. 'sum(rate(http_requests_total[5m])) by (job)'"`
+	Step           string  `json:"step,omitempty" jsonschema:"description=Query step/resolution to check lookbehind windows against (e.g. '1m' '5m')"`
+	EstimateSeries bool    `json:"estimate_series,omitempty" jsonschema:"description=Run a cheap count() query through vmanomaly to estimate the number of returned series"`
+	MaxSeries      float64 `json:"max_series,omitempty" jsonschema:"description=Series count above which the estimate is reported as an error (default: 1000)"`
+	DatasourceURL  string  `json:"datasource_url,omitempty" jsonschema:"description=Datasource URL for the cardinality estimate. Defaults to the datasource configured in vmanomaly"`
+	TenantID       string  `json:"tenant_id,omitempty" jsonschema:"description=Optional tenant ID for the cardinality estimate"`
+}
+
+// AnalyzeQueryResponse is the result of analyze_query tool
+type AnalyzeQueryResponse struct {
+	Summary     string                        `json:"summary" jsonschema_description:"Human-readable summary of the analysis"`
+	Valid       bool                          `json:"valid" jsonschema_description:"Whether the query has no errors and fits max_series"`
+	Report      *analyzer.Report              `json:"report" jsonschema_description:"Parsed query details: metrics, functions, aggregations, group by labels, windows, placeholders and issues"`
+	Cardinality *analyzer.CardinalityEstimate `json:"cardinality,omitempty" jsonschema_description:"Estimated number of series when estimate_series is enabled"`
+}
+
+// ============================================================================
+// Tool Registration Functions
+// ============================================================================
+
+// RegisterAnalyzerTools registers MetricsQL query analysis tools
+func RegisterAnalyzerTools(s *server.MCPServer, client vmanomaly.API) {
+	analyzeQueryTool := mcp.NewTool(
+		"vmanomaly_analyze_query",
+		mcp.WithDescription("Statically analyze a MetricsQL/PromQL query before using it for anomaly detection. Warns about raw counters used without rate()/increase(), missing aggregation, grouping by high-cardinality labels, lookbehind windows shorter than step and dashboard placeholders like $__interval which vmanomaly does not substitute. Optionally estimates the number of returned series with a cheap count() query."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Analyze MetricsQL Query",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[AnalyzeQueryArgs](),
+		mcp.WithOutputSchema[AnalyzeQueryResponse](),
+	)
+	s.AddTool(analyzeQueryTool, mcp.NewStructuredToolHandler(handleAnalyzeQuery(client)))
+}
+
+// ============================================================================
+// Tool Handlers
+// ============================================================================
+
+func handleAnalyzeQuery(client vmanomaly.API) mcp.StructuredToolHandlerFunc[AnalyzeQueryArgs, AnalyzeQueryResponse] {
+	return func(ctx context.Context, req mcp.CallToolRequest, args AnalyzeQueryArgs) (AnalyzeQueryResponse, error) {
+		report := analyzer.Analyze(args.Query, args.Step)
+		resp := AnalyzeQueryResponse{
+			Valid:  report.Valid(),
+			Report: report,
+		}
+
+		var sb strings.Builder
+		if resp.Valid {
+			sb.WriteString("Query has no errors. ")
+		} else {
+			sb.WriteString(fmt.Sprintf("Query is INVALID: %s. ", strings.Join(report.Errors(), "; ")))
+		}
+		if n := len(report.Warnings()); n > 0 {
+			sb.WriteString(fmt.Sprintf("%d warning(s), see report.issues. ", n))
+		}
+
+		if args.EstimateSeries && resp.Valid {
+			maxSeries := int(args.MaxSeries)
+			if maxSeries <= 0 {
+				maxSeries = analyzer.DefaultMaxSeries
+			}
+			estimate, err := analyzer.EstimateCardinality(ctx, client, args.Query, args.Step, args.DatasourceURL, args.TenantID)
+			if err != nil {
+				sb.WriteString(fmt.Sprintf("Cannot estimate cardinality: %v.", err))
+			} else {
+				resp.Cardinality = estimate
+				sb.WriteString(fmt.Sprintf("Estimated series: %d.", estimate.Series))
+				if estimate.Series > maxSeries {
+					resp.Valid = false
+					report.Issues = append(report.Issues, analyzer.Issue{
+						Severity: analyzer.SeverityError,
+						Code:     analyzer.IssueTooManySeries,
+						Message:  fmt.Sprintf("query returns ~%d series, which exceeds max_series=%d", estimate.Series, maxSeries),
+					})
+				}
+			}
+		}
+		resp.Summary = strings.TrimSpace(sb.String())
+
+		return resp, nil
+	}
+}
@@ -0,0 +1,167 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/capacity"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ============================================================================
+// Capacity Planning Tool Arguments (Struct-based schemas)
+// ============================================================================
+
+// PlanShardingArgs defines arguments for plan_sharding tool
+type PlanShardingArgs struct {
+	Config         map[string]any     `json:"config,omitempty" jsonschema:"description=Complete vmanomaly configuration object (reader/schedulers/models/writer). Either config or config_yaml must be set"`
+	ConfigYAML     string             `json:"config_yaml,omitempty" jsonschema:"description=Complete vmanomaly configuration as YAML text. Either config or config_yaml must be set"`
+	Shards         float64            `json:"shards,omitempty" jsonschema:"description=Number of vmanomaly instances to split the config between. Derived from shard_capacity when not set"`
+	ShardCapacity  float64            `json:"shard_capacity,omitempty" jsonschema:"description=Workload in cost units per hour a single instance can handle (default: 1e8 which is ~10k zscore series refitted hourly on 7d of 1m data)"`
+	Series         map[string]float64 `json:"series,omitempty" jsonschema:"description=Known number of series per query alias. Skips cardinality estimation for these queries"`
+	DefaultSeries  float64            `json:"default_series,omitempty" jsonschema:"description=Number of series assumed for queries whose cardinality cannot be estimated (default: 1)"`
+	SkipEstimate   bool               `json:"skip_estimate,omitempty" jsonschema:"description=Do not run count() queries through vmanomaly and rely on series and default_series only"`
+	SkipValidation bool               `json:"skip_validation,omitempty" jsonschema:"description=Skip validation of sub-configs through vmanomaly API"`
+	OutputDir      string             `json:"output_dir,omitempty" jsonschema:"description=Optional directory relative to the output directory set by MCP_OUTPUT_DIR to write sub-configs to as shard-<index>.yaml"`
+	Overwrite      bool               `json:"overwrite,omitempty" jsonschema:"description=Overwrite existing files in output_dir"`
+}
+
+// PlanShardingResponse is the result of plan_sharding tool
+type PlanShardingResponse struct {
+	Summary     string                                    `json:"summary" jsonschema_description:"Human-readable summary of the plan"`
+	Estimate    *capacity.Estimate                        `json:"estimate" jsonschema_description:"Estimated series per query and workload per model and query pair"`
+	Plan        *capacity.Plan                            `json:"plan" jsonschema_description:"Proposed shards with their sub-configs, workload and load relative to shard capacity"`
+	Validation  []ShardValidation                         `json:"validation,omitempty" jsonschema_description:"Validation result of every sub-config"`
+	Limits      *vmanomaly.AnomalyDetectionLimitsResponse `json:"limits,omitempty" jsonschema_description:"Concurrency limits of ad-hoc detection tasks on the connected vmanomaly instance"`
+	OutputFiles []string                                  `json:"output_files,omitempty" jsonschema_description:"Written sub-config files"`
+}
+
+// ShardValidation is the validation result of a sub-config
+type ShardValidation struct {
+	Shard int    `json:"shard"`
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// ============================================================================
+// Tool Registration Functions
+// ============================================================================
+
+// RegisterCapacityTools registers sharding and capacity planning tools, sub-configs are written to files only if outputDir is enabled
+func RegisterCapacityTools(s *server.MCPServer, client vmanomaly.API, outputDir OutputDir) {
+	planShardingTool := mcp.NewTool(
+		"vmanomaly_plan_sharding",
+		mcp.WithDescription("Plan capacity and horizontal scaling of a vmanomaly config. Estimates series cardinality of every query with a cheap count() query, multiplies it by a relative per-model fit/infer cost and scheduler fit_every/infer_every/fit_window cadence, and proposes a balanced split of model and query pairs across N instances. Every shard gets a standalone sub-config which is validated through vmanomaly API. Costs are rough relative estimates, verify them with self-monitoring metrics after deployment."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Plan Sharding and Capacity",
+			ReadOnlyHint:    ptr(!outputDir.Enabled()),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[PlanShardingArgs](),
+		mcp.WithOutputSchema[PlanShardingResponse](),
+	)
+	s.AddTool(planShardingTool, mcp.NewStructuredToolHandler(handlePlanSharding(client, outputDir)))
+}
+
+// ============================================================================
+// Tool Handlers
+// ============================================================================
+
+func handlePlanSharding(client vmanomaly.API, outputDir OutputDir) mcp.StructuredToolHandlerFunc[PlanShardingArgs, PlanShardingResponse] {
+	return func(ctx context.Context, req mcp.CallToolRequest, args PlanShardingArgs) (PlanShardingResponse, error) {
+		var resp PlanShardingResponse
+		cfg, err := parseConfigArg(args.Config, args.ConfigYAML)
+		if err != nil {
+			return resp, fmt.Errorf("invalid config: %w", err)
+		}
+
+		opts := capacity.EstimateOptions{DefaultSeries: int(args.DefaultSeries)}
+		if len(args.Series) > 0 {
+			opts.Series = make(map[string]int, len(args.Series))
+			for alias, n := range args.Series {
+				opts.Series[alias] = int(n)
+			}
+		}
+		var querier capacity.Querier
+		if !args.SkipEstimate {
+			querier = client
+		}
+		resp.Estimate, err = capacity.EstimateWorkload(ctx, querier, cfg, opts)
+		if err != nil {
+			return resp, fmt.Errorf("cannot estimate workload: %w", err)
+		}
+		resp.Plan, err = capacity.Split(cfg, resp.Estimate, capacity.SplitOptions{
+			Shards:        int(args.Shards),
+			ShardCapacity: args.ShardCapacity,
+		})
+		if err != nil {
+			return resp, fmt.Errorf("cannot split config: %w", err)
+		}
+
+		invalid := 0
+		if !args.SkipValidation {
+			for _, sh := range resp.Plan.Shards {
+				v := ShardValidation{Shard: sh.Index}
+				result, err := client.ValidateConfig(ctx, sh.Config)
+				switch {
+				case err != nil:
+					v.Error = err.Error()
+				case !result.IsValid:
+					v.Error = "sub-config is invalid"
+				default:
+					v.Valid = true
+				}
+				if !v.Valid {
+					invalid++
+				}
+				resp.Validation = append(resp.Validation, v)
+			}
+		}
+		if limits, err := client.GetDetectionLimits(ctx); err == nil {
+			resp.Limits = limits
+		}
+
+		if args.OutputDir != "" {
+			for _, sh := range resp.Plan.Shards {
+				data, err := yaml.Marshal(sh.Config)
+				if err != nil {
+					return resp, fmt.Errorf("cannot marshal shard %d: %w", sh.Index, err)
+				}
+				path, err := outputDir.writeFile(filepath.Join(args.OutputDir, fmt.Sprintf("shard-%d.yaml", sh.Index)), data, args.Overwrite)
+				if err != nil {
+					return resp, fmt.Errorf("cannot write shard %d: %w", sh.Index, err)
+				}
+				resp.OutputFiles = append(resp.OutputFiles, path)
+			}
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("Estimated workload: %.0f cost units/hour for %d model/query pair(s). ", resp.Plan.CostPerHour, len(resp.Estimate.Entities)))
+		sb.WriteString(fmt.Sprintf("Proposed %d shard(s) with imbalance %.2f: ", len(resp.Plan.Shards), resp.Plan.Imbalance))
+		shards := make([]string, 0, len(resp.Plan.Shards))
+		for _, sh := range resp.Plan.Shards {
+			shards = append(shards, fmt.Sprintf("#%d %d series, load %.2f", sh.Index, sh.Series, sh.Load))
+		}
+		sb.WriteString(strings.Join(shards, "; "))
+		sb.WriteString(". ")
+		if invalid > 0 {
+			sb.WriteString(fmt.Sprintf("%d sub-config(s) failed validation, see validation. ", invalid))
+		}
+		if n := len(resp.Estimate.Warnings); n > 0 {
+			sb.WriteString(fmt.Sprintf("%d warning(s), see estimate.warnings. ", n))
+		}
+		if len(resp.OutputFiles) > 0 {
+			sb.WriteString(fmt.Sprintf("Sub-configs written to %s.", args.OutputDir))
+		}
+		resp.Summary = strings.TrimSpace(sb.String())
+		return resp, nil
+	}
+}
@@ -0,0 +1,173 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/changelog"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/migration"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/resources"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ============================================================================
+// Changelog Tool Arguments (Struct-based schemas)
+// ============================================================================
+
+// SearchChangelogArgs defines arguments for search_changelog tool
+type SearchChangelogArgs struct {
+	VersionFrom  string   `json:"version_from,omitempty" jsonschema:"description=Return releases newer than this version (exclusive). Use 'runtime' for the version of the connected vmanomaly"`
+	VersionTo    string   `json:"version_to,omitempty" jsonschema:"description=Return releases up to this version (inclusive). Use 'runtime' for the version of the connected vmanomaly"`
+	Keyword      string   `json:"keyword,omitempty" jsonschema:"description=Space-separated terms which all must appear in an entry. Case-insensitive and underscores match spaces. Example: 'prophet' or 'fit_window'"`
+	Kinds        []string `json:"kinds,omitempty" jsonschema:"description=Entry kinds to return,enum=FEATURE,enum=IMPROVEMENT,enum=BUGFIX,enum=BREAKING CHANGE,enum=DEPRECATION"`
+	BreakingOnly bool     `json:"breaking_only,omitempty" jsonschema:"description=Return only breaking changes including backward-incompatible improvements"`
+	Limit        float64  `json:"limit,omitempty" jsonschema:"description=Maximum number of releases to return from the newest (default: 20)"`
+}
+
+// SearchChangelogResponse is the result of search_changelog tool
+type SearchChangelogResponse struct {
+	Summary        string              `json:"summary" jsonschema_description:"Human-readable summary of matching releases"`
+	RuntimeVersion string              `json:"runtime_version,omitempty" jsonschema_description:"Version of the connected vmanomaly if available"`
+	Releases       []changelog.Release `json:"releases" jsonschema_description:"Matching releases from the newest to the oldest with matching entries only"`
+	// NotAvailable are matching releases newer than the connected vmanomaly
+	NotAvailable []string `json:"not_available,omitempty" jsonschema_description:"Matching releases newer than the connected vmanomaly so their changes are not available yet"`
+	Truncated    bool     `json:"truncated,omitempty" jsonschema_description:"Whether more releases match than returned"`
+}
+
+// ============================================================================
+// Tool Registration Functions
+// ============================================================================
+
+// RegisterChangelogTools registers changelog tools
+func RegisterChangelogTools(s *server.MCPServer, client vmanomaly.API) {
+	searchChangelogTool := mcp.NewTool(
+		"vmanomaly_search_changelog",
+		mcp.WithDescription("Search vmanomaly CHANGELOG parsed into versioned entries (version, release date, kind, text). Filters by version range, keyword and entry kind. Use it for questions like 'what changed in the prophet model since v1.18' or 'which breaking changes are between v1.20 and v1.26' instead of vmanomaly_search_docs. Releases newer than the connected vmanomaly are marked as not available."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Search vmanomaly Changelog",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[SearchChangelogArgs](),
+		mcp.WithOutputSchema[SearchChangelogResponse](),
+	)
+	s.AddTool(searchChangelogTool, mcp.NewStructuredToolHandler(handleSearchChangelog(client)))
+}
+
+// ============================================================================
+// Tool Handlers
+// ============================================================================
+
+func handleSearchChangelog(client vmanomaly.API) mcp.StructuredToolHandlerFunc[SearchChangelogArgs, SearchChangelogResponse] {
+	return func(ctx context.Context, req mcp.CallToolRequest, args SearchChangelogArgs) (SearchChangelogResponse, error) {
+		var resp SearchChangelogResponse
+		cl, err := resources.Changelog()
+		if err != nil {
+			return resp, err
+		}
+		runtime, hasRuntime := runtimeVersion(ctx, client)
+		if hasRuntime {
+			resp.RuntimeVersion = runtime.String()
+		}
+
+		q := changelog.Query{Keyword: args.Keyword, Kinds: args.Kinds, BreakingOnly: args.BreakingOnly}
+		for _, bound := range []struct {
+			arg  string
+			dst  **changelog.Version
+			name string
+		}{
+			{args.VersionFrom, &q.From, "version_from"},
+			{args.VersionTo, &q.To, "version_to"},
+		} {
+			switch {
+			case bound.arg == "":
+			case bound.arg == "runtime":
+				if !hasRuntime {
+					return resp, fmt.Errorf("cannot detect the version of the connected vmanomaly for %s", bound.name)
+				}
+				*bound.dst = &runtime
+			default:
+				v, err := changelog.ParseVersion(bound.arg)
+				if err != nil {
+					return resp, fmt.Errorf("invalid %s: %w", bound.name, err)
+				}
+				*bound.dst = &v
+			}
+		}
+
+		limit := int(args.Limit)
+		if limit < 1 {
+			limit = 20
+		}
+		resp.Releases = cl.Search(q)
+		if len(resp.Releases) > limit {
+			resp.Releases = resp.Releases[:limit]
+			resp.Truncated = true
+		}
+		entries := 0
+		for _, r := range resp.Releases {
+			entries += len(r.Entries)
+			if hasRuntime && r.Version.Compare(runtime) > 0 {
+				resp.NotAvailable = append(resp.NotAvailable, r.Version.String())
+			}
+		}
+
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Found %d entries in %d release(s)", entries, len(resp.Releases))
+		if resp.Truncated {
+			fmt.Fprintf(&sb, " (truncated to the newest %d releases)", limit)
+		}
+		if hasRuntime {
+			fmt.Fprintf(&sb, ". Connected vmanomaly is %s", runtime)
+			if n := len(resp.NotAvailable); n > 0 {
+				fmt.Fprintf(&sb, ", changes of %d newer release(s) are not available on it", n)
+			}
+		}
+		resp.Summary = sb.String()
+		return resp, nil
+	}
+}
+
+// runtimeVersion returns the version of the connected vmanomaly from its build info
+func runtimeVersion(ctx context.Context, client vmanomaly.API) (changelog.Version, bool) {
+	info, err := client.GetBuildInfo(ctx)
+	if err != nil {
+		return changelog.Version{}, false
+	}
+	v, err := changelog.ParseVersion(migration.RuntimeVersion(info))
+	if err != nil {
+		return changelog.Version{}, false
+	}
+	return v, true
+}
+
+// availabilityHint returns "available since" hint for docs markdown based on its available_from shortcodes,
+// noting features which are newer than the connected vmanomaly
+func availabilityHint(markdown string, runtime *changelog.Version) string {
+	versions := changelog.AvailableFrom(markdown)
+	if len(versions) == 0 {
+		return ""
+	}
+	names := make([]string, len(versions))
+	var missing []string
+	for i, v := range versions {
+		names[i] = v.String()
+		if runtime != nil && v.Compare(*runtime) > 0 {
+			missing = append(missing, v.String())
+		}
+	}
+	hint := "available since " + strings.Join(names, ", ")
+	switch {
+	case runtime == nil:
+	case len(missing) > 0:
+		hint += fmt.Sprintf("; features from %s are not available on the connected vmanomaly %s", strings.Join(missing, ", "), runtime)
+	default:
+		hint += fmt.Sprintf("; all available on the connected vmanomaly %s", runtime)
+	}
+	return hint
+}
@@ -0,0 +1,32 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/changelog"
+)
+
+func TestAvailabilityHint(t *testing.T) {
+	md := `## Online models {{% available_from "v1.15.0" anomaly %}}
+### Quantile {{% available_from "v1.26.0" anomaly %}}`
+	runtime, _ := changelog.ParseVersion("v1.25.3")
+	tests := []struct {
+		name     string
+		markdown string
+		runtime  *changelog.Version
+		want     string
+	}{
+		{"no shortcodes", "plain text", &runtime, ""},
+		{"no runtime", md, nil, "available since v1.15.0, v1.26.0"},
+		{"newer than runtime", md, &runtime, "features from v1.26.0 are not available on the connected vmanomaly v1.25.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := availabilityHint(tt.markdown, tt.runtime)
+			if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+				t.Errorf("availabilityHint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -17,14 +17,15 @@ import (
 
 // GenerateConfigArgs defines arguments for generate_config tool
 type GenerateConfigArgs struct {
-	Query         string         `json:"query" jsonschema:"required,description=PromQL query to monitor for anomalies"`
-	Step          string         `json:"step" jsonschema:"required,description=Query step/resolution (e.g. '1m' '5m' '1h')"`
-	DatasourceURL string         `json:"datasource_url" jsonschema:"required,format=uri,description=VictoriaMetrics datasource URL (e.g. 'http://victoriametrics:8428')"`
-	ModelSpec     map[string]any `json:"model_spec" jsonschema:"required,description=Model specification as JSON object (must include 'class' field)"`
-	TenantID      string         `json:"tenant_id,omitempty" jsonschema:"description=Optional tenant ID for multi-tenancy support"`
-	FitWindow     string         `json:"fit_window,omitempty" jsonschema:"description=Time window for model fitting (default: '1d')"`
-	FitEvery      string         `json:"fit_every,omitempty" jsonschema:"description=Model retraining frequency (default: '1d')"`
-	InferEvery    string         `json:"infer_every,omitempty" jsonschema:"description=Optional inference cadence for batch processing"`
+	Query          string         `json:"query" jsonschema:"required,description=MetricsQL/PromQL query (datasource_type=vm) or LogsQL query with '| stats' pipe (datasource_type=vmlogs) to monitor for anomalies"`
+	Step           string         `json:"step,omitempty" jsonschema:"description=Query step/resolution (e.g. '1m' '5m' '1h'). Required for vm. For vmlogs it can be inferred from a '_time:<step>' bucket of the query"`
+	DatasourceURL  string         `json:"datasource_url" jsonschema:"required,format=uri,description=VictoriaMetrics or VictoriaLogs datasource URL (e.g. 'http://victoriametrics:8428' or 'http://victorialogs:9428')"`
+	DatasourceType string         `json:"datasource_type,omitempty" jsonschema:"enum=vm,enum=vmlogs,description=Datasource type: 'vm' generates VmReader config and 'vmlogs' generates VLogsReader config. Default: 'vm'"`
+	ModelSpec      map[string]any `json:"model_spec" jsonschema:"required,description=Model specification as JSON object (must include 'class' field)"`
+	TenantID       string         `json:"tenant_id,omitempty" jsonschema:"description=Optional tenant ID for multi-tenancy support"`
+	FitWindow      string         `json:"fit_window,omitempty" jsonschema:"description=Time window for model fitting (default: '1d')"`
+	FitEvery       string         `json:"fit_every,omitempty" jsonschema:"description=Model retraining frequency (default: '1d')"`
+	InferEvery     string         `json:"infer_every,omitempty" jsonschema:"description=Optional inference cadence for batch processing"`
 }
 
 // ValidateConfigArgs defines arguments for validate_config tool
@@ -37,7 +38,20 @@ type ValidateConfigArgs struct {
 // ============================================================================
 
 // RegisterConfigTools registers all configuration-related tools
-func RegisterConfigTools(s *server.MCPServer, client *vmanomaly.Client) {
+func RegisterConfigTools(s *server.MCPServer, client vmanomaly.API) {
+	generateConfigTool := mcp.NewTool(
+		"vmanomaly_generate_config",
+		mcp.WithDescription("Generate a complete vmanomaly YAML configuration (reader, scheduler, model, writer) for a single query and model. Supports VictoriaMetrics (datasource_type=vm) and VictoriaLogs (datasource_type=vmlogs) datasources; LogsQL queries are validated before generation. Validate the result with vmanomaly_validate_config before deployment."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Generate vmanomaly Config",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[GenerateConfigArgs](),
+	)
+	s.AddTool(generateConfigTool, mcp.NewTypedToolHandler(handleGenerateConfig(client)))
+
 	validateConfigTool := mcp.NewTool(
 		"vmanomaly_validate_config",
 		mcp.WithDescription("Validate a complete vmanomaly YAML configuration. Takes a full configuration object (with reader, scheduler, model, writer sections) and returns validation result with normalized config or error details. Use this to verify a complete config before deployment."),
@@ -50,8 +64,63 @@ func RegisterConfigTools(s *server.MCPServer, client *vmanomaly.Client) {
 // Tool Handlers
 // ============================================================================
 
+// handleGenerateConfig handles the generate_config tool
+func handleGenerateConfig(client vmanomaly.API) func(ctx context.Context, req mcp.CallToolRequest, args GenerateConfigArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args GenerateConfigArgs) (*mcp.CallToolResult, error) {
+		configReq := &vmanomaly.ConfigGenerationRequest{
+			Query:          args.Query,
+			Step:           args.Step,
+			DatasourceURL:  args.DatasourceURL,
+			DatasourceType: args.DatasourceType,
+			FitWindow:      args.FitWindow,
+			FitEvery:       args.FitEvery,
+			ModelSpec:      args.ModelSpec,
+		}
+		if configReq.DatasourceType == "" {
+			configReq.DatasourceType = vmanomaly.DatasourceTypeVM
+		}
+		if configReq.FitWindow == "" {
+			configReq.FitWindow = "1d"
+		}
+		if configReq.FitEvery == "" {
+			configReq.FitEvery = "1d"
+		}
+		if args.TenantID != "" {
+			configReq.TenantID = &args.TenantID
+		}
+		if args.InferEvery != "" {
+			configReq.InferEvery = &args.InferEvery
+		}
+
+		var warnings []string
+		switch configReq.DatasourceType {
+		case vmanomaly.DatasourceTypeVMLogs:
+			step, logsWarnings, err := prepareLogsQLQuery(configReq.Query, configReq.Step)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Invalid config request: %v", err)), nil
+			}
+			configReq.Step = step
+			warnings = logsWarnings
+		case vmanomaly.DatasourceTypeVM:
+			if configReq.Step == "" {
+				return mcp.NewToolResultError("Invalid config request: step is required for datasource_type=vm"), nil
+			}
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("Unsupported datasource_type %q, expected 'vm' or 'vmlogs'", configReq.DatasourceType)), nil
+		}
+
+		yamlConfig, err := client.GenerateConfig(ctx, configReq)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to generate config: %v", err)), nil
+		}
+
+		resultMsg := fmt.Sprintf("Generated vmanomaly Config:\n\n```yaml\n%s\n```\n\nValidate it with vmanomaly_validate_config before deployment.%s", yamlConfig, formatWarnings(warnings))
+		return mcp.NewToolResultText(resultMsg), nil
+	}
+}
+
 // handleValidateConfig handles the validate_config tool
-func handleValidateConfig(client *vmanomaly.Client) func(ctx context.Context, req mcp.CallToolRequest, args ValidateConfigArgs) (*mcp.CallToolResult, error) {
+func handleValidateConfig(client vmanomaly.API) func(ctx context.Context, req mcp.CallToolRequest, args ValidateConfigArgs) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest, args ValidateConfigArgs) (*mcp.CallToolResult, error) {
 		// Call API
 		validation, err := client.ValidateConfig(ctx, args.Config)
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/grafana"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ============================================================================
+// Dashboard Tool Arguments (Struct-based schemas)
+// ============================================================================
+
+// GenerateDashboardArgs defines arguments for generate_dashboard tool
+type GenerateDashboardArgs struct {
+	Config             map[string]any `json:"config,omitempty" jsonschema:"description=Complete vmanomaly configuration object (reader/schedulers/models/writer). Either config or config_yaml must be set"`
+	ConfigYAML         string         `json:"config_yaml,omitempty" jsonschema:"description=Complete vmanomaly configuration as YAML text. Either config or config_yaml must be set"`
+	Title              string         `json:"title,omitempty" jsonschema:"description=Dashboard title (default: 'vmanomaly: anomaly detection results')"`
+	UID                string         `json:"uid,omitempty" jsonschema:"description=Dashboard UID: up to 40 letters/digits/'-'/'_' (default: 'vmanomaly-results')"`
+	DatasourceType     string         `json:"datasource_type,omitempty" jsonschema:"enum=prometheus,enum=victoriametrics-metrics-datasource,description=Grafana datasource plugin used for the datasource variable (default: prometheus)"`
+	AnomalyThreshold   float64        `json:"anomaly_threshold,omitempty" jsonschema:"description=Default anomaly score threshold of statistics and threshold lines (default: 1.0)"`
+	Job                string         `json:"job,omitempty" jsonschema:"description=Regexp matching job label of vmanomaly self-monitoring metrics (default: '.*vmanomaly.*')"`
+	SkipSelfMonitoring bool           `json:"skip_self_monitoring,omitempty" jsonschema:"description=Do not add the self-monitoring row"`
+	OutputPath         string         `json:"output_path,omitempty" jsonschema:"description=Optional file path relative to the output directory set by MCP_OUTPUT_DIR to write the dashboard JSON to e.g. for Grafana provisioning"`
+	Overwrite          bool           `json:"overwrite,omitempty" jsonschema:"description=Overwrite output_path if it already exists"`
+}
+
+// ============================================================================
+// Tool Registration Functions
+// ============================================================================
+
+// RegisterDashboardTools registers Grafana dashboard tools, dashboards are written to files only if outputDir is enabled
+func RegisterDashboardTools(s *server.MCPServer, outputDir OutputDir) {
+	generateDashboardTool := mcp.NewTool(
+		"vmanomaly_generate_dashboard",
+		mcp.WithDescription("Generate a Grafana dashboard JSON for results of a vmanomaly config. Contains global anomaly score statistics, a row per query alias with the actual value (y), predicted yhat with [yhat_lower, yhat_upper] band and anomaly_score, template variables for the query alias ('for') and model_alias labels, and a self-monitoring row. Honors writer.metric_format. The dashboard is validated structurally and can be written to a file for provisioning when the server has an output directory configured. Works offline without calling vmanomaly."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Generate Grafana Dashboard",
+			ReadOnlyHint:    ptr(!outputDir.Enabled()),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[GenerateDashboardArgs](),
+	)
+	s.AddTool(generateDashboardTool, mcp.NewTypedToolHandler(handleGenerateDashboard(outputDir)))
+}
+
+// ============================================================================
+// Tool Handlers
+// ============================================================================
+
+func handleGenerateDashboard(outputDir OutputDir) func(ctx context.Context, req mcp.CallToolRequest, args GenerateDashboardArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args GenerateDashboardArgs) (*mcp.CallToolResult, error) {
+		cfg, err := parseConfigArg(args.Config, args.ConfigYAML)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid config: %v", err)), nil
+		}
+
+		dashboard, err := grafana.GenerateDashboard(cfg, grafana.DashboardOptions{
+			Title:              args.Title,
+			UID:                args.UID,
+			DatasourceType:     args.DatasourceType,
+			Threshold:          args.AnomalyThreshold,
+			Job:                args.Job,
+			SkipSelfMonitoring: args.SkipSelfMonitoring,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to generate dashboard: %v", err)), nil
+		}
+		data, err := json.MarshalIndent(dashboard, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to format dashboard: %v", err)), nil
+		}
+
+		if args.OutputPath == "" {
+			return mcp.NewToolResultText(fmt.Sprintf("Generated Grafana dashboard %q (uid %s) for %d query alias(es):\n\n```json\n%s\n```\n\nImport it in Grafana or put it into a provisioned dashboards directory.",
+				dashboard.Title, dashboard.UID, len(cfg.Reader.Queries), data)), nil
+		}
+
+		path, err := outputDir.writeFile(args.OutputPath, append(data, '\n'), args.Overwrite)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to write dashboard: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Grafana dashboard %q (uid %s) for %d query alias(es) written to %s (%d bytes).",
+			dashboard.Title, dashboard.UID, len(cfg.Reader.Queries), path, len(data)+1)), nil
+	}
+}
@@ -0,0 +1,217 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/detection"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// defaultLocalInferWindow is the inference window of local detection when start_infer_s is not set
+	defaultLocalInferWindow = time.Hour
+	// maxInlinePoints limits the number of points of inline series
+	maxInlinePoints = 100000
+)
+
+// ============================================================================
+// Local Detection Tool Arguments (Struct-based schemas)
+// ============================================================================
+
+// DetectLocallyArgs defines arguments for detect_locally tool
+type DetectLocallyArgs struct {
+	Query            string         `json:"query" jsonschema:"required,description=MetricsQL/PromQL query (datasource_type=vm) or LogsQL query with '| stats' pipe (datasource_type=vmlogs)"`
+	Step             string         `json:"step,omitempty" jsonschema:"description=Query step/resolution (e.g. '1m' '5m'). Required for datasource_type=vm"`
+	ModelSpec        map[string]any `json:"model_spec" jsonschema:"required,description=Model specification as for vmanomaly e.g. {\"class\": \"zscore\" \"z_threshold\": 3}. Supported classes: zscore mad std rolling_quantile zscore_online mad_online quantile_online"`
+	StartInferS      float64        `json:"start_infer_s,omitempty" jsonschema:"description=Inference start timestamp (Unix seconds). Default: 1h before end_infer_s"`
+	EndInferS        float64        `json:"end_infer_s,omitempty" jsonschema:"description=Inference end timestamp (Unix seconds). Default: now"`
+	FitWindow        string         `json:"fit_window,omitempty" jsonschema:"description=Window before start_infer_s to fit models on (default: '1d')"`
+	AnomalyThreshold float64        `json:"anomaly_threshold,omitempty" jsonschema:"description=Anomaly score above which points are reported as anomalies (default: 1)"`
+	DatasourceType   string         `json:"datasource_type,omitempty" jsonschema:"enum=vm,enum=vmlogs,description=Datasource type: 'vm' for VictoriaMetrics or 'vmlogs' for VictoriaLogs. Default: 'vm'"`
+	DatasourceURL    string         `json:"datasource_url,omitempty" jsonschema:"description=Datasource URL. Defaults to the datasource configured in vmanomaly"`
+	TenantID         string         `json:"tenant_id,omitempty" jsonschema:"description=Optional tenant ID for multi-tenancy support"`
+	SkipQueryChecks  bool           `json:"skip_query_checks,omitempty" jsonschema:"description=Skip static analysis of the MetricsQL query"`
+}
+
+// DetectLocallyResponse is the result of detect_locally tool
+type DetectLocallyResponse struct {
+	Summary     string            `json:"summary" jsonschema_description:"Human-readable summary of detected anomalies"`
+	FitStartS   float64           `json:"fit_start_s" jsonschema_description:"Start of the fit window (Unix seconds)"`
+	StartInferS float64           `json:"start_infer_s" jsonschema_description:"Inference start timestamp (Unix seconds)"`
+	EndInferS   float64           `json:"end_infer_s" jsonschema_description:"Inference end timestamp (Unix seconds)"`
+	Result      *detection.Result `json:"result" jsonschema_description:"Per-series anomaly_score, yhat, yhat_lower and yhat_upper of inferred points (null for points without a prediction) and points with anomaly_score above the threshold"`
+	Warnings    []string          `json:"warnings,omitempty" jsonschema_description:"Query analysis warnings and model parameters ignored by local detection"`
+}
+
+// DetectInlineArgs defines arguments for detect_inline tool
+type DetectInlineArgs struct {
+	Data             string         `json:"data" jsonschema:"required,description=Inline time series as CSV with a header (a timestamp column and either a 'value' column with label columns or a column per series) or JSON ([[ts value]] pairs or series objects with labels and timestamps/values or point objects or a Prometheus query response) or Prometheus text exposition with millisecond timestamps"`
+	Format           string         `json:"format,omitempty" jsonschema:"enum=auto,enum=csv,enum=json,enum=prometheus,description=Format of data. Default: 'auto' which detects it"`
+	ModelSpec        map[string]any `json:"model_spec" jsonschema:"required,description=Model specification as for vmanomaly e.g. {\"class\": \"mad\" \"threshold\": 3}. Supported classes: zscore mad std rolling_quantile zscore_online mad_online quantile_online"`
+	FitUntilS        float64        `json:"fit_until_s,omitempty" jsonschema:"description=Timestamp (Unix seconds) splitting series: earlier points fit models and later points are scored. Default: offline models fit on all points and all points are scored"`
+	AnomalyThreshold float64        `json:"anomaly_threshold,omitempty" jsonschema:"description=Anomaly score above which points are reported as anomalies (default: 1)"`
+}
+
+// DetectInlineResponse is the result of detect_inline tool
+type DetectInlineResponse struct {
+	Summary  string                `json:"summary" jsonschema_description:"Human-readable summary of detected anomalies"`
+	Result   *vmanomaly.TaskResult `json:"result" jsonschema_description:"Detection result in the shape of a detection task result: data.series has per-series timestamps, y, anomaly_score, yhat, yhat_lower, yhat_upper and anomalies, stats has series, points and anomalies counts"`
+	Warnings []string              `json:"warnings,omitempty" jsonschema_description:"Model parameters ignored by local detection and series without fit data"`
+}
+
+// ============================================================================
+// Tool Registration Functions
+// ============================================================================
+
+// RegisterDetectionTools registers local anomaly detection tools
+func RegisterDetectionTools(s *server.MCPServer, client vmanomaly.API) {
+	detectLocallyTool := mcp.NewTool(
+		"vmanomaly_detect_locally",
+		mcp.WithDescription("Run a simple anomaly detection model (zscore, mad, std, rolling_quantile, zscore_online, mad_online, quantile_online) in-process on data fetched through vmanomaly query API. Returns anomaly_score, yhat, yhat_lower and yhat_upper with vmanomaly semantics including detection_direction, min_dev_from_expected and scale. Unlike vmanomaly_create_detection_task it does not occupy vmanomaly task slots, so it suits quick what-if checks of model parameters. Offline models are fit on fit_window before start_infer_s, online models are updated with it. Online models are approximate: they compute exact quantiles instead of t-digest estimates of vmanomaly, so their results may differ slightly."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Detect Anomalies Locally",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[DetectLocallyArgs](),
+		mcp.WithOutputSchema[DetectLocallyResponse](),
+	)
+	s.AddTool(detectLocallyTool, mcp.NewStructuredToolHandler(handleDetectLocally(client)))
+
+	detectInlineTool := mcp.NewTool(
+		"vmanomaly_detect_inline",
+		mcp.WithDescription("Run anomaly detection on time series passed inline, e.g. pasted from systems other than VictoriaMetrics. Accepts CSV, JSON or Prometheus exposition with timestamps and runs a simple model (zscore, mad, std, rolling_quantile, zscore_online, mad_online, quantile_online) in-process, as vmanomaly API cannot ingest data. Returns scores and anomalies in the same shape as the result of vmanomaly_get_task_status. Use fit_until_s to fit models on history and score only later points. Online models are approximate: they compute exact quantiles instead of t-digest estimates of vmanomaly."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Detect Anomalies in Inline Series",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[DetectInlineArgs](),
+		mcp.WithOutputSchema[DetectInlineResponse](),
+	)
+	s.AddTool(detectInlineTool, mcp.NewStructuredToolHandler(handleDetectInline))
+}
+
+// ============================================================================
+// Tool Handlers
+// ============================================================================
+
+func handleDetectLocally(client vmanomaly.API) mcp.StructuredToolHandlerFunc[DetectLocallyArgs, DetectLocallyResponse] {
+	return func(ctx context.Context, req mcp.CallToolRequest, args DetectLocallyArgs) (DetectLocallyResponse, error) {
+		var resp DetectLocallyResponse
+		spec, specWarnings, err := detection.ParseSpec(args.ModelSpec)
+		if err != nil {
+			return resp, err
+		}
+		// Query and datasource defaults are the same as of detection tasks
+		taskReq, warnings, err := buildDetectionTaskRequest(CreateDetectionTaskArgs{
+			Query:            args.Query,
+			Step:             args.Step,
+			FitWindow:        args.FitWindow,
+			AnomalyThreshold: args.AnomalyThreshold,
+			ModelSpec:        args.ModelSpec,
+			DatasourceType:   args.DatasourceType,
+			DatasourceURL:    args.DatasourceURL,
+			TenantID:         args.TenantID,
+			SkipQueryChecks:  args.SkipQueryChecks,
+		})
+		if err != nil {
+			return resp, err
+		}
+		fitWindow, err := vmanomaly.ParseDuration(taskReq.FitWindow)
+		if err != nil {
+			return resp, fmt.Errorf("invalid fit_window: %w", err)
+		}
+
+		resp.EndInferS = args.EndInferS
+		if resp.EndInferS <= 0 {
+			resp.EndInferS = float64(time.Now().Unix())
+		}
+		resp.StartInferS = args.StartInferS
+		if resp.StartInferS <= 0 {
+			resp.StartInferS = resp.EndInferS - defaultLocalInferWindow.Seconds()
+		}
+		if resp.StartInferS >= resp.EndInferS {
+			return resp, fmt.Errorf("start_infer_s must be before end_infer_s")
+		}
+		resp.FitStartS = resp.StartInferS - fitWindow.Seconds()
+
+		result, err := client.Query(ctx, &vmanomaly.QueryRequest{
+			Query:          taskReq.Query,
+			Start:          &resp.FitStartS,
+			End:            &resp.EndInferS,
+			Step:           taskReq.Step,
+			DatasourceType: taskReq.DatasourceType,
+			DatasourceURL:  taskReq.DatasourceURL,
+			TenantID:       taskReq.TenantID,
+		})
+		if err != nil {
+			return resp, fmt.Errorf("query failed: %w", err)
+		}
+		series, err := vmanomaly.ParseQueryResult(result)
+		if err != nil {
+			return resp, fmt.Errorf("cannot parse query result: %w", err)
+		}
+
+		resp.Result = detection.Detect(spec, series, detection.Options{FitUntil: resp.StartInferS, Threshold: taskReq.AnomalyThreshold})
+		resp.Warnings = append(append(warnings, specWarnings...), resp.Result.Warnings...)
+		resp.Summary = summarizeDetection(resp.Result)
+		return resp, nil
+	}
+}
+
+func handleDetectInline(ctx context.Context, req mcp.CallToolRequest, args DetectInlineArgs) (DetectInlineResponse, error) {
+	var resp DetectInlineResponse
+	spec, warnings, err := detection.ParseSpec(args.ModelSpec)
+	if err != nil {
+		return resp, err
+	}
+	series, err := detection.ParseSeries(args.Format, args.Data)
+	if err != nil {
+		return resp, err
+	}
+	points := 0
+	for _, s := range series {
+		points += len(s.Values)
+	}
+	if points > maxInlinePoints {
+		return resp, fmt.Errorf("data has %d points, at most %d are supported, query large series from a datasource with vmanomaly_detect_locally", points, maxInlinePoints)
+	}
+
+	result := detection.Detect(spec, series, detection.Options{FitUntil: args.FitUntilS, Threshold: args.AnomalyThreshold})
+	resp.Result = result.TaskResult()
+	resp.Warnings = append(warnings, result.Warnings...)
+	resp.Summary = summarizeDetection(result)
+	return resp, nil
+}
+
+// summarizeDetection returns a summary of the detection result with series having the most anomalies first
+func summarizeDetection(r *detection.Result) string {
+	if len(r.Series) == 0 {
+		return "Query returned no series."
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s model scored %d points of %d series, %d anomalies with anomaly_score > %g.", r.Model, r.Points, len(r.Series), r.Anomalies, r.Threshold))
+	for _, s := range r.Series {
+		if len(s.Anomalies) == 0 {
+			continue
+		}
+		top := s.Anomalies[0]
+		for _, a := range s.Anomalies {
+			if a.AnomalyScore > top.AnomalyScore {
+				top = a
+			}
+		}
+		sb.WriteString(fmt.Sprintf(" %v: %d anomalies, max score %.2f at %s (y=%g, yhat=%g).",
+			s.Labels, len(s.Anomalies), top.AnomalyScore, time.Unix(int64(top.Timestamp), 0).UTC().Format(time.RFC3339), top.Y, top.Yhat))
+	}
+	return sb.String()
+}
@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestDetectInline(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("timestamp,host,value\n")
+	for i := range 30 {
+		v := 10 + i%3
+		if i == 25 {
+			v = 50
+		}
+		sb.WriteString(fmt.Sprintf("%d,a,%d\n", 1700000000+60*i, v))
+	}
+
+	resp, err := handleDetectInline(context.Background(), mcp.CallToolRequest{}, DetectInlineArgs{
+		Data:      sb.String(),
+		ModelSpec: map[string]any{"class": "mad", "threshold": 3.0, "detection_direction": "above_expected"},
+		FitUntilS: 1700000000 + 60*20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Result.Status != "success" || resp.Result.Stats["points"] != 10 || resp.Result.Stats["anomalies"] != 1 {
+		t.Fatalf("stats = %v", resp.Result.Stats)
+	}
+	data, err := json.Marshal(resp.Result.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"anomalies":[{"anomaly_score":13,"timestamp":1700001500,"y":50,"yhat":11}]`) {
+		t.Errorf("data = %s", data)
+	}
+	if !strings.Contains(resp.Summary, "1 anomalies") {
+		t.Errorf("summary = %s", resp.Summary)
+	}
+
+	if _, err := handleDetectInline(context.Background(), mcp.CallToolRequest{}, DetectInlineArgs{Data: "up 1", ModelSpec: map[string]any{"class": "zscore"}}); err == nil {
+		t.Error("expected error for samples without timestamps")
+	}
+}
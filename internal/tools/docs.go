@@ -2,34 +2,80 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"strings"
 
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/changelog"
 	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/resources"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
+const (
+	// defaultDocsMaxBytes is the default budget of search_docs results
+	defaultDocsMaxBytes = 12000
+	// bytesPerToken is a rough estimate of bytes per LLM token for English text and markdown
+	bytesPerToken = 4
+	// docResultOverhead is an estimate of bytes taken by fields of a result other than its strings
+	docResultOverhead = 64
+)
+
 // ============================================================================
 // Documentation Search Tool Arguments (Struct-based schemas)
 // ============================================================================
 
 // SearchDocsArgs defines arguments for search_docs tool
 type SearchDocsArgs struct {
-	Query string  `json:"query" jsonschema_description:"Search query for vmanomaly documentation. Supports keywords phrases or natural language questions. Example queries: 'prophet model parameters' 'how to configure seasonality' 'online vs batch models' 'installation requirements' 'troubleshooting errors'. Uses fuzzy matching to find relevant documentation chunks."`
-	Limit float64 `json:"limit,omitempty" jsonschema_description:"Maximum number of documentation resources to return. Range: 1-100. Default: 30. Higher limits provide more context but may include less relevant results."`
+	Query     string  `json:"query" jsonschema_description:"Search query for vmanomaly documentation. Supports keywords phrases or natural language questions. Example queries: 'prophet model parameters' 'how to configure seasonality' 'online vs batch models' 'installation requirements' 'troubleshooting errors'. Uses fuzzy matching to find relevant documentation sections."`
+	Limit     float64 `json:"limit,omitempty" jsonschema_description:"Maximum number of sections to return. Range: 1-100. Default: 10."`
+	Version   string  `json:"version,omitempty" jsonschema_description:"vmanomaly version to search documentation of (e.g. 'v1.24.0'). The newest documentation set not newer than the version is used. Use 'runtime' for the version of the connected vmanomaly or 'latest' for the newest docs. Default: the set picked at startup for the connected vmanomaly. Only the latest documentation is available unless older sets are added with MCP_DOCS_VERSIONS_DIR, and docs_version of the result tells which set was searched."`
+	MaxBytes  float64 `json:"max_bytes,omitempty" jsonschema_description:"Budget of returned results in bytes. Lower ranked results are dropped to fit. Default: 12000."`
+	MaxTokens float64 `json:"max_tokens,omitempty" jsonschema_description:"Budget of returned results in LLM tokens (estimated as 4 bytes per token). The smaller of max_bytes and max_tokens is used."`
+}
+
+// DocSearchResult is a documentation section matching the query
+type DocSearchResult struct {
+	URI          string  `json:"uri" jsonschema_description:"Section URI. Pass it to vmanomaly_get_doc_section for the full section"`
+	HeadingPath  string  `json:"heading_path" jsonschema_description:"Headings from the document title to the section separated by ' > '"`
+	Score        float64 `json:"score" jsonschema_description:"Relevance score. Higher is better"`
+	Snippet      string  `json:"snippet" jsonschema_description:"The most relevant part of the section"`
+	Source       string  `json:"source,omitempty" jsonschema_description:"Name of the local documentation source if the section is not part of upstream vmanomaly docs"`
+	Availability string  `json:"availability,omitempty" jsonschema_description:"Versions the described features are available since compared with the connected vmanomaly"`
+}
+
+// SearchDocsResponse is the result of search_docs tool
+type SearchDocsResponse struct {
+	DocsVersion string            `json:"docs_version" jsonschema_description:"Version of the searched documentation set"`
+	Note        string            `json:"note,omitempty" jsonschema_description:"Note about the searched documentation set or empty results"`
+	Results     []DocSearchResult `json:"results" jsonschema_description:"Matching sections ordered by relevance"`
+	// Omitted is the number of results dropped to fit the budget
+	Omitted int `json:"omitted,omitempty" jsonschema_description:"Number of lower ranked results dropped to fit the budget"`
+}
+
+// GetDocSectionArgs defines arguments for get_doc_section tool
+type GetDocSectionArgs struct {
+	URI      string  `json:"uri" jsonschema:"required" jsonschema_description:"Section URI returned by vmanomaly_search_docs (e.g. 'docs://docs/anomaly-detection/FAQ.md#3.1'). A documentation resource URI without the section number returns the whole chunk."`
+	MaxBytes float64 `json:"max_bytes,omitempty" jsonschema_description:"Maximum size of the returned content in bytes. Default: unlimited."`
+}
+
+// GetDocSectionResponse is the result of get_doc_section tool
+type GetDocSectionResponse struct {
+	resources.Section
+	Truncated bool `json:"truncated,omitempty" jsonschema_description:"Whether the content is cut to max_bytes"`
 }
 
 // ============================================================================
 // Tool Registration Functions
 // ============================================================================
 
-// RegisterDocsTool registers the documentation search tool
-func RegisterDocsTool(s *server.MCPServer) {
+// RegisterDocsTools registers documentation tools
+func RegisterDocsTools(s *server.MCPServer, client vmanomaly.API) {
 	searchDocsTool := mcp.NewTool(
 		"vmanomaly_search_docs",
-		mcp.WithDescription("Search vmanomaly documentation using full-text search with fuzzy matching. Returns relevant documentation resources that can help answer questions about anomaly detection, models, configuration, and vmanomaly features. Use this when you need information about model parameters, configuration syntax, troubleshooting, or feature explanations."),
+		mcp.WithDescription("Search vmanomaly documentation using full-text search with fuzzy matching. Returns ranked section snippets with heading paths, scores and section URIs within a size budget. Use vmanomaly_get_doc_section to read a full section. Use this when you need information about model parameters, configuration syntax, troubleshooting, or feature explanations. Results are annotated with versions the described features are available since, compared with the connected vmanomaly version. Use vmanomaly_search_changelog for questions about changes between versions."),
 		mcp.WithToolAnnotation(mcp.ToolAnnotation{
 			Title:           "Search vmanomaly Docs",
 			ReadOnlyHint:    ptr(true),
@@ -37,8 +83,23 @@ func RegisterDocsTool(s *server.MCPServer) {
 			OpenWorldHint:   ptr(false),
 		}),
 		mcp.WithInputSchema[SearchDocsArgs](),
+		mcp.WithOutputSchema[SearchDocsResponse](),
 	)
-	s.AddTool(searchDocsTool, mcp.NewTypedToolHandler(handleSearchDocs()))
+	s.AddTool(searchDocsTool, mcp.NewStructuredToolHandler(handleSearchDocs(client)))
+
+	getDocSectionTool := mcp.NewTool(
+		"vmanomaly_get_doc_section",
+		mcp.WithDescription("Get the full content of a vmanomaly documentation section by its URI returned by vmanomaly_search_docs."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Get vmanomaly Docs Section",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[GetDocSectionArgs](),
+		mcp.WithOutputSchema[GetDocSectionResponse](),
+	)
+	s.AddTool(getDocSectionTool, mcp.NewStructuredToolHandler(handleGetDocSection))
 }
 
 // ============================================================================
@@ -46,38 +107,111 @@ func RegisterDocsTool(s *server.MCPServer) {
 // ============================================================================
 
 // handleSearchDocs handles the search_docs tool
-func handleSearchDocs() func(ctx context.Context, req mcp.CallToolRequest, args SearchDocsArgs) (*mcp.CallToolResult, error) {
-	return func(ctx context.Context, req mcp.CallToolRequest, args SearchDocsArgs) (*mcp.CallToolResult, error) {
-		// Validate and set defaults
+func handleSearchDocs(client vmanomaly.API) mcp.StructuredToolHandlerFunc[SearchDocsArgs, SearchDocsResponse] {
+	return func(ctx context.Context, req mcp.CallToolRequest, args SearchDocsArgs) (SearchDocsResponse, error) {
+		resp := SearchDocsResponse{Results: []DocSearchResult{}}
 		limit := int(args.Limit)
 		if limit < 1 {
-			limit = 30 // default
+			limit = 10 // default
 		}
+		budget := docsBudget(args.MaxBytes, args.MaxTokens)
 
-		// Search documentation
-		rs, err := resources.SearchDocResources(args.Query, limit)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Search failed: %v", err)), nil
+		version := args.Version
+		if version == "runtime" {
+			v, ok := runtimeVersion(ctx, client)
+			if !ok {
+				return resp, fmt.Errorf("cannot detect the version of the connected vmanomaly")
+			}
+			version = v.String()
 		}
 
-		// Build result with embedded resources
-		result := &mcp.CallToolResult{Content: []mcp.Content{}}
-		for _, resource := range rs {
-			content, err := resources.GetDocResourceContent(resource.URI)
-			if err != nil {
-				log.Printf("error getting content for resource %s: %v", resource.URI, err)
-				continue
+		hits, docsVersion, err := resources.SearchDocSections(ctx, args.Query, version, min(limit, 100), min(budget, resources.DefaultSnippetSize))
+		resp.DocsVersion = docsVersion
+		if errors.Is(err, resources.ErrNoResults) {
+			resp.Note = fmt.Sprintf("No documentation found for query: %s", args.Query)
+			return resp, nil
+		}
+		if err != nil {
+			return resp, fmt.Errorf("search failed: %w", err)
+		}
+		if version != "" && version != "latest" && !strings.EqualFold(strings.TrimPrefix(version, "v"), strings.TrimPrefix(docsVersion, "v")) {
+			resp.Note = fmt.Sprintf("Closest available documentation set for requested %s", version)
+			if sets, err := resources.DocSets(); err == nil && len(sets) == 1 {
+				resp.Note = fmt.Sprintf("Only the latest documentation (%s) is available, requested %s is not shipped. Set MCP_DOCS_VERSIONS_DIR to add older versions", docsVersion, version)
 			}
-			result.Content = append(result.Content, mcp.EmbeddedResource{
-				Type:     "resource",
-				Resource: content,
-			})
 		}
 
-		if len(result.Content) == 0 {
-			return mcp.NewToolResultText(fmt.Sprintf("No documentation found for query: %s", args.Query)), nil
+		var runtime *changelog.Version
+		runtimeChecked := false
+		results := make([]DocSearchResult, 0, len(hits))
+		for _, hit := range hits {
+			result := DocSearchResult{
+				URI:         hit.URI,
+				HeadingPath: strings.Join(hit.HeadingPath, " > "),
+				Score:       hit.Score,
+				Snippet:     hit.Snippet,
+			}
+			if hit.Source != "" && hit.Source != resources.SourceUpstream {
+				result.Source = hit.Source
+			}
+			if strings.Contains(hit.Content, "available_from") {
+				// Build info is requested only if some result has version annotations
+				if !runtimeChecked {
+					if v, ok := runtimeVersion(ctx, client); ok {
+						runtime = &v
+					}
+					runtimeChecked = true
+				}
+				result.Availability = availabilityHint(hit.Content, runtime)
+			}
+			results = append(results, result)
 		}
+		resp.Results, resp.Omitted = fitDocsBudget(results, budget)
+		return resp, nil
+	}
+}
+
+// handleGetDocSection handles the get_doc_section tool
+func handleGetDocSection(_ context.Context, _ mcp.CallToolRequest, args GetDocSectionArgs) (GetDocSectionResponse, error) {
+	section, err := resources.GetDocSection(args.URI)
+	if err != nil {
+		return GetDocSectionResponse{}, err
+	}
+	resp := GetDocSectionResponse{Section: section}
+	if maxBytes := int(args.MaxBytes); maxBytes > 0 && len(section.Content) > maxBytes {
+		resp.Content = resources.Truncate(section.Content, maxBytes)
+		resp.Truncated = true
+	}
+	return resp, nil
+}
 
-		return result, nil
+// docsBudget returns the budget of search results in bytes
+func docsBudget(maxBytes, maxTokens float64) int {
+	budget := defaultDocsMaxBytes
+	if maxBytes > 0 {
+		budget = int(maxBytes)
+	}
+	if maxTokens > 0 {
+		budget = min(budget, int(maxTokens)*bytesPerToken)
+	}
+	return budget
+}
+
+// fitDocsBudget returns results fitting into the budget in bytes and the number of dropped results.
+// The snippet of the first result is cut if it alone does not fit.
+func fitDocsBudget(results []DocSearchResult, budget int) ([]DocSearchResult, int) {
+	used := 0
+	for i, r := range results {
+		size := docResultOverhead + len(r.URI) + len(r.HeadingPath) + len(r.Snippet) + len(r.Source) + len(r.Availability)
+		if used+size <= budget {
+			used += size
+			continue
+		}
+		if i == 0 {
+			results[0].Snippet = resources.Truncate(r.Snippet, max(0, budget-(size-len(r.Snippet))))
+			return results[:1], len(results) - 1
+		}
+		return results[:i], len(results) - i
 	}
+	return results, 0
 }
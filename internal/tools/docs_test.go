@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocsBudget(t *testing.T) {
+	tests := []struct {
+		maxBytes, maxTokens float64
+		want                int
+	}{
+		{0, 0, defaultDocsMaxBytes},
+		{5000, 0, 5000},
+		{5000, 500, 2000},
+		{0, 10000, defaultDocsMaxBytes},
+	}
+	for _, tt := range tests {
+		if got := docsBudget(tt.maxBytes, tt.maxTokens); got != tt.want {
+			t.Errorf("docsBudget(%v, %v) = %d, want %d", tt.maxBytes, tt.maxTokens, got, tt.want)
+		}
+	}
+}
+
+func TestFitDocsBudget(t *testing.T) {
+	newResults := func() []DocSearchResult {
+		return []DocSearchResult{
+			{URI: "docs://a#0.0", Snippet: strings.Repeat("a", 100)},
+			{URI: "docs://b#0.0", Snippet: strings.Repeat("b", 100)},
+			{URI: "docs://c#0.0", Snippet: strings.Repeat("c", 100)},
+		}
+	}
+	size := docResultOverhead + len("docs://a#0.0") + 100
+
+	got, omitted := fitDocsBudget(newResults(), 2*size+10)
+	if len(got) != 2 || omitted != 1 {
+		t.Errorf("got %d results and %d omitted, want 2 and 1", len(got), omitted)
+	}
+
+	got, omitted = fitDocsBudget(newResults(), size-50)
+	if len(got) != 1 || omitted != 2 || len(got[0].Snippet) > 50 || !strings.HasSuffix(got[0].Snippet, "…") {
+		t.Errorf("the first result must be cut to fit, got %+v and %d omitted", got, omitted)
+	}
+}
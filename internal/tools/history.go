@@ -0,0 +1,325 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/taskregistry"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// defaultHistoryLimit is the default maximum number of tasks returned by list_task_history
+const defaultHistoryLimit = 20
+
+// ============================================================================
+// Task History Tool Arguments (Struct-based schemas)
+// ============================================================================
+
+// ListTaskHistoryArgs defines arguments for list_task_history tool
+type ListTaskHistoryArgs struct {
+	Label          string  `json:"label,omitempty" jsonschema_description:"Only tasks with this label"`
+	Status         string  `json:"status,omitempty" jsonschema:"enum=running,enum=done,enum=error,enum=canceled" jsonschema_description:"Only tasks with this last recorded status"`
+	CurrentSession bool    `json:"current_session,omitempty" jsonschema_description:"Only tasks created in the current MCP session. Default: false."`
+	Limit          float64 `json:"limit,omitempty" jsonschema_description:"Maximum number of tasks to return, the most recent first. Default: 20."`
+}
+
+// TaskHistoryItem is a summary of a recorded task
+type TaskHistoryItem struct {
+	TaskID         string     `json:"task_id"`
+	Label          string     `json:"label,omitempty"`
+	Status         string     `json:"status" jsonschema_description:"Last recorded status"`
+	Progress       int        `json:"progress"`
+	Query          string     `json:"query"`
+	Model          any        `json:"model,omitempty" jsonschema_description:"Model class of the task"`
+	Step           string     `json:"step"`
+	DatasourceType string     `json:"datasource_type"`
+	RerunOf        string     `json:"rerun_of,omitempty" jsonschema_description:"ID of the task this one re-runs"`
+	HasResult      bool       `json:"has_result" jsonschema_description:"Whether the final result is cached"`
+	CreatedAt      time.Time  `json:"created_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
+
+// ListTaskHistoryResponse is the result of list_task_history tool
+type ListTaskHistoryResponse struct {
+	Tasks []TaskHistoryItem `json:"tasks"`
+}
+
+// ReopenTaskArgs defines arguments for reopen_task tool
+type ReopenTaskArgs struct {
+	Task string `json:"task" jsonschema:"required" jsonschema_description:"Task ID or label. The most recent task is used for labels of several tasks"`
+}
+
+// ReopenTaskResponse is the result of reopen_task tool
+type ReopenTaskResponse struct {
+	Task *taskregistry.Record `json:"task" jsonschema_description:"Recorded task: request, submitting session, timestamps, status and the cached final result"`
+	Note string               `json:"note,omitempty"`
+}
+
+// CompareTasksArgs defines arguments for compare_tasks tool
+type CompareTasksArgs struct {
+	TaskA string `json:"task_a" jsonschema:"required" jsonschema_description:"Task ID or label of the first (baseline) task"`
+	TaskB string `json:"task_b" jsonschema:"required" jsonschema_description:"Task ID or label of the second task"`
+}
+
+// CompareTasksResponse is the result of compare_tasks tool
+type CompareTasksResponse = taskregistry.Comparison
+
+// RerunTaskArgs defines arguments for rerun_task tool
+type RerunTaskArgs struct {
+	Task             string         `json:"task" jsonschema:"required" jsonschema_description:"Task ID or label of the task to re-run"`
+	Label            string         `json:"label,omitempty" jsonschema_description:"Label of the new task. Default: label of the re-run task"`
+	ModelSpec        map[string]any `json:"model_spec,omitempty" jsonschema_description:"Model specification replacing the recorded one (must include 'class' field)"`
+	Step             string         `json:"step,omitempty" jsonschema_description:"Query step replacing the recorded one"`
+	FitWindow        string         `json:"fit_window,omitempty" jsonschema_description:"Fit window replacing the recorded one"`
+	FitEvery         string         `json:"fit_every,omitempty" jsonschema_description:"Retraining frequency replacing the recorded one"`
+	StartInferS      float64        `json:"start_infer_s,omitempty" jsonschema_description:"Inference start timestamp (Unix seconds) replacing the recorded one"`
+	EndInferS        float64        `json:"end_infer_s,omitempty" jsonschema_description:"Inference end timestamp (Unix seconds) replacing the recorded one"`
+	AnomalyThreshold float64        `json:"anomaly_threshold,omitempty" jsonschema_description:"Anomaly score threshold replacing the recorded one"`
+}
+
+// RerunTaskResponse is the result of rerun_task tool
+type RerunTaskResponse struct {
+	TaskID    string   `json:"task_id" jsonschema_description:"ID of the new task"`
+	Status    string   `json:"status"`
+	Label     string   `json:"label,omitempty"`
+	RerunOf   string   `json:"rerun_of" jsonschema_description:"ID of the re-run task"`
+	Overrides []string `json:"overrides" jsonschema_description:"Request fields replaced by arguments"`
+	Note      string   `json:"note,omitempty"`
+}
+
+// ============================================================================
+// Tool Registration Functions
+// ============================================================================
+
+// RegisterHistoryTools registers tools working with the local history of detection tasks
+func RegisterHistoryTools(s *server.MCPServer, client vmanomaly.API, registry *taskregistry.Registry) {
+	listTaskHistoryTool := mcp.NewTool(
+		"vmanomaly_list_task_history",
+		mcp.WithDescription("List anomaly detection tasks created through this MCP server from the local task history, the most recent first. Unlike vmanomaly_list_tasks, the history keeps tasks and their results after vmanomaly forgets them or restarts. Filter by label, status or the current session."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "List Detection Task History",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[ListTaskHistoryArgs](),
+		mcp.WithOutputSchema[ListTaskHistoryResponse](),
+	)
+	s.AddTool(listTaskHistoryTool, mcp.NewStructuredToolHandler(handleListTaskHistory(registry)))
+
+	reopenTaskTool := mcp.NewTool(
+		"vmanomaly_reopen_task",
+		mcp.WithDescription("Re-open a past detection task from the local task history by ID or label: its full request, timestamps, status and cached final result. Unfinished tasks are refreshed from vmanomaly first."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Re-open Detection Task",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[ReopenTaskArgs](),
+		mcp.WithOutputSchema[ReopenTaskResponse](),
+	)
+	s.AddTool(reopenTaskTool, mcp.NewStructuredToolHandler(handleReopenTask(client, registry)))
+
+	compareTasksTool := mcp.NewTool(
+		"vmanomaly_compare_tasks",
+		mcp.WithDescription("Compare two detection tasks from the local task history by ID or label: differences of their requests (query, model spec, windows) and of their cached results and stats, with deltas for numbers. Use it to see how a model or parameter change affected detection."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Compare Detection Tasks",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[CompareTasksArgs](),
+		mcp.WithOutputSchema[CompareTasksResponse](),
+	)
+	s.AddTool(compareTasksTool, mcp.NewStructuredToolHandler(handleCompareTasks(client, registry)))
+
+	rerunTaskTool := mcp.NewTool(
+		"vmanomaly_rerun_task",
+		mcp.WithDescription("Re-run a past detection task from the local task history by ID or label with the same request, optionally replacing the model spec, step, windows, inference range or threshold. The new task is recorded with a reference to the re-run one; compare them with vmanomaly_compare_tasks."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Re-run Detection Task",
+			ReadOnlyHint:    ptr(false),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[RerunTaskArgs](),
+		mcp.WithOutputSchema[RerunTaskResponse](),
+	)
+	s.AddTool(rerunTaskTool, mcp.NewStructuredToolHandler(handleRerunTask(client, registry)))
+}
+
+// ============================================================================
+// Tool Handlers
+// ============================================================================
+
+// handleListTaskHistory handles the list_task_history tool
+func handleListTaskHistory(registry *taskregistry.Registry) mcp.StructuredToolHandlerFunc[ListTaskHistoryArgs, ListTaskHistoryResponse] {
+	return func(ctx context.Context, req mcp.CallToolRequest, args ListTaskHistoryArgs) (ListTaskHistoryResponse, error) {
+		resp := ListTaskHistoryResponse{Tasks: []TaskHistoryItem{}}
+		limit := int(args.Limit)
+		if limit < 1 {
+			limit = defaultHistoryLimit
+		}
+		filter := taskregistry.Filter{Label: args.Label, Status: args.Status, Limit: limit}
+		if args.CurrentSession {
+			if filter.SessionID = sessionID(ctx); filter.SessionID == "" {
+				return resp, fmt.Errorf("current session is unknown")
+			}
+		}
+		records, err := registry.List(filter)
+		if err != nil {
+			return resp, fmt.Errorf("cannot read task history: %w", err)
+		}
+		for _, rec := range records {
+			item := TaskHistoryItem{
+				TaskID:     rec.TaskID,
+				Label:      rec.Label,
+				Status:     rec.Status,
+				Progress:   rec.Progress,
+				RerunOf:    rec.RerunOf,
+				HasResult:  rec.Result != nil,
+				CreatedAt:  rec.CreatedAt,
+				FinishedAt: rec.FinishedAt,
+			}
+			if rec.Request != nil {
+				item.Query = rec.Request.Query
+				item.Model = rec.Request.ModelSpec["class"]
+				item.Step = rec.Request.Step
+				item.DatasourceType = rec.Request.DatasourceType
+			}
+			resp.Tasks = append(resp.Tasks, item)
+		}
+		return resp, nil
+	}
+}
+
+// handleReopenTask handles the reopen_task tool
+func handleReopenTask(client vmanomaly.API, registry *taskregistry.Registry) mcp.StructuredToolHandlerFunc[ReopenTaskArgs, ReopenTaskResponse] {
+	return func(ctx context.Context, req mcp.CallToolRequest, args ReopenTaskArgs) (ReopenTaskResponse, error) {
+		rec, note, err := refreshedRecord(ctx, client, registry, args.Task)
+		if err != nil {
+			return ReopenTaskResponse{}, err
+		}
+		return ReopenTaskResponse{Task: rec, Note: note}, nil
+	}
+}
+
+// handleCompareTasks handles the compare_tasks tool
+func handleCompareTasks(client vmanomaly.API, registry *taskregistry.Registry) mcp.StructuredToolHandlerFunc[CompareTasksArgs, CompareTasksResponse] {
+	return func(ctx context.Context, req mcp.CallToolRequest, args CompareTasksArgs) (CompareTasksResponse, error) {
+		a, noteA, err := refreshedRecord(ctx, client, registry, args.TaskA)
+		if err != nil {
+			return CompareTasksResponse{}, err
+		}
+		b, noteB, err := refreshedRecord(ctx, client, registry, args.TaskB)
+		if err != nil {
+			return CompareTasksResponse{}, err
+		}
+		if a.TaskID == b.TaskID {
+			return CompareTasksResponse{}, fmt.Errorf("%q and %q refer to the same task %s", args.TaskA, args.TaskB, a.TaskID)
+		}
+		resp := taskregistry.Compare(a, b)
+		for _, note := range []string{noteA, noteB} {
+			if note != "" {
+				resp.Notes = append(resp.Notes, note)
+			}
+		}
+		return resp, nil
+	}
+}
+
+// handleRerunTask handles the rerun_task tool
+func handleRerunTask(client vmanomaly.API, registry *taskregistry.Registry) mcp.StructuredToolHandlerFunc[RerunTaskArgs, RerunTaskResponse] {
+	return func(ctx context.Context, req mcp.CallToolRequest, args RerunTaskArgs) (RerunTaskResponse, error) {
+		rec, err := registry.Get(args.Task)
+		if err != nil {
+			return RerunTaskResponse{}, err
+		}
+		if rec.Request == nil {
+			return RerunTaskResponse{}, fmt.Errorf("task %s has no recorded request", rec.TaskID)
+		}
+
+		taskReq := *rec.Request
+		overrides := []string{}
+		override := func(field string, set bool, apply func()) {
+			if set {
+				apply()
+				overrides = append(overrides, field)
+			}
+		}
+		override("model_spec", args.ModelSpec != nil, func() { taskReq.ModelSpec = args.ModelSpec })
+		override("step", args.Step != "", func() { taskReq.Step = args.Step })
+		override("fit_window", args.FitWindow != "", func() { taskReq.FitWindow = args.FitWindow })
+		override("fit_every", args.FitEvery != "", func() { taskReq.FitEvery = args.FitEvery })
+		override("start_infer_s", args.StartInferS > 0, func() { taskReq.StartInferS = &args.StartInferS })
+		override("end_infer_s", args.EndInferS > 0, func() { taskReq.EndInferS = &args.EndInferS })
+		override("anomaly_threshold", args.AnomalyThreshold > 0, func() { taskReq.AnomalyThreshold = args.AnomalyThreshold })
+
+		task, err := client.CreateDetectionTask(ctx, &taskReq)
+		if err != nil {
+			return RerunTaskResponse{}, fmt.Errorf("failed to create detection task: %w", err)
+		}
+
+		resp := RerunTaskResponse{
+			TaskID:    task.TaskID,
+			Status:    task.Status,
+			Label:     args.Label,
+			RerunOf:   rec.TaskID,
+			Overrides: overrides,
+		}
+		if resp.Label == "" {
+			resp.Label = rec.Label
+		}
+		err = registry.Add(&taskregistry.Record{
+			TaskID:    task.TaskID,
+			Label:     resp.Label,
+			SessionID: sessionID(ctx),
+			RerunOf:   rec.TaskID,
+			Request:   &taskReq,
+			Status:    task.Status,
+		})
+		if err != nil {
+			resp.Note = fmt.Sprintf("The task is not recorded in the local task history: %v", err)
+		}
+		return resp, nil
+	}
+}
+
+// refreshedRecord returns a task of the registry by ID or label. Unfinished tasks are refreshed from vmanomaly,
+// the note explains why a task could not be refreshed.
+func refreshedRecord(ctx context.Context, client vmanomaly.API, registry *taskregistry.Registry, ref string) (*taskregistry.Record, string, error) {
+	rec, err := registry.Get(ref)
+	if err != nil {
+		return nil, "", err
+	}
+	if rec.Finished() {
+		return rec, "", nil
+	}
+	status, err := client.GetTaskStatus(ctx, rec.TaskID)
+	if err != nil {
+		return rec, fmt.Sprintf("vmanomaly cannot return task %s (%v), its last recorded state is shown", rec.TaskID, err), nil
+	}
+	updated, err := registry.Update(status)
+	if err != nil {
+		return rec, fmt.Sprintf("cannot update task %s in the local task history: %v", rec.TaskID, err), nil
+	}
+	if updated == nil {
+		// The record was pruned meanwhile
+		return rec, "", nil
+	}
+	return updated, "", nil
+}
+
+// sessionID returns the ID of the MCP session of the request, empty if it is unknown
+func sessionID(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
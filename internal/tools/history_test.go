@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/taskregistry"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestTaskHistory(t *testing.T) {
+	var created []vmanomaly.AnomalyDetectionTaskRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost:
+			var req vmanomaly.AnomalyDetectionTaskRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			created = append(created, req)
+			fmt.Fprintf(w, `{"task_id": "t%d", "status": "running"}`, len(created))
+		case strings.HasSuffix(r.URL.Path, "/t1"):
+			_, _ = w.Write([]byte(`{"task_id": "t1", "status": "done", "progress": 100, "result_data": {"status": "success", "stats": {"anomalies": 5}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	client := vmanomaly.NewClient(srv.URL, "", nil)
+	registry := taskregistry.New(taskregistry.NewMemoryStore(), 0)
+	ctx := context.Background()
+
+	result, err := handleCreateDetectionTask(client, registry)(ctx, mcp.CallToolRequest{}, CreateDetectionTaskArgs{
+		Query: "sum(up)", Step: "1m", ModelSpec: map[string]any{"class": "zscore"}, SkipQueryChecks: true, Label: "baseline",
+	})
+	if err != nil || result.IsError {
+		t.Fatalf("create failed: %+v, %v", result, err)
+	}
+
+	// The result is cached when the task is done and served after vmanomaly forgets the task
+	status, _, err := taskStatus(ctx, client, registry, "t1")
+	if err != nil || status.Status != "done" {
+		t.Fatalf("taskStatus() = %+v, %v", status, err)
+	}
+	srv.Close()
+	status, note, err := taskStatus(ctx, client, registry, "t1")
+	if err != nil || status.ResultData == nil || note == "" {
+		t.Fatalf("cached status = %+v, %q, %v", status, note, err)
+	}
+
+	srv = httptest.NewServer(srv.Config.Handler)
+	defer srv.Close()
+	client = vmanomaly.NewClient(srv.URL, "", nil)
+	rerun, err := handleRerunTask(client, registry)(ctx, mcp.CallToolRequest{}, RerunTaskArgs{Task: "baseline", ModelSpec: map[string]any{"class": "mad"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rerun.TaskID != "t2" || rerun.RerunOf != "t1" || rerun.Label != "baseline" || len(rerun.Overrides) != 1 {
+		t.Errorf("rerun = %+v", rerun)
+	}
+	if len(created) != 2 || created[1].Query != "sum(up)" || created[1].ModelSpec["class"] != "mad" {
+		t.Errorf("rerun request = %+v", created[1])
+	}
+
+	history, err := handleListTaskHistory(registry)(ctx, mcp.CallToolRequest{}, ListTaskHistoryArgs{Label: "baseline"})
+	if err != nil || len(history.Tasks) != 2 || history.Tasks[0].TaskID != "t2" || !history.Tasks[1].HasResult {
+		t.Errorf("history = %+v, %v", history, err)
+	}
+
+	// t2 is unknown to the test server, its recorded state is compared
+	cmp, err := handleCompareTasks(client, registry)(ctx, mcp.CallToolRequest{}, CompareTasksArgs{TaskA: "t1", TaskB: "t2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmp.RequestDifferences) != 1 || cmp.RequestDifferences[0].Field != "model_spec.class" || len(cmp.Notes) != 2 {
+		t.Errorf("comparison = %+v", cmp)
+	}
+	if _, err := handleCompareTasks(client, registry)(ctx, mcp.CallToolRequest{}, CompareTasksArgs{TaskA: "t2", TaskB: "baseline"}); err == nil {
+		t.Error("expected error comparing a task with itself")
+	}
+}
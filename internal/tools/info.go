@@ -11,12 +11,21 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 )
 
+// ============================================================================
+// Info Tool Arguments (Struct-based schemas)
+// ============================================================================
+
+// GetBuildinfoArgs defines arguments for get_buildinfo tool
+type GetBuildinfoArgs struct {
+	NoCache bool `json:"no_cache,omitempty" jsonschema:"description=Bypass the response cache and fetch build information from vmanomaly (default: false)"`
+}
+
 // ============================================================================
 // Tool Registration Functions
 // ============================================================================
 
 // RegisterInfoTools registers all query and utility tools
-func RegisterInfoTools(s *server.MCPServer, client *vmanomaly.Client) {
+func RegisterInfoTools(s *server.MCPServer, client vmanomaly.API) {
 	// get_buildinfo tool
 	getBuildinfoTool := mcp.NewTool(
 		"vmanomaly_get_buildinfo",
@@ -27,8 +36,9 @@ func RegisterInfoTools(s *server.MCPServer, client *vmanomaly.Client) {
 			DestructiveHint: ptr(false),
 			OpenWorldHint:   ptr(false),
 		}),
+		mcp.WithInputSchema[GetBuildinfoArgs](),
 	)
-	s.AddTool(getBuildinfoTool, handleGetBuildinfo(client))
+	s.AddTool(getBuildinfoTool, mcp.NewTypedToolHandler(handleGetBuildinfo(client)))
 
 	getMetricsTool := mcp.NewTool(
 		"vmanomaly_get_metrics",
@@ -47,8 +57,11 @@ func RegisterInfoTools(s *server.MCPServer, client *vmanomaly.Client) {
 // Tool Handlers
 // ============================================================================
 
-func handleGetBuildinfo(client *vmanomaly.Client) server.ToolHandlerFunc {
-	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func handleGetBuildinfo(client vmanomaly.API) func(ctx context.Context, req mcp.CallToolRequest, args GetBuildinfoArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args GetBuildinfoArgs) (*mcp.CallToolResult, error) {
+		if args.NoCache {
+			ctx = vmanomaly.WithNoCache(ctx)
+		}
 		buildInfo, err := client.GetBuildInfo(ctx)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to get build info: %v", err)), nil
@@ -64,7 +77,7 @@ func handleGetBuildinfo(client *vmanomaly.Client) server.ToolHandlerFunc {
 	}
 }
 
-func handleGetMetrics(client *vmanomaly.Client) server.ToolHandlerFunc {
+func handleGetMetrics(client vmanomaly.API) server.ToolHandlerFunc {
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		metrics, err := client.Metrics(ctx, nil)
 		if err != nil {
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
 )
 
 func TestGetBuildinfo_Error(t *testing.T) {
@@ -13,9 +15,12 @@ func TestGetBuildinfo_Error(t *testing.T) {
 		},
 	}
 
-	_, err := mock.GetBuildInfo(context.Background())
+	result, err := handleGetBuildinfo(mock)(context.Background(), mcp.CallToolRequest{}, GetBuildinfoArgs{})
 
-	if err == nil {
-		t.Error("expected error from API")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Error("expected tool error from API")
 	}
 }
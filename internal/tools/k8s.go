@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/k8s"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmalert"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ============================================================================
+// Kubernetes Tool Arguments (Struct-based schemas)
+// ============================================================================
+
+// GenerateK8sManifestsArgs defines arguments for generate_k8s_manifests tool
+type GenerateK8sManifestsArgs struct {
+	Config           map[string]any `json:"config,omitempty" jsonschema:"description=Complete vmanomaly configuration object (reader/schedulers/models/writer). Either config or config_yaml must be set"`
+	ConfigYAML       string         `json:"config_yaml,omitempty" jsonschema:"description=Complete vmanomaly configuration as YAML text. Either config or config_yaml must be set"`
+	Format           string         `json:"format,omitempty" jsonschema:"enum=operator,enum=helm,description=Output format: 'operator' for VMAnomaly and VMRule custom resources or 'helm' for values.yaml of the victoria-metrics-anomaly chart (default: operator)"`
+	Name             string         `json:"name,omitempty" jsonschema:"description=Name of the VMAnomaly resource (default: 'vmanomaly')"`
+	Namespace        string         `json:"namespace,omitempty" jsonschema:"description=Namespace of generated resources"`
+	Replicas         float64        `json:"replicas,omitempty" jsonschema:"description=Number of replicas per shard"`
+	Shards           float64        `json:"shards,omitempty" jsonschema:"description=Number of shards to split models between"`
+	CPURequest       string         `json:"cpu_request,omitempty" jsonschema:"description=CPU request e.g. '500m'"`
+	MemoryRequest    string         `json:"memory_request,omitempty" jsonschema:"description=Memory request e.g. '512Mi'"`
+	CPULimit         string         `json:"cpu_limit,omitempty" jsonschema:"description=CPU limit e.g. '2'"`
+	MemoryLimit      string         `json:"memory_limit,omitempty" jsonschema:"description=Memory limit e.g. '2Gi'"`
+	LicenseSecret    string         `json:"license_secret,omitempty" jsonschema:"description=Name of the Kubernetes secret holding the vmanomaly license key"`
+	LicenseSecretKey string         `json:"license_secret_key,omitempty" jsonschema:"description=Key of the license secret (default: 'license-key')"`
+	Persistence      bool           `json:"persistence,omitempty" jsonschema:"description=Add a persistent volume and enable settings.restore_state so models survive restarts"`
+	StorageSize      string         `json:"storage_size,omitempty" jsonschema:"description=Persistent volume size (default: '1Gi')"`
+	StorageClass     string         `json:"storage_class,omitempty" jsonschema:"description=Storage class of the persistent volume"`
+	AnomalyThreshold float64        `json:"anomaly_threshold,omitempty" jsonschema:"description=Anomaly score threshold of alerts in VMRule (default: 1.0)"`
+	SkipAlertRules   bool           `json:"skip_alert_rules,omitempty" jsonschema:"description=Do not generate VMRule with anomaly and self-monitoring alerts"`
+	SkipValidation   bool           `json:"skip_validation,omitempty" jsonschema:"description=Skip config validation through vmanomaly API"`
+}
+
+// ============================================================================
+// Tool Registration Functions
+// ============================================================================
+
+// RegisterK8sTools registers Kubernetes deployment tools
+func RegisterK8sTools(s *server.MCPServer, client vmanomaly.API) {
+	generateK8sManifestsTool := mcp.NewTool(
+		"vmanomaly_generate_k8s_manifests",
+		mcp.WithDescription("Convert a vmanomaly config and deployment choices (replicas, shards, resources, license secret, persistence for restore_state) into Kubernetes manifests: a VMAnomaly custom resource for the VictoriaMetrics operator with an accompanying VMRule for generated anomaly and self-monitoring alerts, or values.yaml for the victoria-metrics-anomaly Helm chart. The config is validated through vmanomaly API first."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Generate Kubernetes Manifests",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[GenerateK8sManifestsArgs](),
+	)
+	s.AddTool(generateK8sManifestsTool, mcp.NewTypedToolHandler(handleGenerateK8sManifests(client)))
+}
+
+// ============================================================================
+// Tool Handlers
+// ============================================================================
+
+func handleGenerateK8sManifests(client vmanomaly.API) func(ctx context.Context, req mcp.CallToolRequest, args GenerateK8sManifestsArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args GenerateK8sManifestsArgs) (*mcp.CallToolResult, error) {
+		cfg, err := parseConfigArg(args.Config, args.ConfigYAML)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid config: %v", err)), nil
+		}
+		if !args.SkipValidation {
+			validation, err := client.ValidateConfig(ctx, cfg.Raw)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to validate config: %v", err)), nil
+			}
+			if !validation.IsValid {
+				return mcp.NewToolResultError("Config is INVALID, fix it with vmanomaly_validate_config before deploying"), nil
+			}
+		}
+
+		opts := k8s.Options{
+			Name:             args.Name,
+			Namespace:        args.Namespace,
+			Replicas:         int(args.Replicas),
+			Shards:           int(args.Shards),
+			Resources:        k8sResources(args),
+			LicenseSecret:    args.LicenseSecret,
+			LicenseSecretKey: args.LicenseSecretKey,
+			Persistence:      args.Persistence,
+			StorageSize:      args.StorageSize,
+			StorageClass:     args.StorageClass,
+		}
+
+		var sb strings.Builder
+		switch args.Format {
+		case "helm":
+			values, err := k8s.GenerateHelmValues(cfg, opts)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to generate Helm values: %v", err)), nil
+			}
+			sb.WriteString(fmt.Sprintf("values.yaml for the victoria-metrics-anomaly Helm chart:\n\n```yaml\n%s```\n\n", values))
+			sb.WriteString("Install with: helm install vmanomaly vm/victoria-metrics-anomaly -f values.yaml")
+		case "", "operator":
+			if !args.SkipAlertRules && cfg.Preset == "" {
+				opts.Rules, err = vmalert.GenerateRules(cfg, vmalert.GenerateOptions{Threshold: args.AnomalyThreshold})
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("Failed to generate alert rules: %v", err)), nil
+				}
+			}
+			manifests, err := k8s.GenerateOperatorManifests(cfg, opts)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to generate manifests: %v", err)), nil
+			}
+			sb.WriteString(fmt.Sprintf("Manifests for the VictoriaMetrics operator:\n\n```yaml\n%s```\n\n", manifests))
+			sb.WriteString("Apply with: kubectl apply -f manifests.yaml")
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("Unknown format %q, expected operator or helm", args.Format)), nil
+		}
+		if args.LicenseSecret == "" {
+			sb.WriteString("\n\nNote: vmanomaly requires a license, set license_secret to reference a secret with the license key.")
+		}
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
+
+func k8sResources(args GenerateK8sManifestsArgs) k8s.Resources {
+	var r k8s.Resources
+	set := func(m *map[string]string, name, value string) {
+		if value == "" {
+			return
+		}
+		if *m == nil {
+			*m = map[string]string{}
+		}
+		(*m)[name] = value
+	}
+	set(&r.Requests, "cpu", args.CPURequest)
+	set(&r.Requests, "memory", args.MemoryRequest)
+	set(&r.Limits, "cpu", args.CPULimit)
+	set(&r.Limits, "memory", args.MemoryLimit)
+	return r
+}
@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/logsql"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ============================================================================
+// LogsQL Tool Arguments (Struct-based schemas)
+// ============================================================================
+
+// AnalyzeLogsQLQueryArgs defines arguments for analyze_logsql_query tool
+type AnalyzeLogsQLQueryArgs struct {
+	Query string `json:"query" jsonschema:"required,description=LogsQL query for VictoriaLogs reader. Must contain a '| stats' pipe e.g. '_stream:{app=\"api\"} error | stats by (host) count() as errors'"`
+	Step  string `json:"step,omitempty" jsonschema:"description=Optional step/sampling period to check against the query (e.g. '1m' '5m')"`
+}
+
+// AnalyzeLogsQLQueryResponse is the result of analyze_logsql_query tool
+type AnalyzeLogsQLQueryResponse struct {
+	Summary  string            `json:"summary" jsonschema_description:"Human-readable summary of the analysis"`
+	Valid    bool              `json:"valid" jsonschema_description:"Whether the query can be used by vmanomaly VictoriaLogs reader"`
+	Analysis *logsql.Analysis  `json:"analysis" jsonschema_description:"Parsed query: filter, stats by labels, stats functions, produced series names, inferred step and issues"`
+	Allowed  []string          `json:"allowed_stats_functions" jsonschema_description:"Stats functions supported by vmanomaly"`
+	Series   map[string]string `json:"series,omitempty" jsonschema_description:"Produced series names by stats result name, assuming the query alias 'QUERY_ALIAS'"`
+}
+
+// LogsQLTemplatesArgs defines arguments for logsql_templates tool
+type LogsQLTemplatesArgs struct {
+	Template string            `json:"template,omitempty" jsonschema:"enum=error_rate,enum=log_volume,description=Template to render. Leave empty to list all available templates"`
+	Params   map[string]string `json:"params,omitempty" jsonschema:"description=Template parameters by name (e.g. {\"by\": \"host\"}). Missing parameters use template defaults"`
+}
+
+// ============================================================================
+// Tool Registration Functions
+// ============================================================================
+
+// RegisterLogsQLTools registers tools helping to build LogsQL queries for VictoriaLogs reader
+func RegisterLogsQLTools(s *server.MCPServer) {
+	analyzeLogsQLQueryTool := mcp.NewTool(
+		"vmanomaly_analyze_logsql_query",
+		mcp.WithDescription("Analyze a LogsQL query for vmanomaly VictoriaLogs reader (datasource_type=vmlogs) without running it. Checks that the query has a '| stats' pipe, that all stats functions are supported by vmanomaly, extracts 'by (...)' labels and result names (which become series names) and infers step from '_time:<step>' buckets. Use this before creating detection tasks or configs on logs."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Analyze LogsQL Query",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[AnalyzeLogsQLQueryArgs](),
+		mcp.WithOutputSchema[AnalyzeLogsQLQueryResponse](),
+	)
+	s.AddTool(analyzeLogsQLQueryTool, mcp.NewStructuredToolHandler(handleAnalyzeLogsQLQuery()))
+
+	logsQLTemplatesTool := mcp.NewTool(
+		"vmanomaly_logsql_templates",
+		mcp.WithDescription("List or render example LogsQL query templates for anomaly detection on logs (error rate, log volume). Each template comes with a recommended step and model_spec which can be passed to vmanomaly_create_detection_task or vmanomaly_generate_config with datasource_type=vmlogs."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "LogsQL Anomaly Detection Templates",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[LogsQLTemplatesArgs](),
+	)
+	s.AddTool(logsQLTemplatesTool, mcp.NewTypedToolHandler(handleLogsQLTemplates()))
+}
+
+// ============================================================================
+// Tool Handlers
+// ============================================================================
+
+func handleAnalyzeLogsQLQuery() mcp.StructuredToolHandlerFunc[AnalyzeLogsQLQueryArgs, AnalyzeLogsQLQueryResponse] {
+	return func(ctx context.Context, req mcp.CallToolRequest, args AnalyzeLogsQLQueryArgs) (AnalyzeLogsQLQueryResponse, error) {
+		analysis := logsql.Analyze(args.Query)
+		analysis.ValidateStep(args.Step)
+
+		resp := AnalyzeLogsQLQueryResponse{
+			Valid:    analysis.Valid(),
+			Analysis: analysis,
+			Allowed:  logsql.AllowedStatsFunctions,
+			Series:   make(map[string]string, len(analysis.SeriesNames)),
+		}
+		for _, name := range analysis.SeriesNames {
+			resp.Series[name] = fmt.Sprintf("QUERY_ALIAS__%s", name)
+		}
+
+		var sb strings.Builder
+		if resp.Valid {
+			sb.WriteString("Query is valid for vmanomaly VictoriaLogs reader. ")
+		} else {
+			sb.WriteString(fmt.Sprintf("Query is INVALID: %s. ", strings.Join(analysis.Errors(), "; ")))
+		}
+		if analysis.InferredStep != "" {
+			sb.WriteString(fmt.Sprintf("Inferred step: %s. ", analysis.InferredStep))
+		}
+		if n := len(analysis.Issues) - len(analysis.Errors()); n > 0 {
+			sb.WriteString(fmt.Sprintf("%d warning(s), see analysis.issues.", n))
+		}
+		resp.Summary = strings.TrimSpace(sb.String())
+
+		return resp, nil
+	}
+}
+
+func handleLogsQLTemplates() func(ctx context.Context, req mcp.CallToolRequest, args LogsQLTemplatesArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args LogsQLTemplatesArgs) (*mcp.CallToolResult, error) {
+		if args.Template == "" {
+			var sb strings.Builder
+			sb.WriteString("Available LogsQL templates for anomaly detection on logs:\n")
+			for _, t := range logsql.Templates() {
+				sb.WriteString(fmt.Sprintf("\n- %s: %s\n  query: %s\n  recommended step: %s\n  params:\n", t.Name, t.Description, t.Query, t.Step))
+				for _, name := range slices.Sorted(maps.Keys(t.Params)) {
+					sb.WriteString(fmt.Sprintf("    - %s (default %q): %s\n", name, t.Defaults[name], t.Params[name]))
+				}
+			}
+			return mcp.NewToolResultText(sb.String()), nil
+		}
+
+		t, ok := logsql.GetTemplate(args.Template)
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("Unknown template: %s", args.Template)), nil
+		}
+		query, err := t.Render(args.Params)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to render template: %v", err)), nil
+		}
+		analysis := logsql.Analyze(query)
+		if !analysis.Valid() {
+			return mcp.NewToolResultError(fmt.Sprintf("Rendered query %q is invalid: %s", query, strings.Join(analysis.Errors(), "; "))), nil
+		}
+
+		return mcp.NewToolResultStructured(map[string]any{
+			"query":           query,
+			"datasource_type": vmanomaly.DatasourceTypeVMLogs,
+			"step":            t.Step,
+			"model_spec":      t.ModelSpec,
+			"series_names":    analysis.SeriesNames,
+			"by":              analysis.By,
+		}, fmt.Sprintf("Rendered %s template:\n\n%s\n\nUse datasource_type=vmlogs, step=%s and the recommended model_spec when creating a detection task or config.", t.Name, query, t.Step)), nil
+	}
+}
+
+// prepareLogsQLQuery validates a LogsQL query for vmlogs datasource and resolves the step.
+// If step is empty, the step inferred from `_time:` bucket is used. Returned warnings should be shown to the user.
+func prepareLogsQLQuery(query, step string) (string, []string, error) {
+	analysis := logsql.Analyze(query)
+	if step == "" {
+		step = analysis.InferredStep
+	}
+	analysis.ValidateStep(step)
+	if !analysis.Valid() {
+		return "", nil, fmt.Errorf("invalid LogsQL query: %s", strings.Join(analysis.Errors(), "; "))
+	}
+	if step == "" {
+		return "", nil, fmt.Errorf("step is required: set it explicitly or use a `_time:<step>` bucket in `stats by (...)`")
+	}
+
+	var warnings []string
+	for _, issue := range analysis.Issues {
+		if issue.Severity == logsql.SeverityWarning {
+			warnings = append(warnings, issue.Message)
+		}
+	}
+	return step, warnings, nil
+}
+
+// formatWarnings renders warnings as a bullet list appended to tool responses
+func formatWarnings(warnings []string) string {
+	if len(warnings) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n\nWarnings:\n")
+	for _, w := range warnings {
+		sb.WriteString(fmt.Sprintf("- %s\n", w))
+	}
+	return sb.String()
+}
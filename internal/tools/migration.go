@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/capacity"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/changelog"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/migration"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/resources"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmconfig"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ============================================================================
+// Migration Tool Arguments (Struct-based schemas)
+// ============================================================================
+
+// PlanMigrationArgs defines arguments for plan_migration tool
+type PlanMigrationArgs struct {
+	VersionTo      string             `json:"version_to,omitempty" jsonschema:"description=Target version to upgrade to (default: the latest version in the embedded changelog)"`
+	VersionFrom    string             `json:"version_from,omitempty" jsonschema:"description=Version to upgrade from. Defaults to the version of the stored state or the runtime version of the connected vmanomaly"`
+	Config         map[string]any     `json:"config,omitempty" jsonschema:"description=Optional deployed vmanomaly configuration object used to list models losing state and estimate their refit cost"`
+	ConfigYAML     string             `json:"config_yaml,omitempty" jsonschema:"description=Optional deployed vmanomaly configuration as YAML text"`
+	Series         map[string]float64 `json:"series,omitempty" jsonschema:"description=Known number of series per query alias used for refit cost"`
+	SkipEstimate   bool               `json:"skip_estimate,omitempty" jsonschema:"description=Do not run count() queries through vmanomaly to estimate series of queries"`
+	SkipStateCheck bool               `json:"skip_state_check,omitempty" jsonschema:"description=Do not check compatibility of the stored state through vmanomaly API and rely on the changelog only"`
+}
+
+// PlanMigrationResponse is the result of plan_migration tool
+type PlanMigrationResponse struct {
+	Summary string          `json:"summary" jsonschema_description:"Human-readable summary of the upgrade plan"`
+	Plan    *migration.Plan `json:"plan" jsonschema_description:"Ordered upgrade steps with breaking changes, deprecations, known issues and compatibility of the stored state, models losing state and their refit cost"`
+}
+
+// ============================================================================
+// Tool Registration Functions
+// ============================================================================
+
+// RegisterMigrationTools registers version migration tools
+func RegisterMigrationTools(s *server.MCPServer, client vmanomaly.API) {
+	planMigrationTool := mcp.NewTool(
+		"vmanomaly_plan_migration",
+		mcp.WithDescription("Plan an upgrade of a stateful vmanomaly deployment (settings.restore_state). Combines the runtime and stored state versions, the embedded CHANGELOG.md and compatibility checks of the stored state into an ordered plan: intermediate versions to pass through, breaking changes, deprecations and known issues between the versions, model aliases which lose their state and the expected refit cost based on their fit_window. Pass the deployed config to get per-model refit costs."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Plan vmanomaly Version Migration",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[PlanMigrationArgs](),
+		mcp.WithOutputSchema[PlanMigrationResponse](),
+	)
+	s.AddTool(planMigrationTool, mcp.NewStructuredToolHandler(handlePlanMigration(client)))
+}
+
+// ============================================================================
+// Tool Handlers
+// ============================================================================
+
+func handlePlanMigration(client vmanomaly.API) mcp.StructuredToolHandlerFunc[PlanMigrationArgs, PlanMigrationResponse] {
+	return func(ctx context.Context, req mcp.CallToolRequest, args PlanMigrationArgs) (PlanMigrationResponse, error) {
+		var resp PlanMigrationResponse
+		cl, err := resources.Changelog()
+		if err != nil {
+			return resp, err
+		}
+
+		var runtimeVersion, storedVersion string
+		if info, err := client.GetBuildInfo(ctx); err == nil {
+			runtimeVersion = migration.RuntimeVersion(info)
+		}
+		if !args.SkipStateCheck {
+			if result, err := client.Compatibility(ctx, nil); err == nil {
+				if runtimeVersion == "" {
+					runtimeVersion = result.RuntimeVersion
+				}
+				if result.StoredVersion != nil {
+					storedVersion = *result.StoredVersion
+				}
+			}
+		}
+
+		opts := migration.Options{To: cl.Latest().Version}
+		if args.VersionTo != "" {
+			if opts.To, err = changelog.ParseVersion(args.VersionTo); err != nil {
+				return resp, err
+			}
+		}
+		from := args.VersionFrom
+		if from == "" {
+			from = cmp.Or(storedVersion, runtimeVersion)
+		}
+		if from == "" {
+			return resp, fmt.Errorf("cannot detect the current version, set version_from")
+		}
+		if opts.From, err = changelog.ParseVersion(from); err != nil {
+			return resp, err
+		}
+
+		if args.Config != nil || args.ConfigYAML != "" {
+			cfg, err := parseConfigArg(args.Config, args.ConfigYAML)
+			if err != nil {
+				return resp, fmt.Errorf("invalid config: %w", err)
+			}
+			opts.Config = cfg
+			opts.Series, err = estimateSeries(ctx, client, cfg, args.Series, args.SkipEstimate)
+			if err != nil {
+				return resp, err
+			}
+		}
+
+		var checker migration.Checker
+		if !args.SkipStateCheck {
+			checker = client
+		}
+		resp.Plan, err = migration.Build(ctx, cl, checker, opts)
+		if err != nil {
+			return resp, err
+		}
+		resp.Plan.RuntimeVersion = runtimeVersion
+		if resp.Plan.StoredVersion == "" {
+			resp.Plan.StoredVersion = storedVersion
+		}
+		resp.Summary = buildMigrationSummary(resp.Plan)
+		return resp, nil
+	}
+}
+
+// estimateSeries returns the number of series per query alias, known series take precedence over estimates
+func estimateSeries(ctx context.Context, client vmanomaly.API, cfg *vmconfig.Config, known map[string]float64, skipEstimate bool) (map[string]int, error) {
+	opts := capacity.EstimateOptions{Series: make(map[string]int, len(known))}
+	for alias, n := range known {
+		opts.Series[alias] = int(n)
+	}
+	var querier capacity.Querier
+	if !skipEstimate {
+		querier = client
+	}
+	est, err := capacity.EstimateWorkload(ctx, querier, cfg, opts)
+	if err != nil {
+		return nil, fmt.Errorf("cannot estimate series: %w", err)
+	}
+	return est.Series, nil
+}
+
+func buildMigrationSummary(plan *migration.Plan) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Upgrade from %s to %s in %d step(s):\n", plan.From, plan.To, len(plan.Steps))
+	for i, step := range plan.Steps {
+		fmt.Fprintf(&sb, "%d. %s: %s", i+1, step.Version, step.Reason)
+		if n := len(step.Breaking); n > 0 {
+			fmt.Fprintf(&sb, ", %d breaking change(s)", n)
+		}
+		if n := len(step.Deprecations); n > 0 {
+			fmt.Fprintf(&sb, ", %d deprecation(s)", n)
+		}
+		if n := len(step.KnownIssues); n > 0 {
+			fmt.Fprintf(&sb, ", %d known issue(s)", n)
+		}
+		switch {
+		case step.Compatible == nil:
+		case step.DropEverything:
+			sb.WriteString(", all state is dropped")
+		case !*step.Compatible:
+			sb.WriteString(", stored state is partially incompatible")
+		}
+		sb.WriteString("\n")
+	}
+	if len(plan.StateLoss) > 0 {
+		models := make([]string, 0, len(plan.StateLoss))
+		for _, loss := range plan.StateLoss {
+			models = append(models, loss.Model)
+		}
+		fmt.Fprintf(&sb, "Models losing state: %s (refit cost: %.0f units)\n", strings.Join(models, ", "), plan.RefitCost)
+	} else {
+		sb.WriteString("No models are expected to lose state\n")
+	}
+	for _, w := range plan.Warnings {
+		fmt.Fprintf(&sb, "Warning: %s\n", w)
+	}
+	return sb.String()
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
 )
 
-// MockClient is a mock implementation of vmanomaly.Client for testing
+// MockClient is a mock implementation of vmanomaly.API for testing
 type MockClient struct {
 	GetHealthFunc           func(ctx context.Context) (map[string]any, error)
 	GetBuildInfoFunc        func(ctx context.Context) (map[string]any, error)
@@ -21,8 +21,14 @@ type MockClient struct {
 	CancelTaskFunc          func(ctx context.Context, taskID string) (map[string]bool, error)
 	GetDetectionLimitsFunc  func(ctx context.Context) (*vmanomaly.AnomalyDetectionLimitsResponse, error)
 	QueryFunc               func(ctx context.Context, req *vmanomaly.QueryRequest) (map[string]any, error)
+	ValidateConfigFunc      func(ctx context.Context, config map[string]any) (*vmanomaly.OkValidationResponse, error)
+	MetricsFunc             func(ctx context.Context, config map[string]any) (string, error)
+	CompatibilityFunc       func(ctx context.Context, versionTo *string) (*vmanomaly.CompatibilityCheckResponse, error)
+	GenerateAlertRuleFunc   func(ctx context.Context, req *vmanomaly.AlertRuleRequest) (string, error)
 }
 
+var _ vmanomaly.API = (*MockClient)(nil)
+
 func (m *MockClient) GetHealth(ctx context.Context) (map[string]any, error) {
 	if m.GetHealthFunc != nil {
 		return m.GetHealthFunc(ctx)
@@ -106,3 +112,31 @@ func (m *MockClient) Query(ctx context.Context, req *vmanomaly.QueryRequest) (ma
 	}
 	return nil, errors.New("not implemented")
 }
+
+func (m *MockClient) ValidateConfig(ctx context.Context, config map[string]any) (*vmanomaly.OkValidationResponse, error) {
+	if m.ValidateConfigFunc != nil {
+		return m.ValidateConfigFunc(ctx, config)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockClient) Metrics(ctx context.Context, config map[string]any) (string, error) {
+	if m.MetricsFunc != nil {
+		return m.MetricsFunc(ctx, config)
+	}
+	return "", errors.New("not implemented")
+}
+
+func (m *MockClient) Compatibility(ctx context.Context, versionTo *string) (*vmanomaly.CompatibilityCheckResponse, error) {
+	if m.CompatibilityFunc != nil {
+		return m.CompatibilityFunc(ctx, versionTo)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockClient) GenerateAlertRule(ctx context.Context, req *vmanomaly.AlertRuleRequest) (string, error) {
+	if m.GenerateAlertRuleFunc != nil {
+		return m.GenerateAlertRuleFunc(ctx, req)
+	}
+	return "", errors.New("not implemented")
+}
@@ -15,9 +15,15 @@ import (
 // Model Configuration Tool Arguments (Struct-based schemas)
 // ============================================================================
 
+// ListModelsArgs defines arguments for list_models tool
+type ListModelsArgs struct {
+	NoCache bool `json:"no_cache,omitempty" jsonschema:"description=Bypass the response cache and fetch models from vmanomaly (default: false)"`
+}
+
 // GetModelSchemaArgs defines arguments for get_model_schema tool
 type GetModelSchemaArgs struct {
 	ModelClass string `json:"model_class" jsonschema:"required,enum=zscore,enum=prophet,enum=mad,enum=holtwinters,enum=std,enum=rolling_quantile,enum=isolation_forest_univariate,enum=mad_online,enum=zscore_online,enum=quantile_online,enum=auto,description=Model type to retrieve schema for. Valid values: 'zscore' (statistical z-score) 'prophet' (Facebook Prophet for seasonality) 'mad' (Median Absolute Deviation) 'holtwinters' (triple exponential smoothing) 'std' (standard deviation) 'rolling_quantile' (quantile-based detection) 'isolation_forest_univariate' (ML-based isolation) 'mad_online' (streaming MAD) 'zscore_online' (streaming z-score) 'quantile_online' (streaming quantile) 'auto' (automatic model selection). Use vmanomaly_list_models to see all available types first."`
+	NoCache    bool   `json:"no_cache,omitempty" jsonschema:"description=Bypass the response cache and fetch the schema from vmanomaly (default: false)"`
 }
 
 // ValidateModelConfigArgs defines arguments for validate_model_config tool
@@ -30,7 +36,7 @@ type ValidateModelConfigArgs struct {
 // ============================================================================
 
 // RegisterModelTools registers all model configuration tools
-func RegisterModelTools(s *server.MCPServer, client *vmanomaly.Client) {
+func RegisterModelTools(s *server.MCPServer, client vmanomaly.API) {
 	listModelsTool := mcp.NewTool(
 		"vmanomaly_list_models",
 		mcp.WithDescription("List all available anomaly detection model types supported by vmanomaly. Returns model names that can be used in model configurations. Use this as the first step when selecting a model, then call vmanomaly_get_model_schema to see parameters for your chosen model."),
@@ -40,8 +46,9 @@ func RegisterModelTools(s *server.MCPServer, client *vmanomaly.Client) {
 			DestructiveHint: ptr(false),
 			OpenWorldHint:   ptr(false),
 		}),
+		mcp.WithInputSchema[ListModelsArgs](),
 	)
-	s.AddTool(listModelsTool, handleListModels(client))
+	s.AddTool(listModelsTool, mcp.NewTypedToolHandler(handleListModels(client)))
 
 	getModelSchemaTool := mcp.NewTool(
 		"vmanomaly_get_model_schema",
@@ -74,8 +81,11 @@ func RegisterModelTools(s *server.MCPServer, client *vmanomaly.Client) {
 // Tool Handlers
 // ============================================================================
 
-func handleListModels(client *vmanomaly.Client) server.ToolHandlerFunc {
-	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func handleListModels(client vmanomaly.API) func(ctx context.Context, req mcp.CallToolRequest, args ListModelsArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args ListModelsArgs) (*mcp.CallToolResult, error) {
+		if args.NoCache {
+			ctx = vmanomaly.WithNoCache(ctx)
+		}
 		// Call API
 		models, err := client.ListModels(ctx)
 		if err != nil {
@@ -92,8 +102,11 @@ func handleListModels(client *vmanomaly.Client) server.ToolHandlerFunc {
 	}
 }
 
-func handleGetModelSchema(client *vmanomaly.Client) func(ctx context.Context, req mcp.CallToolRequest, args GetModelSchemaArgs) (*mcp.CallToolResult, error) {
+func handleGetModelSchema(client vmanomaly.API) func(ctx context.Context, req mcp.CallToolRequest, args GetModelSchemaArgs) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest, args GetModelSchemaArgs) (*mcp.CallToolResult, error) {
+		if args.NoCache {
+			ctx = vmanomaly.WithNoCache(ctx)
+		}
 		// Call API
 		schema, err := client.GetModelSchema(ctx, args.ModelClass)
 		if err != nil {
@@ -110,7 +123,7 @@ func handleGetModelSchema(client *vmanomaly.Client) func(ctx context.Context, re
 	}
 }
 
-func handleValidateModelConfig(client *vmanomaly.Client) func(ctx context.Context, req mcp.CallToolRequest, args ValidateModelConfigArgs) (*mcp.CallToolResult, error) {
+func handleValidateModelConfig(client vmanomaly.API) func(ctx context.Context, req mcp.CallToolRequest, args ValidateModelConfigArgs) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest, args ValidateModelConfigArgs) (*mcp.CallToolResult, error) {
 		// Call API
 		validation, err := client.ValidateModel(ctx, args.ModelSpec)
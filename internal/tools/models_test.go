@@ -3,9 +3,12 @@ package tools
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
 )
 
 func TestListModels_Error(t *testing.T) {
@@ -15,10 +18,13 @@ func TestListModels_Error(t *testing.T) {
 		},
 	}
 
-	_, err := mock.ListModels(context.Background())
+	result, err := handleListModels(mock)(context.Background(), mcp.CallToolRequest{}, ListModelsArgs{})
 
-	if err == nil {
-		t.Error("expected error from API")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Error("expected tool error from API")
 	}
 }
 
@@ -29,10 +35,13 @@ func TestGetModelSchema_Error(t *testing.T) {
 		},
 	}
 
-	_, err := mock.GetModelSchema(context.Background(), "invalid")
+	result, err := handleGetModelSchema(mock)(context.Background(), mcp.CallToolRequest{}, GetModelSchemaArgs{ModelClass: "invalid"})
 
-	if err == nil {
-		t.Error("expected error from API")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(resultText(result), "model not found") {
+		t.Errorf("expected tool error with API error, got %+v", result)
 	}
 }
 
@@ -46,14 +55,14 @@ func TestValidateModel_InvalidModel(t *testing.T) {
 		},
 	}
 
-	result, err := mock.ValidateModel(context.Background(), map[string]any{"invalid": "config"})
+	result, err := handleValidateModelConfig(mock)(context.Background(), mcp.CallToolRequest{}, ValidateModelConfigArgs{ModelSpec: map[string]any{"invalid": "config"}})
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if result.Valid {
-		t.Error("expected invalid model")
+	if !strings.Contains(resultText(result), "Model configuration is invalid") {
+		t.Errorf("expected invalid model, got %s", resultText(result))
 	}
 }
 
@@ -67,19 +76,17 @@ func TestGenerateConfig_Defaults(t *testing.T) {
 		},
 	}
 
-	configReq := &vmanomaly.ConfigGenerationRequest{
+	args := GenerateConfigArgs{
 		Query:         "up",
 		Step:          "1m",
 		DatasourceURL: "http://vm:8428",
 		ModelSpec:     map[string]any{"class": "zscore"},
-		FitWindow:     "1d",
-		FitEvery:      "1d",
 	}
 
-	_, err := mock.GenerateConfig(context.Background(), configReq)
+	result, err := handleGenerateConfig(mock)(context.Background(), mcp.CallToolRequest{}, args)
 
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if err != nil || result.IsError {
+		t.Fatalf("unexpected error: %v, %s", err, resultText(result))
 	}
 
 	if receivedReq.FitWindow != "1d" {
@@ -89,6 +96,10 @@ func TestGenerateConfig_Defaults(t *testing.T) {
 	if receivedReq.FitEvery != "1d" {
 		t.Errorf("expected default fit_every=1d, got %s", receivedReq.FitEvery)
 	}
+
+	if receivedReq.DatasourceType != vmanomaly.DatasourceTypeVM {
+		t.Errorf("expected default datasource_type=vm, got %s", receivedReq.DatasourceType)
+	}
 }
 
 func TestGenerateConfig_OverrideDefaults(t *testing.T) {
@@ -101,21 +112,20 @@ func TestGenerateConfig_OverrideDefaults(t *testing.T) {
 		},
 	}
 
-	tenantID := "tenant1"
-	configReq := &vmanomaly.ConfigGenerationRequest{
+	args := GenerateConfigArgs{
 		Query:         "up",
 		Step:          "1m",
 		DatasourceURL: "http://vm:8428",
 		ModelSpec:     map[string]any{"class": "zscore"},
 		FitWindow:     "2h",
 		FitEvery:      "30m",
-		TenantID:      &tenantID,
+		TenantID:      "tenant1",
 	}
 
-	_, err := mock.GenerateConfig(context.Background(), configReq)
+	result, err := handleGenerateConfig(mock)(context.Background(), mcp.CallToolRequest{}, args)
 
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if err != nil || result.IsError {
+		t.Fatalf("unexpected error: %v, %s", err, resultText(result))
 	}
 
 	if receivedReq.FitWindow != "2h" {
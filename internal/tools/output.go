@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// OutputDir is the directory tools write generated files to, e.g. dashboards and sub-configs.
+// Paths given by clients are resolved relative to it, an empty OutputDir disables file output.
+type OutputDir string
+
+// Enabled reports whether tools may write files
+func (d OutputDir) Enabled() bool {
+	return d != ""
+}
+
+// writeFile writes data to path relative to the output directory creating parent directories,
+// existing files are replaced only if overwrite is set. Absolute paths and paths escaping the directory
+// with .. or symlinks are rejected. It returns the full path of the written file.
+func (d OutputDir) writeFile(path string, data []byte, overwrite bool) (string, error) {
+	if !d.Enabled() {
+		return "", fmt.Errorf("file output is disabled, set MCP_OUTPUT_DIR to let tools write files")
+	}
+	if !filepath.IsLocal(path) {
+		return "", fmt.Errorf("path %q must be relative to the output directory and must not contain ..", path)
+	}
+	root, err := os.OpenRoot(string(d))
+	if err != nil {
+		return "", err
+	}
+	defer root.Close()
+
+	// os.Root rejects symlinks pointing outside of the output directory
+	if _, err := root.Stat(path); err == nil && !overwrite {
+		return "", fmt.Errorf("file %s already exists, set overwrite to replace it", path)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	if err := root.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := root.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return filepath.Join(string(d), path), nil
+}
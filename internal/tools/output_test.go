@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputDir_WriteFile(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	out := OutputDir(dir)
+
+	path, err := out.writeFile(filepath.Join("dashboards", "a.json"), []byte("{}"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "dashboards", "a.json"); path != want {
+		t.Errorf("path = %s, want %s", path, want)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "{}" {
+		t.Errorf("file content = %q, %v", data, err)
+	}
+	if _, err := out.writeFile(filepath.Join("dashboards", "a.json"), []byte("[]"), true); err != nil {
+		t.Errorf("overwrite error = %v", err)
+	}
+
+	tests := []struct {
+		out  OutputDir
+		path string
+		err  string
+	}{
+		{"", "a.json", "file output is disabled"},
+		{out, filepath.Join("dashboards", "a.json"), "already exists"},
+		{out, filepath.Join(outside, "a.json"), "must be relative"},
+		{out, filepath.Join("..", "a.json"), "must be relative"},
+		{out, filepath.Join("link", "a.json"), "escapes"},
+	}
+	for _, tt := range tests {
+		if _, err := tt.out.writeFile(tt.path, []byte("{}"), false); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("writeFile(%q) error = %v, want %q", tt.path, err, tt.err)
+		}
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) > 0 {
+		t.Errorf("files were written outside of the output directory: %v", entries)
+	}
+}
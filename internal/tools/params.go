@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/params"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/resources"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// defaultParamsLimit is the default maximum number of parameters returned by lookup_parameter
+const defaultParamsLimit = 50
+
+// ============================================================================
+// Parameter Lookup Tool Arguments (Struct-based schemas)
+// ============================================================================
+
+// LookupParameterArgs defines arguments for lookup_parameter tool
+type LookupParameterArgs struct {
+	Name       string  `json:"name,omitempty" jsonschema_description:"Exact parameter name (e.g. 'z_threshold' 'fit_every' 'restore_state'). Matched case-insensitively."`
+	Component  string  `json:"component,omitempty" jsonschema:"enum=models,enum=reader,enum=writer,enum=scheduler,enum=settings" jsonschema_description:"Config section the parameter belongs to. Default: all sections."`
+	Class      string  `json:"class,omitempty" jsonschema_description:"Class of the component: full name (e.g. 'model.zscore.ZscoreModel') or alias (e.g. 'zscore' 'periodic' 'vm'). Parameters common to all classes of the component are returned too."`
+	Keyword    string  `json:"keyword,omitempty" jsonschema_description:"Substring to find in parameter names and descriptions when the exact name is unknown (e.g. 'timezone')."`
+	SkipSchema bool    `json:"skip_schema,omitempty" jsonschema_description:"Do not merge model parameters with the live schema of the connected vmanomaly. Default: false."`
+	Limit      float64 `json:"limit,omitempty" jsonschema_description:"Maximum number of parameters to return. Default: 50."`
+}
+
+// LookupParameterResponse is the result of lookup_parameter tool
+type LookupParameterResponse struct {
+	Params []params.Param `json:"params" jsonschema_description:"Matching parameters in documentation order. source is 'docs', 'schema' (live model schema only) or 'docs+schema'. docs_default is set when the documented default differs from the live schema"`
+	// SchemaMerged reports whether the live model schema was merged
+	SchemaMerged bool   `json:"schema_merged" jsonschema_description:"Whether parameters are merged with the live model schema of the connected vmanomaly"`
+	Note         string `json:"note,omitempty" jsonschema_description:"Note about empty results or the unavailable live schema"`
+	Omitted      int    `json:"omitted,omitempty" jsonschema_description:"Number of matching parameters dropped to fit the limit"`
+}
+
+// ============================================================================
+// Tool Registration Functions
+// ============================================================================
+
+// RegisterParamsTools registers parameter lookup tools
+func RegisterParamsTools(s *server.MCPServer, client vmanomaly.API) {
+	lookupParameterTool := mcp.NewTool(
+		"vmanomaly_lookup_parameter",
+		mcp.WithDescription("Look up vmanomaly config parameters in a catalog built from the documentation of models, reader, writer, scheduler and settings sections. Returns type, default, example, description and the version the parameter is available since. Model parameters are merged with the live model schema when the connected vmanomaly is reachable, the schema wins on types and defaults. Prefer this over vmanomaly_search_docs for exact questions like 'what is the default of z_threshold for zscore'."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Lookup vmanomaly Config Parameter",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[LookupParameterArgs](),
+		mcp.WithOutputSchema[LookupParameterResponse](),
+	)
+	s.AddTool(lookupParameterTool, mcp.NewStructuredToolHandler(handleLookupParameter(client)))
+}
+
+// ============================================================================
+// Tool Handlers
+// ============================================================================
+
+// handleLookupParameter handles the lookup_parameter tool
+func handleLookupParameter(client vmanomaly.API) mcp.StructuredToolHandlerFunc[LookupParameterArgs, LookupParameterResponse] {
+	return func(ctx context.Context, req mcp.CallToolRequest, args LookupParameterArgs) (LookupParameterResponse, error) {
+		resp := LookupParameterResponse{Params: []params.Param{}}
+		if args.Name == "" && args.Class == "" && args.Keyword == "" {
+			return resp, fmt.Errorf("at least one of name, class or keyword is required")
+		}
+		catalog, err := resources.ParamCatalog()
+		if err != nil {
+			return resp, err
+		}
+		limit := int(args.Limit)
+		if limit < 1 {
+			limit = defaultParamsLimit
+		}
+
+		query := params.Query{Component: args.Component, Class: args.Class, Name: args.Name, Keyword: args.Keyword}
+		found := catalog.Lookup(params.Query{Component: query.Component, Class: query.Class})
+
+		// Only model classes have a live schema
+		class, aliases, known := catalog.ResolveClass(params.ComponentModels, args.Class)
+		isModel := known || (args.Class != "" && args.Component == params.ComponentModels)
+		if isModel && !known {
+			// The class may be missing in docs, e.g. a custom model, but common model args still apply
+			class = args.Class
+			for _, p := range catalog.Lookup(params.Query{Component: params.ComponentModels}) {
+				if p.Class == "" {
+					found = append(found, p)
+				}
+			}
+		}
+		if isModel && !args.SkipSchema {
+			modelClass := args.Class
+			if len(aliases) > 0 {
+				modelClass = aliases[0]
+			}
+			schema, err := client.GetModelSchema(ctx, modelClass)
+			if err != nil {
+				resp.Note = fmt.Sprintf("Live model schema is unavailable, parameters are taken from documentation only: %v", err)
+			} else {
+				found = params.MergeSchema(found, class, aliases, schema)
+				resp.SchemaMerged = true
+			}
+		}
+
+		found = params.Filter(found, query)
+		if len(found) == 0 {
+			resp.Note = strings.TrimSpace(fmt.Sprintf("No parameters found. Use vmanomaly_search_docs for free-text questions. %s", resp.Note))
+			return resp, nil
+		}
+		if len(found) > limit {
+			resp.Omitted = len(found) - limit
+			found = found[:limit]
+		}
+		resp.Params = found
+		return resp, nil
+	}
+}
@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/params"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestLookupParameter(t *testing.T) {
+	var requested string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Query().Get("model_class")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"properties": {"z_threshold": {"type": "number", "default": 3.0}, "provide_series": {"type": "array"}}}`))
+	}))
+	defer srv.Close()
+
+	handler := handleLookupParameter(vmanomaly.NewClient(srv.URL, "", nil))
+	resp, err := handler(context.Background(), mcp.CallToolRequest{}, LookupParameterArgs{Class: "model.zscore.ZscoreModel", Name: "z_threshold"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requested != "zscore" || !resp.SchemaMerged || len(resp.Params) != 1 {
+		t.Fatalf("schema of alias zscore must be merged, requested %q, got %+v", requested, resp)
+	}
+	if p := resp.Params[0]; p.Default != "3" || p.DocsDefault != "2.5" || p.Source != params.SourceBoth {
+		t.Errorf("z_threshold = %+v", p)
+	}
+
+	srv.Close()
+	resp, err = handler(context.Background(), mcp.CallToolRequest{}, LookupParameterArgs{Class: "zscore", Name: "z_threshold"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.SchemaMerged || resp.Note == "" || len(resp.Params) != 1 || resp.Params[0].Default != "2.5" {
+		t.Errorf("documented parameters must be returned if vmanomaly is unreachable, got %+v", resp)
+	}
+
+	if _, err := handler(context.Background(), mcp.CallToolRequest{}, LookupParameterArgs{}); err == nil {
+		t.Error("expected error without name, class and keyword")
+	}
+}
@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/presets"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gopkg.in/yaml.v3"
+)
+
+// ============================================================================
+// Preset Tool Arguments (Struct-based schemas)
+// ============================================================================
+
+// PresetConfigArgs defines arguments for preset_config tool
+type PresetConfigArgs struct {
+	Preset           string  `json:"preset,omitempty" jsonschema:"enum=node-exporter,enum=ui,description=Preset to render. Leave empty to list all available presets"`
+	DatasourceURL    string  `json:"datasource_url,omitempty" jsonschema:"description=VictoriaMetrics URL to read metrics from (required for node-exporter)"`
+	TenantID         string  `json:"tenant_id,omitempty" jsonschema:"description=Source tenant ID for cluster version of VictoriaMetrics (e.g. '0:0')"`
+	WriterURL        string  `json:"writer_url,omitempty" jsonschema:"description=VictoriaMetrics URL to write anomaly scores to. Defaults to datasource_url"`
+	WriterTenantID   string  `json:"writer_tenant_id,omitempty" jsonschema:"description=Destination tenant ID. Defaults to tenant_id"`
+	AnomalyThreshold float64 `json:"anomaly_threshold,omitempty" jsonschema:"description=Anomaly score threshold of generated vmalert rules (default: 1.0)"`
+	SkipValidation   bool    `json:"skip_validation,omitempty" jsonschema:"description=Do not validate the rendered config with vmanomaly"`
+}
+
+// ============================================================================
+// Tool Registration Functions
+// ============================================================================
+
+// RegisterPresetTools registers tools for vmanomaly presets
+func RegisterPresetTools(s *server.MCPServer, client vmanomaly.API) {
+	presetConfigTool := mcp.NewTool(
+		"vmanomaly_preset_config",
+		mcp.WithDescription("List vmanomaly presets or render a preset config with your datasources. Presets (e.g. node-exporter) come with predefined queries, models and schedulers, so only datasource URLs and tenants are needed. The rendered config is validated with vmanomaly and returned together with matching vmalert rules for the produced anomaly scores."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Generate Preset Config",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[PresetConfigArgs](),
+	)
+	s.AddTool(presetConfigTool, mcp.NewTypedToolHandler(handlePresetConfig(client)))
+}
+
+// ============================================================================
+// Tool Handlers
+// ============================================================================
+
+func handlePresetConfig(client vmanomaly.API) func(ctx context.Context, req mcp.CallToolRequest, args PresetConfigArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args PresetConfigArgs) (*mcp.CallToolResult, error) {
+		if args.Preset == "" {
+			return mcp.NewToolResultText(formatPresetList(presets.List())), nil
+		}
+
+		preset, ok := presets.Get(args.Preset)
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("Unknown preset %q. Call this tool without preset to list available presets.", args.Preset)), nil
+		}
+		params := presetParams(preset, args)
+		rendered, err := preset.Render(params)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to render preset: %v", err)), nil
+		}
+
+		configYAML, err := yaml.Marshal(rendered.Config)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to format config: %v", err)), nil
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("vmanomaly config for %q preset:\n\n```yaml\n%s```\n", preset.Name, configYAML))
+
+		if !args.SkipValidation {
+			validation, err := client.ValidateConfig(ctx, rendered.Config)
+			switch {
+			case err != nil:
+				sb.WriteString(fmt.Sprintf("\nValidation failed: %v\n", err))
+			case validation.IsValid:
+				sb.WriteString("\nConfig is valid.\n")
+			default:
+				validationJSON, _ := json.MarshalIndent(validation, "", "  ")
+				sb.WriteString(fmt.Sprintf("\nConfig is INVALID:\n%s\n", validationJSON))
+			}
+		}
+
+		if rendered.Rules != nil {
+			rulesYAML, err := rendered.Rules.Marshal()
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to format vmalert rules: %v", err)), nil
+			}
+			sb.WriteString(fmt.Sprintf("\nvmalert rules for anomaly scores produced by the preset:\n\n```yaml\n%s```\n", rulesYAML))
+		}
+		if preset.AssetsURL != "" {
+			sb.WriteString(fmt.Sprintf("\nOriginal preset assets (alerts and Grafana dashboard): %s\n", preset.AssetsURL))
+		}
+		sb.WriteString(fmt.Sprintf("Docs: %s", preset.DocsURL))
+
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
+
+// presetParams converts tool arguments to preset parameters, passing only the ones the preset accepts
+func presetParams(preset presets.Preset, args PresetConfigArgs) map[string]string {
+	all := map[string]string{
+		presets.ParamDatasourceURL:  args.DatasourceURL,
+		presets.ParamTenantID:       args.TenantID,
+		presets.ParamWriterURL:      args.WriterURL,
+		presets.ParamWriterTenantID: args.WriterTenantID,
+	}
+	if args.AnomalyThreshold > 0 {
+		all[presets.ParamAnomalyThreshold] = strconv.FormatFloat(args.AnomalyThreshold, 'f', -1, 64)
+	}
+	params := make(map[string]string)
+	for _, p := range preset.Params {
+		if v := all[p.Name]; v != "" {
+			params[p.Name] = v
+		}
+	}
+	return params
+}
+
+func formatPresetList(list []presets.Preset) string {
+	var sb strings.Builder
+	sb.WriteString("Available vmanomaly presets:\n")
+	for _, p := range list {
+		sb.WriteString(fmt.Sprintf("\n- %s: %s\n  docs: %s\n", p.Name, p.Description, p.DocsURL))
+		if len(p.Params) > 0 {
+			sb.WriteString("  params:\n")
+			for _, param := range p.Params {
+				suffix := ""
+				if param.Required {
+					suffix = " (required)"
+				} else if param.Default != "" {
+					suffix = fmt.Sprintf(" (default: %s)", param.Default)
+				}
+				sb.WriteString(fmt.Sprintf("    - %s%s: %s\n", param.Name, suffix, param.Description))
+			}
+		}
+		if len(p.Indicators) > 0 {
+			sb.WriteString("  indicators (`for` label of anomaly scores):\n")
+			for _, ind := range p.Indicators {
+				sb.WriteString(fmt.Sprintf("    - %s: %s (%s)\n", ind.Name, ind.Description, strings.Join(ind.Metrics, ", ")))
+			}
+		}
+	}
+	sb.WriteString("\nThe 'default' preset means a fully user-defined config, use vmanomaly_generate_config for it.")
+	return sb.String()
+}
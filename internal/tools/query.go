@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ============================================================================
+// Query Tool Arguments (Struct-based schemas)
+// ============================================================================
+
+// QueryArgs defines arguments for query tool
+type QueryArgs struct {
+	Query          string  `json:"query" jsonschema:"required,description=MetricsQL/PromQL query (datasource_type=vm) or LogsQL query with '| stats' pipe (datasource_type=vmlogs)"`
+	Step           string  `json:"step,omitempty" jsonschema:"description=Query step/resolution (e.g. '1m' '5m'). For vmlogs it can be inferred from a '_time:<step>' bucket of the query"`
+	Start          float64 `json:"start,omitempty" jsonschema:"description=Query start timestamp (Unix seconds)"`
+	End            float64 `json:"end,omitempty" jsonschema:"description=Query end timestamp (Unix seconds)"`
+	DatasourceType string  `json:"datasource_type,omitempty" jsonschema:"enum=vm,enum=vmlogs,description=Datasource type: 'vm' for VictoriaMetrics or 'vmlogs' for VictoriaLogs. Default: 'vm'"`
+	DatasourceURL  string  `json:"datasource_url,omitempty" jsonschema:"description=Datasource URL. Defaults to the datasource configured in vmanomaly"`
+	TenantID       string  `json:"tenant_id,omitempty" jsonschema:"description=Optional tenant ID for multi-tenancy support"`
+}
+
+// ============================================================================
+// Tool Registration Functions
+// ============================================================================
+
+// RegisterQueryTools registers datasource query tools
+func RegisterQueryTools(s *server.MCPServer, client vmanomaly.API) {
+	queryTool := mcp.NewTool(
+		"vmanomaly_query",
+		mcp.WithDescription("Run a range query through vmanomaly against its VictoriaMetrics (MetricsQL) or VictoriaLogs (LogsQL stats) datasource. Use this to preview the series a detection task or config would read. For datasource_type=vmlogs the LogsQL query is validated first and step is inferred from '_time:<step>' buckets when omitted."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Query Datasource via vmanomaly",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[QueryArgs](),
+	)
+	s.AddTool(queryTool, mcp.NewTypedToolHandler(handleQuery(client)))
+}
+
+// ============================================================================
+// Tool Handlers
+// ============================================================================
+
+func handleQuery(client vmanomaly.API) func(ctx context.Context, req mcp.CallToolRequest, args QueryArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args QueryArgs) (*mcp.CallToolResult, error) {
+		queryReq := &vmanomaly.QueryRequest{
+			Query:          args.Query,
+			Step:           args.Step,
+			DatasourceType: args.DatasourceType,
+		}
+		if queryReq.DatasourceType == "" {
+			queryReq.DatasourceType = vmanomaly.DatasourceTypeVM
+		}
+		if args.Start > 0 {
+			queryReq.Start = &args.Start
+		}
+		if args.End > 0 {
+			queryReq.End = &args.End
+		}
+		if args.DatasourceURL != "" {
+			queryReq.DatasourceURL = &args.DatasourceURL
+		}
+		if args.TenantID != "" {
+			queryReq.TenantID = &args.TenantID
+		}
+
+		var warnings []string
+		switch queryReq.DatasourceType {
+		case vmanomaly.DatasourceTypeVMLogs:
+			step, logsWarnings, err := prepareLogsQLQuery(queryReq.Query, queryReq.Step)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Invalid query: %v", err)), nil
+			}
+			queryReq.Step = step
+			warnings = logsWarnings
+		case vmanomaly.DatasourceTypeVM:
+			if queryReq.Step == "" {
+				queryReq.Step = "1m"
+			}
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("Unsupported datasource_type %q, expected 'vm' or 'vmlogs'", queryReq.DatasourceType)), nil
+		}
+
+		result, err := client.Query(ctx, queryReq)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Query failed: %v", err)), nil
+		}
+
+		responseJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(responseJSON) + formatWarnings(warnings)), nil
+	}
+}
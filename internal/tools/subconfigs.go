@@ -0,0 +1,373 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmconfig"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ============================================================================
+// Sub-config Tool Arguments (Struct-based schemas)
+// ============================================================================
+
+// SplitConfigArgs defines arguments for split_config tool
+type SplitConfigArgs struct {
+	Config            map[string]any `json:"config,omitempty" jsonschema:"description=Complete vmanomaly configuration object (reader/schedulers/models/writer). Either config or config_yaml must be set"`
+	ConfigYAML        string         `json:"config_yaml,omitempty" jsonschema:"description=Complete vmanomaly configuration as YAML text. Either config or config_yaml must be set"`
+	SplitBy           string         `json:"split_by,omitempty" jsonschema:"enum=complete,enum=queries,enum=models,enum=schedulers,description=Entity to split the config by like VMANOMALY_SPLIT_BY. 'complete' produces 1 model on 1 query with 1 scheduler per sub-config (default: complete)"`
+	Shards            float64        `json:"shards,omitempty" jsonschema:"description=Number of shards to group sub-configs into like VMANOMALY_MEMBERS_COUNT. Every sub-config is output separately when not set"`
+	ReplicationFactor float64        `json:"replication_factor,omitempty" jsonschema:"description=Number of shards every sub-config is assigned to like VMANOMALY_REPLICATION_FACTOR (default: 1)"`
+	OutputDir         string         `json:"output_dir,omitempty" jsonschema:"description=Optional directory relative to the output directory set by MCP_OUTPUT_DIR to write sub-configs to"`
+	Overwrite         bool           `json:"overwrite,omitempty" jsonschema:"description=Overwrite existing files in output_dir"`
+	SkipValidation    bool           `json:"skip_validation,omitempty" jsonschema:"description=Skip validation of sub-configs through vmanomaly API"`
+}
+
+// MergeConfigsArgs defines arguments for merge_configs tool
+type MergeConfigsArgs struct {
+	ConfigsYAML    []string `json:"configs_yaml,omitempty" jsonschema:"description=Sub-configs as YAML texts"`
+	Paths          []string `json:"paths,omitempty" jsonschema:"description=Sub-config files or directories with .yml/.yaml files"`
+	OutputPath     string   `json:"output_path,omitempty" jsonschema:"description=Optional file path relative to the output directory set by MCP_OUTPUT_DIR to write the merged config to"`
+	Overwrite      bool     `json:"overwrite,omitempty" jsonschema:"description=Overwrite output_path if it already exists"`
+	SkipValidation bool     `json:"skip_validation,omitempty" jsonschema:"description=Skip validation of the merged config through vmanomaly API"`
+}
+
+// PreviewReloadArgs defines arguments for preview_reload tool
+type PreviewReloadArgs struct {
+	CurrentConfigYAML string         `json:"current_config_yaml,omitempty" jsonschema:"description=Currently running config as YAML text. Either current_config_yaml or current_paths must be set"`
+	CurrentPaths      []string       `json:"current_paths,omitempty" jsonschema:"description=Files or directories of the currently running config as passed to vmanomaly"`
+	Config            map[string]any `json:"config,omitempty" jsonschema:"description=New vmanomaly configuration object. Either config or config_yaml must be set"`
+	ConfigYAML        string         `json:"config_yaml,omitempty" jsonschema:"description=New vmanomaly configuration as YAML text. Either config or config_yaml must be set"`
+	SkipValidation    bool           `json:"skip_validation,omitempty" jsonschema:"description=Skip validation of the new config through vmanomaly API"`
+}
+
+// ============================================================================
+// Tool Registration Functions
+// ============================================================================
+
+// RegisterSubConfigTools registers tools for splitting, merging and hot-reloading configs,
+// configs are written to files only if outputDir is enabled
+func RegisterSubConfigTools(s *server.MCPServer, client vmanomaly.API, outputDir OutputDir) {
+	splitConfigTool := mcp.NewTool(
+		"vmanomaly_split_config",
+		mcp.WithDescription("Split a global vmanomaly config into standalone sub-configs by queries, models, schedulers or complete (model, query, scheduler) entities, optionally grouped into N shard files the same way vmanomaly distributes them with VMANOMALY_MEMBERS_COUNT and VMANOMALY_REPLICATION_FACTOR. Sub-configs are validated through vmanomaly API and can be written to a directory."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Split vmanomaly Config",
+			ReadOnlyHint:    ptr(!outputDir.Enabled()),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[SplitConfigArgs](),
+	)
+	s.AddTool(splitConfigTool, mcp.NewTypedToolHandler(handleSplitConfig(client, outputDir)))
+
+	mergeConfigsTool := mcp.NewTool(
+		"vmanomaly_merge_configs",
+		mcp.WithDescription("Merge vmanomaly sub-configs back into a single global config. Detects alias collisions: reader queries, schedulers and models defined differently under the same alias, and conflicting writer, settings or other sections. Models with the same definition are merged by uniting their queries and schedulers. The merged config is validated through vmanomaly API."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Merge vmanomaly Configs",
+			ReadOnlyHint:    ptr(!outputDir.Enabled()),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[MergeConfigsArgs](),
+	)
+	s.AddTool(mergeConfigsTool, mcp.NewTypedToolHandler(handleMergeConfigs(client, outputDir)))
+
+	previewReloadTool := mcp.NewTool(
+		"vmanomaly_preview_reload",
+		mcp.WithDescription("Preview what a config hot reload (--watch) or restart would change compared to the currently running config: added, removed and changed sections and aliases, and which (model, query) pairs keep their state with settings.restore_state, are retrained or dropped. vmanomaly API does not expose the running config, so pass it as YAML or as the files vmanomaly was started with."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Preview Config Reload",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[PreviewReloadArgs](),
+	)
+	s.AddTool(previewReloadTool, mcp.NewTypedToolHandler(handlePreviewReload(client)))
+}
+
+// ============================================================================
+// Tool Handlers
+// ============================================================================
+
+func handleSplitConfig(client vmanomaly.API, outputDir OutputDir) func(ctx context.Context, req mcp.CallToolRequest, args SplitConfigArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args SplitConfigArgs) (*mcp.CallToolResult, error) {
+		cfg, err := parseConfigArg(args.Config, args.ConfigYAML)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid config: %v", err)), nil
+		}
+		subs, err := vmconfig.Split(cfg, args.SplitBy)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to split config: %v", err)), nil
+		}
+
+		var sb strings.Builder
+		outputs := subs
+		if args.Shards > 0 {
+			assigned, err := vmconfig.AssignShards(len(subs), int(args.Shards), int(args.ReplicationFactor))
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to assign shards: %v", err)), nil
+			}
+			outputs = make([]vmconfig.SubConfig, 0, len(assigned))
+			for shard, indexes := range assigned {
+				name := fmt.Sprintf("shard-%d", shard)
+				if len(indexes) == 0 {
+					sb.WriteString(fmt.Sprintf("%s has no sub-configs, the number of shards exceeds %d sub-config(s).\n", name, len(subs)))
+					continue
+				}
+				configs := make([]map[string]any, len(indexes))
+				sources := make([]*vmconfig.Config, len(indexes))
+				for i, idx := range indexes {
+					configs[i] = subs[idx].Config
+					sources[i], _ = vmconfig.FromMap(subs[idx].Config)
+				}
+				merged, collisions, err := vmconfig.Merge(configs)
+				if err == nil && len(collisions) > 0 {
+					err = fmt.Errorf("%s", collisions[0])
+				}
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("Failed to build %s: %v", name, err)), nil
+				}
+				mc, _ := vmconfig.FromMap(merged)
+				extra := vmconfig.ExtraPairs(mc, sources)
+				for _, model := range slices.Sorted(maps.Keys(extra)) {
+					sb.WriteString(fmt.Sprintf("Warning: in %s model %s also runs on query/scheduler pairs %s, consider split_by=queries or models.\n",
+						name, model, strings.Join(extra[model], ", ")))
+				}
+				outputs = append(outputs, vmconfig.SubConfig{Name: name, Config: merged})
+			}
+		}
+
+		invalid, err := writeSubConfigs(ctx, client, &sb, outputs, outputDir, args.OutputDir, args.Overwrite, args.SkipValidation)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		header := fmt.Sprintf("Split config into %d sub-config(s) by %s", len(subs), cmp.Or(args.SplitBy, vmconfig.SplitComplete))
+		if args.Shards > 0 {
+			header += fmt.Sprintf(", grouped into %d shard(s)", len(outputs))
+		}
+		if invalid > 0 {
+			header += fmt.Sprintf(". %d of them failed validation", invalid)
+		}
+		return mcp.NewToolResultText(header + ".\n\n" + sb.String()), nil
+	}
+}
+
+func handleMergeConfigs(client vmanomaly.API, outputDir OutputDir) func(ctx context.Context, req mcp.CallToolRequest, args MergeConfigsArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args MergeConfigsArgs) (*mcp.CallToolResult, error) {
+		configs, names, err := loadConfigs(args.ConfigsYAML, args.Paths)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to load configs: %v", err)), nil
+		}
+		merged, collisions, err := vmconfig.Merge(configs)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to merge configs: %v", err)), nil
+		}
+		if len(collisions) > 0 {
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Found %d collision(s), configs cannot be merged:\n", len(collisions)))
+			for _, c := range collisions {
+				sources := make([]string, len(c.Sources))
+				for i, idx := range c.Sources {
+					sources[i] = names[idx]
+				}
+				name := c.Section
+				if c.Alias != "" {
+					name += "." + c.Alias
+				}
+				sb.WriteString(fmt.Sprintf("- %s: %s in %s\n", name, c.Message, strings.Join(sources, " and ")))
+			}
+			return mcp.NewToolResultError(sb.String()), nil
+		}
+
+		var sb strings.Builder
+		outputs := []vmconfig.SubConfig{{Name: "merged", Config: merged}}
+		dir := ""
+		if args.OutputPath != "" {
+			dir = filepath.Dir(args.OutputPath)
+			outputs[0].Name = strings.TrimSuffix(filepath.Base(args.OutputPath), filepath.Ext(args.OutputPath))
+		}
+		invalid, err := writeSubConfigs(ctx, client, &sb, outputs, outputDir, dir, args.Overwrite, args.SkipValidation)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		header := fmt.Sprintf("Merged %d config(s) without collisions", len(configs))
+		if invalid > 0 {
+			header += ", but the merged config failed validation"
+		}
+		return mcp.NewToolResultText(header + ".\n\n" + sb.String()), nil
+	}
+}
+
+func handlePreviewReload(client vmanomaly.API) func(ctx context.Context, req mcp.CallToolRequest, args PreviewReloadArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args PreviewReloadArgs) (*mcp.CallToolResult, error) {
+		var current []string
+		if args.CurrentConfigYAML != "" {
+			current = append(current, args.CurrentConfigYAML)
+		}
+		configs, _, err := loadConfigs(current, args.CurrentPaths)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to load current config: %v", err)), nil
+		}
+		merged, collisions, err := vmconfig.Merge(configs)
+		if err == nil && len(collisions) > 0 {
+			err = fmt.Errorf("%s", collisions[0])
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid current config: %v", err)), nil
+		}
+		old, err := vmconfig.FromMap(merged)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid current config: %v", err)), nil
+		}
+		updated, err := parseConfigArg(args.Config, args.ConfigYAML)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid new config: %v", err)), nil
+		}
+
+		var sb strings.Builder
+		if !args.SkipValidation {
+			validation, err := client.ValidateConfig(ctx, updated.Raw)
+			switch {
+			case err != nil:
+				sb.WriteString(fmt.Sprintf("Cannot validate the new config: %v\n\n", err))
+			case !validation.IsValid:
+				sb.WriteString("The new config is INVALID: a hot reload will fail and the current config stays active.\n\n")
+			}
+		}
+
+		preview := vmconfig.Diff(old, updated)
+		counts := preview.Counts()
+		sb.WriteString(fmt.Sprintf("Reload preview (restore_state=%t): %d restored, %d retrained, %d added, %d removed (model, query) pair(s).\n",
+			preview.RestoreState, counts[vmconfig.PairRestored], counts[vmconfig.PairRetrained], counts[vmconfig.PairAdded], counts[vmconfig.PairRemoved]))
+		if len(preview.Changes) == 0 {
+			sb.WriteString("\nNo config changes.\n")
+		} else {
+			sb.WriteString("\nChanges:\n")
+			for _, c := range preview.Changes {
+				name := c.Section
+				if c.Alias != "" {
+					name += "." + c.Alias
+				}
+				sb.WriteString(fmt.Sprintf("- %s %s", c.Kind, name))
+				if len(c.Details) > 0 {
+					sb.WriteString(": " + strings.Join(c.Details, "; "))
+				}
+				sb.WriteString("\n")
+			}
+		}
+		sb.WriteString("\nPairs:\n")
+		for _, pc := range preview.Pairs {
+			sb.WriteString(fmt.Sprintf("- %s/%s: %s", pc.Model, pc.Query, pc.Status))
+			if pc.Reason != "" {
+				sb.WriteString(" (" + pc.Reason + ")")
+			}
+			sb.WriteString("\n")
+		}
+		for _, w := range preview.Warnings {
+			sb.WriteString(fmt.Sprintf("\nWarning: %s", w))
+		}
+		return mcp.NewToolResultText(strings.TrimSpace(sb.String())), nil
+	}
+}
+
+// writeSubConfigs validates configs, writes them to dir of the output directory as <name>.yaml
+// or appends them as YAML blocks to sb. It returns the number of configs which failed validation.
+func writeSubConfigs(ctx context.Context, client vmanomaly.API, sb *strings.Builder, configs []vmconfig.SubConfig, outputDir OutputDir, dir string, overwrite, skipValidation bool) (int, error) {
+	invalid := 0
+	for _, sub := range configs {
+		data, err := yaml.Marshal(sub.Config)
+		if err != nil {
+			return 0, fmt.Errorf("failed to format %s: %v", sub.Name, err)
+		}
+		status := ""
+		if !skipValidation {
+			validation, err := client.ValidateConfig(ctx, sub.Config)
+			switch {
+			case err != nil:
+				status, invalid = fmt.Sprintf(" (validation failed: %v)", err), invalid+1
+			case !validation.IsValid:
+				status, invalid = " (INVALID)", invalid+1
+			default:
+				status = " (valid)"
+			}
+		}
+		if dir == "" {
+			sb.WriteString(fmt.Sprintf("%s%s:\n```yaml\n%s```\n\n", sub.Name, status, data))
+			continue
+		}
+		path, err := outputDir.writeFile(filepath.Join(dir, sub.Name+".yaml"), data, overwrite)
+		if err != nil {
+			return 0, fmt.Errorf("failed to write %s: %v", sub.Name, err)
+		}
+		sb.WriteString(fmt.Sprintf("%s%s written to %s\n", sub.Name, status, path))
+	}
+	return invalid, nil
+}
+
+// loadConfigs parses YAML texts and config files, directories are expanded to their .yml/.yaml files
+// the same way vmanomaly does. It returns configs with their display names.
+func loadConfigs(texts, paths []string) ([]map[string]any, []string, error) {
+	var configs []map[string]any
+	var names []string
+	add := func(name string, data []byte) error {
+		var raw map[string]any
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if raw == nil {
+			return fmt.Errorf("%s: config is empty", name)
+		}
+		configs = append(configs, raw)
+		names = append(names, name)
+		return nil
+	}
+	for i, text := range texts {
+		if err := add(fmt.Sprintf("config #%d", i), []byte(text)); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, path := range paths {
+		files := []string{path}
+		if info, err := os.Stat(path); err != nil {
+			return nil, nil, err
+		} else if info.IsDir() {
+			files = nil
+			for _, pattern := range []string{"*.yml", "*.yaml"} {
+				matches, _ := filepath.Glob(filepath.Join(path, pattern))
+				files = append(files, matches...)
+			}
+			slices.Sort(files)
+			if len(files) == 0 {
+				return nil, nil, fmt.Errorf("%s: no .yml or .yaml files found", path)
+			}
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := add(file, data); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if len(configs) == 0 {
+		return nil, nil, fmt.Errorf("no configs given")
+	}
+	return configs, names, nil
+}
@@ -0,0 +1,351 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/analyzer"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/taskregistry"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ============================================================================
+// Anomaly Detection Task Tool Arguments (Struct-based schemas)
+// ============================================================================
+
+// CreateDetectionTaskArgs defines arguments for create_detection_task tool
+type CreateDetectionTaskArgs struct {
+	Query            string         `json:"query" jsonschema:"required,description=MetricsQL/PromQL query (datasource_type=vm) or LogsQL query with '| stats' pipe (datasource_type=vmlogs) to run anomaly detection on"`
+	ModelSpec        map[string]any `json:"model_spec" jsonschema:"required,description=Model specification as JSON object (must include 'class' field). Use vmanomaly_get_model_schema to see available parameters"`
+	Step             string         `json:"step,omitempty" jsonschema:"description=Query step/resolution (e.g. '1m' '5m'). For vmlogs it can be inferred from a '_time:<step>' bucket of the query"`
+	FitWindow        string         `json:"fit_window,omitempty" jsonschema:"description=Time window for model fitting (default: '1d')"`
+	FitEvery         string         `json:"fit_every,omitempty" jsonschema:"description=Model retraining frequency (default: '1d')"`
+	InferEvery       string         `json:"infer_every,omitempty" jsonschema:"description=Optional inference cadence for exact-mode batches"`
+	StartInferS      float64        `json:"start_infer_s,omitempty" jsonschema:"description=Inference start timestamp (Unix seconds)"`
+	EndInferS        float64        `json:"end_infer_s,omitempty" jsonschema:"description=Inference end timestamp (Unix seconds)"`
+	Exact            bool           `json:"exact,omitempty" jsonschema:"description=Enable exact-mode inference for online models"`
+	AnomalyThreshold float64        `json:"anomaly_threshold,omitempty" jsonschema:"description=Anomaly score threshold (default: 1.0)"`
+	DatasourceURL    string         `json:"datasource_url,omitempty" jsonschema:"description=Datasource URL (VictoriaMetrics or VictoriaLogs). Defaults to the datasource configured in vmanomaly"`
+	DatasourceType   string         `json:"datasource_type,omitempty" jsonschema:"enum=vm,enum=vmlogs,description=Datasource type: 'vm' for VictoriaMetrics (MetricsQL) or 'vmlogs' for VictoriaLogs (LogsQL). Default: 'vm'"`
+	TenantID         string         `json:"tenant_id,omitempty" jsonschema:"description=Optional tenant ID for multi-tenancy support"`
+	PassAuthHeaders  bool           `json:"pass_auth_headers,omitempty" jsonschema:"description=Forward Authorization header to the datasource"`
+	EstimateSeries   bool           `json:"estimate_series,omitempty" jsonschema:"description=Run a cheap count() query before submitting the task and reject MetricsQL queries returning more than max_series series"`
+	MaxSeries        float64        `json:"max_series,omitempty" jsonschema:"description=Maximum number of series the MetricsQL query may return when estimate_series is enabled (default: 1000)"`
+	SkipQueryChecks  bool           `json:"skip_query_checks,omitempty" jsonschema:"description=Skip static analysis and cardinality estimation of MetricsQL queries"`
+	Label            string         `json:"label,omitempty" jsonschema:"description=Optional label to find the task later in the local task history (e.g. 'checkout-latency-zscore')"`
+}
+
+// GetTaskStatusArgs defines arguments for get_task_status tool
+type GetTaskStatusArgs struct {
+	TaskID string `json:"task_id" jsonschema:"required,description=Task ID returned by vmanomaly_create_detection_task"`
+}
+
+// ListTasksArgs defines arguments for list_tasks tool
+type ListTasksArgs struct {
+	Limit  float64 `json:"limit,omitempty" jsonschema:"description=Maximum number of tasks to return (default: 20)"`
+	Status string  `json:"status,omitempty" jsonschema:"enum=running,enum=done,enum=error,enum=canceled,description=Optional status filter"`
+}
+
+// CancelTaskArgs defines arguments for cancel_task tool
+type CancelTaskArgs struct {
+	TaskID string `json:"task_id" jsonschema:"required,description=Task ID to cancel"`
+}
+
+// ============================================================================
+// Tool Registration Functions
+// ============================================================================
+
+// RegisterTaskTools registers all anomaly detection task tools, created tasks are recorded in registry
+func RegisterTaskTools(s *server.MCPServer, client vmanomaly.API, registry *taskregistry.Registry) {
+	createDetectionTaskTool := mcp.NewTool(
+		"vmanomaly_create_detection_task",
+		mcp.WithDescription("Start a background anomaly detection task on a VictoriaMetrics (MetricsQL) or VictoriaLogs (LogsQL) query with the given model. Returns a task ID; poll it with vmanomaly_get_task_status to get progress and results. The task is recorded in the local task history with an optional label. For datasource_type=vmlogs the LogsQL query is validated first (stats pipe and supported stats functions) and step is inferred from '_time:<step>' buckets when omitted. MetricsQL queries are statically analyzed (raw counters, missing aggregation, windows shorter than step, dashboard placeholders) before submission and their cardinality is estimated with estimate_series; use skip_query_checks to bypass."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Create Anomaly Detection Task",
+			ReadOnlyHint:    ptr(false),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[CreateDetectionTaskArgs](),
+	)
+	s.AddTool(createDetectionTaskTool, mcp.NewTypedToolHandler(handleCreateDetectionTask(client, registry)))
+
+	getTaskStatusTool := mcp.NewTool(
+		"vmanomaly_get_task_status",
+		mcp.WithDescription("Get status, progress and results of an anomaly detection task. Results (anomaly scores, detected anomalies and stats) are available when status is 'done'. Results of tasks created through this server are cached in the local task history and returned even after vmanomaly forgets the task."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Get Detection Task Status",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[GetTaskStatusArgs](),
+	)
+	s.AddTool(getTaskStatusTool, mcp.NewTypedToolHandler(handleGetTaskStatus(client, registry)))
+
+	listTasksTool := mcp.NewTool(
+		"vmanomaly_list_tasks",
+		mcp.WithDescription("List anomaly detection tasks known to the vmanomaly server with their status and progress."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "List Detection Tasks",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[ListTasksArgs](),
+	)
+	s.AddTool(listTasksTool, mcp.NewTypedToolHandler(handleListTasks(client)))
+
+	cancelTaskTool := mcp.NewTool(
+		"vmanomaly_cancel_task",
+		mcp.WithDescription("Cancel a running anomaly detection task."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Cancel Detection Task",
+			ReadOnlyHint:    ptr(false),
+			DestructiveHint: ptr(true),
+			OpenWorldHint:   ptr(false),
+		}),
+		mcp.WithInputSchema[CancelTaskArgs](),
+	)
+	s.AddTool(cancelTaskTool, mcp.NewTypedToolHandler(handleCancelTask(client)))
+
+	getDetectionLimitsTool := mcp.NewTool(
+		"vmanomaly_get_detection_limits",
+		mcp.WithDescription("Get anomaly detection capacity of the vmanomaly server: maximum concurrent tasks, running tasks and available slots. Check this before starting many tasks."),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Get Detection Limits",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			OpenWorldHint:   ptr(false),
+		}),
+	)
+	s.AddTool(getDetectionLimitsTool, handleGetDetectionLimits(client))
+}
+
+// ============================================================================
+// Tool Handlers
+// ============================================================================
+
+// buildDetectionTaskRequest converts tool arguments to API request, applying defaults and
+// validating LogsQL queries for vmlogs datasource and MetricsQL queries for vm datasource.
+// Returned warnings should be shown to the user.
+func buildDetectionTaskRequest(args CreateDetectionTaskArgs) (*vmanomaly.AnomalyDetectionTaskRequest, []string, error) {
+	taskReq := &vmanomaly.AnomalyDetectionTaskRequest{
+		Query:            args.Query,
+		Step:             args.Step,
+		FitWindow:        args.FitWindow,
+		FitEvery:         args.FitEvery,
+		Exact:            args.Exact,
+		AnomalyThreshold: args.AnomalyThreshold,
+		ModelSpec:        args.ModelSpec,
+		DatasourceType:   args.DatasourceType,
+		PassAuthHeaders:  args.PassAuthHeaders,
+	}
+
+	if taskReq.DatasourceType == "" {
+		taskReq.DatasourceType = vmanomaly.DatasourceTypeVM
+	}
+	if taskReq.FitWindow == "" {
+		taskReq.FitWindow = "1d"
+	}
+	if taskReq.FitEvery == "" {
+		taskReq.FitEvery = "1d"
+	}
+	if taskReq.AnomalyThreshold <= 0 {
+		taskReq.AnomalyThreshold = 1
+	}
+	if args.InferEvery != "" {
+		taskReq.InferEvery = &args.InferEvery
+	}
+	if args.StartInferS > 0 {
+		taskReq.StartInferS = &args.StartInferS
+	}
+	if args.EndInferS > 0 {
+		taskReq.EndInferS = &args.EndInferS
+	}
+	if args.DatasourceURL != "" {
+		taskReq.DatasourceURL = &args.DatasourceURL
+	}
+	if args.TenantID != "" {
+		taskReq.TenantID = &args.TenantID
+	}
+
+	var warnings []string
+	switch taskReq.DatasourceType {
+	case vmanomaly.DatasourceTypeVMLogs:
+		step, logsWarnings, err := prepareLogsQLQuery(taskReq.Query, taskReq.Step)
+		if err != nil {
+			return nil, nil, err
+		}
+		taskReq.Step = step
+		warnings = logsWarnings
+	case vmanomaly.DatasourceTypeVM:
+		if taskReq.Step == "" {
+			return nil, nil, fmt.Errorf("step is required for datasource_type=vm")
+		}
+		if args.SkipQueryChecks {
+			break
+		}
+		report := analyzer.Analyze(taskReq.Query, taskReq.Step)
+		if !report.Valid() {
+			return nil, nil, fmt.Errorf("query analysis failed: %s", strings.Join(report.Errors(), "; "))
+		}
+		warnings = report.Warnings()
+	default:
+		return nil, nil, fmt.Errorf("unsupported datasource_type %q, expected 'vm' or 'vmlogs'", taskReq.DatasourceType)
+	}
+
+	return taskReq, warnings, nil
+}
+
+func handleCreateDetectionTask(client vmanomaly.API, registry *taskregistry.Registry) func(ctx context.Context, req mcp.CallToolRequest, args CreateDetectionTaskArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args CreateDetectionTaskArgs) (*mcp.CallToolResult, error) {
+		taskReq, warnings, err := buildDetectionTaskRequest(args)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid detection task: %v", err)), nil
+		}
+
+		if taskReq.DatasourceType == vmanomaly.DatasourceTypeVM && args.EstimateSeries && !args.SkipQueryChecks {
+			maxSeries := int(args.MaxSeries)
+			if maxSeries <= 0 {
+				maxSeries = analyzer.DefaultMaxSeries
+			}
+			estimate, err := analyzer.EstimateCardinality(ctx, client, taskReq.Query, taskReq.Step, args.DatasourceURL, args.TenantID)
+			switch {
+			case err != nil:
+				warnings = append(warnings, fmt.Sprintf("cannot estimate query cardinality: %v", err))
+			case estimate.Series > maxSeries:
+				return mcp.NewToolResultError(fmt.Sprintf("Query returns ~%d series, which exceeds max_series=%d. Aggregate the query (e.g. sum(...) by (job)) or narrow label filters; raise max_series if this is intended.",
+					estimate.Series, maxSeries)), nil
+			case estimate.Series == 0:
+				warnings = append(warnings, "query returned no series for the most recent points; check label filters and the datasource")
+			}
+		}
+
+		task, err := client.CreateDetectionTask(ctx, taskReq)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to create detection task: %v", err)), nil
+		}
+		err = registry.Add(&taskregistry.Record{
+			TaskID:    task.TaskID,
+			Label:     args.Label,
+			SessionID: sessionID(ctx),
+			Request:   taskReq,
+			Status:    task.Status,
+		})
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("task is not recorded in the local task history: %v", err))
+		}
+
+		responseJSON, err := json.MarshalIndent(task, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to format response: %v", err)), nil
+		}
+
+		resultMsg := fmt.Sprintf("Detection task created:\n%s\n\nUse vmanomaly_get_task_status with task_id=%q to track progress and get results.%s",
+			string(responseJSON), task.TaskID, formatWarnings(warnings))
+		return mcp.NewToolResultText(resultMsg), nil
+	}
+}
+
+func handleGetTaskStatus(client vmanomaly.API, registry *taskregistry.Registry) func(ctx context.Context, req mcp.CallToolRequest, args GetTaskStatusArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args GetTaskStatusArgs) (*mcp.CallToolResult, error) {
+		status, note, err := taskStatus(ctx, client, registry, args.TaskID)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get task status: %v", err)), nil
+		}
+
+		responseJSON, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to format response: %v", err)), nil
+		}
+
+		if note != "" {
+			return mcp.NewToolResultText(fmt.Sprintf("%s\n\n%s", responseJSON, note)), nil
+		}
+		return mcp.NewToolResultText(string(responseJSON)), nil
+	}
+}
+
+// taskStatus returns the status of a task. Finished tasks of the registry are served from it,
+// other tasks are fetched from vmanomaly and updated in the registry. If vmanomaly cannot return a recorded task,
+// its last recorded state is returned. The note explains where the status comes from.
+func taskStatus(ctx context.Context, client vmanomaly.API, registry *taskregistry.Registry, taskID string) (*vmanomaly.AnomalyDetectionTaskStatus, string, error) {
+	rec, _ := registry.Task(taskID)
+	if rec != nil && rec.Finished() {
+		return rec.TaskStatus(), "The task is finished, its result is served from the local task history.", nil
+	}
+	status, err := client.GetTaskStatus(ctx, taskID)
+	if err != nil {
+		if rec == nil {
+			return nil, "", err
+		}
+		return rec.TaskStatus(), fmt.Sprintf("vmanomaly cannot return the task (%v), its last recorded state from the local task history is shown.", err), nil
+	}
+	if _, err := registry.Update(status); err != nil {
+		return status, fmt.Sprintf("Warning: cannot update the local task history: %v", err), nil
+	}
+	return status, "", nil
+}
+
+func handleListTasks(client vmanomaly.API) func(ctx context.Context, req mcp.CallToolRequest, args ListTasksArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args ListTasksArgs) (*mcp.CallToolResult, error) {
+		limit := int(args.Limit)
+		if limit < 1 {
+			limit = 20 // default
+		}
+		var status *string
+		if args.Status != "" {
+			status = &args.Status
+		}
+
+		tasks, err := client.ListTasks(ctx, limit, status)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list tasks: %v", err)), nil
+		}
+
+		responseJSON, err := json.MarshalIndent(tasks, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(responseJSON)), nil
+	}
+}
+
+func handleCancelTask(client vmanomaly.API) func(ctx context.Context, req mcp.CallToolRequest, args CancelTaskArgs) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest, args CancelTaskArgs) (*mcp.CallToolResult, error) {
+		result, err := client.CancelTask(ctx, args.TaskID)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to cancel task: %v", err)), nil
+		}
+
+		responseJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(responseJSON)), nil
+	}
+}
+
+func handleGetDetectionLimits(client vmanomaly.API) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		limits, err := client.GetDetectionLimits(ctx)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get detection limits: %v", err)), nil
+		}
+
+		responseJSON, err := json.MarshalIndent(limits, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(responseJSON)), nil
+	}
+}
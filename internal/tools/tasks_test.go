@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+func TestBuildDetectionTaskRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         CreateDetectionTaskArgs
+		wantErr      bool
+		wantStep     string
+		wantType     string
+		wantWarnings bool
+	}{
+		{
+			name:     "vm defaults",
+			args:     CreateDetectionTaskArgs{Query: "sum(up) by (job)", Step: "1m", ModelSpec: map[string]any{"class": "zscore"}},
+			wantStep: "1m",
+			wantType: vmanomaly.DatasourceTypeVM,
+		},
+		{
+			name:         "vm raw counter",
+			args:         CreateDetectionTaskArgs{Query: "sum(http_requests_total)", Step: "1m"},
+			wantStep:     "1m",
+			wantType:     vmanomaly.DatasourceTypeVM,
+			wantWarnings: true,
+		},
+		{
+			name:    "vm interval placeholder",
+			args:    CreateDetectionTaskArgs{Query: "sum(rate(http_requests_total[$__interval]))", Step: "1m"},
+			wantErr: true,
+		},
+		{
+			name:     "vm placeholder with skipped checks",
+			args:     CreateDetectionTaskArgs{Query: "sum(rate(http_requests_total[$__interval]))", Step: "1m", SkipQueryChecks: true},
+			wantStep: "1m",
+			wantType: vmanomaly.DatasourceTypeVM,
+		},
+		{
+			name:    "vm without step",
+			args:    CreateDetectionTaskArgs{Query: "up", ModelSpec: map[string]any{"class": "zscore"}},
+			wantErr: true,
+		},
+		{
+			name: "vmlogs with explicit step",
+			args: CreateDetectionTaskArgs{
+				Query:          "error | stats by (host) count() as errors",
+				Step:           "5m",
+				DatasourceType: vmanomaly.DatasourceTypeVMLogs,
+			},
+			wantStep: "5m",
+			wantType: vmanomaly.DatasourceTypeVMLogs,
+		},
+		{
+			name: "vmlogs with inferred step",
+			args: CreateDetectionTaskArgs{
+				Query:          "error | stats by (_time:10m, host) count() as errors",
+				DatasourceType: vmanomaly.DatasourceTypeVMLogs,
+			},
+			wantStep:     "10m",
+			wantType:     vmanomaly.DatasourceTypeVMLogs,
+			wantWarnings: true,
+		},
+		{
+			name: "vmlogs without stats pipe",
+			args: CreateDetectionTaskArgs{
+				Query:          "error",
+				Step:           "5m",
+				DatasourceType: vmanomaly.DatasourceTypeVMLogs,
+			},
+			wantErr: true,
+		},
+		{
+			name: "vmlogs with unsupported stats function",
+			args: CreateDetectionTaskArgs{
+				Query:          "* | stats uniq_values(host) as hosts",
+				Step:           "5m",
+				DatasourceType: vmanomaly.DatasourceTypeVMLogs,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown datasource type",
+			args:    CreateDetectionTaskArgs{Query: "up", Step: "1m", DatasourceType: "influx"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, warnings, err := buildDetectionTaskRequest(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildDetectionTaskRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if req.Step != tt.wantStep {
+				t.Errorf("step = %q, want %q", req.Step, tt.wantStep)
+			}
+			if req.DatasourceType != tt.wantType {
+				t.Errorf("datasource_type = %q, want %q", req.DatasourceType, tt.wantType)
+			}
+			if req.FitWindow != "1d" || req.FitEvery != "1d" || req.AnomalyThreshold != 1 {
+				t.Errorf("defaults not applied: fit_window=%q fit_every=%q threshold=%v", req.FitWindow, req.FitEvery, req.AnomalyThreshold)
+			}
+			if (len(warnings) > 0) != tt.wantWarnings {
+				t.Errorf("warnings = %v, wantWarnings %v", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
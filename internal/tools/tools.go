@@ -5,13 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/taskregistry"
 	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
-func RegisterTools(s *server.MCPServer, client *vmanomaly.Client) {
+// RegisterTools registers all tools, tools generating files write them only to outputDir if it is enabled
+func RegisterTools(s *server.MCPServer, client vmanomaly.API, registry *taskregistry.Registry, outputDir OutputDir) {
 	healthTool := mcp.NewTool("vmanomaly_health_check",
 		mcp.WithDescription("Check the health status of the vmanomaly server"),
 		mcp.WithToolAnnotation(mcp.ToolAnnotation{
@@ -25,13 +27,27 @@ func RegisterTools(s *server.MCPServer, client *vmanomaly.Client) {
 
 	RegisterModelTools(s, client)
 	RegisterConfigTools(s, client)
+	RegisterPresetTools(s, client)
+	RegisterTaskTools(s, client, registry)
+	RegisterHistoryTools(s, client, registry)
+	RegisterQueryTools(s, client)
+	RegisterDetectionTools(s, client)
+	RegisterLogsQLTools(s)
+	RegisterAnalyzerTools(s, client)
 	RegisterInfoTools(s, client)
 	RegisterCompatibilityTools(s, client)
 	RegisterAlertTools(s, client)
-	RegisterDocsTool(s)
+	RegisterDashboardTools(s, outputDir)
+	RegisterK8sTools(s, client)
+	RegisterCapacityTools(s, client, outputDir)
+	RegisterSubConfigTools(s, client, outputDir)
+	RegisterMigrationTools(s, client)
+	RegisterChangelogTools(s, client)
+	RegisterDocsTools(s, client)
+	RegisterParamsTools(s, client)
 }
 
-func handleHealthCheck(client *vmanomaly.Client) server.ToolHandlerFunc {
+func handleHealthCheck(client vmanomaly.API) server.ToolHandlerFunc {
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		health, err := client.GetHealth(ctx)
 		if err != nil {
@@ -2,10 +2,54 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/taskregistry"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomalytest"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 )
 
+// resultText returns the text content of a tool result
+func resultText(result *mcp.CallToolResult) string {
+	var sb strings.Builder
+	for _, c := range result.Content {
+		if text, ok := c.(mcp.TextContent); ok {
+			sb.WriteString(text.Text)
+		}
+	}
+	return sb.String()
+}
+
+// callTool calls a tool of the server with a JSON-RPC request
+func callTool(t *testing.T, s *server.MCPServer, name string, args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	params, err := json.Marshal(map[string]any{"name": name, "arguments": args})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": %s}`, params)
+	switch resp := s.HandleMessage(context.Background(), []byte(req)).(type) {
+	case mcp.JSONRPCResponse:
+		result, ok := resp.Result.(mcp.CallToolResult)
+		if !ok {
+			t.Fatalf("unexpected result %T", resp.Result)
+		}
+		return &result
+	case mcp.JSONRPCError:
+		t.Fatalf("%s failed: %s", name, resp.Error.Message)
+	default:
+		t.Fatalf("unexpected response %T", resp)
+	}
+	return nil
+}
+
 func TestHealthCheck_Error(t *testing.T) {
 	mock := &MockClient{
 		GetHealthFunc: func(ctx context.Context) (map[string]any, error) {
@@ -13,9 +57,100 @@ func TestHealthCheck_Error(t *testing.T) {
 		},
 	}
 
-	_, err := mock.GetHealth(context.Background())
+	result, err := handleHealthCheck(mock)(context.Background(), mcp.CallToolRequest{})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(resultText(result), "connection refused") {
+		t.Errorf("expected tool error with API error, got %+v", result)
+	}
+}
+
+func TestRegisterTools(t *testing.T) {
+	s := server.NewMCPServer("test", "v0.0.0")
+	RegisterTools(s, vmanomaly.NewClient("http://localhost:8490", "", nil), taskregistry.New(taskregistry.NewMemoryStore(), 0), "")
+
+	registered := s.ListTools()
+	for _, name := range []string{
+		"vmanomaly_health_check",
+		"vmanomaly_generate_config",
+		"vmanomaly_create_detection_task",
+		"vmanomaly_get_task_status",
+		"vmanomaly_list_task_history",
+		"vmanomaly_reopen_task",
+		"vmanomaly_compare_tasks",
+		"vmanomaly_rerun_task",
+		"vmanomaly_query",
+		"vmanomaly_detect_locally",
+		"vmanomaly_detect_inline",
+		"vmanomaly_analyze_logsql_query",
+		"vmanomaly_analyze_query",
+		"vmanomaly_preset_config",
+		"vmanomaly_generate_config_alert_rules",
+		"vmanomaly_validate_alert_rules",
+		"vmanomaly_generate_dashboard",
+		"vmanomaly_generate_k8s_manifests",
+		"vmanomaly_plan_sharding",
+		"vmanomaly_split_config",
+		"vmanomaly_merge_configs",
+		"vmanomaly_preview_reload",
+		"vmanomaly_plan_migration",
+		"vmanomaly_search_changelog",
+		"vmanomaly_search_docs",
+		"vmanomaly_get_doc_section",
+		"vmanomaly_lookup_parameter",
+		"vmanomaly_logsql_templates",
+	} {
+		if _, ok := registered[name]; !ok {
+			t.Errorf("tool %s is not registered", name)
+		}
+	}
+}
+
+func TestTools_FakeServer(t *testing.T) {
+	fake := vmanomalytest.NewServer(vmanomalytest.WithTaskPolls(2))
+	defer fake.Close()
+	s := server.NewMCPServer("test", "v0.0.0", server.WithToolCapabilities(false))
+	RegisterTools(s, fake.Client(), taskregistry.New(taskregistry.NewMemoryStore(), 0), "")
+
+	if result := callTool(t, s, "vmanomaly_health_check", nil); result.IsError || !strings.Contains(resultText(result), `"ok"`) {
+		t.Errorf("health check = %s", resultText(result))
+	}
+	if result := callTool(t, s, "vmanomaly_validate_model_config", map[string]any{"model_spec": map[string]any{"class": "unknown"}}); !result.IsError {
+		t.Errorf("validation of unknown model must fail, got %s", resultText(result))
+	}
+
+	result := callTool(t, s, "vmanomaly_create_detection_task", map[string]any{
+		"query": "sum(rate(http_requests_total[5m]))", "step": "1m", "model_spec": map[string]any{"class": "zscore"},
+	})
+	if result.IsError || !strings.Contains(resultText(result), "task-1") {
+		t.Fatalf("create task = %s", resultText(result))
+	}
+	if req, ok := fake.Task("task-1"); !ok || req.FitWindow != "1d" {
+		t.Errorf("task request = %+v", req)
+	}
+	for _, want := range []string{`"progress": 50`, `"status": "done"`} {
+		result := callTool(t, s, "vmanomaly_get_task_status", map[string]any{"task_id": "task-1"})
+		if result.IsError || !strings.Contains(resultText(result), want) {
+			t.Errorf("task status = %s, want %s", resultText(result), want)
+		}
+	}
+
+	// The fake series has a spike in the middle of the queried range, which is the inference start
+	result = callTool(t, s, "vmanomaly_detect_locally", map[string]any{
+		"query": "sum(rate(http_requests_total[5m]))", "step": "1m", "model_spec": map[string]any{"class": "zscore", "z_threshold": 10, "foo": 1},
+		"start_infer_s": 1767225600, "end_infer_s": 1767229200, "fit_window": "1h",
+	})
+	if text := resultText(result); result.IsError || !strings.Contains(text, "1 anomalies") || !strings.Contains(text, "2026-01-01T00:00:00Z") || !strings.Contains(text, `parameter \"foo\"`) {
+		t.Errorf("detect locally = %s", text)
+	}
+	if result := callTool(t, s, "vmanomaly_detect_locally", map[string]any{"query": "up", "step": "1m", "model_spec": map[string]any{"class": "prophet"}}); !result.IsError {
+		t.Errorf("local detection of unsupported model must fail, got %s", resultText(result))
+	}
 
-	if err == nil {
-		t.Error("expected error from API")
+	// Only the latest docs are embedded, so an older version can't be picked
+	if result := callTool(t, s, "vmanomaly_search_docs", map[string]any{"query": "zscore", "version": "v1.0.0"}); result.IsError || !strings.Contains(resultText(result), "Only the latest documentation") {
+		t.Errorf("search docs of an old version = %s", resultText(result))
 	}
 }
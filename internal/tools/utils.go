@@ -1,5 +1,25 @@
 package tools
 
+import (
+	"fmt"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmconfig"
+)
+
 func ptr[T any](v T) *T {
 	return &v
 }
+
+// parseConfigArg parses vmanomaly config passed either as an object or as a YAML string
+func parseConfigArg(config map[string]any, configYAML string) (*vmconfig.Config, error) {
+	switch {
+	case len(config) > 0 && configYAML != "":
+		return nil, fmt.Errorf("only one of config and config_yaml must be set")
+	case len(config) > 0:
+		return vmconfig.FromMap(config)
+	case configYAML != "":
+		return vmconfig.Parse([]byte(configYAML))
+	default:
+		return nil, fmt.Errorf("config or config_yaml must be set")
+	}
+}
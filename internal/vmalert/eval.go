@@ -0,0 +1,626 @@
+package vmalert
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+// defaultLookback is how far back a selector looks for the latest sample of a series
+const defaultLookback = 5 * time.Minute
+
+// Sample is a point of an instant vector returned by the local evaluator
+type Sample struct {
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+}
+
+// point is a raw sample of a stored series, stale marks the end of the series
+type point struct {
+	ts    int64 // milliseconds
+	value float64
+	stale bool
+}
+
+type storedSeries struct {
+	labels map[string]string
+	points []point
+}
+
+// storage is an in-memory series storage for unit tests
+type storage struct {
+	series map[string]*storedSeries
+}
+
+func newStorage() *storage {
+	return &storage{series: map[string]*storedSeries{}}
+}
+
+func (s *storage) add(labels map[string]string, p point) {
+	key := labelsKey(labels)
+	ss, ok := s.series[key]
+	if !ok {
+		ss = &storedSeries{labels: labels}
+		s.series[key] = ss
+	}
+	i := sort.Search(len(ss.points), func(i int) bool { return ss.points[i].ts >= p.ts })
+	if i < len(ss.points) && ss.points[i].ts == p.ts {
+		ss.points[i] = p
+		return
+	}
+	ss.points = slices.Insert(ss.points, i, p)
+}
+
+// value is a result of expression evaluation: either a scalar or an instant vector
+type value struct {
+	vector   []Sample
+	scalar   float64
+	isScalar bool
+}
+
+func scalarValue(v float64) value {
+	return value{scalar: v, isScalar: true}
+}
+
+// evaluator is a minimal MetricsQL evaluator working over in-memory storage.
+// It covers what alerting rules on anomaly_score and vmanomaly self-monitoring metrics use: selectors with offset,
+// rate, increase, changes and basic *_over_time rollups, absent and vector functions, sum, min, max, avg
+// and count aggregations, arithmetic and comparison operators and and/or/unless set operators.
+// Everything else, e.g. subqueries or group_left(), is reported as unsupported.
+type evaluator struct {
+	storage *storage
+	ts      int64         // evaluation timestamp in milliseconds
+	step    time.Duration // default rollup window
+}
+
+func (ev *evaluator) at(ts int64) *evaluator {
+	c := *ev
+	c.ts = ts
+	return &c
+}
+
+func (ev *evaluator) eval(e metricsql.Expr) (value, error) {
+	switch e := e.(type) {
+	case *metricsql.NumberExpr:
+		return scalarValue(e.N), nil
+	case *metricsql.DurationExpr:
+		return scalarValue(float64(e.Duration(ev.step.Milliseconds())) / 1e3), nil
+	case *metricsql.MetricExpr:
+		return value{vector: ev.selectInstant(e, ev.ts)}, nil
+	case *metricsql.RollupExpr:
+		if err := checkRollup(e); err != nil {
+			return value{}, err
+		}
+		if e.Window != nil {
+			return value{}, fmt.Errorf("range selector %s must be wrapped into a rollup function", e.AppendString(nil))
+		}
+		return ev.at(ev.ts - ev.offset(e)).eval(e.Expr)
+	case *metricsql.FuncExpr:
+		return ev.evalFunc(e)
+	case *metricsql.AggrFuncExpr:
+		return ev.evalAggr(e)
+	case *metricsql.BinaryOpExpr:
+		return ev.evalBinary(e)
+	case *metricsql.StringExpr:
+		return value{}, fmt.Errorf("unexpected string %q", e.S)
+	default:
+		return value{}, fmt.Errorf("unsupported expression %s", e.AppendString(nil))
+	}
+}
+
+func checkRollup(re *metricsql.RollupExpr) error {
+	switch {
+	case re.ForSubquery():
+		return fmt.Errorf("subquery %s is not supported by the local evaluator", re.AppendString(nil))
+	case re.At != nil:
+		return fmt.Errorf("@ modifier in %s is not supported by the local evaluator", re.AppendString(nil))
+	}
+	return nil
+}
+
+func (ev *evaluator) offset(re *metricsql.RollupExpr) int64 {
+	if re.Offset == nil {
+		return 0
+	}
+	return re.Offset.Duration(ev.step.Milliseconds())
+}
+
+// selectInstant returns the latest non-stale sample within the lookback window for every matching series
+func (ev *evaluator) selectInstant(me *metricsql.MetricExpr, ts int64) []Sample {
+	var result []Sample
+	for _, ss := range ev.matchingSeries(me) {
+		i := sort.Search(len(ss.points), func(i int) bool { return ss.points[i].ts > ts }) - 1
+		if i < 0 {
+			continue
+		}
+		p := ss.points[i]
+		if p.stale || p.ts <= ts-defaultLookback.Milliseconds() {
+			continue
+		}
+		result = append(result, Sample{Labels: maps.Clone(ss.labels), Value: p.value})
+	}
+	return result
+}
+
+func (ev *evaluator) matchingSeries(me *metricsql.MetricExpr) []*storedSeries {
+	var result []*storedSeries
+	for _, key := range slices.Sorted(maps.Keys(ev.storage.series)) {
+		ss := ev.storage.series[key]
+		if slices.ContainsFunc(me.LabelFilterss, func(filters []metricsql.LabelFilter) bool {
+			return matchFilters(filters, ss.labels)
+		}) {
+			result = append(result, ss)
+		}
+	}
+	return result
+}
+
+func matchFilters(filters []metricsql.LabelFilter, labels map[string]string) bool {
+	for _, f := range filters {
+		v := labels[f.Label]
+		matched := v == f.Value
+		if f.IsRegexp {
+			re, err := regexp.Compile("^(?:" + f.Value + ")$")
+			matched = err == nil && re.MatchString(v)
+		}
+		if matched == f.IsNegative {
+			return false
+		}
+	}
+	return true
+}
+
+// rollupFuncs calculate a value from raw points of the lookbehind window
+var rollupFuncs = map[string]func(points []point) (float64, bool){
+	"rate": func(points []point) (float64, bool) {
+		if len(points) < 2 {
+			return 0, false
+		}
+		dt := float64(points[len(points)-1].ts-points[0].ts) / 1e3
+		return counterIncrease(points) / dt, true
+	},
+	"increase": func(points []point) (float64, bool) {
+		return counterIncrease(points), len(points) >= 2
+	},
+	"changes": func(points []point) (float64, bool) {
+		n := 0
+		for i := 1; i < len(points); i++ {
+			if points[i].value != points[i-1].value {
+				n++
+			}
+		}
+		return float64(n), true
+	},
+	"avg_over_time": func(points []point) (float64, bool) {
+		return sumPoints(points) / float64(len(points)), true
+	},
+	"sum_over_time": func(points []point) (float64, bool) {
+		return sumPoints(points), true
+	},
+	"min_over_time": func(points []point) (float64, bool) {
+		return slices.MinFunc(points, func(a, b point) int { return cmp.Compare(a.value, b.value) }).value, true
+	},
+	"max_over_time": func(points []point) (float64, bool) {
+		return slices.MaxFunc(points, func(a, b point) int { return cmp.Compare(a.value, b.value) }).value, true
+	},
+	"count_over_time": func(points []point) (float64, bool) {
+		return float64(len(points)), true
+	},
+	"last_over_time": func(points []point) (float64, bool) {
+		return points[len(points)-1].value, true
+	},
+}
+
+func counterIncrease(points []point) float64 {
+	var result float64
+	for i := 1; i < len(points); i++ {
+		d := points[i].value - points[i-1].value
+		if d < 0 {
+			// counter reset
+			d = points[i].value
+		}
+		result += d
+	}
+	return result
+}
+
+func sumPoints(points []point) float64 {
+	var sum float64
+	for _, p := range points {
+		sum += p.value
+	}
+	return sum
+}
+
+func (ev *evaluator) evalFunc(fe *metricsql.FuncExpr) (value, error) {
+	fn, isRollup := rollupFuncs[fe.Name]
+	if !isRollup && fe.Name != "absent" && fe.Name != "vector" {
+		return value{}, fmt.Errorf("function %s() is not supported by the local evaluator", fe.Name)
+	}
+	if len(fe.Args) != 1 {
+		return value{}, fmt.Errorf("%s() expects 1 argument, got %d", fe.Name, len(fe.Args))
+	}
+	arg := fe.Args[0]
+	switch fe.Name {
+	case "absent":
+		v, err := ev.evalVector(arg)
+		if err != nil {
+			return value{}, err
+		}
+		return absent(arg, v), nil
+	case "vector":
+		s, err := ev.evalScalar(arg)
+		if err != nil {
+			return value{}, err
+		}
+		return value{vector: []Sample{{Labels: map[string]string{}, Value: s}}}, nil
+	}
+
+	// MetricsQL allows omitting the window, e.g. rate(x), it defaults to the step
+	re, ok := arg.(*metricsql.RollupExpr)
+	if !ok {
+		re = &metricsql.RollupExpr{Expr: arg}
+	}
+	if err := checkRollup(re); err != nil {
+		return value{}, err
+	}
+	me, ok := re.Expr.(*metricsql.MetricExpr)
+	if !ok {
+		return value{}, fmt.Errorf("%s() expects a series selector, got %s", fe.Name, re.Expr.AppendString(nil))
+	}
+	window := ev.step.Milliseconds()
+	if re.Window != nil {
+		window = re.Window.Duration(window)
+	}
+	end := ev.ts - ev.offset(re)
+	start := end - window
+
+	var result []Sample
+	for _, ss := range ev.matchingSeries(me) {
+		var points []point
+		for _, p := range ss.points {
+			if p.ts > start && p.ts <= end && !p.stale {
+				points = append(points, p)
+			}
+		}
+		if len(points) == 0 {
+			continue
+		}
+		v, ok := fn(points)
+		if !ok {
+			continue
+		}
+		labels := maps.Clone(ss.labels)
+		if !fe.KeepMetricNames {
+			delete(labels, "__name__")
+		}
+		result = append(result, Sample{Labels: labels, Value: v})
+	}
+	return value{vector: result}, nil
+}
+
+// absent returns 1 with labels of equality filters if the vector is empty
+func absent(arg metricsql.Expr, v []Sample) value {
+	if len(v) > 0 {
+		return value{}
+	}
+	labels := map[string]string{}
+	if me, ok := arg.(*metricsql.MetricExpr); ok && len(me.LabelFilterss) == 1 {
+		for _, f := range me.LabelFilterss[0] {
+			if f.Label != "__name__" && !f.IsRegexp && !f.IsNegative {
+				labels[f.Label] = f.Value
+			}
+		}
+	}
+	return value{vector: []Sample{{Labels: labels, Value: 1}}}
+}
+
+func (ev *evaluator) evalScalar(e metricsql.Expr) (float64, error) {
+	v, err := ev.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	if !v.isScalar {
+		return 0, fmt.Errorf("expected scalar argument, got instant vector")
+	}
+	return v.scalar, nil
+}
+
+func (ev *evaluator) evalVector(e metricsql.Expr) ([]Sample, error) {
+	v, err := ev.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	if v.isScalar {
+		return nil, fmt.Errorf("expected instant vector argument, got scalar")
+	}
+	return v.vector, nil
+}
+
+func (ev *evaluator) evalAggr(ae *metricsql.AggrFuncExpr) (value, error) {
+	switch ae.Name {
+	case "sum", "min", "max", "avg", "count":
+	default:
+		return value{}, fmt.Errorf("aggregate function %s() is not supported by the local evaluator", ae.Name)
+	}
+	if len(ae.Args) != 1 {
+		return value{}, fmt.Errorf("%s() expects 1 argument, got %d", ae.Name, len(ae.Args))
+	}
+	input, err := ev.evalVector(ae.Args[0])
+	if err != nil {
+		return value{}, err
+	}
+
+	groups := map[string][]float64{}
+	groupLabels := map[string]map[string]string{}
+	for _, s := range input {
+		labels := groupingLabels(s.Labels, ae.Modifier)
+		key := labelsKey(labels)
+		groups[key] = append(groups[key], s.Value)
+		groupLabels[key] = labels
+	}
+
+	var result []Sample
+	for _, key := range slices.Sorted(maps.Keys(groups)) {
+		values := groups[key]
+		var v float64
+		switch ae.Name {
+		case "sum", "avg":
+			for _, x := range values {
+				v += x
+			}
+			if ae.Name == "avg" {
+				v /= float64(len(values))
+			}
+		case "min":
+			v = slices.Min(values)
+		case "max":
+			v = slices.Max(values)
+		case "count":
+			v = float64(len(values))
+		}
+		result = append(result, Sample{Labels: groupLabels[key], Value: v})
+	}
+	return value{vector: result}, nil
+}
+
+// groupingLabels returns labels kept by a by(...) or without(...) modifier, no modifier drops all labels
+func groupingLabels(labels map[string]string, modifier metricsql.ModifierExpr) map[string]string {
+	switch strings.ToLower(modifier.Op) {
+	case "by", "on":
+		result := map[string]string{}
+		for _, name := range modifier.Args {
+			if v, ok := labels[name]; ok {
+				result[name] = v
+			}
+		}
+		return result
+	case "without", "ignoring":
+		return withoutLabels(labels, modifier.Args)
+	}
+	return map[string]string{}
+}
+
+// withoutLabels returns labels except the metric name and the given names
+func withoutLabels(labels map[string]string, names []string) map[string]string {
+	result := map[string]string{}
+	for name, v := range labels {
+		if name != "__name__" && !slices.Contains(names, name) {
+			result[name] = v
+		}
+	}
+	return result
+}
+
+func (ev *evaluator) evalBinary(be *metricsql.BinaryOpExpr) (value, error) {
+	op := strings.ToLower(be.Op)
+	isSetOp := op == "and" || op == "or" || op == "unless"
+	if !isSetOp && !isArithmeticOp(op) && !metricsql.IsBinaryOpCmp(op) {
+		return value{}, fmt.Errorf("operator %q is not supported by the local evaluator", be.Op)
+	}
+	if be.JoinModifier.Op != "" {
+		return value{}, fmt.Errorf("%s() is not supported by the local evaluator", be.JoinModifier.Op)
+	}
+	left, err := ev.eval(be.Left)
+	if err != nil {
+		return value{}, err
+	}
+	right, err := ev.eval(be.Right)
+	if err != nil {
+		return value{}, err
+	}
+
+	if isSetOp {
+		if left.isScalar || right.isScalar {
+			return value{}, fmt.Errorf("operator %q is not allowed between scalars", be.Op)
+		}
+		return value{vector: evalSetOp(be, op, left.vector, right.vector)}, nil
+	}
+
+	switch {
+	case left.isScalar && right.isScalar:
+		v, keep := applyOp(op, left.scalar, right.scalar)
+		if metricsql.IsBinaryOpCmp(op) {
+			v = boolValue(keep)
+		}
+		return scalarValue(v), nil
+	case left.isScalar || right.isScalar:
+		vector, scalar, vectorLeft := left.vector, right.scalar, true
+		if left.isScalar {
+			vector, scalar, vectorLeft = right.vector, left.scalar, false
+		}
+		var result []Sample
+		for _, s := range vector {
+			l, r := s.Value, scalar
+			if !vectorLeft {
+				l, r = scalar, s.Value
+			}
+			v, keep := applyOp(op, l, r)
+			if out, ok := binaryResult(be, op, s.Labels, s.Value, v, keep); ok {
+				result = append(result, out)
+			}
+		}
+		return value{vector: result}, nil
+	}
+
+	rightBySig := map[string]Sample{}
+	for _, s := range right.vector {
+		sig := signature(s.Labels, be)
+		if _, ok := rightBySig[sig]; ok {
+			return value{}, fmt.Errorf("found duplicate series on the right side of %q, many-to-many matching is not supported", be.Op)
+		}
+		rightBySig[sig] = s
+	}
+	var result []Sample
+	seen := map[string]bool{}
+	for _, l := range left.vector {
+		sig := signature(l.Labels, be)
+		r, ok := rightBySig[sig]
+		if !ok {
+			continue
+		}
+		if seen[sig] {
+			return value{}, fmt.Errorf("found duplicate series on the left side of %q, many-to-one matching is not supported", be.Op)
+		}
+		seen[sig] = true
+		v, keep := applyOp(op, l.Value, r.Value)
+		labels := l.Labels
+		switch strings.ToLower(be.GroupModifier.Op) {
+		case "on":
+			labels = groupingLabels(l.Labels, be.GroupModifier)
+		case "ignoring":
+			labels = withoutLabels(l.Labels, be.GroupModifier.Args)
+			if name, ok := l.Labels["__name__"]; ok {
+				labels["__name__"] = name
+			}
+		}
+		if out, ok := binaryResult(be, op, labels, l.Value, v, keep); ok {
+			result = append(result, out)
+		}
+	}
+	return value{vector: result}, nil
+}
+
+// binaryResult builds the output sample of a vector operation. Comparisons without `bool`
+// filter samples and keep the original value, other operations drop the metric name.
+func binaryResult(be *metricsql.BinaryOpExpr, op string, labels map[string]string, orig, v float64, keep bool) (Sample, bool) {
+	labels = maps.Clone(labels)
+	if metricsql.IsBinaryOpCmp(op) && !be.Bool {
+		return Sample{Labels: labels, Value: orig}, keep
+	}
+	if !be.KeepMetricNames {
+		delete(labels, "__name__")
+	}
+	if metricsql.IsBinaryOpCmp(op) {
+		v = boolValue(keep)
+	}
+	return Sample{Labels: labels, Value: v}, true
+}
+
+func evalSetOp(be *metricsql.BinaryOpExpr, op string, left, right []Sample) []Sample {
+	rightSigs := map[string]bool{}
+	for _, s := range right {
+		rightSigs[signature(s.Labels, be)] = true
+	}
+	var result []Sample
+	switch op {
+	case "and":
+		for _, s := range left {
+			if rightSigs[signature(s.Labels, be)] {
+				result = append(result, s)
+			}
+		}
+	case "unless":
+		for _, s := range left {
+			if !rightSigs[signature(s.Labels, be)] {
+				result = append(result, s)
+			}
+		}
+	case "or":
+		leftSigs := map[string]bool{}
+		for _, s := range left {
+			leftSigs[signature(s.Labels, be)] = true
+		}
+		result = append(result, left...)
+		for _, s := range right {
+			if !leftSigs[signature(s.Labels, be)] {
+				result = append(result, s)
+			}
+		}
+	}
+	return result
+}
+
+// signature returns the key matching series of both sides of a binary operation
+func signature(labels map[string]string, be *metricsql.BinaryOpExpr) string {
+	if strings.ToLower(be.GroupModifier.Op) == "on" {
+		return labelsKey(groupingLabels(labels, be.GroupModifier))
+	}
+	return labelsKey(withoutLabels(labels, be.GroupModifier.Args))
+}
+
+func isArithmeticOp(op string) bool {
+	return op == "+" || op == "-" || op == "*" || op == "/"
+}
+
+// applyOp applies an arithmetic or comparison operator, for comparisons it returns the left value and the comparison result
+func applyOp(op string, l, r float64) (float64, bool) {
+	switch op {
+	case "+":
+		return l + r, true
+	case "-":
+		return l - r, true
+	case "*":
+		return l * r, true
+	case "/":
+		return l / r, true
+	case "==":
+		return l, l == r
+	case "!=":
+		return l, l != r
+	case ">":
+		return l, l > r
+	case "<":
+		return l, l < r
+	case ">=":
+		return l, l >= r
+	}
+	return l, l <= r
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func labelsKey(labels map[string]string) string {
+	var sb strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		sb.WriteString(name)
+		sb.WriteByte('=')
+		sb.WriteString(labels[name])
+		sb.WriteByte(0xff)
+	}
+	return sb.String()
+}
+
+// formatLabels formats labels as a series selector, e.g. {job="api"}
+func formatLabels(labels map[string]string) string {
+	name := labels["__name__"]
+	var pairs []string
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		if k != "__name__" {
+			pairs = append(pairs, fmt.Sprintf("%s=%q", k, labels[k]))
+		}
+	}
+	return name + "{" + strings.Join(pairs, ", ") + "}"
+}
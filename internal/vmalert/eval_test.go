@@ -0,0 +1,66 @@
+package vmalert
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+func TestEvaluator(t *testing.T) {
+	st := newStorage()
+	for _, in := range []InputSeries{
+		{Series: `anomaly_score{for="rps", job="api"}`, Values: "0.5 0.5 3 3 0.5"},
+		{Series: `anomaly_score{for="rps", job="web"}`, Values: "0.5 0.5 0.5 0.5 0.5"},
+		{Series: `vmanomaly_model_runs_skipped{job="api"}`, Values: "0 0 2 5 5"},
+	} {
+		if err := loadInputSeries(st, in, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ev := &evaluator{storage: st, ts: (3 * time.Minute).Milliseconds(), step: time.Minute}
+
+	tests := []struct {
+		expr string
+		want string // Samples formatted as "labels value" sorted by labels
+		err  string
+	}{
+		{expr: `anomaly_score > 1`, want: `anomaly_score{for="rps", job="api"} 3`},
+		{expr: `anomaly_score{job=~"w.*"} offset 2m`, want: `anomaly_score{for="rps", job="web"} 0.5`},
+		{expr: `max_over_time(anomaly_score{job="api"}[2m])`, want: `{for="rps", job="api"} 3`},
+		{expr: `sum by (job) (increase(vmanomaly_model_runs_skipped[3m])) > 0`, want: `{job="api"} 5`},
+		{expr: `changes(vmanomaly_model_runs_skipped[3m])`, want: `{job="api"} 2`},
+		{expr: `count(anomaly_score >= bool 1)`, want: `{} 2`},
+		{expr: `anomaly_score > 1 and on(job) vmanomaly_model_runs_skipped`, want: `anomaly_score{for="rps", job="api"} 3`},
+		{expr: `absent(anomaly_score{job="db"})`, want: `{job="db"} 1`},
+		{expr: `WITH (s = anomaly_score{job="web"}) s * 2`, want: `{for="rps", job="web"} 1`},
+		{expr: `max_over_time(anomaly_score[5m:1m])`, err: "subquery"},
+		{expr: `topk(1, anomaly_score)`, err: "not supported"},
+		{expr: `anomaly_score / on(job) group_left vmanomaly_model_runs_skipped`, err: "group_left() is not supported"},
+	}
+	for _, tt := range tests {
+		e, err := metricsql.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		v, err := ev.eval(e)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error = %v, want %q", tt.expr, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		got := map[string]float64{}
+		for _, s := range v.vector {
+			got[formatLabels(s.Labels)] = s.Value
+		}
+		if g := formatSamples(got); g != "["+tt.want+"]" {
+			t.Errorf("%s = %s, want [%s]", tt.expr, g, tt.want)
+		}
+	}
+}
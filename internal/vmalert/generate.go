@@ -0,0 +1,195 @@
+package vmalert
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmconfig"
+)
+
+// Defaults of GenerateOptions
+const (
+	DefaultGroupName   = "vmanomaly-anomalies"
+	DefaultThreshold   = 1.0
+	DefaultForPeriods  = 2
+	DefaultJobSelector = ".*vmanomaly.*"
+	HealthGroupName    = "vmanomaly-health"
+)
+
+// GenerateOptions configure GenerateRules
+type GenerateOptions struct {
+	GroupName string
+	// Threshold is the anomaly score threshold for queries without an entry in Thresholds
+	Threshold float64
+	// Thresholds are per-query anomaly score thresholds by query alias
+	Thresholds map[string]float64
+	// ForPeriods is the number of consecutive inference runs the anomaly score must stay above
+	// the threshold before the alert fires, `for:` is ForPeriods * infer_every.
+	// Zero means DefaultForPeriods, negative value disables `for:`.
+	ForPeriods int
+	// Job is the regexp matching `job` label of scraped vmanomaly self-monitoring metrics
+	Job string
+	// SkipSelfMonitoring disables "skipped runs" and "too many restarts" alerts
+	SkipSelfMonitoring bool
+}
+
+// GenerateRules builds vmalert rules for a vmanomaly config: one alerting rule per model/query pair
+// matching produced anomaly scores by query alias and `model_alias` labels, plus self-monitoring alerts
+func GenerateRules(c *vmconfig.Config, opts GenerateOptions) (*RuleFile, error) {
+	if c.Preset != "" {
+		return nil, fmt.Errorf("config uses %q preset, which comes with its own alerting rules", c.Preset)
+	}
+	if opts.GroupName == "" {
+		opts.GroupName = DefaultGroupName
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultThreshold
+	}
+	if opts.Job == "" {
+		opts.Job = DefaultJobSelector
+	}
+	if opts.ForPeriods == 0 {
+		opts.ForPeriods = DefaultForPeriods
+	}
+	for alias, threshold := range opts.Thresholds {
+		if _, ok := c.Query(alias); !ok {
+			return nil, fmt.Errorf("threshold is set for unknown query alias %q", alias)
+		}
+		if threshold <= 0 {
+			return nil, fmt.Errorf("threshold for query %q must be positive, got %v", alias, threshold)
+		}
+	}
+
+	group := Group{Name: opts.GroupName}
+	var minInferEvery time.Duration
+	for _, model := range c.Models {
+		inferEvery, err := maxInferEvery(c, model)
+		if err != nil {
+			return nil, err
+		}
+		if minInferEvery == 0 || (inferEvery > 0 && inferEvery < minInferEvery) {
+			minInferEvery = inferEvery
+		}
+		for _, alias := range model.Queries {
+			group.Rules = append(group.Rules, anomalyRule(c, model, alias, thresholdFor(opts, alias), inferEvery, opts.ForPeriods))
+		}
+	}
+	if minInferEvery > 0 {
+		// evaluate as often as the most frequent scheduler writes new anomaly scores
+		group.Interval = vmanomaly.FormatDuration(minInferEvery)
+	}
+
+	f := &RuleFile{Groups: []Group{group}}
+	if !opts.SkipSelfMonitoring {
+		f.Groups = append(f.Groups, healthGroup(opts.Job))
+	}
+	return f, nil
+}
+
+func thresholdFor(opts GenerateOptions, alias string) float64 {
+	if t, ok := opts.Thresholds[alias]; ok {
+		return t
+	}
+	return opts.Threshold
+}
+
+// maxInferEvery returns the longest infer_every of the model schedulers,
+// so `for:` spans the required number of runs of the slowest scheduler
+func maxInferEvery(c *vmconfig.Config, model vmconfig.Model) (time.Duration, error) {
+	var result time.Duration
+	for _, alias := range model.Schedulers {
+		s, _ := c.Scheduler(alias)
+		if s.InferEvery == "" {
+			continue
+		}
+		d, err := vmanomaly.ParseDuration(s.InferEvery)
+		if err != nil {
+			return 0, fmt.Errorf("schedulers.%s: invalid infer_every %q: %w", alias, s.InferEvery, err)
+		}
+		result = max(result, d)
+	}
+	return result, nil
+}
+
+func anomalyRule(c *vmconfig.Config, model vmconfig.Model, queryAlias string, threshold float64, inferEvery time.Duration, forPeriods int) Rule {
+	queryLabel, queryValue := c.Writer.QueryLabel(queryAlias)
+	matchers := []string{
+		fmt.Sprintf("%s=%q", queryLabel, queryValue),
+		fmt.Sprintf("model_alias=%q", model.Alias),
+	}
+	extra := c.Writer.ExtraLabels()
+	for _, name := range slices.Sorted(maps.Keys(extra)) {
+		matchers = append(matchers, fmt.Sprintf("%s=%q", name, extra[name]))
+	}
+
+	rule := Rule{
+		Alert: CamelCase(queryAlias) + CamelCase(model.Alias) + "Anomaly",
+		Expr: fmt.Sprintf("%s{%s} > %s",
+			c.Writer.MetricName("anomaly_score"), strings.Join(matchers, ", "), strconv.FormatFloat(threshold, 'f', -1, 64)),
+		Labels: map[string]string{
+			"severity":    "warning",
+			"query_alias": queryAlias,
+		},
+		Annotations: map[string]string{
+			"summary": fmt.Sprintf("Anomaly in %q detected by %q model", queryAlias, model.Alias),
+			"description": fmt.Sprintf("anomaly_score of %s model (%s) exceeds %s. Query: %s. Labels: {{ $labels }}. Value: {{ $value }}",
+				model.Alias, model.Class, strconv.FormatFloat(threshold, 'f', -1, 64), queryExpr(c, queryAlias)),
+		},
+	}
+	if inferEvery > 0 && forPeriods > 0 {
+		rule.For = vmanomaly.FormatDuration(inferEvery * time.Duration(forPeriods))
+	}
+	return rule
+}
+
+func queryExpr(c *vmconfig.Config, alias string) string {
+	q, _ := c.Query(alias)
+	return q.Expr
+}
+
+// healthGroup returns vmanomaly self-monitoring alerts from
+// https://docs.victoriametrics.com/anomaly-detection/self-monitoring/#alerting-rules
+func healthGroup(job string) Group {
+	return Group{
+		Name: HealthGroupName,
+		Rules: []Rule{
+			{
+				Alert:  "TooManyRestarts",
+				Expr:   fmt.Sprintf(`changes(process_start_time_seconds{job=~%q}[15m]) > 2`, job),
+				Labels: map[string]string{"severity": "critical"},
+				Annotations: map[string]string{
+					"summary":     "{{ $labels.job }} too many restarts (instance {{ $labels.instance }})",
+					"description": "Job {{ $labels.job }} (instance {{ $labels.instance }}) has restarted more than twice in the last 15 minutes. It might be crashlooping.",
+				},
+			},
+			{
+				Alert: "SkippedModelRunsDetected",
+				Expr: fmt.Sprintf(`sum by (job, instance, model_alias, scheduler_alias, query_key, stage) (increase(vmanomaly_model_runs_skipped{job=~%q}[15m])) > 0`,
+					job),
+				For:    "5m",
+				Labels: map[string]string{"severity": "warning"},
+				Annotations: map[string]string{
+					"summary": "{{ $labels.job }} skipped model runs (instance {{ $labels.instance }})",
+					"description": "Model {{ $labels.model_alias }} skipped {{ $labels.stage }} runs on query {{ $labels.query_key }} " +
+						"(scheduler {{ $labels.scheduler_alias }}). Possible reasons: no new valid data, no trained model for new series or NaN/Inf values.",
+				},
+			},
+		},
+	}
+}
+
+// CamelCase converts an alias to CamelCase, e.g. cpu_usage-5m -> CpuUsage5m
+func CamelCase(s string) string {
+	var sb strings.Builder
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return sb.String()
+}
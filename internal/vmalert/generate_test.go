@@ -0,0 +1,113 @@
+package vmalert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmconfig"
+)
+
+const testConfig = `
+reader:
+  sampling_period: 1m
+  queries:
+    cpu: sum(rate(node_cpu_seconds_total[5m])) by (mode)
+    rps: sum(rate(http_requests_total[5m]))
+schedulers:
+  s1:
+    infer_every: 1m
+  s5:
+    infer_every: 5m
+models:
+  zscore:
+    class: zscore
+    queries: [rps]
+    schedulers: [s1, s5]
+  mad:
+    class: mad
+    schedulers: [s1]
+writer:
+  metric_format:
+    __name__: vmanomaly_$VAR
+    for: $QUERY_KEY
+`
+
+func mustParseConfig(t *testing.T, data string) *vmconfig.Config {
+	t.Helper()
+	c, err := vmconfig.Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("cannot parse config: %v", err)
+	}
+	return c
+}
+
+func TestGenerateRules(t *testing.T) {
+	f, err := GenerateRules(mustParseConfig(t, testConfig), GenerateOptions{Thresholds: map[string]float64{"rps": 2.5}})
+	if err != nil {
+		t.Fatalf("GenerateRules() error = %v", err)
+	}
+	if len(f.Groups) != 2 || f.Groups[1].Name != HealthGroupName {
+		t.Fatalf("expected anomaly and health groups, got %+v", f.Groups)
+	}
+
+	group := f.Groups[0]
+	if group.Interval != "1m" {
+		t.Errorf("interval = %q, want 1m", group.Interval)
+	}
+	want := map[string]struct{ expr, forDur string }{
+		"CpuMadAnomaly":    {`vmanomaly_anomaly_score{for="cpu", model_alias="mad"} > 1`, "2m"},
+		"RpsMadAnomaly":    {`vmanomaly_anomaly_score{for="rps", model_alias="mad"} > 2.5`, "2m"},
+		"RpsZscoreAnomaly": {`vmanomaly_anomaly_score{for="rps", model_alias="zscore"} > 2.5`, "10m"},
+	}
+	if len(group.Rules) != len(want) {
+		t.Fatalf("got %d rules, want %d", len(group.Rules), len(want))
+	}
+	for _, r := range group.Rules {
+		w, ok := want[r.Alert]
+		if !ok {
+			t.Errorf("unexpected rule %q", r.Alert)
+			continue
+		}
+		if r.Expr != w.expr || r.For != w.forDur {
+			t.Errorf("%s: got expr=%q for=%q, want expr=%q for=%q", r.Alert, r.Expr, r.For, w.expr, w.forDur)
+		}
+	}
+
+	out, err := f.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(out, "TooManyRestarts") || !strings.Contains(out, "SkippedModelRunsDetected") {
+		t.Errorf("self-monitoring alerts are missing:\n%s", out)
+	}
+}
+
+func TestGenerateRules_Errors(t *testing.T) {
+	c := mustParseConfig(t, testConfig)
+	if _, err := GenerateRules(c, GenerateOptions{Thresholds: map[string]float64{"unknown": 1}}); err == nil {
+		t.Error("expected error for unknown query alias")
+	}
+	if _, err := GenerateRules(c, GenerateOptions{Thresholds: map[string]float64{"cpu": -1}}); err == nil {
+		t.Error("expected error for negative threshold")
+	}
+
+	preset, err := vmconfig.Parse([]byte(`preset: node-exporter`))
+	if err != nil {
+		t.Fatalf("cannot parse config: %v", err)
+	}
+	if _, err := GenerateRules(preset, GenerateOptions{}); err == nil {
+		t.Error("expected error for preset config")
+	}
+}
+
+func TestCamelCase(t *testing.T) {
+	for in, want := range map[string]string{
+		"page_faults":  "PageFaults",
+		"cpu-usage.5m": "CpuUsage5m",
+		"zscore":       "Zscore",
+	} {
+		if got := CamelCase(in); got != want {
+			t.Errorf("CamelCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package vmalert
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// templateData is the data available in label and annotation templates of alerting rules
+type templateData struct {
+	Labels         map[string]string
+	Value          float64
+	Expr           string
+	ActiveAt       time.Time
+	For            time.Duration
+	AlertID        uint64
+	GroupID        uint64
+	ExternalLabels map[string]string
+	ExternalURL    string
+}
+
+// templateHeader defines variables vmalert exposes to templates
+const templateHeader = `{{- $labels := .Labels -}}{{- $value := .Value -}}{{- $expr := .Expr -}}` +
+	`{{- $activeAt := .ActiveAt -}}{{- $for := .For -}}{{- $alertID := .AlertID -}}{{- $groupID := .GroupID -}}` +
+	`{{- $externalLabels := .ExternalLabels -}}{{- $externalURL := .ExternalURL -}}`
+
+// templateFuncs mirrors the most used functions of vmalert templates.
+// Functions querying the datasource cannot be evaluated offline and fail at execution.
+var templateFuncs = template.FuncMap{
+	"humanize":           humanize,
+	"humanize1024":       humanize1024,
+	"humanizePercentage": func(v any) (string, error) { f, err := templateFloat(v); return fmt.Sprintf("%.4g%%", f*100), err },
+	"humanizeDuration":   humanizeDuration,
+	"humanizeTimestamp": func(v any) (string, error) {
+		f, err := templateFloat(v)
+		return time.Unix(0, int64(f*1e9)).UTC().String(), err
+	},
+	"toUpper":      strings.ToUpper,
+	"toLower":      strings.ToLower,
+	"title":        title,
+	"toString":     func(v any) string { return fmt.Sprint(v) },
+	"quotesEscape": func(s string) string { return strings.ReplaceAll(s, `"`, `\"`) },
+	"jsonEscape": func(s string) (string, error) {
+		b, err := json.Marshal(s)
+		return string(b), err
+	},
+	"htmlEscape": html.EscapeString,
+	"crlfEscape": func(s string) string { return strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(s) },
+	"stripPort": func(hostPort string) string {
+		if host, _, err := net.SplitHostPort(hostPort); err == nil {
+			return host
+		}
+		return hostPort
+	},
+	"stripDomain": func(host string) string {
+		name, _, _ := strings.Cut(host, ".")
+		return name
+	},
+	"match": regexp.MatchString,
+	"reReplaceAll": func(pattern, repl, text string) (string, error) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", err
+		}
+		return re.ReplaceAllString(text, repl), nil
+	},
+	"parseDuration": func(s string) (float64, error) {
+		d, err := time.ParseDuration(s)
+		return d.Seconds(), err
+	},
+	"now": func() float64 { return float64(time.Now().UnixNano()) / 1e9 },
+	"args": func(args ...any) map[string]any {
+		result := make(map[string]any, len(args))
+		for i, a := range args {
+			result[fmt.Sprintf("arg%d", i)] = a
+		}
+		return result
+	},
+	"query":    func(string) ([]any, error) { return nil, fmt.Errorf("query function is not supported offline") },
+	"first":    func(v []any) (any, error) { return nil, fmt.Errorf("first function is not supported offline") },
+	"label":    func(string, any) (string, error) { return "", fmt.Errorf("label function is not supported offline") },
+	"value":    func(any) (float64, error) { return 0, fmt.Errorf("value function is not supported offline") },
+	"strvalue": func(any) (string, error) { return "", fmt.Errorf("strvalue function is not supported offline") },
+	"sortByLabel": func(any, string) ([]any, error) {
+		return nil, fmt.Errorf("sortByLabel function is not supported offline")
+	},
+	"pathEscape":  func(s string) string { return strings.ReplaceAll(s, "/", "%2F") },
+	"safeHtml":    func(s string) string { return s },
+	"externalURL": func() string { return "" },
+	"pathPrefix":  func() string { return "" },
+}
+
+func newTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(templateHeader + text)
+}
+
+func execTemplate(name, text string, data templateData) (string, error) {
+	tmpl, err := newTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func templateFloat(v any) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("cannot convert %T to number", v)
+	}
+}
+
+// humanize formats a number with SI prefixes, e.g. 1234 -> 1.234k
+func humanize(v any) (string, error) {
+	f, err := templateFloat(v)
+	if err != nil {
+		return "", err
+	}
+	if f == 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprintf("%.4g", f), nil
+	}
+	prefix := ""
+	if math.Abs(f) >= 1 {
+		for _, p := range []string{"k", "M", "G", "T", "P", "E", "Z", "Y"} {
+			if math.Abs(f) < 1000 {
+				break
+			}
+			prefix = p
+			f /= 1000
+		}
+		return fmt.Sprintf("%.4g%s", f, prefix), nil
+	}
+	for _, p := range []string{"m", "u", "n", "p", "f", "a", "z", "y"} {
+		if math.Abs(f) >= 1 {
+			break
+		}
+		prefix = p
+		f *= 1000
+	}
+	return fmt.Sprintf("%.4g%s", f, prefix), nil
+}
+
+// humanize1024 formats a number with binary prefixes, e.g. 2048 -> 2ki
+func humanize1024(v any) (string, error) {
+	f, err := templateFloat(v)
+	if err != nil {
+		return "", err
+	}
+	if math.Abs(f) <= 1 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprintf("%.4g", f), nil
+	}
+	prefix := ""
+	for _, p := range []string{"ki", "Mi", "Gi", "Ti", "Pi", "Ei", "Zi", "Yi"} {
+		if math.Abs(f) < 1024 {
+			break
+		}
+		prefix = p
+		f /= 1024
+	}
+	return fmt.Sprintf("%.4g%s", f, prefix), nil
+}
+
+// humanizeDuration formats seconds as a duration, e.g. 3725 -> 1h 2m 5s
+func humanizeDuration(v any) (string, error) {
+	f, err := templateFloat(v)
+	if err != nil {
+		return "", err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprintf("%.4g", f), nil
+	}
+	if f == 0 {
+		return "0s", nil
+	}
+	if math.Abs(f) >= 1 {
+		sign := ""
+		if f < 0 {
+			sign, f = "-", -f
+		}
+		secs := int64(f)
+		days, hours, minutes, seconds := secs/86400, secs/3600%24, secs/60%60, secs%60
+		switch {
+		case days != 0:
+			return fmt.Sprintf("%s%dd %dh %dm %ds", sign, days, hours, minutes, seconds), nil
+		case hours != 0:
+			return fmt.Sprintf("%s%dh %dm %ds", sign, hours, minutes, seconds), nil
+		case minutes != 0:
+			return fmt.Sprintf("%s%dm %ds", sign, minutes, seconds), nil
+		}
+		return fmt.Sprintf("%s%.4gs", sign, f), nil
+	}
+	prefix := ""
+	for _, p := range []string{"m", "u", "n", "p", "f", "a", "z", "y"} {
+		if math.Abs(f) >= 1 {
+			break
+		}
+		prefix = p
+		f *= 1000
+	}
+	return fmt.Sprintf("%.4g%ss", f, prefix), nil
+}
+
+// title upper-cases the first letter of every word
+func title(s string) string {
+	prev := ' '
+	return strings.Map(func(r rune) rune {
+		defer func() { prev = r }()
+		if unicode.IsSpace(prev) || unicode.IsPunct(prev) {
+			return unicode.ToTitle(r)
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,606 @@
+package vmalert
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+// defaultTestInterval is the default interval of input series and rule evaluation in unit tests
+const defaultTestInterval = time.Minute
+
+// UnitTestFile is a vmalert-tool unit test file.
+// Rules are passed separately, so rule_files are ignored.
+//
+// See https://docs.victoriametrics.com/victoriametrics/vmalert-tool/#unit-tests for the format.
+type UnitTestFile struct {
+	RuleFiles          []string   `yaml:"rule_files,omitempty"`
+	EvaluationInterval string     `yaml:"evaluation_interval,omitempty"`
+	GroupEvalOrder     []string   `yaml:"group_eval_order,omitempty"`
+	Tests              []TestCase `yaml:"tests"`
+}
+
+// TestCase is a set of input series with expectations evaluated against them
+type TestCase struct {
+	Name              string              `yaml:"name,omitempty"`
+	Interval          string              `yaml:"interval,omitempty"`
+	InputSeries       []InputSeries       `yaml:"input_series"`
+	AlertRuleTests    []AlertRuleTest     `yaml:"alert_rule_test,omitempty"`
+	MetricsqlExprTest []MetricsqlExprTest `yaml:"metricsql_expr_test,omitempty"`
+	ExternalLabels    map[string]string   `yaml:"external_labels,omitempty"`
+}
+
+// InputSeries is a synthetic series in expanding notation, e.g. values: '1+1x10 _ stale'
+type InputSeries struct {
+	Series string `yaml:"series"`
+	Values string `yaml:"values"`
+}
+
+// AlertRuleTest checks alerts firing at the given time
+type AlertRuleTest struct {
+	EvalTime  string     `yaml:"eval_time"`
+	GroupName string     `yaml:"groupname"`
+	AlertName string     `yaml:"alertname"`
+	ExpAlerts []ExpAlert `yaml:"exp_alerts"`
+}
+
+// ExpAlert is an expected firing alert
+type ExpAlert struct {
+	ExpLabels      map[string]string `yaml:"exp_labels"`
+	ExpAnnotations map[string]string `yaml:"exp_annotations,omitempty"`
+}
+
+// MetricsqlExprTest checks the result of an expression at the given time
+type MetricsqlExprTest struct {
+	Expr       string      `yaml:"expr"`
+	EvalTime   string      `yaml:"eval_time"`
+	ExpSamples []ExpSample `yaml:"exp_samples"`
+}
+
+// ExpSample is an expected sample, labels are in series selector notation, e.g. 'up{job="api"}'
+type ExpSample struct {
+	Labels string  `yaml:"labels"`
+	Value  float64 `yaml:"value"`
+}
+
+// TestReport is the result of unit tests
+type TestReport struct {
+	Tests    int      `json:"tests"` // Number of alert_rule_test and metricsql_expr_test checks
+	Failed   int      `json:"failed"`
+	Failures []string `json:"failures,omitempty"`
+}
+
+// Passed reports whether all checks passed
+func (r *TestReport) Passed() bool {
+	return r.Failed == 0 && len(r.Failures) == 0
+}
+
+func (r *TestReport) fail(path, format string, args ...any) {
+	r.Failed++
+	r.Failures = append(r.Failures, path+": "+fmt.Sprintf(format, args...))
+}
+
+// ParseUnitTests parses YAML content of a vmalert-tool unit test file
+func ParseUnitTests(data []byte) (*UnitTestFile, error) {
+	var f UnitTestFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("cannot parse unit tests: %w", err)
+	}
+	if len(f.Tests) == 0 {
+		return nil, fmt.Errorf("no tests defined")
+	}
+	return &f, nil
+}
+
+// RunUnitTests evaluates rules against synthetic input series of every test case with the local evaluator
+// and compares firing alerts and expression results with expectations.
+// Problems with the test file itself are reported as failures too.
+func RunUnitTests(rules *RuleFile, tests *UnitTestFile) *TestReport {
+	report := &TestReport{}
+	evalInterval := defaultTestInterval
+	if tests.EvaluationInterval != "" {
+		d, err := vmanomaly.ParseDuration(tests.EvaluationInterval)
+		if err != nil || d <= 0 {
+			report.fail("evaluation_interval", "invalid duration %q", tests.EvaluationInterval)
+			return report
+		}
+		evalInterval = d
+	}
+	groups, err := orderGroups(rules, tests.GroupEvalOrder)
+	if err != nil {
+		report.fail("group_eval_order", "%s", err)
+		return report
+	}
+	for i, tc := range tests.Tests {
+		path := fmt.Sprintf("tests[%d]", i)
+		if tc.Name != "" {
+			path += fmt.Sprintf(" (%s)", tc.Name)
+		}
+		runTestCase(report, path, groups, evalInterval, tc)
+	}
+	return report
+}
+
+func orderGroups(rules *RuleFile, order []string) ([]Group, error) {
+	if len(order) == 0 {
+		return rules.Groups, nil
+	}
+	var result []Group
+	for _, name := range order {
+		i := slices.IndexFunc(rules.Groups, func(g Group) bool { return g.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown group %q", name)
+		}
+		result = append(result, rules.Groups[i])
+	}
+	return result, nil
+}
+
+// compiledRule is a rule with parsed expression and durations
+type compiledRule struct {
+	Rule
+	expr        metricsql.Expr
+	forDuration time.Duration
+}
+
+// testGroup is a rule group being evaluated in a unit test
+type testGroup struct {
+	Group
+	interval time.Duration
+	rules    []compiledRule
+	// alerts are active alerts by rule index and labels key
+	alerts []map[string]*activeAlert
+	// history are firing alerts by evaluation time
+	history []groupSnapshot
+}
+
+type activeAlert struct {
+	labels      map[string]string
+	annotations map[string]string
+	activeAt    int64
+	firing      bool
+}
+
+type groupSnapshot struct {
+	ts     int64
+	firing map[string][]*activeAlert // by alert name
+}
+
+func compileGroup(g Group, evalInterval time.Duration) (*testGroup, error) {
+	tg := &testGroup{Group: g, interval: evalInterval}
+	if g.Interval != "" {
+		d, err := vmanomaly.ParseDuration(g.Interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("group %q: invalid interval %q", g.Name, g.Interval)
+		}
+		tg.interval = d
+	}
+	if g.Type != "" && g.Type != "prometheus" {
+		return nil, fmt.Errorf("group %q: rules of type %q cannot be evaluated locally", g.Name, g.Type)
+	}
+	for _, r := range g.Rules {
+		e, err := metricsql.Parse(r.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("group %q rule %q: invalid expression: %w", g.Name, r.Name(), err)
+		}
+		cr := compiledRule{Rule: r, expr: e}
+		if r.For != "" {
+			if cr.forDuration, err = vmanomaly.ParseDuration(r.For); err != nil {
+				return nil, fmt.Errorf("group %q rule %q: invalid for %q: %w", g.Name, r.Name(), r.For, err)
+			}
+		}
+		tg.rules = append(tg.rules, cr)
+		tg.alerts = append(tg.alerts, map[string]*activeAlert{})
+	}
+	return tg, nil
+}
+
+func runTestCase(report *TestReport, path string, groups []Group, evalInterval time.Duration, tc TestCase) {
+	interval := defaultTestInterval
+	if tc.Interval != "" {
+		d, err := vmanomaly.ParseDuration(tc.Interval)
+		if err != nil || d <= 0 {
+			report.fail(path+".interval", "invalid duration %q", tc.Interval)
+			return
+		}
+		interval = d
+	}
+
+	st := newStorage()
+	for i, in := range tc.InputSeries {
+		if err := loadInputSeries(st, in, interval); err != nil {
+			report.fail(fmt.Sprintf("%s.input_series[%d]", path, i), "%s", err)
+			return
+		}
+	}
+
+	var testGroups []*testGroup
+	for _, g := range groups {
+		tg, err := compileGroup(g, evalInterval)
+		if err != nil {
+			report.fail(path, "%s", err)
+			return
+		}
+		testGroups = append(testGroups, tg)
+	}
+
+	// evaluate groups up to the latest eval_time, so recording rules feed later checks
+	var maxEvalTime time.Duration
+	alertTimes := make([]time.Duration, len(tc.AlertRuleTests))
+	for i, at := range tc.AlertRuleTests {
+		d, err := vmanomaly.ParseDuration(at.EvalTime)
+		if err != nil {
+			report.fail(fmt.Sprintf("%s.alert_rule_test[%d].eval_time", path, i), "invalid duration %q", at.EvalTime)
+			return
+		}
+		alertTimes[i] = d
+		maxEvalTime = max(maxEvalTime, d)
+	}
+	exprTimes := make([]time.Duration, len(tc.MetricsqlExprTest))
+	for i, et := range tc.MetricsqlExprTest {
+		d, err := vmanomaly.ParseDuration(et.EvalTime)
+		if err != nil {
+			report.fail(fmt.Sprintf("%s.metricsql_expr_test[%d].eval_time", path, i), "invalid duration %q", et.EvalTime)
+			return
+		}
+		exprTimes[i] = d
+		maxEvalTime = max(maxEvalTime, d)
+	}
+	if err := evalGroups(st, testGroups, maxEvalTime, tc.ExternalLabels); err != nil {
+		report.fail(path, "%s", err)
+		return
+	}
+
+	for i, at := range tc.AlertRuleTests {
+		report.Tests++
+		checkAlerts(report, fmt.Sprintf("%s.alert_rule_test[%d]", path, i), testGroups, at, alertTimes[i])
+	}
+	for i, et := range tc.MetricsqlExprTest {
+		report.Tests++
+		ev := &evaluator{storage: st, ts: exprTimes[i].Milliseconds(), step: evalInterval}
+		checkExpr(report, fmt.Sprintf("%s.metricsql_expr_test[%d]", path, i), ev, et)
+	}
+}
+
+func loadInputSeries(st *storage, in InputSeries, interval time.Duration) error {
+	labels, err := parseSeriesLabels(in.Series)
+	if err != nil {
+		return err
+	}
+	points, err := parseInputValues(in.Values)
+	if err != nil {
+		return err
+	}
+	for i, p := range points {
+		if p == nil {
+			continue
+		}
+		p.ts = int64(i) * interval.Milliseconds()
+		st.add(labels, *p)
+	}
+	return nil
+}
+
+// parseSeriesLabels parses a series in selector notation, e.g. 'up{job="api"}'
+func parseSeriesLabels(s string) (map[string]string, error) {
+	labels := map[string]string{}
+	if strings.TrimSpace(s) == "" || strings.TrimSpace(s) == "{}" {
+		return labels, nil
+	}
+	e, err := metricsql.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid series %q: %w", s, err)
+	}
+	me, ok := e.(*metricsql.MetricExpr)
+	if !ok || len(me.LabelFilterss) != 1 {
+		return nil, fmt.Errorf("invalid series %q: expected metric name with labels", s)
+	}
+	for _, f := range me.LabelFilterss[0] {
+		if f.IsRegexp || f.IsNegative {
+			return nil, fmt.Errorf("invalid series %q: only = is allowed in label pairs", s)
+		}
+		labels[f.Label] = f.Value
+	}
+	return labels, nil
+}
+
+// parseInputValues expands values notation: 'a+bxn' and 'a-bxn' produce n+1 values starting from a,
+// 'axn' repeats a n+1 times, '_' is a missing value, '_xn' are n missing values and 'stale' ends the series.
+// Missing values are returned as nil points.
+func parseInputValues(s string) ([]*point, error) {
+	var result []*point
+	for _, field := range strings.Fields(s) {
+		switch {
+		case field == "_":
+			result = append(result, nil)
+			continue
+		case field == "stale":
+			result = append(result, &point{value: math.NaN(), stale: true})
+			continue
+		}
+		i := strings.LastIndex(field, "x")
+		if i < 0 {
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", field)
+			}
+			result = append(result, &point{value: v})
+			continue
+		}
+		n, err := strconv.Atoi(field[i+1:])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid repetition count in %q", field)
+		}
+		expr := field[:i]
+		if expr == "_" {
+			for range n {
+				result = append(result, nil)
+			}
+			continue
+		}
+		start, delta, err := parseValueStep(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q: %w", field, err)
+		}
+		for j := 0; j <= n; j++ {
+			result = append(result, &point{value: start + delta*float64(j)})
+		}
+	}
+	return result, nil
+}
+
+// parseValueStep parses 'a+b', 'a-b' or 'a' into start value and increment
+func parseValueStep(s string) (float64, float64, error) {
+	// skip the sign of the start value and exponent signs, e.g. -1e-3+2
+	for i := 1; i < len(s); i++ {
+		if (s[i] == '+' || s[i] == '-') && s[i-1] != 'e' && s[i-1] != 'E' {
+			start, err := strconv.ParseFloat(s[:i], 64)
+			if err != nil {
+				return 0, 0, err
+			}
+			delta, err := strconv.ParseFloat(s[i+1:], 64)
+			if err != nil {
+				return 0, 0, err
+			}
+			if s[i] == '-' {
+				delta = -delta
+			}
+			return start, delta, nil
+		}
+	}
+	start, err := strconv.ParseFloat(s, 64)
+	return start, 0, err
+}
+
+// evalGroups evaluates all groups at their intervals from zero up to until, in evaluation order within the same timestamp
+func evalGroups(st *storage, groups []*testGroup, until time.Duration, externalLabels map[string]string) error {
+	type event struct {
+		ts    int64
+		group int
+	}
+	var events []event
+	for i, g := range groups {
+		for ts := time.Duration(0); ts <= until; ts += g.interval {
+			events = append(events, event{ts: ts.Milliseconds(), group: i})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].ts < events[j].ts })
+
+	for _, e := range events {
+		g := groups[e.group]
+		ev := &evaluator{storage: st, ts: e.ts, step: g.interval}
+		snapshot := groupSnapshot{ts: e.ts, firing: map[string][]*activeAlert{}}
+		for i, r := range g.rules {
+			v, err := ev.eval(r.expr)
+			if err != nil {
+				return fmt.Errorf("group %q rule %q at %s: %w", g.Name, r.Name(), time.Duration(e.ts)*time.Millisecond, err)
+			}
+			if v.isScalar {
+				v.vector = []Sample{{Labels: map[string]string{}, Value: v.scalar}}
+			}
+			if r.Record != "" {
+				for _, s := range v.vector {
+					labels := maps.Clone(s.Labels)
+					maps.Copy(labels, r.Labels)
+					labels["__name__"] = r.Record
+					st.add(labels, point{ts: e.ts, value: s.Value})
+				}
+				continue
+			}
+			if err := g.updateAlerts(i, e.ts, v.vector, externalLabels); err != nil {
+				return fmt.Errorf("group %q rule %q: %w", g.Name, r.Name(), err)
+			}
+			for _, key := range slices.Sorted(maps.Keys(g.alerts[i])) {
+				if a := g.alerts[i][key]; a.firing {
+					snapshot.firing[r.Alert] = append(snapshot.firing[r.Alert], a)
+				}
+			}
+		}
+		g.history = append(g.history, snapshot)
+	}
+	return nil
+}
+
+// updateAlerts moves alerts of a rule through pending and firing states and resolves alerts without samples
+func (g *testGroup) updateAlerts(ruleIdx int, ts int64, samples []Sample, externalLabels map[string]string) error {
+	r := g.rules[ruleIdx]
+	active := g.alerts[ruleIdx]
+	seen := map[string]bool{}
+	for _, s := range samples {
+		labels := maps.Clone(s.Labels)
+		delete(labels, "__name__")
+		data := templateData{Labels: labels, Value: s.Value, Expr: r.Expr, For: r.forDuration, ExternalLabels: externalLabels}
+		alertLabels := maps.Clone(labels)
+		for name, text := range r.Labels {
+			v, err := execTemplate(name, text, data)
+			if err != nil {
+				return fmt.Errorf("cannot execute template of label %q: %w", name, err)
+			}
+			alertLabels[name] = v
+		}
+		alertLabels["alertname"] = r.Alert
+
+		key := labelsKey(alertLabels)
+		seen[key] = true
+		a, ok := active[key]
+		if !ok {
+			a = &activeAlert{labels: alertLabels, activeAt: ts}
+			active[key] = a
+		}
+		a.firing = ts-a.activeAt >= r.forDuration.Milliseconds()
+
+		data.Labels = alertLabels
+		data.ActiveAt = time.UnixMilli(a.activeAt).UTC()
+		a.annotations = map[string]string{}
+		for name, text := range r.Annotations {
+			v, err := execTemplate(name, text, data)
+			if err != nil {
+				return fmt.Errorf("cannot execute template of annotation %q: %w", name, err)
+			}
+			a.annotations[name] = v
+		}
+	}
+	for key := range active {
+		if !seen[key] {
+			delete(active, key)
+		}
+	}
+	return nil
+}
+
+func checkAlerts(report *TestReport, path string, groups []*testGroup, at AlertRuleTest, evalTime time.Duration) {
+	i := slices.IndexFunc(groups, func(g *testGroup) bool { return g.Name == at.GroupName })
+	if i < 0 {
+		report.fail(path, "unknown group %q", at.GroupName)
+		return
+	}
+	g := groups[i]
+	if !slices.ContainsFunc(g.rules, func(r compiledRule) bool { return r.Alert == at.AlertName }) {
+		report.fail(path, "group %q has no alerting rule %q", at.GroupName, at.AlertName)
+		return
+	}
+
+	var got []*activeAlert
+	ts := evalTime.Milliseconds()
+	for _, s := range g.history {
+		if s.ts <= ts {
+			got = s.firing[at.AlertName]
+		}
+	}
+
+	var gotAlerts, wantAlerts []string
+	for _, a := range got {
+		gotAlerts = append(gotAlerts, formatLabels(a.labels))
+	}
+	for _, exp := range at.ExpAlerts {
+		labels := maps.Clone(exp.ExpLabels)
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels["alertname"] = at.AlertName
+		wantAlerts = append(wantAlerts, formatLabels(labels))
+	}
+	slices.Sort(gotAlerts)
+	slices.Sort(wantAlerts)
+	if !slices.Equal(gotAlerts, wantAlerts) {
+		report.fail(path, "%s at %s: expected firing alerts %s, got %s",
+			at.AlertName, vmanomaly.FormatDuration(evalTime), formatList(wantAlerts), formatList(gotAlerts))
+		return
+	}
+
+	// annotations are compared only for expected alerts defining them
+	for _, exp := range at.ExpAlerts {
+		if len(exp.ExpAnnotations) == 0 {
+			continue
+		}
+		labels := maps.Clone(exp.ExpLabels)
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels["alertname"] = at.AlertName
+		i := slices.IndexFunc(got, func(a *activeAlert) bool { return labelsKey(a.labels) == labelsKey(labels) })
+		for _, name := range slices.Sorted(maps.Keys(exp.ExpAnnotations)) {
+			if v, want := got[i].annotations[name], exp.ExpAnnotations[name]; v != want {
+				report.fail(path, "%s %s: annotation %q = %q, want %q", at.AlertName, formatLabels(labels), name, v, want)
+			}
+		}
+	}
+}
+
+func checkExpr(report *TestReport, path string, ev *evaluator, et MetricsqlExprTest) {
+	e, err := metricsql.Parse(et.Expr)
+	if err != nil {
+		report.fail(path, "invalid expression %q: %s", et.Expr, err)
+		return
+	}
+	v, err := ev.eval(e)
+	if err != nil {
+		report.fail(path, "cannot evaluate %q: %s", et.Expr, err)
+		return
+	}
+	if v.isScalar {
+		v.vector = []Sample{{Labels: map[string]string{}, Value: v.scalar}}
+	}
+
+	got := map[string]float64{}
+	for _, s := range v.vector {
+		got[formatLabels(s.Labels)] = s.Value
+	}
+	want := map[string]float64{}
+	for i, exp := range et.ExpSamples {
+		labels, err := parseSeriesLabels(exp.Labels)
+		if err != nil {
+			report.fail(fmt.Sprintf("%s.exp_samples[%d]", path, i), "%s", err)
+			return
+		}
+		want[formatLabels(labels)] = exp.Value
+	}
+
+	mismatch := len(got) != len(want)
+	for k, w := range want {
+		g, ok := got[k]
+		if !ok || !almostEqual(g, w) {
+			mismatch = true
+		}
+	}
+	if mismatch {
+		report.fail(path, "%s at %s: expected %s, got %s",
+			et.Expr, vmanomaly.FormatDuration(time.Duration(ev.ts)*time.Millisecond), formatSamples(want), formatSamples(got))
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	if a == b {
+		return true
+	}
+	return math.Abs(a-b) <= 1e-9*math.Max(math.Abs(a), math.Abs(b))
+}
+
+func formatSamples(samples map[string]float64) string {
+	var items []string
+	for _, k := range slices.Sorted(maps.Keys(samples)) {
+		items = append(items, fmt.Sprintf("%s %s", k, strconv.FormatFloat(samples[k], 'g', -1, 64)))
+	}
+	return formatList(items)
+}
+
+func formatList(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
@@ -0,0 +1,170 @@
+package vmalert
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+const testRules = `
+groups:
+  - name: anomalies
+    rules:
+      - alert: RpsAnomaly
+        expr: anomaly_score{for="rps"} > 1
+        for: 2m
+        labels:
+          severity: warning
+        annotations:
+          summary: "{{ $labels.for }} anomaly score is {{ $value }}"
+      - record: job:requests:rate2m
+        expr: sum(rate(http_requests_total[2m])) by (job)
+      - alert: HighRate
+        expr: job:requests:rate2m > 1
+`
+
+func TestRunUnitTests(t *testing.T) {
+	rules, report := Validate([]byte(testRules))
+	if !report.Valid() {
+		t.Fatalf("invalid rules: %+v", report.Issues)
+	}
+
+	tests, err := ParseUnitTests([]byte(`
+evaluation_interval: 1m
+tests:
+  - interval: 1m
+    input_series:
+      - series: 'anomaly_score{for="rps", model_alias="zscore"}'
+        values: '0.5x3 3x5 0.5x3'
+      - series: 'http_requests_total{job="api", instance="a"}'
+        values: '0+60x10'
+      - series: 'http_requests_total{job="api", instance="b"}'
+        values: '0+120x10'
+    alert_rule_test:
+      - eval_time: 5m
+        groupname: anomalies
+        alertname: RpsAnomaly
+      - eval_time: 6m
+        groupname: anomalies
+        alertname: RpsAnomaly
+        exp_alerts:
+          - exp_labels:
+              for: rps
+              model_alias: zscore
+              severity: warning
+            exp_annotations:
+              summary: rps anomaly score is 3
+      - eval_time: 11m
+        groupname: anomalies
+        alertname: RpsAnomaly
+      - eval_time: 5m
+        groupname: anomalies
+        alertname: HighRate
+        exp_alerts:
+          - exp_labels:
+              job: api
+    metricsql_expr_test:
+      - expr: sum(rate(http_requests_total[5m])) by (job)
+        eval_time: 5m
+        exp_samples:
+          - labels: '{job="api"}'
+            value: 3
+      - expr: job:requests:rate2m
+        eval_time: 4m
+        exp_samples:
+          - labels: 'job:requests:rate2m{job="api"}'
+            value: 3
+`))
+	if err != nil {
+		t.Fatalf("ParseUnitTests() error = %v", err)
+	}
+	result := RunUnitTests(rules, tests)
+	if !result.Passed() || result.Tests != 6 {
+		t.Errorf("expected 6 passed checks, got %+v", result)
+	}
+}
+
+func TestRunUnitTests_Failures(t *testing.T) {
+	rules, _ := Validate([]byte(testRules))
+	tests, err := ParseUnitTests([]byte(`
+tests:
+  - input_series:
+      - series: 'anomaly_score{for="rps"}'
+        values: '0.5 0.5 3x5'
+    alert_rule_test:
+      - eval_time: 3m
+        groupname: anomalies
+        alertname: RpsAnomaly
+        exp_alerts:
+          - exp_labels:
+              for: rps
+              severity: warning
+      - eval_time: 1m
+        groupname: unknown
+        alertname: RpsAnomaly
+    metricsql_expr_test:
+      - expr: anomaly_score * 2
+        eval_time: 3m
+        exp_samples:
+          - labels: '{for="rps"}'
+            value: 5
+`))
+	if err != nil {
+		t.Fatalf("ParseUnitTests() error = %v", err)
+	}
+	result := RunUnitTests(rules, tests)
+	if result.Failed != 3 {
+		t.Fatalf("expected 3 failures, got %+v", result)
+	}
+	for i, want := range []string{"expected firing alerts", "unknown group", `expected [{for="rps"} 5], got [{for="rps"} 6]`} {
+		if !strings.Contains(result.Failures[i], want) {
+			t.Errorf("failure %d = %q, want it to contain %q", i, result.Failures[i], want)
+		}
+	}
+}
+
+func TestParseInputValues(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []float64 // -1 is a missing value, NaN is a stale marker
+	}{
+		{in: "1+2x3", want: []float64{1, 3, 5, 7}},
+		{in: "-1-1x2 _ 4", want: []float64{-1, -2, -3, -1, 4}},
+		{in: "5x2 _x2 stale", want: []float64{5, 5, 5, -1, -1, math.NaN()}},
+		{in: "1e-3+1e-3x1", want: []float64{0.001, 0.002}},
+	}
+	for _, tt := range tests {
+		points, err := parseInputValues(tt.in)
+		if err != nil {
+			t.Errorf("parseInputValues(%q) error = %v", tt.in, err)
+			continue
+		}
+		var got []float64
+		for _, p := range points {
+			switch {
+			case p == nil:
+				got = append(got, -1)
+			case p.stale:
+				got = append(got, math.NaN())
+			default:
+				got = append(got, p.value)
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseInputValues(%q) = %v, want %v", tt.in, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if !almostEqual(got[i], tt.want[i]) {
+				t.Errorf("parseInputValues(%q) = %v, want %v", tt.in, got, tt.want)
+				break
+			}
+		}
+	}
+
+	for _, in := range []string{"abc", "1+x3", "1x-1"} {
+		if _, err := parseInputValues(in); err == nil {
+			t.Errorf("parseInputValues(%q): expected error", in)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package vmalert
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/analyzer"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+// Severity levels of validation issues
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a problem found in a rules file
+type Issue struct {
+	Severity string `json:"severity"` // error|warning
+	Path     string `json:"path"`     // Location in the file, e.g. groups[0].rules[1].expr
+	Message  string `json:"message"`
+}
+
+// ValidationReport is the result of offline rules file validation
+type ValidationReport struct {
+	Groups int     `json:"groups"`
+	Rules  int     `json:"rules"`
+	Issues []Issue `json:"issues,omitempty"`
+}
+
+// Valid reports whether validation found no errors
+func (r *ValidationReport) Valid() bool {
+	return !slices.ContainsFunc(r.Issues, func(issue Issue) bool { return issue.Severity == SeverityError })
+}
+
+// Errors returns all issues with error severity formatted as "path: message"
+func (r *ValidationReport) Errors() []string {
+	var msgs []string
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			msgs = append(msgs, issue.Path+": "+issue.Message)
+		}
+	}
+	return msgs
+}
+
+func (r *ValidationReport) add(severity, path, format string, args ...any) {
+	r.Issues = append(r.Issues, Issue{Severity: severity, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+var (
+	// knownGroupFields and knownRuleFields are fields supported by vmalert,
+	// see https://docs.victoriametrics.com/victoriametrics/vmalert/#groups
+	knownGroupFields = []string{
+		"name", "interval", "eval_offset", "eval_delay", "eval_alignment", "limit", "type", "concurrency",
+		"labels", "params", "headers", "notifier_headers", "debug", "tenant", "rules",
+	}
+	knownRuleFields = []string{
+		"alert", "record", "expr", "for", "keep_firing_for", "labels", "annotations", "debug",
+		"update_entries_limit",
+	}
+
+	labelNameRe  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+)
+
+// Validate parses a vmalert rules file and checks it without running vmalert: group and rule
+// structure, durations, MetricsQL expressions, label names and label/annotation templates.
+// The returned rule file is nil if the content is not a valid YAML.
+func Validate(data []byte) (*RuleFile, *ValidationReport) {
+	report := &ValidationReport{}
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		report.add(SeverityError, "", "cannot parse YAML: %s", err)
+		return nil, report
+	}
+	f, err := Parse(data)
+	if err != nil {
+		report.add(SeverityError, "", "%s", err)
+		return nil, report
+	}
+	checkUnknownFields(report, raw)
+
+	if len(f.Groups) == 0 {
+		report.add(SeverityError, "groups", "no rule groups defined")
+	}
+	groupNames := map[string]bool{}
+	for i, g := range f.Groups {
+		path := fmt.Sprintf("groups[%d]", i)
+		report.Groups++
+		switch {
+		case g.Name == "":
+			report.add(SeverityError, path+".name", "group name must be set")
+		case groupNames[g.Name]:
+			report.add(SeverityError, path+".name", "duplicate group name %q", g.Name)
+		}
+		groupNames[g.Name] = true
+		if g.Interval != "" {
+			checkDuration(report, path+".interval", g.Interval)
+		}
+		switch g.Type {
+		case "", "prometheus", "graphite", "vlogs":
+		default:
+			report.add(SeverityError, path+".type", "unsupported group type %q, expected prometheus, graphite or vlogs", g.Type)
+		}
+		if len(g.Rules) == 0 {
+			report.add(SeverityWarning, path+".rules", "group %q has no rules", g.Name)
+		}
+
+		seen := map[string]int{}
+		for j, rule := range g.Rules {
+			rulePath := fmt.Sprintf("%s.rules[%d]", path, j)
+			report.Rules++
+			validateRule(report, rulePath, g, rule)
+			key := ruleKey(rule)
+			if prev, ok := seen[key]; ok {
+				report.add(SeverityError, rulePath, "duplicate of rule %s.rules[%d] %q", path, prev, rule.Name())
+				continue
+			}
+			seen[key] = j
+		}
+	}
+	return f, report
+}
+
+func validateRule(report *ValidationReport, path string, g Group, rule Rule) {
+	switch {
+	case rule.Alert == "" && rule.Record == "":
+		report.add(SeverityError, path, "either alert or record must be set")
+	case rule.Alert != "" && rule.Record != "":
+		report.add(SeverityError, path, "only one of alert and record can be set, got alert %q and record %q", rule.Alert, rule.Record)
+	case rule.Record != "" && !metricNameRe.MatchString(rule.Record):
+		report.add(SeverityError, path+".record", "invalid metric name %q", rule.Record)
+	}
+
+	if strings.TrimSpace(rule.Expr) == "" {
+		report.add(SeverityError, path+".expr", "expression must be set")
+	} else if g.Type == "" || g.Type == "prometheus" {
+		// the datasource may support MetricsQL the vendored parser does not know yet,
+		// so parse failures leave the expression unverified instead of failing the rule
+		_, placeholders, err := analyzer.Parse(rule.Expr)
+		if len(placeholders) > 0 {
+			report.add(SeverityError, path+".expr", "expression contains unresolved placeholders %s", strings.Join(placeholders, ", "))
+		} else if err != nil {
+			report.add(SeverityWarning, path+".expr", "cannot verify MetricsQL expression %q: %s", rule.Expr, err)
+		}
+	}
+
+	if rule.For != "" {
+		if rule.Record != "" {
+			report.add(SeverityWarning, path+".for", "for is ignored by recording rules")
+		} else {
+			checkDuration(report, path+".for", rule.For)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(rule.Labels)) {
+		labelPath := path + ".labels." + name
+		if !labelNameRe.MatchString(name) {
+			report.add(SeverityError, labelPath, "invalid label name %q", name)
+		}
+		if rule.Alert != "" {
+			checkTemplate(report, labelPath, rule.Labels[name])
+		}
+	}
+	if len(rule.Annotations) > 0 && rule.Record != "" {
+		report.add(SeverityWarning, path+".annotations", "annotations are ignored by recording rules")
+	}
+	for _, name := range slices.Sorted(maps.Keys(rule.Annotations)) {
+		checkTemplate(report, path+".annotations."+name, rule.Annotations[name])
+	}
+}
+
+func checkDuration(report *ValidationReport, path, value string) {
+	d, err := vmanomaly.ParseDuration(value)
+	switch {
+	case err != nil:
+		report.add(SeverityError, path, "invalid duration %q: %s", value, err)
+	case d < 0:
+		report.add(SeverityError, path, "duration %q must not be negative", value)
+	}
+}
+
+func checkTemplate(report *ValidationReport, path, text string) {
+	if _, err := newTemplate(path, text); err != nil {
+		report.add(SeverityError, path, "invalid template: %s", err)
+	}
+}
+
+func checkUnknownFields(report *ValidationReport, raw map[string]any) {
+	for key := range raw {
+		if key != "groups" {
+			report.add(SeverityWarning, key, "unknown field %q", key)
+		}
+	}
+	groups, _ := raw["groups"].([]any)
+	for i, g := range groups {
+		group, _ := g.(map[string]any)
+		for _, key := range slices.Sorted(maps.Keys(group)) {
+			if !slices.Contains(knownGroupFields, key) {
+				report.add(SeverityWarning, fmt.Sprintf("groups[%d].%s", i, key), "unknown group field %q", key)
+			}
+		}
+		rules, _ := group["rules"].([]any)
+		for j, r := range rules {
+			rule, _ := r.(map[string]any)
+			for _, key := range slices.Sorted(maps.Keys(rule)) {
+				if !slices.Contains(knownRuleFields, key) {
+					report.add(SeverityWarning, fmt.Sprintf("groups[%d].rules[%d].%s", i, j, key), "unknown rule field %q", key)
+				}
+			}
+		}
+	}
+}
+
+// ruleKey identifies a rule the same way vmalert does when looking for duplicates within a group
+func ruleKey(r Rule) string {
+	var sb strings.Builder
+	sb.WriteString(r.Alert + "\x00" + r.Record + "\x00" + r.Expr)
+	for _, name := range slices.Sorted(maps.Keys(r.Labels)) {
+		sb.WriteString("\x00" + name + "=" + r.Labels[name])
+	}
+	return sb.String()
+}
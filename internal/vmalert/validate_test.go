@@ -0,0 +1,146 @@
+package vmalert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		rules      string
+		wantValid  bool
+		wantIssues []string // substrings of expected issue messages
+	}{
+		{
+			name: "valid",
+			rules: `
+groups:
+  - name: anomalies
+    interval: 1m
+    rules:
+      - alert: RpsAnomaly
+        expr: anomaly_score{for="rps"} > 1
+        for: 5m
+        labels:
+          severity: warning
+        annotations:
+          summary: "{{ $labels.for }} score is {{ $value | humanize }}"
+      - record: job:rps:rate5m
+        expr: sum(rate(http_requests_total[5m])) by (job)
+`,
+			wantValid: true,
+		},
+		{
+			name: "invalid expression and template",
+			rules: `
+groups:
+  - name: g
+    rules:
+      - alert: Broken
+        expr: sum(rate(x[5m])
+        annotations:
+          summary: "{{ $labels.job "
+`,
+			wantIssues: []string{"cannot verify MetricsQL expression", "invalid template"},
+		},
+		{
+			name: "unverified expression is not an error",
+			rules: `
+groups:
+  - name: g
+    rules:
+      - alert: Unknown
+        expr: some_future_function(anomaly_score) > 1
+`,
+			wantValid:  true,
+			wantIssues: []string{"cannot verify MetricsQL expression"},
+		},
+		{
+			name: "rule structure",
+			rules: `
+groups:
+  - name: g
+    interval: abc
+    rules:
+      - alert: A
+        record: b
+        expr: up
+      - record: "bad-name"
+        expr: up
+      - alert: WithPlaceholder
+        expr: rate(x[$__interval]) > 0
+        labels:
+          bad-label: x
+  - name: g
+    rules: []
+`,
+			wantIssues: []string{
+				"invalid duration", "only one of alert and record", "invalid metric name", "unresolved placeholders",
+				"invalid label name", "duplicate group name", "has no rules",
+			},
+		},
+		{
+			name: "duplicates and unknown fields",
+			rules: `
+groups:
+  - name: g
+    unknown: 1
+    rules:
+      - alert: A
+        expr: up == 0
+        typo: x
+      - alert: A
+        expr: up == 0
+`,
+			wantIssues: []string{"unknown group field", "unknown rule field", "duplicate of rule"},
+		},
+		{
+			name: "logs rules are not parsed as MetricsQL",
+			rules: `
+groups:
+  - name: logs
+    type: vlogs
+    rules:
+      - alert: Errors
+        expr: 'error | stats count() as errors | filter errors:>10'
+`,
+			wantValid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, report := Validate([]byte(tt.rules))
+			if report.Valid() != tt.wantValid {
+				t.Errorf("Valid() = %v, want %v; issues: %+v", report.Valid(), tt.wantValid, report.Issues)
+			}
+			for _, want := range tt.wantIssues {
+				found := false
+				for _, issue := range report.Issues {
+					if strings.Contains(issue.Message, want) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Errorf("expected issue containing %q, got %+v", want, report.Issues)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_GeneratedRules(t *testing.T) {
+	f, err := GenerateRules(mustParseConfig(t, testConfig), GenerateOptions{})
+	if err != nil {
+		t.Fatalf("GenerateRules() error = %v", err)
+	}
+	out, err := f.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if _, report := Validate([]byte(out)); !report.Valid() || len(report.Issues) > 0 {
+		t.Errorf("generated rules have issues: %+v", report.Issues)
+	}
+}
@@ -0,0 +1,65 @@
+// Package vmalert contains types of vmalert rule files, which are produced for vmanomaly anomaly scores.
+//
+// See https://docs.victoriametrics.com/victoriametrics/vmalert/#groups for the file format.
+package vmalert
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RuleFile is the content of a vmalert rules file
+type RuleFile struct {
+	Groups []Group `yaml:"groups" json:"groups"`
+}
+
+// Group is a vmalert rule group
+type Group struct {
+	Name     string `yaml:"name" json:"name"`
+	Interval string `yaml:"interval,omitempty" json:"interval,omitempty"`
+	Type     string `yaml:"type,omitempty" json:"type,omitempty"`
+	Rules    []Rule `yaml:"rules" json:"rules"`
+}
+
+// Rule is a vmalert alerting or recording rule
+type Rule struct {
+	Alert       string            `yaml:"alert,omitempty" json:"alert,omitempty"`
+	Record      string            `yaml:"record,omitempty" json:"record,omitempty"`
+	Expr        string            `yaml:"expr" json:"expr"`
+	For         string            `yaml:"for,omitempty" json:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+}
+
+// Name returns the alert or record name of the rule
+func (r Rule) Name() string {
+	if r.Alert != "" {
+		return r.Alert
+	}
+	return r.Record
+}
+
+// Marshal returns YAML representation of the rule file
+func (f *RuleFile) Marshal() (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(f); err != nil {
+		return "", fmt.Errorf("cannot marshal rules: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("cannot marshal rules: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// Parse parses YAML content of a vmalert rules file
+func Parse(data []byte) (*RuleFile, error) {
+	var f RuleFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("cannot parse rules: %w", err)
+	}
+	return &f, nil
+}
@@ -0,0 +1,28 @@
+package vmanomaly
+
+import "context"
+
+// API is the vmanomaly API used by tools and resources, it is implemented by *Client
+type API interface {
+	GetHealth(ctx context.Context) (map[string]any, error)
+	GetBuildInfo(ctx context.Context) (map[string]any, error)
+	Metrics(ctx context.Context, config map[string]any) (string, error)
+
+	ListModels(ctx context.Context) (*ModelsListResponse, error)
+	GetModelSchema(ctx context.Context, modelClass string) (map[string]any, error)
+	ValidateModel(ctx context.Context, modelSpec map[string]any) (*ModelValidationResponse, error)
+	GenerateConfig(ctx context.Context, req *ConfigGenerationRequest) (string, error)
+	ValidateConfig(ctx context.Context, config map[string]any) (*OkValidationResponse, error)
+
+	CreateDetectionTask(ctx context.Context, req *AnomalyDetectionTaskRequest) (*AnomalyDetectionTaskResponse, error)
+	GetTaskStatus(ctx context.Context, taskID string) (*AnomalyDetectionTaskStatus, error)
+	ListTasks(ctx context.Context, limit int, status *string) (*AnomalyDetectionTaskListResponse, error)
+	CancelTask(ctx context.Context, taskID string) (map[string]bool, error)
+	GetDetectionLimits(ctx context.Context) (*AnomalyDetectionLimitsResponse, error)
+
+	Query(ctx context.Context, req *QueryRequest) (map[string]any, error)
+	Compatibility(ctx context.Context, versionTo *string) (*CompatibilityCheckResponse, error)
+	GenerateAlertRule(ctx context.Context, req *AlertRuleRequest) (string, error)
+}
+
+var _ API = (*Client)(nil)
@@ -0,0 +1,218 @@
+package vmanomaly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// DefaultCacheTTL is the default lifetime of cached responses
+const DefaultCacheTTL = 5 * time.Minute
+
+const (
+	// versionCheckInterval is how often the vmanomaly version is checked on calls served from the cache,
+	// so cached responses of an upgraded vmanomaly are dropped before they expire
+	versionCheckInterval = 30 * time.Second
+	// cacheFetchTimeout limits shared fetches, they are not canceled with the caller that started them
+	cacheFetchTimeout = 30 * time.Second
+)
+
+// Endpoints with cached responses, they are used as values of the endpoint metric label
+const (
+	cacheEndpointModels      = "models"
+	cacheEndpointModelSchema = "model_schema"
+	cacheEndpointBuildInfo   = "buildinfo"
+)
+
+type noCacheKey struct{}
+
+// WithNoCache returns a context for client calls bypassing the response cache, like NoCache of QueryRequest
+// bypasses the cache of the datasource. Fresh responses are stored in the cache for following calls.
+func WithNoCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// isNoCache reports whether ctx bypasses the response cache
+func isNoCache(ctx context.Context) bool {
+	noCache, _ := ctx.Value(noCacheKey{}).(bool)
+	return noCache
+}
+
+// BuildVersion extracts the vmanomaly version from its build info
+func BuildVersion(buildInfo map[string]any) string {
+	for _, key := range []string{"vmanomaly", "version"} {
+		if v, ok := buildInfo[key].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// cacheEntry is a cached response body
+type cacheEntry struct {
+	body    []byte
+	expires time.Time
+}
+
+// flight is an in-flight request, concurrent requests of the same key wait for it instead of
+// sending the same request
+type flight struct {
+	done chan struct{}
+	body []byte
+	err  error
+}
+
+// responseCache caches raw response bodies for a TTL. Bodies are decoded on every call,
+// so callers may modify returned values.
+type responseCache struct {
+	ttl time.Duration
+	ms  *metrics.Set
+	now func() time.Time
+
+	// checkVersion fetches the build info of vmanomaly and passes its version to setVersion
+	checkVersion func(ctx context.Context) error
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	flights map[string]*flight
+	version string    // vmanomaly version the entries were fetched from
+	checked time.Time // Time of the last version check
+	gen     uint64    // Incremented on invalidation, responses fetched before it are not cached
+}
+
+func newResponseCache(ttl time.Duration, ms *metrics.Set) *responseCache {
+	return &responseCache{
+		ttl:     ttl,
+		ms:      ms,
+		now:     time.Now,
+		entries: map[string]cacheEntry{},
+		flights: map[string]*flight{},
+	}
+}
+
+// get returns the cached response of the key or fetches it. Concurrent fetches of the same key are deduplicated,
+// the shared fetch outlives the caller that started it, so canceling one caller does not fail the others.
+func (c *responseCache) get(ctx context.Context, endpoint, key string, fetch func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	if endpoint != cacheEndpointBuildInfo {
+		c.refreshVersion(ctx)
+	}
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && !isNoCache(ctx) && c.now().Before(e.expires) {
+		c.mu.Unlock()
+		c.inc("hits", endpoint)
+		return e.body, nil
+	}
+	c.inc("misses", endpoint)
+	f, ok := c.flights[key]
+	if !ok {
+		f = &flight{done: make(chan struct{})}
+		c.flights[key] = f
+		go c.fetch(context.WithoutCancel(ctx), endpoint, key, c.gen, f, fetch)
+	}
+	c.mu.Unlock()
+	select {
+	case <-f.done:
+		return f.body, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetch completes the flight f and caches its response unless the cache was invalidated after gen
+func (c *responseCache) fetch(ctx context.Context, endpoint, key string, gen uint64, f *flight, fetch func(ctx context.Context) ([]byte, error)) {
+	ctx, cancel := context.WithTimeout(ctx, cacheFetchTimeout)
+	defer cancel()
+	f.body, f.err = fetch(ctx)
+
+	c.mu.Lock()
+	delete(c.flights, key)
+	if f.err == nil && (gen == c.gen || endpoint == cacheEndpointBuildInfo) {
+		ttl := c.ttl
+		if endpoint == cacheEndpointBuildInfo {
+			ttl = min(ttl, versionCheckInterval)
+		}
+		c.entries[key] = cacheEntry{body: f.body, expires: c.now().Add(ttl)}
+	}
+	c.mu.Unlock()
+	close(f.done)
+}
+
+// refreshVersion checks the vmanomaly version if it was not checked for versionCheckInterval.
+// Errors are ignored, cached responses are kept until they expire if vmanomaly is unavailable.
+func (c *responseCache) refreshVersion(ctx context.Context) {
+	if c.checkVersion == nil {
+		return
+	}
+	c.mu.Lock()
+	due := !c.now().Before(c.checked.Add(versionCheckInterval))
+	if due {
+		c.checked = c.now()
+	}
+	c.mu.Unlock()
+	if due {
+		_ = c.checkVersion(ctx)
+	}
+}
+
+// setVersion drops cached responses of other vmanomaly versions
+func (c *responseCache) setVersion(version string) {
+	if version == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checked = c.now()
+	if c.version != "" && c.version != version {
+		clear(c.entries)
+		c.gen++
+		if c.ms != nil {
+			c.ms.GetOrCreateCounter(`mcp_vmanomaly_client_cache_invalidations_total`).Inc()
+		}
+	}
+	c.version = version
+}
+
+// inc increments the hits or misses counter of the endpoint
+func (c *responseCache) inc(result, endpoint string) {
+	if c.ms != nil {
+		c.ms.GetOrCreateCounter(fmt.Sprintf(`mcp_vmanomaly_client_cache_%s_total{endpoint=%q}`, result, endpoint)).Inc()
+	}
+}
+
+// EnableCache caches responses of ListModels, GetModelSchema and GetBuildInfo for ttl, hits and misses are counted
+// in ms if it is set. The vmanomaly version is checked every versionCheckInterval independently of ttl
+// and cached responses are dropped when it changes. The cache is disabled if ttl is not positive.
+func (c *Client) EnableCache(ttl time.Duration, ms *metrics.Set) {
+	if ttl <= 0 {
+		c.cache = nil
+		return
+	}
+	c.cache = newResponseCache(ttl, ms)
+	c.cache.checkVersion = func(ctx context.Context) error {
+		_, err := c.GetBuildInfo(ctx)
+		return err
+	}
+}
+
+// cachedGet sends a GET request through the response cache if it is enabled
+func (c *Client) cachedGet(ctx context.Context, endpoint, path string) ([]byte, error) {
+	fetch := func(ctx context.Context) ([]byte, error) {
+		body, err := c.doRequest(ctx, http.MethodGet, path, nil)
+		if err == nil && endpoint == cacheEndpointBuildInfo && c.cache != nil {
+			var info map[string]any
+			if json.Unmarshal(body, &info) == nil {
+				c.cache.setVersion(BuildVersion(info))
+			}
+		}
+		return body, err
+	}
+	if c.cache == nil {
+		return fetch(ctx)
+	}
+	return c.cache.get(ctx, endpoint, path, fetch)
+}
@@ -0,0 +1,203 @@
+package vmanomaly
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+func TestClient_Cache(t *testing.T) {
+	var requests sync.Map // path -> *atomic.Int64
+	var version atomic.Value
+	version.Store("v1.26.0")
+	count := func(path string) int64 {
+		n, _ := requests.LoadOrStore(path, &atomic.Int64{})
+		return n.(*atomic.Int64).Load()
+	}
+	client, server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		n, _ := requests.LoadOrStore(r.URL.Path, &atomic.Int64{})
+		n.(*atomic.Int64).Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/models":
+			time.Sleep(20 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"models": ["zscore", "mad"]}`))
+		case "/api/v1/model/schema":
+			_, _ = w.Write([]byte(`{"properties": {"z_threshold": {"type": "number"}}}`))
+		case "/api/v1/server/buildinfo":
+			fmt.Fprintf(w, `{"version": %q}`, version.Load())
+		}
+	})
+	defer server.Close()
+	ms := metrics.NewSet()
+	client.EnableCache(time.Minute, ms)
+	ctx := context.Background()
+
+	// Concurrent calls share one request
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.ListModels(ctx); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := client.ListModels(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, count("/api/v1/models"), int64(1))
+	hits := ms.GetOrCreateCounter(`mcp_vmanomaly_client_cache_hits_total{endpoint="models"}`).Get()
+	misses := ms.GetOrCreateCounter(`mcp_vmanomaly_client_cache_misses_total{endpoint="models"}`).Get()
+	if hits < 1 || hits+misses != 6 {
+		t.Errorf("got %d hits and %d misses of 6 calls", hits, misses)
+	}
+
+	// Returned values may be modified by callers
+	schema, _ := client.GetModelSchema(ctx, "zscore")
+	schema["properties"] = nil
+	schema, _ = client.GetModelSchema(ctx, "zscore")
+	if schema["properties"] == nil {
+		t.Error("cached response is modified by a caller")
+	}
+	assertEqual(t, count("/api/v1/model/schema"), int64(1))
+	assertEqual(t, ms.GetOrCreateCounter(`mcp_vmanomaly_client_cache_hits_total{endpoint="model_schema"}`).Get(), uint64(1))
+
+	// no_cache bypasses the cache
+	if _, err := client.GetModelSchema(WithNoCache(ctx), "zscore"); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, count("/api/v1/model/schema"), int64(2))
+
+	// A new version drops cached responses
+	if _, err := client.GetBuildInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	version.Store("v1.27.0")
+	info, err := client.GetBuildInfo(WithNoCache(ctx))
+	if err != nil || BuildVersion(info) != "v1.27.0" {
+		t.Fatalf("GetBuildInfo() = %v, %v", info, err)
+	}
+	if _, err := client.ListModels(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, count("/api/v1/models"), int64(2))
+	assertEqual(t, ms.GetOrCreateCounter(`mcp_vmanomaly_client_cache_invalidations_total`).Get(), uint64(1))
+	if info, _ := client.GetBuildInfo(ctx); BuildVersion(info) != "v1.27.0" {
+		t.Errorf("build info of the new version must be cached, got %v", info)
+	}
+	assertEqual(t, count("/api/v1/server/buildinfo"), int64(2))
+
+	// Responses expire after TTL
+	client.cache.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := client.ListModels(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, count("/api/v1/models"), int64(3))
+}
+
+func TestClient_CacheDisabled(t *testing.T) {
+	var requests atomic.Int64
+	client, server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"models": []}`))
+	})
+	defer server.Close()
+	client.EnableCache(0, nil)
+	for range 2 {
+		if _, err := client.ListModels(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertEqual(t, requests.Load(), int64(2))
+}
+
+func TestClient_CacheVersionCheck(t *testing.T) {
+	var models, buildInfo atomic.Int64
+	var version atomic.Value
+	version.Store("v1.26.0")
+	client, server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/models":
+			models.Add(1)
+			_, _ = w.Write([]byte(`{"models": ["zscore"]}`))
+		case "/api/v1/server/buildinfo":
+			buildInfo.Add(1)
+			fmt.Fprintf(w, `{"version": %q}`, version.Load())
+		}
+	})
+	defer server.Close()
+	client.EnableCache(time.Hour, nil)
+	start := time.Now()
+	client.cache.now = func() time.Time { return start }
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := client.ListModels(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertEqual(t, models.Load(), int64(1))
+	assertEqual(t, buildInfo.Load(), int64(1))
+
+	// An upgrade is noticed after versionCheckInterval although responses are cached for an hour
+	version.Store("v1.27.0")
+	client.cache.now = func() time.Time { return start.Add(versionCheckInterval) }
+	if _, err := client.ListModels(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, buildInfo.Load(), int64(2))
+	assertEqual(t, models.Load(), int64(2))
+	if info, _ := client.GetBuildInfo(ctx); BuildVersion(info) != "v1.27.0" {
+		t.Errorf("GetBuildInfo() = %v, want the new version", info)
+	}
+	assertEqual(t, buildInfo.Load(), int64(2))
+}
+
+func TestClient_CacheSharedFetch(t *testing.T) {
+	release := make(chan struct{})
+	var requests atomic.Int64
+	client, server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/models" {
+			requests.Add(1)
+			<-release
+		}
+		_, _ = w.Write([]byte(`{"models": ["zscore"], "version": "v1.26.0"}`))
+	})
+	defer server.Close()
+	client.EnableCache(time.Minute, nil)
+
+	// The first caller is canceled while the second one waits for the same fetch
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := client.ListModels(ctx)
+		first <- err
+	}()
+	for requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	second := make(chan error)
+	go func() {
+		_, err := client.ListModels(context.Background())
+		second <- err
+	}()
+	cancel()
+	if err := <-first; err == nil {
+		t.Error("expected error for the canceled caller")
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("waiting caller error = %v", err)
+	}
+	assertEqual(t, requests.Load(), int64(1))
+}
@@ -17,6 +17,7 @@ type Client struct {
 	httpClient    *http.Client
 	bearerToken   string
 	customHeaders map[string]string
+	cache         *responseCache // Cache of static responses, nil if disabled
 }
 
 func NewClient(baseURL, bearerToken string, customHeaders map[string]string) *Client {
@@ -30,6 +31,11 @@ func NewClient(baseURL, bearerToken string, customHeaders map[string]string) *Cl
 	}
 }
 
+// SetTransport replaces the transport of the HTTP client, e.g. to record or replay requests in tests
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+}
+
 func (c *Client) doRequest(ctx context.Context, method, path string, body any) ([]byte, error) {
 	var reqBody io.Reader
 	if body != nil {
@@ -94,7 +100,7 @@ func (c *Client) GetHealth(ctx context.Context) (map[string]any, error) {
 
 // ListModels returns a list of available anomaly detection model types
 func (c *Client) ListModels(ctx context.Context) (*ModelsListResponse, error) {
-	respBody, err := c.doRequest(ctx, http.MethodGet, "/api/v1/models", nil)
+	respBody, err := c.cachedGet(ctx, cacheEndpointModels, "/api/v1/models")
 	if err != nil {
 		return nil, err
 	}
@@ -108,8 +114,8 @@ func (c *Client) ListModels(ctx context.Context) (*ModelsListResponse, error) {
 }
 
 func (c *Client) GetModelSchema(ctx context.Context, modelClass string) (map[string]any, error) {
-	path := fmt.Sprintf("/api/v1/model/schema?model_class=%s", modelClass)
-	respBody, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	path := "/api/v1/model/schema?" + url.Values{"model_class": {modelClass}}.Encode()
+	respBody, err := c.cachedGet(ctx, cacheEndpointModelSchema, path)
 	if err != nil {
 		return nil, err
 	}
@@ -139,20 +145,24 @@ func (c *Client) ValidateModel(ctx context.Context, modelSpec map[string]any) (*
 func (c *Client) GenerateConfig(ctx context.Context, req *ConfigGenerationRequest) (string, error) {
 	// Build query parameters
 	path := fmt.Sprintf("/api/vmanomaly/config.yaml?step=%s&query=%s&datasource_url=%s&fit_window=%s&fit_every=%s",
-		req.Step, req.Query, req.DatasourceURL, req.FitWindow, req.FitEvery)
+		url.QueryEscape(req.Step), url.QueryEscape(req.Query), url.QueryEscape(req.DatasourceURL),
+		url.QueryEscape(req.FitWindow), url.QueryEscape(req.FitEvery))
 
+	if req.DatasourceType != "" {
+		path += fmt.Sprintf("&datasource_type=%s", url.QueryEscape(req.DatasourceType))
+	}
 	if req.TenantID != nil {
-		path += fmt.Sprintf("&tenant_id=%s", *req.TenantID)
+		path += fmt.Sprintf("&tenant_id=%s", url.QueryEscape(*req.TenantID))
 	}
 	if req.InferEvery != nil {
-		path += fmt.Sprintf("&infer_every=%s", *req.InferEvery)
+		path += fmt.Sprintf("&infer_every=%s", url.QueryEscape(*req.InferEvery))
 	}
 
 	modelSpecJSON, err := json.Marshal(req.ModelSpec)
 	if err != nil {
 		return "", fmt.Errorf("failed to encode model_spec: %w", err)
 	}
-	path += fmt.Sprintf("&model_spec=%s", string(modelSpecJSON))
+	path += fmt.Sprintf("&model_spec=%s", url.QueryEscape(string(modelSpecJSON)))
 
 	respBody, err := c.doRequest(ctx, http.MethodGet, path, nil)
 	if err != nil {
@@ -263,7 +273,7 @@ func (c *Client) Query(ctx context.Context, req *QueryRequest) (map[string]any,
 }
 
 func (c *Client) GetBuildInfo(ctx context.Context) (map[string]any, error) {
-	respBody, err := c.doRequest(ctx, http.MethodGet, "/api/v1/server/buildinfo", nil)
+	respBody, err := c.cachedGet(ctx, cacheEndpointBuildInfo, "/api/v1/server/buildinfo")
 	if err != nil {
 		return nil, err
 	}
@@ -1,26 +1,27 @@
-//go:build integration
-
 package vmanomaly_test
 
 import (
 	"context"
-	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomalytest"
 )
 
+// Integration scenarios replay golden files from testdata/fixtures by default.
+// Run them against a real vmanomaly with VMANOMALY_FIXTURES=live,
+// or re-record golden files with VMANOMALY_FIXTURES=record (see `make record-fixtures-docker`).
+// Golden files with the "synthetic" source are placeholders with assumed responses, not recorded from vmanomaly,
+// so scenarios replaying them do not pin vmanomaly behaviour until they are recorded.
+
 func getTestClient(t *testing.T) *vmanomaly.Client {
 	t.Helper()
 
-	endpoint := os.Getenv("VMANOMALY_ENDPOINT")
-	if endpoint == "" {
-		endpoint = "http://localhost:8490"
-	}
-
-	client := vmanomaly.NewClient(endpoint)
+	path := filepath.Join("testdata", "fixtures", strings.ReplaceAll(t.Name(), "/", "_")+".json")
+	client := vmanomalytest.FixtureClient(t, path)
 
 	waitForServer(t, client)
 
@@ -48,6 +49,14 @@ func waitForServer(t *testing.T, client *vmanomaly.Client) {
 	}
 }
 
+// pollDelay returns the delay between task status polls, replayed scenarios are not delayed
+func pollDelay() time.Duration {
+	if vmanomalytest.Mode() == vmanomalytest.ModeReplay {
+		return 0
+	}
+	return time.Second
+}
+
 func TestIntegration_GetHealth(t *testing.T) {
 	client := getTestClient(t)
 	ctx := context.Background()
@@ -71,7 +80,7 @@ func TestIntegration_GetBuildInfo(t *testing.T) {
 		t.Fatalf("GetBuildInfo() failed: %v", err)
 	}
 
-	if result["version"] == nil {
+	if vmanomaly.BuildVersion(result) == "" {
 		t.Error("expected version field in build info")
 	}
 }
@@ -257,7 +266,7 @@ func TestIntegration_TaskLifecycle(t *testing.T) {
 			t.Skip("no task ID from create test")
 		}
 
-		time.Sleep(1 * time.Second)
+		time.Sleep(pollDelay())
 
 		status, err := client.GetTaskStatus(ctx, taskID)
 		if err != nil {
@@ -321,3 +330,81 @@ func TestIntegration_TaskLifecycle(t *testing.T) {
 		t.Logf("Task canceled successfully: %s", taskID)
 	})
 }
+
+func TestIntegration_TaskPolling(t *testing.T) {
+	client := getTestClient(t)
+	ctx := context.Background()
+
+	end := 1767225600.0 // 2026-01-01T00:00:00Z, fixed so recorded requests match on replay
+	start := end - 3600
+	task, err := client.CreateDetectionTask(ctx, &vmanomaly.AnomalyDetectionTaskRequest{
+		Query:            "up",
+		Step:             "1m",
+		StartInferS:      &start,
+		EndInferS:        &end,
+		FitWindow:        "1h",
+		FitEvery:         "1h",
+		AnomalyThreshold: 1,
+		DatasourceType:   "vm",
+		ModelSpec:        map[string]any{"class": "zscore", "z_threshold": 2.5},
+	})
+	if err != nil {
+		t.Fatalf("CreateDetectionTask() failed: %v", err)
+	}
+
+	var status *vmanomaly.AnomalyDetectionTaskStatus
+	for range 60 {
+		status, err = client.GetTaskStatus(ctx, task.TaskID)
+		if err != nil {
+			t.Fatalf("GetTaskStatus() failed: %v", err)
+		}
+		if status.Status != "running" {
+			break
+		}
+		time.Sleep(pollDelay())
+	}
+
+	switch status.Status {
+	case "done":
+		if status.Progress != 100 || status.ResultData == nil {
+			t.Errorf("done task must have full progress and result, got %+v", status)
+		}
+	case "error":
+		if status.Error == nil && (status.ResultData == nil || status.ResultData.Error == nil) {
+			t.Errorf("failed task must have an error, got %+v", status)
+		}
+	default:
+		t.Errorf("task is not finished after polling: %+v", status)
+	}
+}
+
+func TestIntegration_FailurePaths(t *testing.T) {
+	client := getTestClient(t)
+	ctx := context.Background()
+
+	t.Run("invalid model spec", func(t *testing.T) {
+		_, err := client.CreateDetectionTask(ctx, &vmanomaly.AnomalyDetectionTaskRequest{
+			Query:     "up",
+			Step:      "1m",
+			FitWindow: "1h",
+			FitEvery:  "1h",
+			ModelSpec: map[string]any{"class": "no_such_model"},
+		})
+		if err == nil || !strings.Contains(err.Error(), "API error") {
+			t.Errorf("expected API error for unknown model class, got %v", err)
+		}
+	})
+
+	t.Run("unknown task", func(t *testing.T) {
+		_, err := client.GetTaskStatus(ctx, "00000000-0000-0000-0000-000000000000")
+		if err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf("expected 404 for unknown task, got %v", err)
+		}
+	})
+
+	t.Run("unknown model schema", func(t *testing.T) {
+		if _, err := client.GetModelSchema(ctx, "no_such_model"); err == nil {
+			t.Error("expected error for unknown model class")
+		}
+	})
+}
@@ -182,6 +182,13 @@ func TestClient_GetModelSchema(t *testing.T) {
 			response:   `{"type":"object"}`,
 			wantErr:    false,
 		},
+		{
+			name:       "reserved characters in model class",
+			modelClass: "model.zscore&x=1 #a",
+			statusCode: 200,
+			response:   `{"type":"object"}`,
+			wantErr:    false,
+		},
 		{
 			name:       "404 unknown model",
 			modelClass: "unknown",
@@ -339,6 +346,21 @@ func TestClient_GenerateConfig(t *testing.T) {
 			response:   "schedulers:\n  model: prophet\n",
 			wantErr:    false,
 		},
+		{
+			name: "success with vmlogs datasource",
+			request: &ConfigGenerationRequest{
+				Step:           "5m",
+				Query:          "* | stats by (host) count() as logs",
+				DatasourceURL:  "http://localhost:9428",
+				DatasourceType: DatasourceTypeVMLogs,
+				FitWindow:      "1d",
+				FitEvery:       "1d",
+				ModelSpec:      map[string]any{"class": "zscore_online"},
+			},
+			statusCode: 200,
+			response:   "reader:\n  class: vlogs\n",
+			wantErr:    false,
+		},
 		{
 			name: "500 error",
 			request: &ConfigGenerationRequest{
@@ -365,6 +387,7 @@ func TestClient_GenerateConfig(t *testing.T) {
 				assertEqual(t, query.Get("step"), tt.request.Step)
 				assertEqual(t, query.Get("query"), tt.request.Query)
 				assertEqual(t, query.Get("datasource_url"), tt.request.DatasourceURL)
+				assertEqual(t, query.Get("datasource_type"), tt.request.DatasourceType)
 
 				if tt.request.TenantID != nil {
 					assertEqual(t, query.Get("tenant_id"), *tt.request.TenantID)
@@ -0,0 +1,89 @@
+package vmanomaly
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationUnits maps duration suffixes accepted by vmanomaly, VictoriaMetrics and VictoriaLogs
+// to their length. Longer suffixes must be checked first ("ms" before "m" and "s").
+var durationUnits = []struct {
+	suffix string
+	unit   time.Duration
+}{
+	{"ms", time.Millisecond},
+	{"s", time.Second},
+	{"m", time.Minute},
+	{"h", time.Hour},
+	{"d", 24 * time.Hour},
+	{"w", 7 * 24 * time.Hour},
+	{"y", 365 * 24 * time.Hour},
+}
+
+// ParseDuration parses duration strings in the format used by vmanomaly configs
+// (e.g. "30s", "5m", "1h30m", "1d", "2w"). Unlike time.ParseDuration it supports
+// day, week and year suffixes and treats a bare number as seconds.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+
+	var total time.Duration
+	rest := s
+	for rest != "" {
+		i := 0
+		for i < len(rest) && (rest[i] == '.' || (rest[i] >= '0' && rest[i] <= '9')) {
+			i++
+		}
+		if i == 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		value, err := strconv.ParseFloat(rest[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		rest = rest[i:]
+
+		matched := false
+		for _, u := range durationUnits {
+			if strings.HasPrefix(rest, u.suffix) {
+				total += time.Duration(value * float64(u.unit))
+				rest = rest[len(u.suffix):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return 0, fmt.Errorf("invalid duration %q: unknown unit", s)
+		}
+	}
+	return total, nil
+}
+
+// FormatDuration formats a duration using the largest units accepted by ParseDuration,
+// e.g. 90*time.Minute becomes "1h30m" and 48*time.Hour becomes "2d".
+func FormatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "0s"
+	}
+	var sb strings.Builder
+	for i := len(durationUnits) - 1; i >= 0; i-- {
+		u := durationUnits[i]
+		if u.suffix == "y" || u.suffix == "w" {
+			continue
+		}
+		if d >= u.unit {
+			n := d / u.unit
+			d -= n * u.unit
+			sb.WriteString(strconv.FormatInt(int64(n), 10))
+			sb.WriteString(u.suffix)
+		}
+	}
+	return sb.String()
+}
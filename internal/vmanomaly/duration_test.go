@@ -0,0 +1,57 @@
+package vmanomaly
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "30s", want: 30 * time.Second},
+		{input: "5m", want: 5 * time.Minute},
+		{input: "1h30m", want: 90 * time.Minute},
+		{input: "1d", want: 24 * time.Hour},
+		{input: "2w", want: 14 * 24 * time.Hour},
+		{input: "500ms", want: 500 * time.Millisecond},
+		{input: "1.5h", want: 90 * time.Minute},
+		{input: "60", want: time.Minute},
+		{input: "", wantErr: true},
+		{input: "abc", wantErr: true},
+		{input: "5x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDuration(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDuration(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseDuration(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		input time.Duration
+		want  string
+	}{
+		{input: 0, want: "0s"},
+		{input: 30 * time.Second, want: "30s"},
+		{input: 90 * time.Minute, want: "1h30m"},
+		{input: 48 * time.Hour, want: "2d"},
+		{input: 25*time.Hour + time.Second, want: "1d1h1s"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatDuration(tt.input); got != tt.want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package vmanomaly
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Series is a single time series returned by the query endpoint
+type Series struct {
+	Labels     map[string]string `json:"labels"`
+	Timestamps []float64         `json:"timestamps"` // Unix seconds
+	Values     []float64         `json:"values"`
+}
+
+// ParseQueryResult converts a Prometheus-style query response
+// ({"status":"success","data":{"resultType":"matrix","result":[...]}}) into a list of series.
+// Both "matrix" and "vector" result types are supported; the response may also be the bare "data" object.
+func ParseQueryResult(result map[string]any) ([]Series, error) {
+	if status, ok := result["status"].(string); ok && status != "success" {
+		errMsg, _ := result["error"].(string)
+		return nil, fmt.Errorf("query returned status %q: %s", status, errMsg)
+	}
+	data := result
+	if d, ok := result["data"].(map[string]any); ok {
+		data = d
+	}
+	rawResult, ok := data["result"].([]any)
+	if !ok {
+		if data["result"] == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unexpected query result format: %T", data["result"])
+	}
+
+	series := make([]Series, 0, len(rawResult))
+	for i, item := range rawResult {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unexpected series #%d format: %T", i, item)
+		}
+		s := Series{Labels: map[string]string{}}
+		if metric, ok := obj["metric"].(map[string]any); ok {
+			for k, v := range metric {
+				s.Labels[k] = fmt.Sprint(v)
+			}
+		}
+		var points []any
+		if values, ok := obj["values"].([]any); ok {
+			points = values
+		} else if value, ok := obj["value"].([]any); ok {
+			points = []any{value}
+		}
+		for _, p := range points {
+			ts, v, err := parseSample(p)
+			if err != nil {
+				return nil, fmt.Errorf("series #%d: %w", i, err)
+			}
+			s.Timestamps = append(s.Timestamps, ts)
+			s.Values = append(s.Values, v)
+		}
+		series = append(series, s)
+	}
+	return series, nil
+}
+
+func parseSample(p any) (float64, float64, error) {
+	pair, ok := p.([]any)
+	if !ok || len(pair) != 2 {
+		return 0, 0, fmt.Errorf("unexpected sample format: %v", p)
+	}
+	ts, err := toFloat(pair[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid sample timestamp: %w", err)
+	}
+	v, err := toFloat(pair[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid sample value: %w", err)
+	}
+	return ts, v, nil
+}
+
+func toFloat(v any) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("unexpected type %T", v)
+	}
+}
@@ -0,0 +1,73 @@
+package vmanomaly
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseQueryResult(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   string
+		wantSeries int
+		wantValues []float64 // values of the first series
+		wantErr    bool
+	}{
+		{
+			name:       "matrix",
+			response:   `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"job":"a"},"values":[[1700000000,"1"],[1700000060,"2.5"]]},{"metric":{"job":"b"},"values":[]}]}}`,
+			wantSeries: 2,
+			wantValues: []float64{1, 2.5},
+		},
+		{
+			name:       "vector",
+			response:   `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000,"42"]}]}}`,
+			wantSeries: 1,
+			wantValues: []float64{42},
+		},
+		{
+			name:       "bare data",
+			response:   `{"resultType":"matrix","result":[{"metric":{},"values":[[1700000000,"NaN"]]}]}`,
+			wantSeries: 1,
+		},
+		{
+			name:       "empty result",
+			response:   `{"status":"success","data":{"resultType":"matrix","result":[]}}`,
+			wantSeries: 0,
+		},
+		{
+			name:     "error status",
+			response: `{"status":"error","error":"bad query"}`,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid value",
+			response: `{"status":"success","data":{"result":[{"metric":{},"values":[[1700000000,"abc"]]}]}}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result map[string]any
+			if err := json.Unmarshal([]byte(tt.response), &result); err != nil {
+				t.Fatalf("invalid test response: %v", err)
+			}
+			series, err := ParseQueryResult(result)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQueryResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(series) != tt.wantSeries {
+				t.Fatalf("got %d series, want %d", len(series), tt.wantSeries)
+			}
+			for i, want := range tt.wantValues {
+				if series[0].Values[i] != want {
+					t.Errorf("value[%d] = %v, want %v", i, series[0].Values[i], want)
+				}
+			}
+		})
+	}
+}
@@ -99,6 +99,12 @@ const (
 	ModelClassAuto                ModelClassEnum = "auto"
 )
 
+// Datasource types supported by vmanomaly readers
+const (
+	DatasourceTypeVM     = "vm"
+	DatasourceTypeVMLogs = "vmlogs"
+)
+
 // ============================================================================
 // Query Types
 // ============================================================================
@@ -122,14 +128,15 @@ type QueryRequest struct {
 
 // ConfigGenerationRequest represents parameters for generating a config
 type ConfigGenerationRequest struct {
-	Step          string         `json:"step"`                  // Query step/resolution
-	Query         string         `json:"query"`                 // PromQL query
-	DatasourceURL string         `json:"datasource_url"`        // Datasource URL
-	TenantID      *string        `json:"tenant_id,omitempty"`   // Optional tenant ID
-	FitWindow     string         `json:"fit_window"`            // Time window for model fitting (default: "1d")
-	FitEvery      string         `json:"fit_every"`             // Model retraining frequency (default: "1d")
-	InferEvery    *string        `json:"infer_every,omitempty"` // Optional inference cadence
-	ModelSpec     map[string]any `json:"model_spec"`            // Model specification (JSON-encoded)
+	Step           string         `json:"step"`                      // Query step/resolution
+	Query          string         `json:"query"`                     // PromQL or LogsQL query
+	DatasourceURL  string         `json:"datasource_url"`            // Datasource URL
+	DatasourceType string         `json:"datasource_type,omitempty"` // Datasource type: vm or vmlogs (default: "vm")
+	TenantID       *string        `json:"tenant_id,omitempty"`       // Optional tenant ID
+	FitWindow      string         `json:"fit_window"`                // Time window for model fitting (default: "1d")
+	FitEvery       string         `json:"fit_every"`                 // Model retraining frequency (default: "1d")
+	InferEvery     *string        `json:"infer_every,omitempty"`     // Optional inference cadence
+	ModelSpec      map[string]any `json:"model_spec"`                // Model specification (JSON-encoded)
 }
 
 // ============================================================================
@@ -0,0 +1,426 @@
+package vmanomalytest
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+// FixtureMode selects how fixture clients serve requests
+type FixtureMode string
+
+// Fixture modes, set with the VMANOMALY_FIXTURES environment variable
+const (
+	// ModeReplay serves requests from golden files, tests without golden files fail
+	ModeReplay FixtureMode = "replay"
+	// ModeLive sends requests to VMANOMALY_ENDPOINT without recording them
+	ModeLive FixtureMode = "live"
+	// ModeRecord sends requests to VMANOMALY_ENDPOINT and overwrites golden files with redacted interactions
+	ModeRecord FixtureMode = "record"
+)
+
+// Redacted replaces secrets in recorded interactions
+const Redacted = "REDACTED"
+
+// Sources of golden files
+const (
+	// SourceVmanomaly marks golden files recorded from a running vmanomaly
+	SourceVmanomaly = "vmanomaly"
+	// SourceSynthetic marks placeholder golden files which were not recorded from vmanomaly,
+	// they only check the client against assumed responses
+	SourceSynthetic = "synthetic"
+)
+
+// Fixture is a golden file with recorded request/response pairs of a vmanomaly server
+type Fixture struct {
+	Source           string        `json:"source,omitempty"`            // SourceVmanomaly or SourceSynthetic
+	VmanomalyVersion string        `json:"vmanomaly_version,omitempty"` // Version of the recorded server
+	Interactions     []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request with its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a recorded request, headers are not recorded
+type RecordedRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"` // Path with query, without the server address
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// RecordedResponse is a recorded response, Body holds JSON bodies and Text other bodies
+type RecordedResponse struct {
+	StatusCode  int             `json:"status_code"`
+	ContentType string          `json:"content_type,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+	Text        string          `json:"text,omitempty"`
+}
+
+// LoadFixture reads a golden file
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Recorded reports whether the fixture was recorded from a running vmanomaly of a known version
+func (f *Fixture) Recorded() bool {
+	return f.Source == SourceVmanomaly && f.VmanomalyVersion != ""
+}
+
+// Save writes the fixture to a golden file
+func (f *Fixture) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ============================================================================
+// Recording
+// ============================================================================
+
+// Recorder is a transport recording interactions with secrets redacted
+type Recorder struct {
+	next http.RoundTripper
+
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+// NewRecorder returns a recorder sending requests with next, http.DefaultTransport is used if next is nil
+func NewRecorder(next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{next: next}
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	interaction := Interaction{
+		Request:  recorded,
+		Response: RecordedResponse{StatusCode: resp.StatusCode, ContentType: resp.Header.Get("Content-Type")},
+	}
+	if json.Valid(body) {
+		interaction.Response.Body = redactJSON(body)
+	} else {
+		interaction.Response.Text = redactString(string(body))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if req.URL.Path == "/api/v1/server/buildinfo" && resp.StatusCode == http.StatusOK {
+		var info map[string]any
+		if json.Unmarshal(body, &info) == nil {
+			r.fixture.VmanomalyVersion = vmanomaly.BuildVersion(info)
+		}
+	}
+	r.fixture.Interactions = append(r.fixture.Interactions, interaction)
+	return resp, nil
+}
+
+// Fixture returns recorded interactions
+func (r *Recorder) Fixture() *Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := r.fixture
+	f.Interactions = append([]Interaction(nil), r.fixture.Interactions...)
+	return &f
+}
+
+// recordRequest returns the redacted request, the request body is restored for sending
+func recordRequest(req *http.Request) (RecordedRequest, error) {
+	recorded := RecordedRequest{Method: req.Method, URL: redactURL(req.URL)}
+	if req.Body == nil || req.Body == http.NoBody {
+		return recorded, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return recorded, fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	if len(body) == 0 {
+		return recorded, nil
+	}
+	if !json.Valid(body) {
+		return recorded, fmt.Errorf("request body of %s %s is not JSON", req.Method, req.URL.Path)
+	}
+	recorded.Body = redactJSON(body)
+	return recorded, nil
+}
+
+// ============================================================================
+// Replay
+// ============================================================================
+
+// Replayer is a transport serving recorded interactions. Requests are matched by method, URL and body,
+// repeated requests get recorded responses in order, e.g. polls of a task status.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayer returns a replayer of the fixture
+func NewReplayer(f *Fixture) *Replayer {
+	return &Replayer{interactions: f.Interactions, used: make([]bool, len(f.Interactions))}
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.interactions {
+		if r.used[i] || !sameRequest(interaction.Request, recorded) {
+			continue
+		}
+		r.used[i] = true
+		resp := interaction.Response
+		body := resp.Text
+		if resp.Body != nil {
+			body = string(resp.Body)
+		}
+		header := http.Header{}
+		if resp.ContentType != "" {
+			header.Set("Content-Type", resp.ContentType)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+			StatusCode:    resp.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no recorded response for %s %s", recorded.Method, recorded.URL)
+}
+
+// Unused returns interactions which were not replayed
+func (r *Replayer) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []Interaction
+	for i, interaction := range r.interactions {
+		if !r.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
+
+// sameRequest reports whether requests have the same method, URL and JSON body regardless of key order
+func sameRequest(a, b RecordedRequest) bool {
+	if a.Method != b.Method || a.URL != b.URL {
+		return false
+	}
+	if len(a.Body) == 0 || len(b.Body) == 0 {
+		return len(a.Body) == len(b.Body)
+	}
+	var va, vb any
+	if json.Unmarshal(a.Body, &va) != nil || json.Unmarshal(b.Body, &vb) != nil {
+		return false
+	}
+	ja, _ := json.Marshal(va)
+	jb, _ := json.Marshal(vb)
+	return bytes.Equal(ja, jb)
+}
+
+// ============================================================================
+// Fixture Clients
+// ============================================================================
+
+// Mode returns the fixture mode set with VMANOMALY_FIXTURES, ModeReplay by default
+func Mode() FixtureMode {
+	switch mode := FixtureMode(os.Getenv("VMANOMALY_FIXTURES")); mode {
+	case ModeLive, ModeRecord:
+		return mode
+	default:
+		return ModeReplay
+	}
+}
+
+// FixtureClient returns a vmanomaly client for a test scenario depending on Mode:
+// it replays the golden file at path and fails the test if it is missing, or sends requests to VMANOMALY_ENDPOINT
+// (default http://localhost:8490) and records them to the golden file. Replay fails the test if some recorded interactions are not requested.
+func FixtureClient(t testing.TB, path string) *vmanomaly.Client {
+	t.Helper()
+	mode := Mode()
+	if mode == ModeReplay {
+		f, err := LoadFixture(path)
+		if errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("fixture %s is not recorded, record it with VMANOMALY_FIXTURES=record against a running vmanomaly", path)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !f.Recorded() {
+			t.Logf("fixture %s is %s, it does not pin responses of a real vmanomaly until it is recorded with VMANOMALY_FIXTURES=record", path, cmp.Or(f.Source, "of unknown source"))
+		}
+		replayer := NewReplayer(f)
+		t.Cleanup(func() {
+			for _, interaction := range replayer.Unused() {
+				t.Errorf("recorded request %s %s is not replayed", interaction.Request.Method, interaction.Request.URL)
+			}
+		})
+		client := vmanomaly.NewClient("http://vmanomaly.fixture", "", nil)
+		client.SetTransport(replayer)
+		return client
+	}
+
+	endpoint := os.Getenv("VMANOMALY_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "http://localhost:8490"
+	}
+	client := vmanomaly.NewClient(endpoint, os.Getenv("VMANOMALY_BEARER_TOKEN"), nil)
+	if mode == ModeRecord {
+		// Build info is requested before recording, so every golden file has the version of the server
+		info, err := client.GetBuildInfo(context.Background())
+		if err != nil {
+			t.Fatalf("failed to get the version of vmanomaly at %s: %v", endpoint, err)
+		}
+		version := vmanomaly.BuildVersion(info)
+		if version == "" {
+			t.Fatalf("build info of vmanomaly at %s has no version: %v", endpoint, info)
+		}
+		recorder := NewRecorder(nil)
+		client.SetTransport(recorder)
+		t.Cleanup(func() {
+			if t.Failed() || t.Skipped() {
+				return
+			}
+			f := recorder.Fixture()
+			f.Source, f.VmanomalyVersion = SourceVmanomaly, version
+			if err := f.Save(path); err != nil {
+				t.Errorf("failed to save fixture: %v", err)
+			}
+		})
+	}
+	return client
+}
+
+// ============================================================================
+// Redaction
+// ============================================================================
+
+// secretKeys are parts of JSON keys and query parameters with secret values
+var secretKeys = []string{"password", "token", "secret", "authorization", "api_key", "apikey", "credential"}
+
+// userInfoRe matches credentials in URLs
+var userInfoRe = regexp.MustCompile(`(\w+://)[^/@\s"]+@`)
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range secretKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactString(s string) string {
+	return userInfoRe.ReplaceAllString(s, "${1}"+Redacted+"@")
+}
+
+// redactURL returns the path and query of u with secret query parameters redacted
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	for key, values := range query {
+		for i := range values {
+			if isSecretKey(key) {
+				values[i] = Redacted
+			} else {
+				values[i] = redactString(values[i])
+			}
+		}
+	}
+	if len(query) == 0 {
+		return u.Path
+	}
+	return u.Path + "?" + query.Encode()
+}
+
+// redactJSON redacts values of secret keys and credentials in URLs of a JSON document
+func redactJSON(data []byte) json.RawMessage {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return data
+	}
+	redacted, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return data
+	}
+	return redacted
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if s, ok := value.(string); ok && s != "" && isSecretKey(key) {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = redactValue(value)
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	case string:
+		return redactString(v)
+	}
+	return v
+}
@@ -0,0 +1,587 @@
+// Package vmanomalytest provides an in-process fake vmanomaly server, so tool handlers and the MCP server
+// can be tested end-to-end without a running vmanomaly.
+package vmanomalytest
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults of the fake server
+const (
+	DefaultVersion       = "v1.26.0"
+	DefaultTaskPolls     = 3
+	DefaultMaxConcurrent = 2
+)
+
+// Models are model classes served by the fake server
+var Models = []string{
+	string(vmanomaly.ModelClassZScore),
+	string(vmanomaly.ModelClassZScoreOnline),
+	string(vmanomaly.ModelClassMAD),
+	string(vmanomaly.ModelClassMADOnline),
+	string(vmanomaly.ModelClassStd),
+	string(vmanomaly.ModelClassRollingQuantile),
+	string(vmanomaly.ModelClassQuantileOnline),
+	string(vmanomaly.ModelClassProphet),
+	string(vmanomaly.ModelClassHoltWinters),
+	string(vmanomaly.ModelClassIsolationForestUniv),
+	string(vmanomaly.ModelClassAuto),
+}
+
+// Option configures the fake server
+type Option func(s *Server)
+
+// WithVersion sets the vmanomaly version reported by build info and used as the runtime version of compatibility checks
+func WithVersion(version string) Option {
+	return func(s *Server) { s.version = version }
+}
+
+// WithTaskPolls sets the number of status requests a task needs to finish, progress grows evenly with every request
+func WithTaskPolls(n int) Option {
+	return func(s *Server) { s.taskPolls = max(n, 1) }
+}
+
+// WithMaxConcurrent sets the number of running tasks, creating more tasks fails with 429
+func WithMaxConcurrent(n int) Option {
+	return func(s *Server) { s.maxConcurrent = n }
+}
+
+// WithState sets the version of the persisted state, the state is incompatible with other versions
+// if models to purge are set
+func WithState(storedVersion string, modelsToPurge ...string) Option {
+	return func(s *Server) {
+		s.storedVersion = storedVersion
+		s.modelsToPurge = modelsToPurge
+	}
+}
+
+// Server is a fake vmanomaly server emulating health, build info, models, model schemas and validation,
+// config generation, detection tasks with simulated progress, queries and compatibility checks
+type Server struct {
+	URL string
+
+	srv           *httptest.Server
+	version       string
+	taskPolls     int
+	maxConcurrent int
+	storedVersion string
+	modelsToPurge []string
+
+	mu        sync.Mutex
+	tasks     map[string]*task
+	order     []string // Task IDs in creation order
+	requests  map[string]int
+	failNext  string
+	createdAt time.Time
+}
+
+// task is a detection task with its request and status
+type task struct {
+	req    vmanomaly.AnomalyDetectionTaskRequest
+	status vmanomaly.AnomalyDetectionTaskStatus
+	polls  int
+}
+
+// NewServer starts a fake vmanomaly server, it must be closed by the caller
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		version:       DefaultVersion,
+		taskPolls:     DefaultTaskPolls,
+		maxConcurrent: DefaultMaxConcurrent,
+		tasks:         map[string]*task{},
+		requests:      map[string]int{},
+		createdAt:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.srv = httptest.NewServer(s)
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Client returns a vmanomaly client of the server
+func (s *Server) Client() *vmanomaly.Client {
+	return vmanomaly.NewClient(s.URL, "", nil)
+}
+
+// Requests returns the number of requests served for the path
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// SetVersion changes the version reported by the server, e.g. to emulate an upgrade
+func (s *Server) SetVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+}
+
+// FailNextTask makes the next created task fail with the message on its first status request
+func (s *Server) FailNextTask(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failNext = message
+}
+
+// Task returns the request of a created task
+func (s *Server) Task(taskID string) (vmanomaly.AnomalyDetectionTaskRequest, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tasks[taskID]
+	if !ok {
+		return vmanomaly.AnomalyDetectionTaskRequest{}, false
+	}
+	return t.req, true
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[r.URL.Path]++
+
+	const tasksPath = "/api/v1/anomaly_detection/tasks"
+	switch path := r.URL.Path; {
+	case path == "/health":
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+	case path == "/api/v1/server/buildinfo":
+		writeJSON(w, http.StatusOK, vmanomaly.BuildInfo{Vmanomaly: s.version, Vmui: s.version})
+	case path == "/metrics":
+		s.serveMetrics(w)
+	case path == "/api/v1/models":
+		writeJSON(w, http.StatusOK, vmanomaly.ModelsListResponse{Models: Models})
+	case path == "/api/v1/model/schema":
+		s.serveModelSchema(w, r)
+	case path == "/api/v1/model/validate" && r.Method == http.MethodPost:
+		s.serveValidateModel(w, r)
+	case path == "/api/v1/anomaly_detection/limits":
+		running := s.running()
+		writeJSON(w, http.StatusOK, vmanomaly.AnomalyDetectionLimitsResponse{
+			MaxConcurrent: s.maxConcurrent,
+			Running:       running,
+			Available:     max(s.maxConcurrent-running, 0),
+		})
+	case path == tasksPath && r.Method == http.MethodPost:
+		s.serveCreateTask(w, r)
+	case path == tasksPath && r.Method == http.MethodGet:
+		s.serveListTasks(w, r)
+	case strings.HasPrefix(path, tasksPath+"/"):
+		s.serveTask(w, r, strings.TrimPrefix(path, tasksPath+"/"))
+	case path == "/api/vmanomaly/config.yaml":
+		s.serveConfig(w, r)
+	case path == "/api/v1/query" && r.Method == http.MethodPost:
+		s.serveQuery(w, r)
+	case path == "/api/v1/compatibility":
+		s.serveCompatibility(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (s *Server) serveMetrics(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintf(w, "vmanomaly_version_info{version=%q} 1\n", s.version)
+	fmt.Fprintf(w, "vmanomaly_tasks_running %d\n", s.running())
+}
+
+func (s *Server) serveModelSchema(w http.ResponseWriter, r *http.Request) {
+	class := r.URL.Query().Get("model_class")
+	if !slices.Contains(Models, class) {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Unknown model class %q", class))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"title": class,
+		"type":  "object",
+		"properties": map[string]any{
+			"class":                 map[string]any{"const": class},
+			"queries":               map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"detection_direction":   map[string]any{"enum": []string{"both", "above_expected", "below_expected"}, "default": "both"},
+			"min_dev_from_expected": map[string]any{"type": "number", "default": 0},
+			"z_threshold":           map[string]any{"type": "number", "default": 2.5},
+		},
+		"required": []string{"class"},
+	})
+}
+
+func (s *Server) serveValidateModel(w http.ResponseWriter, r *http.Request) {
+	var spec map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateModelSpec(spec); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	validated := map[string]any{"detection_direction": "both", "min_dev_from_expected": 0.0}
+	for k, v := range spec {
+		validated[k] = v
+	}
+	writeJSON(w, http.StatusOK, vmanomaly.ModelValidationResponse{Valid: true, ModelSpec: validated})
+}
+
+func (s *Server) serveCreateTask(w http.ResponseWriter, r *http.Request) {
+	var req vmanomaly.AnomalyDetectionTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Query == "" {
+		writeError(w, http.StatusUnprocessableEntity, "query is required")
+		return
+	}
+	if req.ModelSpec != nil {
+		if err := validateModelSpec(req.ModelSpec); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+	}
+	if s.running() >= s.maxConcurrent {
+		writeError(w, http.StatusTooManyRequests, fmt.Sprintf("Too many running tasks, max_concurrent=%d", s.maxConcurrent))
+		return
+	}
+
+	id := fmt.Sprintf("task-%d", len(s.order)+1)
+	started := s.createdAt.Add(time.Duration(len(s.order)) * time.Minute).Format(time.RFC3339)
+	t := &task{
+		req: req,
+		status: vmanomaly.AnomalyDetectionTaskStatus{
+			TaskID:    id,
+			Status:    "running",
+			Message:   "Task is queued",
+			StartedAt: &started,
+			UpdatedAt: started,
+			Metrics:   map[string]any{},
+		},
+	}
+	if s.failNext != "" {
+		msg := s.failNext
+		t.status.Error = &msg
+		s.failNext = ""
+	}
+	s.tasks[id] = t
+	s.order = append(s.order, id)
+	writeJSON(w, http.StatusOK, vmanomaly.AnomalyDetectionTaskResponse{TaskID: id, Status: t.status.Status})
+}
+
+func (s *Server) serveListTasks(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+	status := r.URL.Query().Get("status")
+	resp := vmanomaly.AnomalyDetectionTaskListResponse{Tasks: []vmanomaly.AnomalyDetectionTaskListItem{}}
+	for i := len(s.order) - 1; i >= 0 && len(resp.Tasks) < limit; i-- {
+		st := s.tasks[s.order[i]].status
+		if status != "" && st.Status != status {
+			continue
+		}
+		resp.Tasks = append(resp.Tasks, vmanomaly.AnomalyDetectionTaskListItem{
+			TaskID:    st.TaskID,
+			Status:    st.Status,
+			Progress:  st.Progress,
+			Message:   st.Message,
+			StartedAt: st.StartedAt,
+			UpdatedAt: st.UpdatedAt,
+			Metrics:   st.Metrics,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) serveTask(w http.ResponseWriter, r *http.Request, id string) {
+	t, ok := s.tasks[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Task %s not found", id))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.advance(t)
+		writeJSON(w, http.StatusOK, t.status)
+	case http.MethodDelete:
+		canceled := t.status.Status == "running"
+		if canceled {
+			t.status.Status = "canceled"
+			t.status.Message = "Task is canceled"
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"canceled": canceled})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+// advance simulates progress of a running task on a status request
+func (s *Server) advance(t *task) {
+	if t.status.Status != "running" {
+		return
+	}
+	t.polls++
+	if t.status.Error != nil {
+		t.status.Status = "error"
+		t.status.Message = "Task failed"
+		t.status.ResultData = &vmanomaly.TaskResult{Status: "error", Error: t.status.Error}
+		return
+	}
+	t.status.Progress = min(100*t.polls/s.taskPolls, 100)
+	t.status.Metrics = map[string]any{"polls": t.polls}
+	if t.status.Progress < 100 {
+		t.status.Message = fmt.Sprintf("Processing, %d%%", t.status.Progress)
+		return
+	}
+	t.status.Status = "done"
+	t.status.Message = "Task is done"
+	t.status.ResultData = taskResult(t.req, float64(s.createdAt.Unix()))
+}
+
+func (s *Server) serveQuery(w http.ResponseWriter, r *http.Request) {
+	var req vmanomaly.QueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Query == "" {
+		writeError(w, http.StatusUnprocessableEntity, "query is required")
+		return
+	}
+	step, err := vmanomaly.ParseDuration(req.Step)
+	if err != nil || step <= 0 {
+		step = time.Minute
+	}
+	end := float64(s.createdAt.Unix())
+	if req.End != nil {
+		end = *req.End
+	}
+	start := end - 60*step.Seconds()
+	if req.Start != nil {
+		start = *req.Start
+	}
+	s1 := Series(req.Query, start, end, step.Seconds())
+	values := make([]any, len(s1.Timestamps))
+	for i := range s1.Timestamps {
+		values[i] = []any{s1.Timestamps[i], strconv.FormatFloat(s1.Values[i], 'f', -1, 64)}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data": map[string]any{
+			"resultType": "matrix",
+			"result":     []any{map[string]any{"metric": s1.Labels, "values": values}},
+		},
+	})
+}
+
+// serveConfig renders a config with one query, a periodic scheduler and the model of the request
+func (s *Server) serveConfig(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	if params.Get("query") == "" || params.Get("datasource_url") == "" {
+		writeError(w, http.StatusUnprocessableEntity, "query and datasource_url are required")
+		return
+	}
+	var model map[string]any
+	if err := json.Unmarshal([]byte(params.Get("model_spec")), &model); err != nil || model == nil {
+		model = map[string]any{"class": string(vmanomaly.ModelClassZScore)}
+	}
+	if err := validateModelSpec(model); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	model["queries"] = []string{"q1"}
+	datasourceType := cmp.Or(params.Get("datasource_type"), "vm")
+	scheduler := map[string]any{
+		"class":      "periodic",
+		"fit_window": cmp.Or(params.Get("fit_window"), "1d"),
+		"fit_every":  cmp.Or(params.Get("fit_every"), "1d"),
+	}
+	if inferEvery := params.Get("infer_every"); inferEvery != "" {
+		scheduler["infer_every"] = inferEvery
+	}
+	config := map[string]any{
+		"reader": map[string]any{
+			"class":           datasourceType,
+			"datasource_url":  params.Get("datasource_url"),
+			"sampling_period": cmp.Or(params.Get("step"), "1m"),
+			"queries":         map[string]any{"q1": map[string]any{"expr": params.Get("query")}},
+		},
+		"schedulers": map[string]any{"periodic": scheduler},
+		"models":     map[string]any{"model": model},
+		"writer":     map[string]any{"class": "vm", "datasource_url": params.Get("datasource_url")},
+	}
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(data)
+}
+
+func (s *Server) serveCompatibility(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("version_to")
+	if target == "" {
+		target = s.version
+	}
+	resp := vmanomaly.CompatibilityCheckResponse{
+		RuntimeVersion: target,
+		GlobalCheck:    vmanomaly.GlobalCompatibilityCheck{IsCompatible: true},
+	}
+	if s.storedVersion != "" {
+		resp.StoredVersion = &s.storedVersion
+		resp.GlobalCheck.HasState = true
+		requirement := vmanomaly.CompatibilityRequirement{
+			RuntimeVersion:  target,
+			OriginVersion:   s.storedVersion,
+			MinStateVersion: s.storedVersion,
+			Description:     "State must be produced by the same version",
+		}
+		resp.GlobalCheck.Requirement = &requirement
+		if len(s.modelsToPurge) > 0 && target != s.storedVersion {
+			reason := fmt.Sprintf("state of %s is incompatible with %s", s.storedVersion, target)
+			resp.GlobalCheck.IsCompatible = false
+			resp.GlobalCheck.Reason = &reason
+			assessment := &vmanomaly.ComponentCompatibilityAssessment{ModelsToPurge: s.modelsToPurge}
+			for _, model := range s.modelsToPurge {
+				assessment.Issues = append(assessment.Issues, vmanomaly.ComponentCompatibilityIssue{
+					Component:        "model",
+					Subcomponent:     model,
+					Requirement:      requirement,
+					Reason:           &reason,
+					AffectedEntities: []string{model},
+				})
+			}
+			resp.ComponentAssessment = assessment
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// running returns the number of running tasks
+func (s *Server) running() int {
+	n := 0
+	for _, t := range s.tasks {
+		if t.status.Status == "running" {
+			n++
+		}
+	}
+	return n
+}
+
+// Series returns a deterministic daily sine wave of the query with a spike in the middle
+func Series(query string, start, end, step float64) vmanomaly.Series {
+	s := vmanomaly.Series{Labels: map[string]string{"__name__": "fake", "query": query}}
+	if step <= 0 {
+		return s
+	}
+	n := int((end-start)/step) + 1
+	for i := range n {
+		ts := start + float64(i)*step
+		v := 100 + 10*math.Sin(2*math.Pi*ts/86400)
+		if i == n/2 {
+			v += 100
+		}
+		s.Timestamps = append(s.Timestamps, ts)
+		s.Values = append(s.Values, v)
+	}
+	return s
+}
+
+// taskResult scores the series of the task request against its mean and standard deviation
+// if the task has no inference window, it ends at end
+func taskResult(req vmanomaly.AnomalyDetectionTaskRequest, end float64) *vmanomaly.TaskResult {
+	step, err := vmanomaly.ParseDuration(req.Step)
+	if err != nil || step <= 0 {
+		step = time.Minute
+	}
+	if req.EndInferS != nil {
+		end = *req.EndInferS
+	}
+	start := end - 60*step.Seconds()
+	if req.StartInferS != nil {
+		start = *req.StartInferS
+	}
+	series := Series(req.Query, start, end, step.Seconds())
+
+	var mean, sd float64
+	for _, v := range series.Values {
+		mean += v
+	}
+	mean /= float64(max(len(series.Values), 1))
+	for _, v := range series.Values {
+		sd += (v - mean) * (v - mean)
+	}
+	sd = math.Sqrt(sd / float64(max(len(series.Values), 1)))
+	threshold := req.AnomalyThreshold
+	if threshold <= 0 {
+		threshold = 1
+	}
+
+	scores := make([]float64, len(series.Values))
+	anomalies := 0
+	for i, v := range series.Values {
+		if sd > 0 {
+			scores[i] = math.Abs(v-mean) / (3 * sd)
+		}
+		if scores[i] > threshold {
+			anomalies++
+		}
+	}
+	return &vmanomaly.TaskResult{
+		Status: "success",
+		Data: map[string]any{
+			"series": []any{map[string]any{
+				"labels":        series.Labels,
+				"timestamps":    series.Timestamps,
+				"anomaly_score": scores,
+				"yhat":          mean,
+				"yhat_lower":    mean - 3*sd,
+				"yhat_upper":    mean + 3*sd,
+			}},
+		},
+		Stats: map[string]any{"series": 1, "points": len(series.Values), "anomalies": anomalies},
+	}
+}
+
+// validateModelSpec checks the model class of a model spec
+func validateModelSpec(spec map[string]any) error {
+	class, _ := spec["class"].(string)
+	if class == "" {
+		return fmt.Errorf("model_spec.class is required")
+	}
+	if !slices.Contains(Models, class) {
+		return fmt.Errorf("unknown model class %q", class)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error in the FastAPI format used by vmanomaly
+func writeError(w http.ResponseWriter, code int, detail string) {
+	writeJSON(w, code, map[string]any{"detail": detail})
+}
@@ -0,0 +1,90 @@
+package vmanomalytest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics-Community/mcp-vmanomaly/internal/vmanomaly"
+)
+
+func TestServer(t *testing.T) {
+	srv := NewServer(WithTaskPolls(2), WithMaxConcurrent(1), WithState("v1.25.0", "prophet"))
+	defer srv.Close()
+	client := srv.Client()
+	ctx := context.Background()
+
+	if health, err := client.GetHealth(ctx); err != nil || health["status"] != "ok" {
+		t.Errorf("GetHealth() = %v, %v", health, err)
+	}
+	if info, err := client.GetBuildInfo(ctx); err != nil || vmanomaly.BuildVersion(info) != DefaultVersion {
+		t.Errorf("GetBuildInfo() = %v, %v", info, err)
+	}
+	if _, err := client.GetModelSchema(ctx, "unknown"); err == nil || !strings.Contains(err.Error(), "422") {
+		t.Errorf("GetModelSchema(unknown) error = %v, want 422", err)
+	}
+	if v, err := client.ValidateModel(ctx, map[string]any{"class": "zscore"}); err != nil || !v.Valid || v.ModelSpec["detection_direction"] != "both" {
+		t.Errorf("ValidateModel() = %+v, %v", v, err)
+	}
+	config, err := client.GenerateConfig(ctx, &vmanomaly.ConfigGenerationRequest{
+		Query: "up", Step: "1m", DatasourceURL: "http://victoriametrics:8428", FitWindow: "1h", FitEvery: "30m",
+		ModelSpec: map[string]any{"class": "mad"},
+	})
+	if err != nil || !strings.Contains(config, "schedulers:") || !strings.Contains(config, "class: mad") {
+		t.Errorf("GenerateConfig() = %s, %v", config, err)
+	}
+
+	// Tasks finish after the configured number of status requests
+	created, err := client.CreateDetectionTask(ctx, &vmanomaly.AnomalyDetectionTaskRequest{Query: "up", Step: "1m", ModelSpec: map[string]any{"class": "zscore"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CreateDetectionTask(ctx, &vmanomaly.AnomalyDetectionTaskRequest{Query: "up", Step: "1m"}); err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("CreateDetectionTask() over the limit error = %v, want 429", err)
+	}
+	for _, want := range []struct {
+		status   string
+		progress int
+	}{{"running", 50}, {"done", 100}, {"done", 100}} {
+		status, err := client.GetTaskStatus(ctx, created.TaskID)
+		if err != nil || status.Status != want.status || status.Progress != want.progress {
+			t.Fatalf("GetTaskStatus() = %+v, %v, want %s %d%%", status, err, want.status, want.progress)
+		}
+	}
+	status, _ := client.GetTaskStatus(ctx, created.TaskID)
+	if status.ResultData == nil || status.ResultData.Stats["anomalies"] != 1.0 {
+		t.Errorf("ResultData = %+v, want 1 anomaly", status.ResultData)
+	}
+
+	srv.FailNextTask("datasource is unreachable")
+	failed, _ := client.CreateDetectionTask(ctx, &vmanomaly.AnomalyDetectionTaskRequest{Query: "up", Step: "1m"})
+	if status, err := client.GetTaskStatus(ctx, failed.TaskID); err != nil || status.Status != "error" || *status.Error != "datasource is unreachable" {
+		t.Errorf("failed task status = %+v, %v", status, err)
+	}
+	if tasks, err := client.ListTasks(ctx, 10, nil); err != nil || len(tasks.Tasks) != 2 || tasks.Tasks[0].TaskID != failed.TaskID {
+		t.Errorf("ListTasks() = %+v, %v", tasks, err)
+	}
+	if _, err := client.GetTaskStatus(ctx, "unknown"); err == nil {
+		t.Error("expected error for unknown task")
+	}
+
+	result, err := client.Query(ctx, &vmanomaly.QueryRequest{Query: "up", Step: "1m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if series, err := vmanomaly.ParseQueryResult(result); err != nil || len(series) != 1 || len(series[0].Values) != 61 {
+		t.Errorf("ParseQueryResult() = %+v, %v", series, err)
+	}
+
+	compat, err := client.Compatibility(ctx, nil)
+	if err != nil || compat.GlobalCheck.IsCompatible || compat.ComponentAssessment.ModelsToPurge[0] != "prophet" {
+		t.Errorf("Compatibility() = %+v, %v", compat, err)
+	}
+	version := "v1.25.0"
+	if compat, err := client.Compatibility(ctx, &version); err != nil || !compat.GlobalCheck.IsCompatible {
+		t.Errorf("Compatibility(%s) = %+v, %v", version, compat, err)
+	}
+	if srv.Requests("/api/v1/compatibility") != 2 {
+		t.Errorf("Requests() = %d, want 2", srv.Requests("/api/v1/compatibility"))
+	}
+}
@@ -0,0 +1,245 @@
+package vmconfig
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Kinds of config changes
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Statuses of (model, query) pairs after a reload
+const (
+	PairRestored  = "restored"  // State is reused, no retraining
+	PairRetrained = "retrained" // Model is fit from scratch on fit_window
+	PairAdded     = "added"     // New pair, fit from scratch on fit_window
+	PairRemoved   = "removed"   // Pair is dropped together with its state
+)
+
+// Change is a change of a config section or alias
+type Change struct {
+	Kind    string   `json:"kind"`
+	Section string   `json:"section"`
+	Alias   string   `json:"alias,omitempty"`
+	Details []string `json:"details,omitempty"`
+}
+
+// PairChange is the expected outcome of a reload for a model running on a query
+type PairChange struct {
+	Model  string `json:"model"`
+	Query  string `json:"query"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ReloadPreview describes what a hot reload or restart of vmanomaly with a new config would change
+type ReloadPreview struct {
+	Changes      []Change     `json:"changes"`
+	Pairs        []PairChange `json:"pairs"`
+	RestoreState bool         `json:"restore_state"` // Whether settings.restore_state is enabled in the new config
+	Warnings     []string     `json:"warnings,omitempty"`
+}
+
+// Counts returns the number of pairs per status
+func (p *ReloadPreview) Counts() map[string]int {
+	counts := map[string]int{}
+	for _, pc := range p.Pairs {
+		counts[pc.Status]++
+	}
+	return counts
+}
+
+// Diff previews a reload from the old config to the new one. Without settings.restore_state
+// every model is retrained; with it vmanomaly reuses state of (model, query) pairs whose model,
+// schedulers and query are unchanged, see
+// https://docs.victoriametrics.com/anomaly-detection/components/settings/#state-restoration
+func Diff(old, new *Config) *ReloadPreview {
+	p := &ReloadPreview{}
+	settings := section(new.Raw, "settings")
+	p.RestoreState, _ = settings["restore_state"].(bool)
+
+	// Top-level sections other than aliased entities
+	oldRaw, newRaw := others(old.Raw), others(new.Raw)
+	for _, key := range slices.Sorted(maps.Keys(union2(oldRaw, newRaw))) {
+		p.addChange(key, "", asMap(oldRaw[key]), asMap(newRaw[key]), oldRaw[key] != nil, newRaw[key] != nil)
+	}
+
+	changedQueries := map[string]bool{}
+	oldQueries := section(section(old.Raw, "reader"), "queries")
+	newQueries := section(section(new.Raw, "reader"), "queries")
+	for _, alias := range slices.Sorted(maps.Keys(union2(oldQueries, newQueries))) {
+		oq, oldOK := old.Query(alias)
+		nq, newOK := new.Query(alias)
+		if oldOK && newOK && oq == nq && reflect.DeepEqual(oldQueries[alias], newQueries[alias]) {
+			continue
+		}
+		changedQueries[alias] = true
+		p.addChange("reader.queries", alias, queryMap(oq, oldQueries[alias]), queryMap(nq, newQueries[alias]), oldOK, newOK)
+	}
+
+	changedSchedulers := map[string]bool{}
+	oldSchedulers, newSchedulers := old.rawSchedulers(), new.rawSchedulers()
+	for _, alias := range slices.Sorted(maps.Keys(union2(oldSchedulers, newSchedulers))) {
+		o, oldOK := oldSchedulers[alias]
+		n, newOK := newSchedulers[alias]
+		if oldOK && newOK && reflect.DeepEqual(o, n) {
+			continue
+		}
+		changedSchedulers[alias] = true
+		p.addChange("schedulers", alias, asMap(o), asMap(n), oldOK, newOK)
+	}
+
+	changedModels := map[string]bool{}
+	for _, alias := range slices.Sorted(slices.Values(union(modelAliases(old), modelAliases(new)))) {
+		om, oldOK := old.Model(alias)
+		nm, newOK := new.Model(alias)
+		if oldOK && newOK {
+			details := diffKeys(withoutAttachments(om.Params), withoutAttachments(nm.Params))
+			if !slices.Equal(om.Schedulers, nm.Schedulers) {
+				details = append(details, "schedulers: "+formatList(om.Schedulers)+" -> "+formatList(nm.Schedulers))
+			}
+			if len(details) > 0 {
+				changedModels[alias] = true
+			}
+			if !slices.Equal(om.Queries, nm.Queries) {
+				details = append(details, "queries: "+formatList(om.Queries)+" -> "+formatList(nm.Queries))
+			}
+			if len(details) > 0 {
+				p.Changes = append(p.Changes, Change{Kind: ChangeChanged, Section: "models", Alias: alias, Details: details})
+			}
+			continue
+		}
+		p.addChange("models", alias, om.Params, nm.Params, oldOK, newOK)
+	}
+
+	// (model, query) pairs
+	oldPairs := map[[2]string]bool{}
+	for _, m := range old.Models {
+		for _, q := range m.Queries {
+			oldPairs[[2]string{m.Alias, q}] = true
+		}
+	}
+	for _, m := range new.Models {
+		for _, q := range m.Queries {
+			pc := PairChange{Model: m.Alias, Query: q, Status: PairRestored}
+			switch {
+			case !oldPairs[[2]string{m.Alias, q}]:
+				pc.Status = PairAdded
+			case !p.RestoreState:
+				pc.Status, pc.Reason = PairRetrained, "settings.restore_state is disabled"
+			case changedModels[m.Alias]:
+				pc.Status, pc.Reason = PairRetrained, "model arguments or schedulers changed"
+			case changedQueries[q]:
+				pc.Status, pc.Reason = PairRetrained, "query changed"
+			default:
+				for _, s := range m.Schedulers {
+					if changedSchedulers[s] {
+						pc.Status, pc.Reason = PairRetrained, "scheduler "+s+" changed"
+						break
+					}
+				}
+			}
+			delete(oldPairs, [2]string{m.Alias, q})
+			p.Pairs = append(p.Pairs, pc)
+		}
+	}
+	for _, m := range old.Models {
+		for _, q := range m.Queries {
+			if oldPairs[[2]string{m.Alias, q}] {
+				p.Pairs = append(p.Pairs, PairChange{Model: m.Alias, Query: q, Status: PairRemoved, Reason: "state is dropped"})
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(readerConnection(old), readerConnection(new)) {
+		p.Warnings = append(p.Warnings, "reader connection settings changed, restored models may be inferred on data from a different datasource")
+	}
+	if restore, _ := section(old.Raw, "settings")["restore_state"].(bool); restore && !p.RestoreState {
+		p.Warnings = append(p.Warnings, "restore_state is switched off, the state database and model dumps will be removed")
+	}
+	return p
+}
+
+func (p *ReloadPreview) addChange(sectionName, alias string, old, new map[string]any, oldOK, newOK bool) {
+	switch {
+	case !oldOK && !newOK:
+	case !oldOK:
+		p.Changes = append(p.Changes, Change{Kind: ChangeAdded, Section: sectionName, Alias: alias})
+	case !newOK:
+		p.Changes = append(p.Changes, Change{Kind: ChangeRemoved, Section: sectionName, Alias: alias})
+	default:
+		if details := diffKeys(old, new); len(details) > 0 {
+			p.Changes = append(p.Changes, Change{Kind: ChangeChanged, Section: sectionName, Alias: alias, Details: details})
+		}
+	}
+}
+
+// others returns top-level sections except for aliased queries, schedulers and models,
+// reader connection settings are reported as the reader section
+func others(raw map[string]any) map[string]any {
+	result := maps.Clone(raw)
+	for _, key := range []string{"schedulers", "scheduler", "models", "model"} {
+		delete(result, key)
+	}
+	if reader := section(raw, "reader"); reader != nil {
+		reader = maps.Clone(reader)
+		delete(reader, "queries")
+		result["reader"] = reader
+	}
+	return result
+}
+
+func readerConnection(c *Config) [3]string {
+	return [3]string{c.Reader.Class, c.Reader.DatasourceURL, c.Reader.TenantID}
+}
+
+// queryMap represents a query for diffing, including the effective step
+func queryMap(q Query, raw any) map[string]any {
+	m, _ := raw.(map[string]any)
+	m = maps.Clone(m)
+	if m == nil {
+		m = map[string]any{}
+	}
+	m["expr"] = q.Expr
+	m["step"] = q.Step
+	return m
+}
+
+// asMap returns a section as a map, scalar values are wrapped under the "value" key
+func asMap(v any) map[string]any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		return v
+	default:
+		return map[string]any{"value": v}
+	}
+}
+
+func union2(a, b map[string]any) map[string]any {
+	result := maps.Clone(a)
+	if result == nil {
+		result = map[string]any{}
+	}
+	maps.Copy(result, b)
+	return result
+}
+
+func modelAliases(c *Config) []string {
+	aliases := make([]string, 0, len(c.Models))
+	for _, m := range c.Models {
+		aliases = append(aliases, m.Alias)
+	}
+	return aliases
+}
+
+func formatList(items []string) string {
+	return "[" + strings.Join(items, ", ") + "]"
+}
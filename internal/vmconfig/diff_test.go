@@ -0,0 +1,64 @@
+package vmconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := mustParse(t, testConfig+"settings:\n  restore_state: true\n")
+	updated := mustParse(t, strings.NewReplacer(
+		"sum(rate(node_cpu_seconds_total[5m])) by (mode)", "sum(rate(node_cpu_seconds_total[10m])) by (mode)",
+		"    queries: [rps]\n", "    queries: [rps]\n    z_threshold: 3.5\n",
+		"env: prod", "env: dev",
+		"    rps:\n", "    errors: sum(rate(http_errors_total[5m]))\n    rps:\n",
+	).Replace(testConfig)+`settings:
+  restore_state: true
+`)
+
+	p := Diff(old, updated)
+	if !p.RestoreState {
+		t.Errorf("restore_state must be detected")
+	}
+	got := map[string]string{}
+	for _, pc := range p.Pairs {
+		got[pc.Model+"/"+pc.Query] = pc.Status
+	}
+	want := map[string]string{
+		"prophet/cpu":    PairRetrained, // query changed
+		"prophet/rps":    PairRestored,
+		"prophet/errors": PairAdded,
+		"zscore/rps":     PairRetrained, // z_threshold added
+	}
+	for pair, status := range want {
+		if got[pair] != status {
+			t.Errorf("%s status = %q, want %q", pair, got[pair], status)
+		}
+	}
+
+	changes := map[string]string{}
+	for _, c := range p.Changes {
+		changes[c.Section+"/"+c.Alias] = c.Kind
+	}
+	for key, kind := range map[string]string{
+		"writer/":               ChangeChanged,
+		"reader.queries/cpu":    ChangeChanged,
+		"reader.queries/errors": ChangeAdded,
+		"models/zscore":         ChangeChanged,
+		"models/prophet":        ChangeChanged, // queries extended
+	} {
+		if changes[key] != kind {
+			t.Errorf("change %s = %q, want %q (all: %v)", key, changes[key], kind, changes)
+		}
+	}
+
+	// Without restore_state every remaining pair is retrained, removed pairs are reported
+	p = Diff(updated, mustParse(t, testConfig))
+	counts := p.Counts()
+	if counts[PairRetrained] != 3 || counts[PairRemoved] != 1 {
+		t.Errorf("counts = %v", counts)
+	}
+	if len(p.Warnings) != 1 || !strings.Contains(p.Warnings[0], "restore_state is switched off") {
+		t.Errorf("warnings = %v", p.Warnings)
+	}
+}
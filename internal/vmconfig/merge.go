@@ -0,0 +1,209 @@
+package vmconfig
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Collision is a conflicting definition of the same alias or section in merged configs
+type Collision struct {
+	Section string `json:"section"`         // e.g. reader.queries, models, writer
+	Alias   string `json:"alias,omitempty"` // Empty for whole sections
+	Sources []int  `json:"sources"`         // Indexes of conflicting configs
+	Message string `json:"message"`
+}
+
+func (c Collision) String() string {
+	name := c.Section
+	if c.Alias != "" {
+		name += "." + c.Alias
+	}
+	return fmt.Sprintf("%s: %s (configs %v)", name, c.Message, c.Sources)
+}
+
+// Merge merges sub-configs into a single global config.
+//
+// Reader queries and schedulers defined under the same alias must be identical.
+// Models defined under the same alias must be identical except for queries and schedulers,
+// which are united. All other sections (reader connection settings, writer, settings, monitoring)
+// must be equal in every config which sets them. Conflicts are reported as collisions,
+// the merged config is returned only if there are none.
+func Merge(configs []map[string]any) (map[string]any, []Collision, error) {
+	if len(configs) == 0 {
+		return nil, nil, fmt.Errorf("no configs to merge")
+	}
+	parsed := make([]*Config, len(configs))
+	for i, raw := range configs {
+		c, err := FromMap(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("config %d: %w", i, err)
+		}
+		if c.Preset != "" {
+			return nil, nil, fmt.Errorf("config %d: preset configs cannot be merged", i)
+		}
+		parsed[i] = c
+	}
+
+	var collisions []Collision
+	merged := map[string]any{}
+	origin := map[string]int{}
+	mergeValue := func(sectionName, alias string, dst map[string]any, key string, value any, i int) {
+		originKey := sectionName + "\x00" + key
+		prev, ok := dst[key]
+		switch {
+		case !ok:
+			dst[key] = value
+			origin[originKey] = i
+		case !reflect.DeepEqual(prev, value):
+			collisions = append(collisions, Collision{
+				Section: sectionName,
+				Alias:   alias,
+				Sources: []int{origin[originKey], i},
+				Message: "defined differently",
+			})
+		}
+	}
+
+	reader := map[string]any{}
+	queries := map[string]any{}
+	schedulers := map[string]any{}
+	models := map[string]map[string]any{}
+	modelOrigin := map[string]int{}
+	for i, c := range parsed {
+		raw := c.Raw
+		for _, key := range slices.Sorted(maps.Keys(raw)) {
+			switch key {
+			case "reader", "schedulers", "scheduler", "models", "model":
+			default:
+				mergeValue(key, "", merged, key, raw[key], i)
+			}
+		}
+
+		rawReader := section(raw, "reader")
+		for _, key := range slices.Sorted(maps.Keys(rawReader)) {
+			if key != "queries" {
+				mergeValue("reader."+key, "", reader, key, rawReader[key], i)
+			}
+		}
+		rawQueries := section(rawReader, "queries")
+		for _, q := range c.Reader.Queries {
+			mergeValue("reader.queries", q.Alias, queries, q.Alias, rawQueries[q.Alias], i)
+		}
+
+		rawSchedulers := c.rawSchedulers()
+		for _, s := range c.Schedulers {
+			mergeValue("schedulers", s.Alias, schedulers, s.Alias, rawSchedulers[s.Alias], i)
+		}
+
+		for _, m := range c.Models {
+			params := maps.Clone(m.Params)
+			delete(params, "queries")
+			delete(params, "schedulers")
+			prev, ok := models[m.Alias]
+			if !ok {
+				params["queries"] = slices.Clone(m.Queries)
+				params["schedulers"] = slices.Clone(m.Schedulers)
+				models[m.Alias] = params
+				modelOrigin[m.Alias] = i
+				continue
+			}
+			if diff := diffKeys(withoutAttachments(prev), params); len(diff) > 0 {
+				collisions = append(collisions, Collision{
+					Section: "models",
+					Alias:   m.Alias,
+					Sources: []int{modelOrigin[m.Alias], i},
+					Message: "defined differently: " + strings.Join(diff, ", "),
+				})
+				continue
+			}
+			prev["queries"] = union(prev["queries"].([]string), m.Queries)
+			prev["schedulers"] = union(prev["schedulers"].([]string), m.Schedulers)
+		}
+	}
+	if len(collisions) > 0 {
+		return nil, collisions, nil
+	}
+
+	reader["queries"] = queries
+	merged["reader"] = reader
+	merged["schedulers"] = schedulers
+	mergedModels := make(map[string]any, len(models))
+	for alias, params := range models {
+		params["queries"] = sortedAny(params["queries"].([]string))
+		params["schedulers"] = sortedAny(params["schedulers"].([]string))
+		mergedModels[alias] = params
+	}
+	merged["models"] = mergedModels
+
+	if _, err := FromMap(merged); err != nil {
+		return nil, nil, fmt.Errorf("merged config is invalid: %w", err)
+	}
+	return merged, nil, nil
+}
+
+// ExtraPairs returns (query, scheduler) pairs each model runs on in the merged config,
+// which none of the source configs ran it on. Uniting queries and schedulers of a model
+// may produce such pairs when sub-configs select different schedulers for different queries.
+func ExtraPairs(merged *Config, sources []*Config) map[string][]string {
+	expected := map[string]bool{}
+	for _, c := range sources {
+		for _, m := range c.Models {
+			for _, q := range m.Queries {
+				for _, s := range m.Schedulers {
+					expected[m.Alias+"\x00"+q+"\x00"+s] = true
+				}
+			}
+		}
+	}
+	extra := map[string][]string{}
+	for _, m := range merged.Models {
+		for _, q := range m.Queries {
+			for _, s := range m.Schedulers {
+				if !expected[m.Alias+"\x00"+q+"\x00"+s] {
+					extra[m.Alias] = append(extra[m.Alias], q+"/"+s)
+				}
+			}
+		}
+	}
+	return extra
+}
+
+func withoutAttachments(params map[string]any) map[string]any {
+	params = maps.Clone(params)
+	delete(params, "queries")
+	delete(params, "schedulers")
+	return params
+}
+
+func union(a, b []string) []string {
+	result := slices.Clone(a)
+	for _, item := range b {
+		if !slices.Contains(result, item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// diffKeys returns sorted descriptions of keys which differ between two sections
+func diffKeys(old, new map[string]any) []string {
+	var diff []string
+	for _, key := range slices.Sorted(maps.Keys(old)) {
+		v, ok := new[key]
+		switch {
+		case !ok:
+			diff = append(diff, fmt.Sprintf("%s removed", key))
+		case !reflect.DeepEqual(old[key], v):
+			diff = append(diff, fmt.Sprintf("%s: %v -> %v", key, old[key], v))
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(new)) {
+		if _, ok := old[key]; !ok {
+			diff = append(diff, fmt.Sprintf("%s: %v added", key, new[key]))
+		}
+	}
+	return diff
+}
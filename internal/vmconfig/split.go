@@ -0,0 +1,191 @@
+package vmconfig
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Entities used to split a global config into sub-configs, see
+// https://docs.victoriametrics.com/anomaly-detection/scaling-vmanomaly/#sub-configuration
+const (
+	SplitComplete     = "complete" // 1 model on 1 query attached to 1 scheduler per sub-config
+	SplitByQueries    = "queries"
+	SplitByModels     = "models"
+	SplitBySchedulers = "schedulers"
+)
+
+// Selection selects queries and schedulers of a model for a sub-config
+type Selection struct {
+	Queries    []string
+	Schedulers []string
+}
+
+// SubConfig is a standalone part of a global config
+type SubConfig struct {
+	Name   string         `json:"name"`
+	Config map[string]any `json:"config"`
+}
+
+// Subset builds a standalone config containing only the selected models, queries and schedulers.
+// Unknown sections like settings and monitoring are preserved, flat scheduler and model sections
+// are converted to aliased ones. Empty selection lists select all queries or schedulers of a model.
+func (c *Config) Subset(models map[string]Selection) (map[string]any, error) {
+	raw := maps.Clone(c.Raw)
+	delete(raw, "scheduler")
+	delete(raw, "model")
+
+	rawQueries := section(section(c.Raw, "reader"), "queries")
+	rawSchedulers := c.rawSchedulers()
+	queries := map[string]any{}
+	schedulers := map[string]any{}
+	subModels := make(map[string]any, len(models))
+	for alias, sel := range models {
+		m, ok := c.Model(alias)
+		if !ok {
+			return nil, fmt.Errorf("unknown model alias %q", alias)
+		}
+		if len(sel.Queries) == 0 {
+			sel.Queries = m.Queries
+		}
+		if len(sel.Schedulers) == 0 {
+			sel.Schedulers = m.Schedulers
+		}
+		for _, q := range sel.Queries {
+			if !slices.Contains(m.Queries, q) {
+				return nil, fmt.Errorf("model %s is not attached to query %q", alias, q)
+			}
+			queries[q] = rawQueries[q]
+		}
+		for _, s := range sel.Schedulers {
+			if !slices.Contains(m.Schedulers, s) {
+				return nil, fmt.Errorf("model %s is not attached to scheduler %q", alias, s)
+			}
+			schedulers[s] = rawSchedulers[s]
+		}
+		params := maps.Clone(m.Params)
+		params["queries"] = sortedAny(sel.Queries)
+		params["schedulers"] = sortedAny(sel.Schedulers)
+		subModels[alias] = params
+	}
+
+	reader := maps.Clone(section(c.Raw, "reader"))
+	reader["queries"] = queries
+	raw["reader"] = reader
+	raw["schedulers"] = schedulers
+	raw["models"] = subModels
+
+	if _, err := FromMap(raw); err != nil {
+		return nil, fmt.Errorf("invalid sub-config: %w", err)
+	}
+	return raw, nil
+}
+
+// Split splits the config into sub-configs by the given entity, one of Split* constants.
+// Sub-configs are ordered by aliases, so the same config is always split the same way.
+func Split(c *Config, by string) ([]SubConfig, error) {
+	if c.Preset != "" {
+		return nil, fmt.Errorf("preset configs cannot be split")
+	}
+	var parts []struct {
+		name   string
+		models map[string]Selection
+	}
+	add := func(name string, models map[string]Selection) {
+		if len(models) > 0 {
+			parts = append(parts, struct {
+				name   string
+				models map[string]Selection
+			}{name, models})
+		}
+	}
+
+	switch by {
+	case SplitComplete, "":
+		for _, m := range c.Models {
+			for _, q := range m.Queries {
+				for _, s := range m.Schedulers {
+					add(fmt.Sprintf("%s-%s-%s", m.Alias, q, s), map[string]Selection{m.Alias: {Queries: []string{q}, Schedulers: []string{s}}})
+				}
+			}
+		}
+	case SplitByQueries:
+		for _, q := range c.Reader.Queries {
+			models := map[string]Selection{}
+			for _, m := range c.Models {
+				if slices.Contains(m.Queries, q.Alias) {
+					models[m.Alias] = Selection{Queries: []string{q.Alias}}
+				}
+			}
+			add(q.Alias, models)
+		}
+	case SplitByModels:
+		for _, m := range c.Models {
+			add(m.Alias, map[string]Selection{m.Alias: {}})
+		}
+	case SplitBySchedulers:
+		for _, s := range c.Schedulers {
+			models := map[string]Selection{}
+			for _, m := range c.Models {
+				if slices.Contains(m.Schedulers, s.Alias) {
+					models[m.Alias] = Selection{Schedulers: []string{s.Alias}}
+				}
+			}
+			add(s.Alias, models)
+		}
+	default:
+		return nil, fmt.Errorf("unknown split entity %q, expected one of %s, %s, %s, %s",
+			by, SplitComplete, SplitByQueries, SplitByModels, SplitBySchedulers)
+	}
+
+	subs := make([]SubConfig, 0, len(parts))
+	for _, p := range parts {
+		cfg, err := c.Subset(p.models)
+		if err != nil {
+			return nil, fmt.Errorf("sub-config %s: %w", p.name, err)
+		}
+		subs = append(subs, SubConfig{Name: p.name, Config: cfg})
+	}
+	return subs, nil
+}
+
+// AssignShards distributes sub-configs between shards the same way vmanomaly does with
+// VMANOMALY_MEMBERS_COUNT and VMANOMALY_REPLICATION_FACTOR: sub-config i is assigned to
+// shards i, i+1, ..., i+replication-1 modulo shards. It returns sub-config indexes per shard.
+func AssignShards(subConfigs, shards, replication int) ([][]int, error) {
+	if shards < 1 {
+		return nil, fmt.Errorf("number of shards must be positive")
+	}
+	if replication < 1 {
+		replication = 1
+	}
+	if replication > shards {
+		return nil, fmt.Errorf("replication factor %d exceeds the number of shards %d", replication, shards)
+	}
+	assigned := make([][]int, shards)
+	for i := range subConfigs {
+		for r := range replication {
+			shard := (i + r) % shards
+			assigned[shard] = append(assigned[shard], i)
+		}
+	}
+	return assigned, nil
+}
+
+// rawSchedulers returns raw scheduler sections by alias, including the flat `scheduler` section
+func (c *Config) rawSchedulers() map[string]any {
+	schedulers := section(c.Raw, "schedulers")
+	if flat := section(c.Raw, "scheduler"); len(flat) > 0 && len(schedulers) == 0 {
+		schedulers = map[string]any{DefaultSchedulerAlias: flat}
+	}
+	return schedulers
+}
+
+func sortedAny(items []string) []any {
+	sorted := slices.Sorted(slices.Values(items))
+	result := make([]any, len(sorted))
+	for i, item := range sorted {
+		result[i] = item
+	}
+	return result
+}
@@ -0,0 +1,140 @@
+package vmconfig
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func mustParse(t *testing.T, data string) *Config {
+	t.Helper()
+	c, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return c
+}
+
+func TestSplit(t *testing.T) {
+	c := mustParse(t, testConfig)
+	tests := []struct {
+		by    string
+		names []string
+	}{
+		{SplitComplete, []string{"prophet-cpu-s1", "prophet-cpu-s2", "prophet-rps-s1", "prophet-rps-s2", "zscore-rps-s2"}},
+		{SplitByQueries, []string{"cpu", "rps"}},
+		{SplitByModels, []string{"prophet", "zscore"}},
+		{SplitBySchedulers, []string{"s1", "s2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			subs, err := Split(c, tt.by)
+			if err != nil {
+				t.Fatalf("Split() error = %v", err)
+			}
+			var names []string
+			for _, sub := range subs {
+				names = append(names, sub.Name)
+				if _, err := FromMap(sub.Config); err != nil {
+					t.Errorf("sub-config %s is invalid: %v", sub.Name, err)
+				}
+				if _, ok := sub.Config["writer"]; !ok {
+					t.Errorf("sub-config %s lost writer section", sub.Name)
+				}
+			}
+			if !slices.Equal(names, tt.names) {
+				t.Errorf("names = %v, want %v", names, tt.names)
+			}
+
+			// Merging sub-configs back restores the original config
+			configs := make([]map[string]any, len(subs))
+			for i, sub := range subs {
+				configs[i] = sub.Config
+			}
+			merged, collisions, err := Merge(configs)
+			if err != nil || len(collisions) > 0 {
+				t.Fatalf("Merge() = %v, %v", collisions, err)
+			}
+			mc, err := FromMap(merged)
+			if err != nil {
+				t.Fatalf("merged config is invalid: %v", err)
+			}
+			if !reflect.DeepEqual(mc.Models[0].Queries, c.Models[0].Queries) || !reflect.DeepEqual(mc.Schedulers, c.Schedulers) {
+				t.Errorf("merged config differs from the original: %+v", mc)
+			}
+		})
+	}
+
+	if _, err := Split(c, "extra_filters"); err == nil {
+		t.Error("expected error for unknown split entity")
+	}
+	if c.Raw["models"].(map[string]any)["prophet"].(map[string]any)["queries"] != nil {
+		t.Error("original config must not be modified")
+	}
+}
+
+func TestAssignShards(t *testing.T) {
+	// Example from https://docs.victoriametrics.com/anomaly-detection/scaling-vmanomaly/#high-availability
+	got, err := AssignShards(9, 3, 2)
+	if err != nil {
+		t.Fatalf("AssignShards() error = %v", err)
+	}
+	want := [][]int{{0, 2, 3, 5, 6, 8}, {0, 1, 3, 4, 6, 7}, {1, 2, 4, 5, 7, 8}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AssignShards() = %v, want %v", got, want)
+	}
+	if _, err := AssignShards(9, 2, 3); err == nil {
+		t.Error("expected error for replication factor above shards")
+	}
+}
+
+func TestMerge_Collisions(t *testing.T) {
+	a := mustParse(t, testConfig)
+	b := mustParse(t, strings.NewReplacer(
+		"sum(rate(http_requests_total[5m]))", "sum(rate(http_requests_total[1m]))",
+		"fit_window: 14d", "fit_window: 1d",
+		"class: zscore", "class: mad",
+		"env: prod", "env: dev",
+	).Replace(testConfig))
+
+	_, collisions, err := Merge([]map[string]any{a.Raw, b.Raw})
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	var got []string
+	for _, c := range collisions {
+		got = append(got, c.Section+"/"+c.Alias)
+	}
+	want := []string{"writer/", "reader.queries/rps", "schedulers/s1", "models/zscore"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collisions = %v, want %v", got, want)
+	}
+}
+
+func TestExtraPairs(t *testing.T) {
+	c := mustParse(t, testConfig)
+	a, err := c.Subset(map[string]Selection{"prophet": {Queries: []string{"cpu"}, Schedulers: []string{"s1"}}})
+	if err != nil {
+		t.Fatalf("Subset() error = %v", err)
+	}
+	b, err := c.Subset(map[string]Selection{"prophet": {Queries: []string{"rps"}, Schedulers: []string{"s2"}}})
+	if err != nil {
+		t.Fatalf("Subset() error = %v", err)
+	}
+	merged, _, err := Merge([]map[string]any{a, b})
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	mc, _ := FromMap(merged)
+	ac, _ := FromMap(a)
+	bc, _ := FromMap(b)
+	extra := ExtraPairs(mc, []*Config{ac, bc})
+	if !slices.Equal(extra["prophet"], []string{"cpu/s2", "rps/s1"}) {
+		t.Errorf("ExtraPairs() = %v", extra)
+	}
+
+	if _, err := c.Subset(map[string]Selection{"zscore": {Queries: []string{"cpu"}}}); err == nil {
+		t.Error("expected error for a query the model is not attached to")
+	}
+}
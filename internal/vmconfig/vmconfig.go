@@ -0,0 +1,346 @@
+// Package vmconfig parses vmanomaly service configs into a normalized form.
+//
+// Backward compatible forms are converted the same way vmanomaly does it:
+// flat `scheduler` and `model` sections become `default_scheduler` and `default_model` aliases,
+// string queries become {expr: ...} with step taken from reader `sampling_period`,
+// and models without `queries`/`schedulers` args are attached to all queries/schedulers.
+// See https://docs.victoriametrics.com/anomaly-detection/components/ for the format.
+package vmconfig
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Aliases used by vmanomaly for old-style flat sections
+const (
+	DefaultSchedulerAlias = "default_scheduler"
+	DefaultModelAlias     = "default_model"
+)
+
+// Config is a normalized vmanomaly config
+type Config struct {
+	Preset     string      `json:"preset,omitempty"`
+	Reader     Reader      `json:"reader"`
+	Schedulers []Scheduler `json:"schedulers"` // Sorted by alias
+	Models     []Model     `json:"models"`     // Sorted by alias
+	Writer     Writer      `json:"writer"`
+
+	// Raw is the original config, used to produce derived configs without losing unknown args
+	Raw map[string]any `json:"-"`
+}
+
+// Reader is the reader section of the config
+type Reader struct {
+	Class          string  `json:"class,omitempty"`
+	DatasourceURL  string  `json:"datasource_url,omitempty"`
+	TenantID       string  `json:"tenant_id,omitempty"`
+	SamplingPeriod string  `json:"sampling_period,omitempty"`
+	Queries        []Query `json:"queries"` // Sorted by alias
+}
+
+// Query is a reader query
+type Query struct {
+	Alias string `json:"alias"`
+	Expr  string `json:"expr"`
+	Step  string `json:"step,omitempty"` // Query step, falls back to reader sampling_period
+}
+
+// Scheduler is a scheduler of the config
+type Scheduler struct {
+	Alias      string `json:"alias"`
+	Class      string `json:"class,omitempty"`
+	InferEvery string `json:"infer_every,omitempty"`
+	FitEvery   string `json:"fit_every,omitempty"` // Falls back to infer_every
+	FitWindow  string `json:"fit_window,omitempty"`
+}
+
+// Model is a model of the config
+type Model struct {
+	Alias              string         `json:"alias"`
+	Class              string         `json:"class"`
+	Queries            []string       `json:"queries"`
+	Schedulers         []string       `json:"schedulers"`
+	DetectionDirection string         `json:"detection_direction,omitempty"`
+	ProvideSeries      []string       `json:"provide_series,omitempty"`
+	Params             map[string]any `json:"params,omitempty"` // Full model section, including the args above
+}
+
+// Writer is the writer section of the config
+type Writer struct {
+	DatasourceURL string            `json:"datasource_url,omitempty"`
+	TenantID      string            `json:"tenant_id,omitempty"`
+	MetricFormat  map[string]string `json:"metric_format,omitempty"`
+}
+
+// Parse parses vmanomaly config in YAML (or JSON) format
+func Parse(data []byte) (*Config, error) {
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("cannot parse config: %w", err)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("config is empty")
+	}
+	return FromMap(raw)
+}
+
+// FromMap builds normalized config from a decoded vmanomaly config
+func FromMap(raw map[string]any) (*Config, error) {
+	c := &Config{Raw: raw}
+	c.Preset, _ = raw["preset"].(string)
+
+	if err := c.parseReader(section(raw, "reader")); err != nil {
+		return nil, err
+	}
+	if err := c.parseSchedulers(raw); err != nil {
+		return nil, err
+	}
+	if err := c.parseModels(raw); err != nil {
+		return nil, err
+	}
+	c.parseWriter(section(raw, "writer"))
+
+	if c.Preset != "" {
+		return c, nil
+	}
+	if len(c.Reader.Queries) == 0 {
+		return nil, fmt.Errorf("reader.queries must contain at least one query")
+	}
+	if len(c.Schedulers) == 0 {
+		return nil, fmt.Errorf("schedulers section must contain at least one scheduler")
+	}
+	if len(c.Models) == 0 {
+		return nil, fmt.Errorf("models section must contain at least one model")
+	}
+	return c, nil
+}
+
+func (c *Config) parseReader(reader map[string]any) error {
+	c.Reader = Reader{
+		Class:          str(reader["class"]),
+		DatasourceURL:  str(reader["datasource_url"]),
+		TenantID:       str(reader["tenant_id"]),
+		SamplingPeriod: str(reader["sampling_period"]),
+	}
+	queries, _ := reader["queries"].(map[string]any)
+	for _, alias := range slices.Sorted(maps.Keys(queries)) {
+		q := Query{Alias: alias}
+		switch v := queries[alias].(type) {
+		case string:
+			q.Expr = v
+		case map[string]any:
+			q.Expr = str(v["expr"])
+			q.Step = str(v["step"])
+		default:
+			return fmt.Errorf("reader.queries.%s: expected expression or mapping, got %T", alias, v)
+		}
+		if strings.TrimSpace(q.Expr) == "" {
+			return fmt.Errorf("reader.queries.%s: expr must be set", alias)
+		}
+		if q.Step == "" {
+			q.Step = c.Reader.SamplingPeriod
+		}
+		c.Reader.Queries = append(c.Reader.Queries, q)
+	}
+	return nil
+}
+
+func (c *Config) parseSchedulers(raw map[string]any) error {
+	schedulers := section(raw, "schedulers")
+	if flat := section(raw, "scheduler"); len(flat) > 0 && len(schedulers) == 0 {
+		schedulers = map[string]any{DefaultSchedulerAlias: flat}
+	}
+	for _, alias := range slices.Sorted(maps.Keys(schedulers)) {
+		s, ok := schedulers[alias].(map[string]any)
+		if !ok {
+			return fmt.Errorf("schedulers.%s: expected mapping, got %T", alias, schedulers[alias])
+		}
+		sched := Scheduler{
+			Alias:      alias,
+			Class:      str(s["class"]),
+			InferEvery: str(s["infer_every"]),
+			FitEvery:   str(s["fit_every"]),
+			FitWindow:  str(s["fit_window"]),
+		}
+		if sched.FitEvery == "" {
+			sched.FitEvery = sched.InferEvery
+		}
+		c.Schedulers = append(c.Schedulers, sched)
+	}
+	return nil
+}
+
+func (c *Config) parseModels(raw map[string]any) error {
+	models := section(raw, "models")
+	if flat := section(raw, "model"); len(flat) > 0 && len(models) == 0 {
+		models = map[string]any{DefaultModelAlias: flat}
+	}
+	for _, alias := range slices.Sorted(maps.Keys(models)) {
+		m, ok := models[alias].(map[string]any)
+		if !ok {
+			return fmt.Errorf("models.%s: expected mapping, got %T", alias, models[alias])
+		}
+		model := Model{
+			Alias:              alias,
+			Class:              str(m["class"]),
+			Queries:            strList(m["queries"]),
+			Schedulers:         strList(m["schedulers"]),
+			DetectionDirection: str(m["detection_direction"]),
+			ProvideSeries:      strList(m["provide_series"]),
+			Params:             m,
+		}
+		if model.Class == "" {
+			return fmt.Errorf("models.%s: class must be set", alias)
+		}
+		if len(model.Queries) == 0 {
+			model.Queries = c.QueryAliases()
+		}
+		if len(model.Schedulers) == 0 {
+			model.Schedulers = c.SchedulerAliases()
+		}
+		for _, q := range model.Queries {
+			if _, ok := c.Query(q); !ok {
+				return fmt.Errorf("models.%s: unknown query alias %q", alias, q)
+			}
+		}
+		for _, s := range model.Schedulers {
+			if _, ok := c.Scheduler(s); !ok {
+				return fmt.Errorf("models.%s: unknown scheduler alias %q", alias, s)
+			}
+		}
+		c.Models = append(c.Models, model)
+	}
+	return nil
+}
+
+func (c *Config) parseWriter(writer map[string]any) {
+	c.Writer = Writer{
+		DatasourceURL: str(writer["datasource_url"]),
+		TenantID:      str(writer["tenant_id"]),
+	}
+	if mf, ok := writer["metric_format"].(map[string]any); ok {
+		c.Writer.MetricFormat = make(map[string]string, len(mf))
+		for k, v := range mf {
+			c.Writer.MetricFormat[k] = str(v)
+		}
+	}
+}
+
+// QueryAliases returns sorted aliases of reader queries
+func (c *Config) QueryAliases() []string {
+	aliases := make([]string, 0, len(c.Reader.Queries))
+	for _, q := range c.Reader.Queries {
+		aliases = append(aliases, q.Alias)
+	}
+	return aliases
+}
+
+// SchedulerAliases returns sorted aliases of schedulers
+func (c *Config) SchedulerAliases() []string {
+	aliases := make([]string, 0, len(c.Schedulers))
+	for _, s := range c.Schedulers {
+		aliases = append(aliases, s.Alias)
+	}
+	return aliases
+}
+
+// Query returns a reader query by its alias
+func (c *Config) Query(alias string) (Query, bool) {
+	for _, q := range c.Reader.Queries {
+		if q.Alias == alias {
+			return q, true
+		}
+	}
+	return Query{}, false
+}
+
+// Scheduler returns a scheduler by its alias
+func (c *Config) Scheduler(alias string) (Scheduler, bool) {
+	for _, s := range c.Schedulers {
+		if s.Alias == alias {
+			return s, true
+		}
+	}
+	return Scheduler{}, false
+}
+
+// Model returns a model by its alias
+func (c *Config) Model(alias string) (Model, bool) {
+	for _, m := range c.Models {
+		if m.Alias == alias {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
+
+// MetricName returns the name of a produced series, e.g. anomaly_score,
+// taking writer metric_format `__name__` into account
+func (w Writer) MetricName(series string) string {
+	format := w.MetricFormat["__name__"]
+	if format == "" {
+		format = "$VAR"
+	}
+	return strings.ReplaceAll(format, "$VAR", series)
+}
+
+// QueryLabel returns the label holding the query alias of produced series and its value for the given alias.
+// vmanomaly uses `for: $QUERY_KEY` by default.
+func (w Writer) QueryLabel(alias string) (string, string) {
+	for _, name := range slices.Sorted(maps.Keys(w.MetricFormat)) {
+		if value := w.MetricFormat[name]; strings.Contains(value, "$QUERY_KEY") {
+			return name, strings.ReplaceAll(value, "$QUERY_KEY", alias)
+		}
+	}
+	return "for", alias
+}
+
+// ExtraLabels returns static labels added to produced series by writer metric_format
+func (w Writer) ExtraLabels() map[string]string {
+	labels := make(map[string]string)
+	for name, value := range w.MetricFormat {
+		if name == "__name__" || strings.Contains(value, "$") {
+			continue
+		}
+		labels[name] = value
+	}
+	return labels
+}
+
+func section(raw map[string]any, name string) map[string]any {
+	s, _ := raw[name].(map[string]any)
+	return s
+}
+
+func str(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func strList(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			result = append(result, str(item))
+		}
+		return result
+	case []string:
+		return v
+	default:
+		return nil
+	}
+}
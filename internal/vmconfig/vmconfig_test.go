@@ -0,0 +1,132 @@
+package vmconfig
+
+import (
+	"slices"
+	"testing"
+)
+
+const testConfig = `
+reader:
+  class: vm
+  datasource_url: http://victoriametrics:8428
+  sampling_period: 1m
+  queries:
+    cpu: sum(rate(node_cpu_seconds_total[5m])) by (mode)
+    rps:
+      expr: sum(rate(http_requests_total[5m]))
+      step: 30s
+schedulers:
+  s1:
+    infer_every: 1m
+    fit_window: 14d
+  s2:
+    infer_every: 5m
+    fit_every: 1h
+    fit_window: 7d
+models:
+  zscore:
+    class: zscore
+    queries: [rps]
+    schedulers: [s2]
+  prophet:
+    class: prophet
+writer:
+  datasource_url: http://victoriametrics:8428
+  metric_format:
+    __name__: vmanomaly_$VAR
+    for: q_$QUERY_KEY
+    env: prod
+`
+
+func TestParse(t *testing.T) {
+	c, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if !slices.Equal(c.QueryAliases(), []string{"cpu", "rps"}) {
+		t.Errorf("QueryAliases() = %v", c.QueryAliases())
+	}
+	if q, _ := c.Query("cpu"); q.Step != "1m" {
+		t.Errorf("cpu step = %q, want sampling_period", q.Step)
+	}
+	if q, _ := c.Query("rps"); q.Step != "30s" {
+		t.Errorf("rps step = %q, want 30s", q.Step)
+	}
+	if s, _ := c.Scheduler("s1"); s.FitEvery != "1m" {
+		t.Errorf("s1 fit_every = %q, want infer_every", s.FitEvery)
+	}
+
+	prophet, ok := c.Model("prophet")
+	if !ok {
+		t.Fatal("prophet model not found")
+	}
+	if !slices.Equal(prophet.Queries, []string{"cpu", "rps"}) || !slices.Equal(prophet.Schedulers, []string{"s1", "s2"}) {
+		t.Errorf("implicit queries/schedulers not applied: %+v", prophet)
+	}
+
+	if name := c.Writer.MetricName("anomaly_score"); name != "vmanomaly_anomaly_score" {
+		t.Errorf("MetricName() = %q", name)
+	}
+	if label, value := c.Writer.QueryLabel("cpu"); label != "for" || value != "q_cpu" {
+		t.Errorf("QueryLabel() = %q, %q", label, value)
+	}
+	if extra := c.Writer.ExtraLabels(); len(extra) != 1 || extra["env"] != "prod" {
+		t.Errorf("ExtraLabels() = %v", extra)
+	}
+}
+
+func TestParse_OldStyle(t *testing.T) {
+	c, err := Parse([]byte(`
+reader:
+  queries:
+    q: up
+scheduler:
+  infer_every: 1m
+model:
+  class: zscore
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if _, ok := c.Scheduler(DefaultSchedulerAlias); !ok {
+		t.Errorf("expected %s, got %+v", DefaultSchedulerAlias, c.Schedulers)
+	}
+	m, ok := c.Model(DefaultModelAlias)
+	if !ok || !slices.Equal(m.Schedulers, []string{DefaultSchedulerAlias}) {
+		t.Errorf("expected %s attached to %s, got %+v", DefaultModelAlias, DefaultSchedulerAlias, c.Models)
+	}
+	if name := c.Writer.MetricName("anomaly_score"); name != "anomaly_score" {
+		t.Errorf("MetricName() = %q", name)
+	}
+	if label, value := c.Writer.QueryLabel("q"); label != "for" || value != "q" {
+		t.Errorf("QueryLabel() = %q, %q", label, value)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]string{
+		"empty":           ``,
+		"no queries":      "schedulers: {s: {infer_every: 1m}}\nmodels: {m: {class: zscore}}",
+		"unknown query":   "reader: {queries: {q: up}}\nschedulers: {s: {infer_every: 1m}}\nmodels: {m: {class: zscore, queries: [x]}}",
+		"unknown sched":   "reader: {queries: {q: up}}\nschedulers: {s: {infer_every: 1m}}\nmodels: {m: {class: zscore, schedulers: [x]}}",
+		"no model class":  "reader: {queries: {q: up}}\nschedulers: {s: {infer_every: 1m}}\nmodels: {m: {queries: [q]}}",
+		"empty expr":      "reader: {queries: {q: {step: 1m}}}\nschedulers: {s: {infer_every: 1m}}\nmodels: {m: {class: zscore}}",
+		"invalid yaml":    "reader: [",
+		"no models":       "reader: {queries: {q: up}}\nschedulers: {s: {infer_every: 1m}}",
+		"bad scheduler":   "reader: {queries: {q: up}}\nschedulers: {s: 1}\nmodels: {m: {class: zscore}}",
+		"bad query value": "reader: {queries: {q: [1]}}\nschedulers: {s: {infer_every: 1m}}\nmodels: {m: {class: zscore}}",
+	}
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse([]byte(config)); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+
+	c, err := Parse([]byte(`preset: node-exporter`))
+	if err != nil || c.Preset != "node-exporter" {
+		t.Errorf("preset config should be accepted, got %v, %v", c, err)
+	}
+}
@@ -3,26 +3,43 @@ set -e
 set -o pipefail
 
 # Update vmanomaly documentation from VictoriaMetrics docs repository
+#
+# Usage:
+#   scripts/update-docs.sh                    - update the latest docs
+#   scripts/update-docs.sh <version> <ref>    - add docs of vmanomaly <version> (e.g. v1.24.0)
+#                                               from the given commit or branch of the docs repository
+
+VERSION=${1:-}
+REF=${2:-main}
+ROOT=$(pwd -P)
+DEST="$ROOT/internal/resources/docs"
+if [ -n "$VERSION" ]; then
+  if [ -z "$2" ]; then
+    echo "❌ Docs repository ref is required for a versioned docs set"
+    exit 1
+  fi
+  DEST="$ROOT/internal/resources/docs/versions/$VERSION"
+fi
 
 # Remove existing docs
-rm -rf internal/resources/docs/anomaly-detection
+rm -rf "$DEST/anomaly-detection"
 
 # Clone VictoriaMetrics docs repository with sparse checkout
-git clone --no-checkout --depth=1 https://github.com/VictoriaMetrics/vmdocs.git /tmp/vmdocs-temp
+git clone --no-checkout --filter=blob:none https://github.com/VictoriaMetrics/vmdocs.git /tmp/vmdocs-temp
 cd /tmp/vmdocs-temp
 
 # Setup sparse checkout to get only anomaly-detection folder
 git sparse-checkout init --cone
 git sparse-checkout set content/docs/anomaly-detection
-git checkout main
+git checkout "$REF"
 
 # Copy anomaly-detection docs to our resources
-mkdir -p $(pwd -P | sed 's|/tmp/vmdocs-temp||')/internal/resources/docs
-cp -r content/docs/anomaly-detection $(pwd -P | sed 's|/tmp/vmdocs-temp||')/internal/resources/docs/
+mkdir -p "$DEST"
+cp -r content/docs/anomaly-detection "$DEST/"
 
 # Cleanup
 cd -
 rm -rf /tmp/vmdocs-temp
 
 echo "✅ Documentation updated successfully!"
-echo "📁 Location: internal/resources/docs/anomaly-detection"
+echo "📁 Location: $DEST/anomaly-detection"